@@ -0,0 +1,51 @@
+// Package api - Git clone status
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/git"
+)
+
+// GitStatusPath is the git status endpoint, relative to the API base path
+const GitStatusPath = "/api/git/status"
+
+// GitStatusProvider reports the clone status of each --repo
+// Implemented by git.StatusTracker
+type GitStatusProvider interface {
+	Statuses() []git.RepoStatus
+}
+
+// SetGitStatus reports the given clone status from the git status endpoint
+func (h *LogsHandler) SetGitStatus(status GitStatusProvider) {
+	h.gitStatus = status
+}
+
+// HandleGetGitStatus returns the clone status of each repository
+// GET /api/git/status
+//
+// Each entry has state pending, done or error, the commit SHA once cloned and the
+// error of a failed clone. repos is empty when no --repo is configured.
+func (h *LogsHandler) HandleGetGitStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	repos := []git.RepoStatus{}
+	if h.gitStatus != nil {
+		repos = h.gitStatus.Statuses()
+	}
+	for i := range repos {
+		repos[i].URL = h.redactor.String(repos[i].URL)
+		repos[i].Error = h.redactor.String(repos[i].Error)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"repos": repos,
+	}); err != nil {
+		h.logger.Error("failed to encode git status response", err)
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/git"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+func TestHandleGetGitStatus(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"true"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() { _ = mgr.CloseLogFile() }()
+
+	h := NewLogsHandler(mgr, log)
+	getRepos := func(t *testing.T) []git.RepoStatus {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.HandleGetGitStatus(rec, httptest.NewRequest(http.MethodGet, GitStatusPath, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		var resp struct {
+			Repos []git.RepoStatus `json:"repos"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("invalid git status response: %v", err)
+		}
+		return resp.Repos
+	}
+
+	t.Run("no repositories", func(t *testing.T) {
+		if repos := getRepos(t); len(repos) != 0 {
+			t.Errorf("expected no repos, got %v", repos)
+		}
+	})
+
+	status := git.NewStatusTracker()
+	app := status.Add("https://ghp_token@github.com/org/app", "main", "/srv/app", false)
+	data := status.Add("https://github.com/org/data", "v2", "/srv/data", true)
+	status.Add("https://github.com/org/docs", "main", "/srv/docs", false)
+	h.SetGitStatus(status)
+
+	status.SetDone(app, "0123abcd")
+	status.SetError(data, errors.New("repository not found"))
+
+	repos := getRepos(t)
+	if len(repos) != 3 {
+		t.Fatalf("expected 3 repos, got %d", len(repos))
+	}
+	if repos[0].State != git.RepoDone || repos[0].Commit != "0123abcd" {
+		t.Errorf("expected app done at 0123abcd, got %+v", repos[0])
+	}
+	if repos[0].URL != "https://github.com/org/app" {
+		t.Errorf("expected credentials removed from URL, got %q", repos[0].URL)
+	}
+	if repos[1].State != git.RepoError || repos[1].Error != "repository not found" || !repos[1].Optional {
+		t.Errorf("expected optional data repo in error, got %+v", repos[1])
+	}
+	if repos[2].State != git.RepoPending {
+		t.Errorf("expected docs pending, got %+v", repos[2])
+	}
+}
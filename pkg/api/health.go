@@ -0,0 +1,183 @@
+// Package api - Composite health endpoint
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/health"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+// HealthPath is the composite health endpoint, relative to the interim base path
+// Unauthenticated and reachable while the app is running, so it can back a readiness probe
+const HealthPath = "/api/health"
+
+// defaultHubReportMaxAge is how recent a successful activity report must be to count as hub
+// reachability without pinging the hub, when SetHubChecker is given no maximum age
+// (twice the default activity reporting interval)
+const defaultHubReportMaxAge = 10 * time.Minute
+
+// hubPingTimeout bounds the hub ping made when there is no recent activity report
+const hubPingTimeout = 5 * time.Second
+
+// Component statuses reported by the health endpoint
+const (
+	healthStatusOK       = "ok"
+	healthStatusDown     = "down"
+	healthStatusDisabled = "disabled" // Not configured, ignored for overall health
+)
+
+// BackendChecker checks whether the app answers its ready check
+// Implemented by health.Checker
+type BackendChecker interface {
+	Check(ctx context.Context) error
+	LastResult() health.Result
+}
+
+// HubChecker reports JupyterHub reachability
+// Implemented by hub.Client
+type HubChecker interface {
+	Ping(ctx context.Context) error
+	LastActivityReport() time.Time
+}
+
+// SetHealthChecker lets the health endpoint check the app with its ready check
+func (h *LogsHandler) SetHealthChecker(checker BackendChecker) {
+	h.healthChecker = checker
+}
+
+// SetHubChecker lets the health endpoint report JupyterHub reachability (nil = hub not configured)
+// An activity report younger than maxReportAge counts as reachable without a ping; it should
+// cover the reporting interval plus jitter (0 = defaultHubReportMaxAge).
+func (h *LogsHandler) SetHubChecker(checker HubChecker, maxReportAge time.Duration) {
+	h.hubChecker = checker
+	h.hubReportMaxAge = maxReportAge
+}
+
+// HandleGetHealth summarizes the health of the app, the proxy and the JupyterHub connection
+// GET /api/health
+//
+// Returns 200 if every configured component is healthy and 503 otherwise, with the
+// process state at the top level for simple probes. The endpoint is unauthenticated, so
+// neither logs nor internal details (backend URL, error messages) are included; errors
+// are logged at debug level instead:
+//
+//	backend:      process state (healthy when running)
+//	health_check: ready check result, re-run on each request while the app is running
+//	hub:          last successful activity report, or a fresh ping if there is none recently
+//	proxy:        always ok while the proxy answers
+func (h *LogsHandler) HandleGetHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state := h.manager.GetState()
+	backend := map[string]interface{}{
+		"status": healthStatusDown,
+		"state":  state,
+		"pid":    h.manager.GetPID(),
+	}
+	if state == process.StateRunning {
+		backend["status"] = healthStatusOK
+	}
+
+	healthCheck, healthCheckErr := h.healthCheckStatus(r.Context(), state)
+	hub, hubErr := h.hubStatus(r.Context())
+
+	healthy := backend["status"] == healthStatusOK &&
+		healthCheck["status"] != healthStatusDown &&
+		hub["status"] != healthStatusDown
+
+	response := map[string]interface{}{
+		"healthy": healthy,
+		"state":   state,
+		"components": map[string]interface{}{
+			"backend":      backend,
+			"health_check": healthCheck,
+			"hub":          hub,
+			"proxy": map[string]interface{}{
+				"status":  healthStatusOK,
+				"version": Version,
+			},
+		},
+	}
+
+	if !healthy {
+		h.logger.Debug("health check endpoint reporting unhealthy",
+			"backend", backend["status"],
+			"health_check", healthCheck["status"],
+			"health_check_error", healthCheckErr,
+			"hub", hub["status"],
+			"hub_error", hubErr)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode health response", err)
+	}
+}
+
+// healthCheckStatus reports the ready check result, and its error for logging
+// The check only runs again while the app is running; otherwise the last startup result is reported
+func (h *LogsHandler) healthCheckStatus(ctx context.Context, state process.ProcessState) (map[string]interface{}, error) {
+	if h.healthChecker == nil {
+		return map[string]interface{}{"status": healthStatusDisabled}, nil
+	}
+
+	if state == process.StateRunning {
+		_ = h.healthChecker.Check(ctx)
+	}
+
+	result := h.healthChecker.LastResult()
+	status := map[string]interface{}{"status": healthStatusOK}
+	if !result.CheckedAt.IsZero() {
+		status["checked_at"] = result.CheckedAt
+		status["latency_ms"] = result.Latency.Milliseconds()
+	}
+	if result.CheckedAt.IsZero() || result.Err != nil {
+		status["status"] = healthStatusDown
+	}
+	return status, result.Err
+}
+
+// hubStatus reports JupyterHub reachability, and the ping error for logging
+// A recent successful activity report is enough; otherwise the hub is pinged
+func (h *LogsHandler) hubStatus(ctx context.Context) (map[string]interface{}, error) {
+	if h.hubChecker == nil {
+		return map[string]interface{}{"status": healthStatusDisabled}, nil
+	}
+
+	status := map[string]interface{}{"status": healthStatusOK}
+	lastReport := h.hubChecker.LastActivityReport()
+	if !lastReport.IsZero() {
+		status["last_activity_report"] = lastReport
+		maxAge := h.hubReportMaxAge
+		if maxAge <= 0 {
+			maxAge = defaultHubReportMaxAge
+		}
+		if time.Since(lastReport) < maxAge {
+			status["checked_by"] = "activity_report"
+			return status, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hubPingTimeout)
+	defer cancel()
+
+	status["checked_by"] = "ping"
+	err := h.hubChecker.Ping(ctx)
+	if err != nil {
+		status["status"] = healthStatusDown
+	}
+	return status, err
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/health"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+// fakeBackendChecker returns a fixed ready check result
+type fakeBackendChecker struct {
+	err  error
+	last health.Result
+}
+
+func (c *fakeBackendChecker) Check(ctx context.Context) error {
+	c.last = health.Result{CheckedAt: time.Now(), Latency: 3 * time.Millisecond, Err: c.err}
+	return c.err
+}
+
+func (c *fakeBackendChecker) LastResult() health.Result {
+	return c.last
+}
+
+// fakeHubChecker returns a fixed ping result and counts pings
+type fakeHubChecker struct {
+	pingErr    error
+	lastReport time.Time
+	pings      int
+}
+
+func (c *fakeHubChecker) Ping(ctx context.Context) error {
+	c.pings++
+	return c.pingErr
+}
+
+func (c *fakeHubChecker) LastActivityReport() time.Time {
+	return c.lastReport
+}
+
+type healthResponse struct {
+	Healthy    bool                              `json:"healthy"`
+	State      string                            `json:"state"`
+	Components map[string]map[string]interface{} `json:"components"`
+}
+
+func getHealth(t *testing.T, h *LogsHandler) (int, healthResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.HandleGetHealth(rec, httptest.NewRequest(http.MethodGet, HealthPath, nil))
+
+	var resp healthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode health response: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func TestHandleGetHealth(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+	}()
+
+	h := NewLogsHandler(mgr, log)
+	backend := &fakeBackendChecker{}
+	hub := &fakeHubChecker{}
+	h.SetHealthChecker(backend)
+	// As with --keep-alive-interval 900: longer than the default maximum report age
+	const hubMaxAge = 30 * time.Minute
+	h.SetHubChecker(hub, hubMaxAge)
+
+	t.Run("backend not started", func(t *testing.T) {
+		code, resp := getHealth(t, h)
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, code)
+		}
+		if resp.Healthy {
+			t.Error("expected healthy false")
+		}
+		if resp.State != "initializing" {
+			t.Errorf("expected state initializing, got %q", resp.State)
+		}
+		if got := resp.Components["backend"]["status"]; got != "down" {
+			t.Errorf("expected backend status down, got %v", got)
+		}
+		if got := resp.Components["health_check"]["status"]; got != "down" {
+			t.Errorf("expected health_check status down before any check, got %v", got)
+		}
+		if got := resp.Components["proxy"]["status"]; got != "ok" {
+			t.Errorf("expected proxy status ok, got %v", got)
+		}
+	})
+
+	if err := mgr.Start(t.Context()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	t.Run("all healthy", func(t *testing.T) {
+		code, resp := getHealth(t, h)
+		if code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, code)
+		}
+		if !resp.Healthy {
+			t.Errorf("expected healthy true, got components %v", resp.Components)
+		}
+		if resp.State != "running" {
+			t.Errorf("expected state running, got %q", resp.State)
+		}
+		if got := resp.Components["health_check"]["latency_ms"]; got != float64(3) {
+			t.Errorf("expected health_check latency_ms 3, got %v", got)
+		}
+		if got := resp.Components["hub"]["checked_by"]; got != "ping" {
+			t.Errorf("expected hub checked by ping without activity reports, got %v", got)
+		}
+	})
+
+	t.Run("backend failing ready check", func(t *testing.T) {
+		backend.err = errors.New("unhealthy status code: 500")
+		defer func() { backend.err = nil }()
+
+		code, resp := getHealth(t, h)
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, code)
+		}
+		if resp.Healthy {
+			t.Error("expected healthy false")
+		}
+		if got := resp.Components["backend"]["status"]; got != "ok" {
+			t.Errorf("expected backend process status ok, got %v", got)
+		}
+		if got := resp.Components["health_check"]["status"]; got != "down" {
+			t.Errorf("expected health_check status down, got %v", got)
+		}
+		// Unauthenticated: internal details stay in the logs
+		for _, key := range []string{"error", "url"} {
+			if got, ok := resp.Components["health_check"][key]; ok {
+				t.Errorf("expected no health_check %s in the response, got %v", key, got)
+			}
+		}
+	})
+
+	t.Run("hub down", func(t *testing.T) {
+		hub.pingErr = errors.New("failed to ping hub (connection refused)")
+		defer func() { hub.pingErr = nil }()
+
+		code, resp := getHealth(t, h)
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, code)
+		}
+		if resp.Healthy {
+			t.Error("expected healthy false")
+		}
+		if got := resp.Components["hub"]["status"]; got != "down" {
+			t.Errorf("expected hub status down, got %v", got)
+		}
+		if got, ok := resp.Components["hub"]["error"]; ok {
+			t.Errorf("expected no hub error in the response, got %v", got)
+		}
+	})
+
+	t.Run("recent activity report skips ping", func(t *testing.T) {
+		hub.pingErr = errors.New("ping should not be called")
+		hub.lastReport = time.Now()
+		hub.pings = 0
+		defer func() {
+			hub.pingErr = nil
+			hub.lastReport = time.Time{}
+		}()
+
+		code, resp := getHealth(t, h)
+		if code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, code)
+		}
+		if hub.pings != 0 {
+			t.Errorf("expected no hub ping, got %d", hub.pings)
+		}
+		if got := resp.Components["hub"]["checked_by"]; got != "activity_report" {
+			t.Errorf("expected hub checked by activity_report, got %v", got)
+		}
+	})
+
+	t.Run("activity report within configured max age skips ping", func(t *testing.T) {
+		hub.lastReport = time.Now().Add(-2 * defaultHubReportMaxAge)
+		hub.pings = 0
+		defer func() { hub.lastReport = time.Time{} }()
+
+		if code, _ := getHealth(t, h); code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, code)
+		}
+		if hub.pings != 0 {
+			t.Errorf("expected no hub ping, got %d", hub.pings)
+		}
+	})
+
+	t.Run("stale activity report pings hub", func(t *testing.T) {
+		hub.lastReport = time.Now().Add(-2 * hubMaxAge)
+		hub.pings = 0
+		defer func() { hub.lastReport = time.Time{} }()
+
+		if code, _ := getHealth(t, h); code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, code)
+		}
+		if hub.pings != 1 {
+			t.Errorf("expected 1 hub ping, got %d", hub.pings)
+		}
+	})
+
+	t.Run("hub not configured", func(t *testing.T) {
+		h := NewLogsHandler(mgr, log)
+		code, resp := getHealth(t, h)
+		if code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, code)
+		}
+		for _, component := range []string{"hub", "health_check"} {
+			if got := resp.Components[component]["status"]; got != "disabled" {
+				t.Errorf("expected %s status disabled, got %v", component, got)
+			}
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.HandleGetHealth(rec, httptest.NewRequest(http.MethodPost, HealthPath, nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+		}
+	})
+}
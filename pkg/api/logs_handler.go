@@ -2,14 +2,21 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/auth"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/redact"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/ui"
 )
 
@@ -21,10 +28,34 @@ var (
 // LogsHandler provides HTTP endpoints for accessing subprocess logs
 // This allows jhub-apps to surface logs to users
 type LogsHandler struct {
-	manager *process.ManagerWithLogs
-	logger  *logger.Logger
+	manager  *process.ManagerWithLogs
+	logger   *logger.Logger
+	redactor *redact.Redactor // Masks secrets in process info (captured logs are redacted at capture)
+	upgrader websocket.Upgrader
+
+	deployment DeploymentTracker // Interim page state reset by a manual restart (nil = none)
+	restarting atomic.Bool       // A manual restart is in progress
+
+	healthChecker   BackendChecker // Ready check reported by the health endpoint (nil = none)
+	hubChecker      HubChecker     // JupyterHub reachability reported by the health endpoint (nil = none)
+	hubReportMaxAge time.Duration  // Age up to which an activity report replaces a hub ping (0 = default)
+
+	gitStatus GitStatusProvider // Clone status reported by the git status endpoint (nil = no --repo)
+
+	rateLimiter *middleware.RateLimiter // Per-client limit on the /api/logs/* endpoints (nil = unlimited)
+
+	logoURL string // Served instead of the embedded logo (empty = embedded logo)
+
+	failureLogLines int // Default number of lines returned once the process has failed (0 = the usual default)
 }
 
+// Log stream (WebSocket and SSE) timings
+const (
+	streamPingInterval       = 30 * time.Second // Keeps idle connections open through proxies
+	streamWriteTimeout       = 10 * time.Second
+	streamStateCheckInterval = 500 * time.Millisecond // How often to check whether the process has exited
+)
+
 // NewLogsHandler creates a new logs API handler
 func NewLogsHandler(manager *process.ManagerWithLogs, log *logger.Logger) *LogsHandler {
 	return &LogsHandler{
@@ -33,17 +64,45 @@ func NewLogsHandler(manager *process.ManagerWithLogs, log *logger.Logger) *LogsH
 	}
 }
 
+// SetRedactor masks secret values in the command and working directory reported by the stats API
+func (h *LogsHandler) SetRedactor(redactor *redact.Redactor) {
+	h.redactor = redactor
+}
+
+// SetRateLimiter limits how often each client may call the /api/logs/* endpoints
+// Must be called before the routes are registered.
+func (h *LogsHandler) SetRateLimiter(rl *middleware.RateLimiter) {
+	h.rateLimiter = rl
+}
+
+// SetFailureLogLines sets how many recent lines /api/logs returns by default once the process has failed
+// This is the tail the failure page shows; an explicit lines parameter still wins.
+func (h *LogsHandler) SetFailureLogLines(n int) {
+	h.failureLogLines = n
+}
+
 // HandleGetLogs returns recent logs
-// GET /api/logs?lines=100&stream=stdout
+// GET /api/logs?lines=100&stream=stdout&raw=true
+// raw=true returns lines with the ANSI escape codes removed by --strip-ansi
+// Without lines, a failed process gets the last --failure-log-lines lines
+// With offset, pages through the buffer instead (see handleGetLogsPage)
 func (h *LogsHandler) HandleGetLogs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	if r.URL.Query().Has("offset") {
+		h.handleGetLogsPage(w, r)
+		return
+	}
+
 	// Parse query parameters
 	linesStr := r.URL.Query().Get("lines")
 	lines := 100 // default
+	if linesStr == "" && h.failureLogLines > 0 && h.manager.GetState() == process.StateFailed {
+		lines = min(h.failureLogLines, 10000)
+	}
 	if linesStr != "" {
 		if n, err := strconv.Atoi(linesStr); err == nil && n > 0 {
 			lines = n
@@ -62,6 +121,15 @@ func (h *LogsHandler) HandleGetLogs(w http.ResponseWriter, r *http.Request) {
 		entries = h.manager.GetRecentLogs(lines)
 	}
 
+	raw := r.URL.Query().Get("raw") == "true"
+	if raw {
+		for i := range entries {
+			if entries[i].Raw != "" {
+				entries[i].Line = entries[i].Raw
+			}
+		}
+	}
+
 	stats := h.manager.GetLogStats()
 
 	response := map[string]interface{}{
@@ -70,6 +138,7 @@ func (h *LogsHandler) HandleGetLogs(w http.ResponseWriter, r *http.Request) {
 		"query": map[string]interface{}{
 			"lines":  lines,
 			"stream": stream,
+			"raw":    raw,
 		},
 	}
 
@@ -86,6 +155,262 @@ func (h *LogsHandler) HandleGetLogs(w http.ResponseWriter, r *http.Request) {
 		"stream", stream)
 }
 
+// handleGetLogsPage returns one page of the buffered logs, oldest first
+// offset counts lines captured since start, so pages don't shift as the buffer wraps;
+// lines no longer buffered are skipped. Clients request next_offset while has_more is set.
+// The stream filter applies within the page, so a filtered page may hold fewer than limit lines.
+// GET /api/logs?offset=0&limit=100&stream=stdout&raw=true
+func (h *LogsHandler) handleGetLogsPage(w http.ResponseWriter, r *http.Request) {
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid offset parameter", http.StatusBadRequest)
+		return
+	}
+
+	limit := 100 // default
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = n
+		if limit > 10000 {
+			limit = 10000 // cap at 10k lines for safety
+		}
+	}
+
+	stream := r.URL.Query().Get("stream") // "stdout", "stderr", or "" for all
+	raw := r.URL.Query().Get("raw") == "true"
+
+	page, nextOffset, hasMore := h.manager.GetLogsPage(offset, limit)
+	entries := make([]process.LogEntry, 0, len(page))
+	for _, entry := range page {
+		if (stream == "stdout" || stream == "stderr") && entry.Stream != stream {
+			continue
+		}
+		if raw && entry.Raw != "" {
+			entry.Line = entry.Raw
+		}
+		entries = append(entries, entry)
+	}
+
+	response := map[string]interface{}{
+		"logs":        entries,
+		"stats":       h.manager.GetLogStats(),
+		"next_offset": nextOffset,
+		"has_more":    hasMore,
+		"query": map[string]interface{}{
+			"offset": offset,
+			"limit":  limit,
+			"stream": stream,
+			"raw":    raw,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode logs response", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// HandleStreamLogs pushes new log entries to the client as they are captured
+// WebSocket upgrades get one JSON-encoded LogEntry per message; other requests get
+// Server-Sent Events with one JSON-encoded LogEntry per "data:" line. The stream ends
+// once the subprocess has exited (stopped or failed) or the client goes away.
+// GET /api/logs/stream?stream=stdout
+func (h *LogsHandler) HandleStreamLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stream := r.URL.Query().Get("stream") // "stdout", "stderr", or "" for all
+	if stream != "" && stream != "stdout" && stream != "stderr" {
+		http.Error(w, "stream must be stdout or stderr", http.StatusBadRequest)
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		h.streamLogsWebSocket(w, r, stream)
+	} else {
+		h.streamLogsSSE(w, r, stream)
+	}
+}
+
+// streamLogsWebSocket streams log entries over a WebSocket
+func (h *LogsHandler) streamLogsWebSocket(w http.ResponseWriter, r *http.Request, stream string) {
+	// The default upgrader rejects cross-origin requests, so other sites can't read the logs
+	// using the user's cookies
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an HTTP error
+		h.logger.Debug("log stream upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Read (and discard) client frames so close and pong control frames are handled
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	h.logger.Debug("log stream opened", "stream", stream, "remote_addr", r.RemoteAddr)
+
+	entries := h.manager.StreamLogs(ctx)
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+	stateCheck := time.NewTicker(streamStateCheckInterval)
+	defer stateCheck.Stop()
+
+	closeWith := func(code int, reason string) {
+		msg := websocket.FormatCloseMessage(code, reason)
+		_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(streamWriteTimeout))
+	}
+	send := func(entry process.LogEntry) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return conn.WriteJSON(entry)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Client went away or the request was cancelled
+			closeWith(websocket.CloseNormalClosure, "")
+			return
+
+		case entry, ok := <-entries:
+			if !ok {
+				return
+			}
+			if stream != "" && entry.Stream != stream {
+				continue
+			}
+			if err := send(entry); err != nil {
+				h.logger.Debug("log stream write failed", "error", err)
+				return
+			}
+
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+
+		case <-stateCheck.C:
+			if state := h.manager.GetState(); state == process.StateStopped || state == process.StateFailed {
+				drainStream(entries, stream, send)
+				closeWith(websocket.CloseNormalClosure, "process "+string(state))
+				return
+			}
+		}
+	}
+}
+
+// streamLogsSSE streams log entries as Server-Sent Events
+// A final "end" event carries the reason the stream closed (e.g. "process stopped")
+func (h *LogsHandler) streamLogsSSE(w http.ResponseWriter, r *http.Request, stream string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop nginx-style proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	h.logger.Debug("log event stream opened", "stream", stream, "remote_addr", r.RemoteAddr)
+
+	ctx := r.Context()
+	entries := h.manager.StreamLogs(ctx)
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+	stateCheck := time.NewTicker(streamStateCheckInterval)
+	defer stateCheck.Stop()
+
+	send := func(entry process.LogEntry) error {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case entry, ok := <-entries:
+			if !ok {
+				return
+			}
+			if stream != "" && entry.Stream != stream {
+				continue
+			}
+			if err := send(entry); err != nil {
+				h.logger.Debug("log event stream write failed", "error", err)
+				return
+			}
+
+		case <-ping.C:
+			// Comment line, ignored by EventSource; keeps idle connections open through proxies
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-stateCheck.C:
+			if state := h.manager.GetState(); state == process.StateStopped || state == process.StateFailed {
+				drainStream(entries, stream, send)
+				fmt.Fprintf(w, "event: end\ndata: process %s\n\n", state)
+				flusher.Flush()
+				return
+			}
+		}
+	}
+}
+
+// drainStream forwards entries still arriving on the stream channel for a short while
+// Gives StreamLogs one more poll to deliver the final lines (often the traceback)
+func drainStream(entries <-chan process.LogEntry, stream string, send func(process.LogEntry) error) {
+	deadline := time.After(2 * streamStateCheckInterval)
+	for {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				return
+			}
+			if stream != "" && entry.Stream != stream {
+				continue
+			}
+			if err := send(entry); err != nil {
+				return
+			}
+		case <-deadline:
+			return
+		}
+	}
+}
+
 // HandleGetLogsSince returns logs since a specific timestamp
 // GET /api/logs/since?timestamp=2025-01-15T10:30:00Z
 func (h *LogsHandler) HandleGetLogsSince(w http.ResponseWriter, r *http.Request) {
@@ -122,6 +447,74 @@ func (h *LogsHandler) HandleGetLogsSince(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// HandleGetLogsContext returns the log lines surrounding a target line, like grep -C
+// The target is either an explicit line number or the most recent line matching a query
+// GET /api/logs/context?line=42&around=5
+// GET /api/logs/context?match=Traceback&around=5
+func (h *LogsHandler) HandleGetLogsContext(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	around := 5 // default
+	if aroundStr := r.URL.Query().Get("around"); aroundStr != "" {
+		n, err := strconv.Atoi(aroundStr)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid around parameter", http.StatusBadRequest)
+			return
+		}
+		around = n
+		if around > 1000 {
+			around = 1000 // cap for safety
+		}
+	}
+
+	var target int
+	match := r.URL.Query().Get("match")
+	if lineStr := r.URL.Query().Get("line"); lineStr != "" {
+		n, err := strconv.Atoi(lineStr)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid line parameter", http.StatusBadRequest)
+			return
+		}
+		target = n
+	} else if match != "" {
+		target = h.manager.FindLastLogMatch(match)
+		if target == 0 {
+			http.Error(w, "no log line matches query", http.StatusNotFound)
+			return
+		}
+	} else {
+		http.Error(w, "line or match parameter required", http.StatusBadRequest)
+		return
+	}
+
+	entries, startLine := h.manager.GetLogsRange(target-around, target+around)
+	if len(entries) == 0 || target < startLine || target >= startLine+len(entries) {
+		http.Error(w, "line not in log buffer", http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"logs":        entries,
+		"target_line": target,
+		"start_line":  startLine,
+		"end_line":    startLine + len(entries) - 1,
+		"query": map[string]interface{}{
+			"around": around,
+			"match":  match,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode logs response", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
 // HandleGetStats returns log buffer statistics
 // GET /api/logs/stats
 func (h *LogsHandler) HandleGetStats(w http.ResponseWriter, r *http.Request) {
@@ -132,22 +525,52 @@ func (h *LogsHandler) HandleGetStats(w http.ResponseWriter, r *http.Request) {
 
 	stats := h.manager.GetLogStats()
 	processState := map[string]interface{}{
-		"state":   string(h.manager.GetState()),
-		"pid":     h.manager.GetPID(),
-		"uptime":  h.manager.GetUptime().Seconds(),
-		"running": h.manager.IsRunning(),
+		"state":               string(h.manager.GetState()),
+		"pid":                 h.manager.GetPID(),
+		"uptime":              h.manager.GetUptime().Seconds(),
+		"running":             h.manager.IsRunning(),
+		"restart_count":       h.manager.GetRestartCount(),
+		"crash_loop_detected": h.manager.CrashLoopDetected(),
+		"degraded":            h.manager.IsDegraded(),
+		"message":             h.manager.GetStatusMessage(),
+		"startup_phase":       string(h.manager.GetStartupPhase()),
+		"startup_phases":      h.manager.GetStartupPhases(),
+	}
+
+	// Sampled per request; null when no process is running or /proc is unavailable
+	var resourceUsage *process.ResourceUsage
+	if usage, err := h.manager.GetResourceUsage(); err == nil {
+		resourceUsage = &usage
 	}
 
+	// command is kept for older clients; it is the executed command
+	executedCommand := h.redactor.Strings(h.manager.GetCommand())
 	processInfo := map[string]interface{}{
-		"command": h.manager.GetCommand(),
-		"workdir": h.manager.GetWorkDir(),
+		"command":          executedCommand,
+		"user_command":     h.redactor.Strings(h.manager.GetUserCommand()),
+		"executed_command": executedCommand,
+		"workdir":          h.redactor.String(h.manager.GetWorkDir()),
+	}
+
+	// Lets the interim page fetch the final logs before it is redirected to the app
+	gracePeriodActive := false
+	var gracePeriodExpiresAt *time.Time // null until the app is deployed
+	if h.deployment != nil {
+		active, expiresAt := h.deployment.GracePeriodState()
+		gracePeriodActive = active
+		if !expiresAt.IsZero() {
+			gracePeriodExpiresAt = &expiresAt
+		}
 	}
 
 	response := map[string]interface{}{
-		"logs_stats":    stats,
-		"process_state": processState,
-		"process_info":  processInfo,
-		"version":       Version,
+		"logs_stats":              stats,
+		"process_state":           processState,
+		"process_info":            processInfo,
+		"resource_usage":          resourceUsage,
+		"grace_period_active":     gracePeriodActive,
+		"grace_period_expires_at": gracePeriodExpiresAt,
+		"version":                 Version,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -158,6 +581,29 @@ func (h *LogsHandler) HandleGetStats(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleGetLogLevels returns buffered log counts by detected level and by stream
+// GET /api/logs/levels
+func (h *LogsHandler) HandleGetLogLevels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	summary := h.manager.GetLogLevelSummary()
+	response := map[string]interface{}{
+		"levels":  summary.Levels,
+		"streams": summary.Streams,
+		"total":   summary.Total,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode levels response", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
 // HandleClearLogs clears the log buffer
 // DELETE /api/logs
 func (h *LogsHandler) HandleClearLogs(w http.ResponseWriter, r *http.Request) {
@@ -193,10 +639,10 @@ func (h *LogsHandler) HandleGetAllLogs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := map[string]interface{}{
-		"logs":       lines,
-		"count":      len(lines),
-		"source":     "file",
-		"log_file":   h.manager.GetLogFilePath(),
+		"logs":     lines,
+		"count":    len(lines),
+		"source":   "file",
+		"log_file": h.manager.GetLogFilePath(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -207,7 +653,12 @@ func (h *LogsHandler) HandleGetAllLogs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleGetLogo returns the logo PNG file
+// SetLogoURL redirects logo requests to a custom logo (--brand-logo-url)
+func (h *LogsHandler) SetLogoURL(logoURL string) {
+	h.logoURL = logoURL
+}
+
+// HandleGetLogo returns the logo PNG file, or redirects to the custom logo if one is set
 // GET /static/logo.png
 func (h *LogsHandler) HandleGetLogo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet && r.Method != http.MethodHead {
@@ -215,6 +666,11 @@ func (h *LogsHandler) HandleGetLogo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.logoURL != "" {
+		http.Redirect(w, r, h.logoURL, http.StatusFound)
+		return
+	}
+
 	w.Header().Set("Content-Type", "image/png")
 	w.Header().Set("Cache-Control", "public, max-age=3600") // Cache for 1 hour
 	w.WriteHeader(http.StatusOK)
@@ -263,19 +719,33 @@ func (h *LogsHandler) HandleGetJS(w http.ResponseWriter, r *http.Request) {
 
 // RegisterRoutes registers all log API routes with a http.ServeMux
 func (h *LogsHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/api/logs", h.HandleGetLogs)
-	mux.HandleFunc("/api/logs/all", h.HandleGetAllLogs)
-	mux.HandleFunc("/api/logs/since", h.HandleGetLogsSince)
-	mux.HandleFunc("/api/logs/stats", h.HandleGetStats)
-	mux.HandleFunc("/api/logs/clear", h.HandleClearLogs)
+	mux.Handle("/api/logs", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogs)))
+	mux.Handle("/api/logs/all", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetAllLogs)))
+	mux.Handle("/api/logs/since", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogsSince)))
+	mux.Handle("/api/logs/context", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogsContext)))
+	mux.Handle("/api/logs/search", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleSearchLogs)))
+	mux.Handle("/api/logs/stats", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetStats)))
+	mux.Handle("/api/logs/levels", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogLevels)))
+	mux.Handle("/api/logs/stream", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleStreamLogs)))
+	mux.Handle(StateStreamPath, h.rateLimiter.Wrap(http.HandlerFunc(h.HandleStateStream)))
+	mux.Handle("/api/logs/clear", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleClearLogs)))
+	mux.Handle(HealthPath, h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetHealth)))
+	mux.HandleFunc(GitStatusPath, h.HandleGetGitStatus)
 
 	h.logger.Info("log API routes registered",
 		"endpoints", []string{
 			"GET /api/logs",
 			"GET /api/logs/all",
 			"GET /api/logs/since",
+			"GET /api/logs/context",
+			"GET /api/logs/search",
 			"GET /api/logs/stats",
+			"GET /api/logs/levels",
+			"GET /api/logs/stream (WebSocket or SSE)",
+			"GET " + StateStreamPath + " (SSE)",
 			"DELETE /api/logs/clear",
+			"GET " + HealthPath,
+			"GET " + GitStatusPath,
 		})
 }
 
@@ -283,11 +753,18 @@ func (h *LogsHandler) RegisterRoutes(mux *http.ServeMux) {
 // For example, with prefix "/user/admin/app", routes become:
 // /user/admin/app/api/logs, /user/admin/app/api/logs/all, etc.
 func (h *LogsHandler) RegisterRoutesWithPrefix(mux *http.ServeMux, prefix string) {
-	mux.HandleFunc(prefix+"/api/logs", h.HandleGetLogs)
-	mux.HandleFunc(prefix+"/api/logs/all", h.HandleGetAllLogs)
-	mux.HandleFunc(prefix+"/api/logs/since", h.HandleGetLogsSince)
-	mux.HandleFunc(prefix+"/api/logs/stats", h.HandleGetStats)
-	mux.HandleFunc(prefix+"/api/logs/clear", h.HandleClearLogs)
+	mux.Handle(prefix+"/api/logs", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogs)))
+	mux.Handle(prefix+"/api/logs/all", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetAllLogs)))
+	mux.Handle(prefix+"/api/logs/since", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogsSince)))
+	mux.Handle(prefix+"/api/logs/context", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogsContext)))
+	mux.Handle(prefix+"/api/logs/search", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleSearchLogs)))
+	mux.Handle(prefix+"/api/logs/stats", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetStats)))
+	mux.Handle(prefix+"/api/logs/levels", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogLevels)))
+	mux.Handle(prefix+"/api/logs/stream", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleStreamLogs)))
+	mux.Handle(prefix+StateStreamPath, h.rateLimiter.Wrap(http.HandlerFunc(h.HandleStateStream)))
+	mux.Handle(prefix+"/api/logs/clear", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleClearLogs)))
+	mux.Handle(prefix+HealthPath, h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetHealth)))
+	mux.HandleFunc(prefix+GitStatusPath, h.HandleGetGitStatus)
 
 	h.logger.Info("log API routes registered with prefix",
 		"prefix", prefix,
@@ -295,8 +772,15 @@ func (h *LogsHandler) RegisterRoutesWithPrefix(mux *http.ServeMux, prefix string
 			"GET " + prefix + "/api/logs",
 			"GET " + prefix + "/api/logs/all",
 			"GET " + prefix + "/api/logs/since",
+			"GET " + prefix + "/api/logs/context",
+			"GET " + prefix + "/api/logs/search",
 			"GET " + prefix + "/api/logs/stats",
+			"GET " + prefix + "/api/logs/levels",
+			"GET " + prefix + "/api/logs/stream (WebSocket or SSE)",
+			"GET " + prefix + StateStreamPath + " (SSE)",
 			"DELETE " + prefix + "/api/logs/clear",
+			"GET " + prefix + HealthPath,
+			"GET " + prefix + GitStatusPath,
 		})
 }
 
@@ -315,11 +799,18 @@ func (h *LogsHandler) RegisterRoutesWithPrefix(mux *http.ServeMux, prefix string
 //   - mux: The HTTP request multiplexer
 //   - basePath: The base interim path (e.g., "/_temp/jhub-app-proxy" or "/user/admin/app/_temp/jhub-app-proxy")
 func (h *LogsHandler) RegisterInterimRoutes(mux *http.ServeMux, basePath string) {
-	mux.HandleFunc(basePath+"/api/logs", h.HandleGetLogs)
-	mux.HandleFunc(basePath+"/api/logs/all", h.HandleGetAllLogs)
-	mux.HandleFunc(basePath+"/api/logs/since", h.HandleGetLogsSince)
-	mux.HandleFunc(basePath+"/api/logs/stats", h.HandleGetStats)
-	mux.HandleFunc(basePath+"/api/logs/clear", h.HandleClearLogs)
+	mux.Handle(basePath+"/api/logs", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogs)))
+	mux.Handle(basePath+"/api/logs/all", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetAllLogs)))
+	mux.Handle(basePath+"/api/logs/since", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogsSince)))
+	mux.Handle(basePath+"/api/logs/context", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogsContext)))
+	mux.Handle(basePath+"/api/logs/search", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleSearchLogs)))
+	mux.Handle(basePath+"/api/logs/stats", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetStats)))
+	mux.Handle(basePath+"/api/logs/levels", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetLogLevels)))
+	mux.Handle(basePath+"/api/logs/stream", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleStreamLogs)))
+	mux.Handle(basePath+StateStreamPath, h.rateLimiter.Wrap(http.HandlerFunc(h.HandleStateStream)))
+	mux.Handle(basePath+"/api/logs/clear", h.rateLimiter.Wrap(http.HandlerFunc(h.HandleClearLogs)))
+	mux.Handle(basePath+HealthPath, h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetHealth)))
+	mux.HandleFunc(basePath+GitStatusPath, h.HandleGetGitStatus)
 	mux.HandleFunc(basePath+"/static/logo.png", h.HandleGetLogo)
 	mux.HandleFunc(basePath+"/static/logs.css", h.HandleGetCSS)
 	mux.HandleFunc(basePath+"/static/logs.js", h.HandleGetJS)
@@ -330,44 +821,73 @@ func (h *LogsHandler) RegisterInterimRoutes(mux *http.ServeMux, basePath string)
 			"GET " + basePath + "/api/logs",
 			"GET " + basePath + "/api/logs/all",
 			"GET " + basePath + "/api/logs/since",
+			"GET " + basePath + "/api/logs/context",
+			"GET " + basePath + "/api/logs/search",
 			"GET " + basePath + "/api/logs/stats",
+			"GET " + basePath + "/api/logs/levels",
+			"GET " + basePath + "/api/logs/stream (WebSocket or SSE)",
+			"GET " + basePath + StateStreamPath + " (SSE)",
 			"DELETE " + basePath + "/api/logs/clear",
+			"GET " + basePath + HealthPath,
+			"GET " + basePath + GitStatusPath,
 			"GET " + basePath + "/static/logo.png",
 			"GET " + basePath + "/static/logs.css",
 			"GET " + basePath + "/static/logs.js",
 		})
 }
 
-// RegisterInterimRoutesWithAuth registers all log API routes under the interim path with OAuth authentication
-// CRITICAL SECURITY: Use this method instead of RegisterInterimRoutes when OAuth is enabled!
+// RegisterInterimRoutesWithAuth registers all log API routes under the interim path with authentication
+// CRITICAL SECURITY: Use this method instead of RegisterInterimRoutes when OAuth or Basic auth is enabled!
 //
 // Note: Static assets (CSS, JS) are not protected by OAuth as they're just static files needed to render the page.
 //
 // Parameters:
 //   - mux: The HTTP request multiplexer
 //   - basePath: The base interim path
-//   - oauthMW: OAuth middleware for authentication
-func (h *LogsHandler) RegisterInterimRoutesWithAuth(mux *http.ServeMux, basePath string, oauthMW *auth.OAuthMiddleware) {
-	// Wrap each API handler with OAuth middleware
-	mux.Handle(basePath+"/api/logs", oauthMW.Wrap(http.HandlerFunc(h.HandleGetLogs)))
-	mux.Handle(basePath+"/api/logs/all", oauthMW.Wrap(http.HandlerFunc(h.HandleGetAllLogs)))
-	mux.Handle(basePath+"/api/logs/since", oauthMW.Wrap(http.HandlerFunc(h.HandleGetLogsSince)))
-	mux.Handle(basePath+"/api/logs/stats", oauthMW.Wrap(http.HandlerFunc(h.HandleGetStats)))
-	mux.Handle(basePath+"/api/logs/clear", oauthMW.Wrap(http.HandlerFunc(h.HandleClearLogs)))
+//   - authMW: OAuth or Basic auth middleware
+func (h *LogsHandler) RegisterInterimRoutesWithAuth(mux *http.ServeMux, basePath string, authMW auth.Middleware) {
+	// Wrap each API handler with the auth middleware
+	// The rate limit runs first, so unauthenticated floods are turned away cheaply
+	mux.Handle(basePath+"/api/logs", h.rateLimiter.Wrap(authMW.Wrap(http.HandlerFunc(h.HandleGetLogs))))
+	mux.Handle(basePath+"/api/logs/all", h.rateLimiter.Wrap(authMW.Wrap(http.HandlerFunc(h.HandleGetAllLogs))))
+	mux.Handle(basePath+"/api/logs/since", h.rateLimiter.Wrap(authMW.Wrap(http.HandlerFunc(h.HandleGetLogsSince))))
+	mux.Handle(basePath+"/api/logs/context", h.rateLimiter.Wrap(authMW.Wrap(http.HandlerFunc(h.HandleGetLogsContext))))
+	mux.Handle(basePath+"/api/logs/search", h.rateLimiter.Wrap(authMW.Wrap(http.HandlerFunc(h.HandleSearchLogs))))
+	mux.Handle(basePath+"/api/logs/stats", h.rateLimiter.Wrap(authMW.Wrap(http.HandlerFunc(h.HandleGetStats))))
+	mux.Handle(basePath+"/api/logs/levels", h.rateLimiter.Wrap(authMW.Wrap(http.HandlerFunc(h.HandleGetLogLevels))))
+	mux.Handle(basePath+"/api/logs/stream", h.rateLimiter.Wrap(authMW.Wrap(http.HandlerFunc(h.HandleStreamLogs))))
+	mux.Handle(basePath+StateStreamPath, h.rateLimiter.Wrap(authMW.Wrap(http.HandlerFunc(h.HandleStateStream))))
+	mux.Handle(basePath+"/api/logs/clear", h.rateLimiter.Wrap(authMW.Wrap(http.HandlerFunc(h.HandleClearLogs))))
+	mux.Handle(basePath+GitStatusPath, authMW.Wrap(http.HandlerFunc(h.HandleGetGitStatus)))
+	// Only registered with authentication: restarting the app must never be open to anonymous users
+	mux.Handle(basePath+ProcessRestartPath, authMW.Wrap(http.HandlerFunc(h.HandleRestartProcess)))
+
+	// The health endpoint is not protected - it backs readiness probes, which can't log in,
+	// and only reports component status (no logs). It is rate limited since each request
+	// runs a live ready check and may ping the Hub.
+	mux.Handle(basePath+HealthPath, h.rateLimiter.Wrap(http.HandlerFunc(h.HandleGetHealth)))
 
 	// Static assets are not protected - they're just CSS/JS/image files
 	mux.HandleFunc(basePath+"/static/logo.png", h.HandleGetLogo)
 	mux.HandleFunc(basePath+"/static/logs.css", h.HandleGetCSS)
 	mux.HandleFunc(basePath+"/static/logs.js", h.HandleGetJS)
 
-	h.logger.Info("interim log API routes registered WITH OAUTH PROTECTION",
+	h.logger.Info("interim log API routes registered WITH AUTHENTICATION",
 		"base_path", basePath,
 		"endpoints", []string{
 			"GET " + basePath + "/api/logs",
 			"GET " + basePath + "/api/logs/all",
 			"GET " + basePath + "/api/logs/since",
+			"GET " + basePath + "/api/logs/context",
+			"GET " + basePath + "/api/logs/search",
 			"GET " + basePath + "/api/logs/stats",
+			"GET " + basePath + "/api/logs/levels",
+			"GET " + basePath + "/api/logs/stream (WebSocket or SSE)",
+			"GET " + basePath + StateStreamPath + " (SSE)",
 			"DELETE " + basePath + "/api/logs/clear",
+			"GET " + basePath + GitStatusPath,
+			"POST " + basePath + ProcessRestartPath,
+			"GET " + basePath + HealthPath + " (unauthenticated)",
 			"GET " + basePath + "/static/logo.png",
 			"GET " + basePath + "/static/logs.css",
 			"GET " + basePath + "/static/logs.js",
@@ -0,0 +1,715 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/command"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/interim"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/redact"
+)
+
+// statsPhase fetches /api/logs/stats and returns process_state.startup_phase and the phase history
+func statsPhase(t *testing.T, h *LogsHandler) (string, []string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/logs/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp struct {
+		ProcessState struct {
+			StartupPhase  string                    `json:"startup_phase"`
+			StartupPhases []process.PhaseTransition `json:"startup_phases"`
+		} `json:"process_state"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid stats response: %v", err)
+	}
+
+	var history []string
+	for _, p := range resp.ProcessState.StartupPhases {
+		history = append(history, string(p.Phase))
+	}
+	return resp.ProcessState.StartupPhase, history
+}
+
+func waitForPhase(t *testing.T, h *LogsHandler, want process.StartupPhase) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		phase, history := statsPhase(t, h)
+		if phase == string(want) {
+			return history
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected startup_phase %q, still %q (history %v)", want, phase, history)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHandleGetStats_StartupPhases(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	phases := process.NewPhaseTracker()
+	healthy := make(chan struct{})
+
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+		ReadyCheck: func(ctx context.Context) error {
+			select {
+			case <-healthy:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+		Phases: phases,
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+		_ = mgr.CloseLogFile()
+	}()
+	h := NewLogsHandler(mgr, log)
+
+	waitForPhase(t, h, process.PhaseInitializing)
+
+	// main reports the pre-start steps
+	phases.Set(process.PhaseCloning)
+	waitForPhase(t, h, process.PhaseCloning)
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	waitForPhase(t, h, process.PhaseWaitingForHealth)
+
+	close(healthy)
+	history := waitForPhase(t, h, process.PhaseReady)
+
+	want := []string{"initializing", "cloning", "starting_process", "waiting_for_health", "ready"}
+	if len(history) != len(want) {
+		t.Fatalf("expected phase history %v, got %v", want, history)
+	}
+	for i := range want {
+		if history[i] != want[i] {
+			t.Errorf("expected phase history %v, got %v", want, history)
+			break
+		}
+	}
+}
+
+func TestHandleGetStats_Command(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+
+	// An existing absolute env path and CONDA_PREFIX let conda activation run without conda
+	envPath := t.TempDir()
+	t.Setenv("CONDA_PREFIX", t.TempDir())
+	userCmd := []string{"python", "app.py"}
+	cmd, err := command.NewBuilder(log).Build(userCmd, envPath)
+	if err != nil {
+		t.Fatalf("failed to build command: %v", err)
+	}
+	if len(cmd) == len(userCmd) {
+		t.Fatalf("expected conda activation to wrap the command, got %v", cmd)
+	}
+
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command:     cmd,
+		UserCommand: userCmd,
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	h := NewLogsHandler(mgr, log)
+
+	rec := httptest.NewRecorder()
+	h.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/logs/stats", nil))
+	var resp struct {
+		ProcessInfo struct {
+			Command         []string `json:"command"`
+			UserCommand     []string `json:"user_command"`
+			ExecutedCommand []string `json:"executed_command"`
+		} `json:"process_info"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid stats response: %v", err)
+	}
+
+	info := resp.ProcessInfo
+	if strings.Join(info.UserCommand, " ") != "python app.py" {
+		t.Errorf("expected user_command [python app.py], got %v", info.UserCommand)
+	}
+	if strings.Join(info.ExecutedCommand, " ") != strings.Join(cmd, " ") {
+		t.Errorf("expected executed_command %v, got %v", cmd, info.ExecutedCommand)
+	}
+	if len(info.ExecutedCommand) < 2 || info.ExecutedCommand[1] != "run" {
+		t.Errorf("expected executed_command to be wrapped in conda run, got %v", info.ExecutedCommand)
+	}
+	if strings.Join(info.Command, " ") != strings.Join(cmd, " ") {
+		t.Errorf("expected command to stay the executed command, got %v", info.Command)
+	}
+}
+
+func TestHandleGetStats_ResourceUsage(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+		_ = mgr.CloseLogFile()
+	}()
+	h := NewLogsHandler(mgr, log)
+
+	getUsage := func() *process.ResourceUsage {
+		rec := httptest.NewRecorder()
+		h.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/logs/stats", nil))
+		var resp struct {
+			ResourceUsage *process.ResourceUsage `json:"resource_usage"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("invalid stats response: %v", err)
+		}
+		return resp.ResourceUsage
+	}
+
+	if usage := getUsage(); usage != nil {
+		t.Errorf("expected null resource_usage before start, got %+v", usage)
+	}
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("resource usage requires /proc")
+	}
+
+	usage := getUsage()
+	if usage == nil {
+		t.Fatal("expected resource_usage for a running process")
+	}
+	if usage.MemoryRSSBytes == 0 {
+		t.Error("expected non-zero memory_rss_bytes")
+	}
+	if usage.Processes != 1 {
+		t.Errorf("expected 1 process, got %d", usage.Processes)
+	}
+}
+
+func TestHandleGetStats_GracePeriod(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		_ = mgr.CloseLogFile()
+	}()
+
+	interimHandler := interim.NewHandler(interim.Config{Manager: mgr, Logger: log})
+	h := NewLogsHandler(mgr, log)
+	h.SetDeploymentTracker(interimHandler)
+
+	getGracePeriod := func(t *testing.T) (bool, *time.Time) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.HandleGetStats(rec, httptest.NewRequest(http.MethodGet, "/api/logs/stats", nil))
+		var resp struct {
+			Active    *bool      `json:"grace_period_active"`
+			ExpiresAt *time.Time `json:"grace_period_expires_at"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("invalid stats response: %v", err)
+		}
+		if resp.Active == nil {
+			t.Fatal("expected grace_period_active in stats response")
+		}
+		return *resp.Active, resp.ExpiresAt
+	}
+
+	t.Run("before deployment", func(t *testing.T) {
+		active, expiresAt := getGracePeriod(t)
+		if active {
+			t.Error("expected grace period inactive before deployment")
+		}
+		if expiresAt != nil {
+			t.Errorf("expected null grace_period_expires_at before deployment, got %v", expiresAt)
+		}
+	})
+
+	t.Run("after deployment", func(t *testing.T) {
+		before := time.Now()
+		interimHandler.MarkAppDeployed()
+		after := time.Now()
+
+		active, expiresAt := getGracePeriod(t)
+		if !active {
+			t.Error("expected grace period active right after deployment")
+		}
+		if expiresAt == nil {
+			t.Fatal("expected grace_period_expires_at after deployment")
+		}
+		if expiresAt.Before(before.Add(interim.GracePeriod)) || expiresAt.After(after.Add(interim.GracePeriod)) {
+			t.Errorf("expected grace period to expire %v after deployment, got %v", interim.GracePeriod, expiresAt)
+		}
+	})
+
+	t.Run("after reset", func(t *testing.T) {
+		interimHandler.ResetDeployment()
+		if active, expiresAt := getGracePeriod(t); active || expiresAt != nil {
+			t.Errorf("expected no grace period after reset, got active %v expiring %v", active, expiresAt)
+		}
+	})
+}
+
+func TestHandleGetLogs_Raw(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sh", "-c", `printf '\033[32mready\033[0m\n'`},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10, StripANSI: true}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() { _ = mgr.CloseLogFile() }()
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(mgr.GetRecentLogs(1)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the process output to be captured")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	h := NewLogsHandler(mgr, log)
+	getLine := func(t *testing.T, query string) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.HandleGetLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs"+query, nil))
+		var resp struct {
+			Logs []process.LogEntry `json:"logs"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("invalid logs response: %v", err)
+		}
+		if len(resp.Logs) != 1 {
+			t.Fatalf("expected 1 log entry, got %d", len(resp.Logs))
+		}
+		return resp.Logs[0].Line
+	}
+
+	if got := getLine(t, ""); got != "ready" {
+		t.Errorf("expected stripped line %q, got %q", "ready", got)
+	}
+	if got := getLine(t, "?raw=true"); got != "\x1b[32mready\x1b[0m" {
+		t.Errorf("expected raw line with escape codes, got %q", got)
+	}
+}
+
+func TestHandleGetLogs_Page(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sh", "-c", "for i in 1 2 3 4 5; do echo line $i; done"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() { _ = mgr.CloseLogFile() }()
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(mgr.GetRecentLogs(-1)) < 5 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the process output to be captured")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	type pageResponse struct {
+		Logs       []process.LogEntry `json:"logs"`
+		NextOffset int                `json:"next_offset"`
+		HasMore    bool               `json:"has_more"`
+	}
+	h := NewLogsHandler(mgr, log)
+	getPage := func(t *testing.T, query string) pageResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.HandleGetLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		var resp pageResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("invalid logs response: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("pages through the buffer", func(t *testing.T) {
+		var lines []string
+		offset := 0
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatal("expected paging to end")
+			}
+			resp := getPage(t, "?offset="+strconv.Itoa(offset)+"&limit=2")
+			for _, e := range resp.Logs {
+				lines = append(lines, e.Line)
+			}
+			offset = resp.NextOffset
+			if !resp.HasMore {
+				break
+			}
+		}
+		if got := strings.Join(lines, ","); got != "line 1,line 2,line 3,line 4,line 5" {
+			t.Errorf("expected all five lines in order, got %s", got)
+		}
+		if offset != 5 {
+			t.Errorf("expected final next_offset 5, got %d", offset)
+		}
+	})
+
+	t.Run("offset beyond buffer", func(t *testing.T) {
+		resp := getPage(t, "?offset=100")
+		if len(resp.Logs) != 0 || resp.HasMore || resp.NextOffset != 5 {
+			t.Errorf("expected an empty last page with next_offset 5, got %d logs, next_offset %d, has_more %v",
+				len(resp.Logs), resp.NextOffset, resp.HasMore)
+		}
+	})
+
+	t.Run("lines without offset", func(t *testing.T) {
+		resp := getPage(t, "?lines=2")
+		if len(resp.Logs) != 2 || resp.Logs[1].Line != "line 5" {
+			t.Errorf("expected the 2 most recent lines, got %v", resp.Logs)
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"?offset=-1", "?offset=abc", "?offset=0&limit=0", "?offset=0&limit=x"} {
+			rec := httptest.NewRecorder()
+			h.HandleGetLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs"+query, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+			}
+		}
+	})
+}
+
+func TestHandleGetLogs_FailureLogLines(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sh", "-c", "for i in $(seq 1 20); do echo line $i; done; sleep 30"},
+		ReadyCheck: func(context.Context) error {
+			return errors.New("app did not become ready")
+		},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 100}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+		_ = mgr.CloseLogFile()
+	}()
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(mgr.GetRecentLogs(-1)) < 20 || mgr.GetState() != process.StateFailed {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 20 captured lines and a failed process, got %d lines in state %s",
+				len(mgr.GetRecentLogs(-1)), mgr.GetState())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	h := NewLogsHandler(mgr, log)
+	h.SetFailureLogLines(5)
+	getLines := func(t *testing.T, query string) []string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.HandleGetLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs"+query, nil))
+		var resp struct {
+			Logs []process.LogEntry `json:"logs"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("invalid logs response: %v", err)
+		}
+		var lines []string
+		for _, e := range resp.Logs {
+			lines = append(lines, e.Line)
+		}
+		return lines
+	}
+
+	if got := strings.Join(getLines(t, ""), ","); got != "line 16,line 17,line 18,line 19,line 20" {
+		t.Errorf("expected the last 5 lines, got %s", got)
+	}
+	if got := getLines(t, "?lines=10"); len(got) != 10 {
+		t.Errorf("expected an explicit lines parameter to win, got %d lines", len(got))
+	}
+}
+
+func TestHandleGetLogs_RedactsHubToken(t *testing.T) {
+	const hubToken = "0123456789abcdef0123456789abcdef"
+	t.Setenv(redact.HubTokenEnv, hubToken)
+
+	redactor, _ := redact.FromEnv(nil)
+	patterns, err := redact.CompilePatterns(nil)
+	if err != nil {
+		t.Fatalf("failed to compile default patterns: %v", err)
+	}
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sh", "-c", `env | grep JUPYTERHUB_API_TOKEN; echo "GET /?token=abc123xyz"; echo "Authorization: Bearer eyJhbGciOi.payload"`},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10, Redactor: redactor.WithPatterns(patterns)}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() { _ = mgr.CloseLogFile() }()
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(mgr.GetRecentLogs(3)) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the process output to be captured")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	h := NewLogsHandler(mgr, log)
+	for _, target := range []string{"/api/logs", "/api/logs?raw=true", "/api/logs/all"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if strings.HasPrefix(target, "/api/logs/all") {
+			h.HandleGetAllLogs(rec, req)
+		} else {
+			h.HandleGetLogs(rec, req)
+		}
+		body := rec.Body.String()
+		for _, secret := range []string{hubToken, "abc123xyz", "eyJhbGciOi.payload"} {
+			if strings.Contains(body, secret) {
+				t.Errorf("expected %q to be redacted from %s, got %s", secret, target, body)
+			}
+		}
+		if !strings.Contains(body, redact.Placeholder) {
+			t.Errorf("expected %s to contain %q, got %s", target, redact.Placeholder, body)
+		}
+	}
+}
+
+func TestHandleStreamLogs(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		// Output starts after the client has connected, then the process exits
+		Command: []string{"sh", "-c", "sleep 0.5; echo hello; echo oops >&2; echo world; sleep 0.2"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() { _ = mgr.CloseLogFile() }()
+
+	mux := http.NewServeMux()
+	NewLogsHandler(mgr, log).RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/logs/stream?stream=stdout"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to open log stream: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	var lines []string
+	for {
+		var entry process.LogEntry
+		err := conn.ReadJSON(&entry)
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				t.Fatalf("expected normal close after the process exited, got %v", err)
+			}
+			break
+		}
+		if entry.Stream != "stdout" {
+			t.Errorf("expected only stdout entries, got %+v", entry)
+		}
+		lines = append(lines, entry.Line)
+	}
+
+	if strings.Join(lines, ",") != "hello,world" {
+		t.Errorf("expected streamed lines [hello world], got %v", lines)
+	}
+}
+
+func TestHandleStreamLogs_SSE(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sh", "-c", "sleep 0.5; echo hello; echo oops >&2; echo world; sleep 0.2"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() { _ = mgr.CloseLogFile() }()
+
+	mux := http.NewServeMux()
+	NewLogsHandler(mgr, log).RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(srv.URL + "/api/logs/stream?stream=stdout")
+	if err != nil {
+		t.Fatalf("failed to open event stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected Content-Type text/event-stream, got %q", ct)
+	}
+
+	var lines []string
+	var end string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "event: end" && scanner.Scan() {
+			end = strings.TrimPrefix(scanner.Text(), "data: ")
+			break
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var entry process.LogEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			t.Fatalf("invalid event data %q: %v", data, err)
+		}
+		if entry.Stream != "stdout" {
+			t.Errorf("expected only stdout entries, got %+v", entry)
+		}
+		lines = append(lines, entry.Line)
+	}
+
+	if strings.Join(lines, ",") != "hello,world" {
+		t.Errorf("expected streamed lines [hello world], got %v", lines)
+	}
+	if end != "process stopped" {
+		t.Errorf("expected end event %q, got %q", "process stopped", end)
+	}
+}
+
+func TestHandleStreamLogs_InvalidStream(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{Command: []string{"true"}},
+		process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() { _ = mgr.CloseLogFile() }()
+
+	rec := httptest.NewRecorder()
+	NewLogsHandler(mgr, log).HandleStreamLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs/stream?stream=bogus", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestRegisterInterimRoutes_RateLimit(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{Command: []string{"true"}},
+		process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() { _ = mgr.CloseLogFile() }()
+
+	const basePath = "/_temp/jhub-app-proxy"
+	h := NewLogsHandler(mgr, log)
+	h.SetRateLimiter(middleware.NewRateLimiter(middleware.RateLimitConfig{Rate: 1, Burst: 2}))
+	mux := http.NewServeMux()
+	h.RegisterInterimRoutes(mux, basePath)
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, basePath+path, nil))
+		return rec.Code
+	}
+
+	// The bucket is shared by all logs API endpoints
+	for _, path := range []string{"/api/logs/all", "/api/logs/stats"} {
+		if code := get(path); code != http.StatusOK {
+			t.Fatalf("expected status 200 for %s within the burst, got %d", path, code)
+		}
+	}
+	if code := get("/api/logs/all"); code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429 above the burst, got %d", code)
+	}
+
+	// The health endpoint runs a live check on every request, so it is limited too
+	if code := get(HealthPath); code != http.StatusTooManyRequests {
+		t.Errorf("expected %s to be rate limited, got %d", HealthPath, code)
+	}
+
+	// Static assets are not limited
+	if code := get("/static/logs.css"); code == http.StatusTooManyRequests {
+		t.Error("expected /static/logs.css not to be rate limited")
+	}
+}
+
+func TestHandleGetLogo(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	h := NewLogsHandler(nil, log)
+
+	rec := httptest.NewRecorder()
+	h.HandleGetLogo(rec, httptest.NewRequest(http.MethodGet, "/static/logo.png", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("expected the embedded PNG, got status %d and %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	h.SetLogoURL("https://cdn.example.com/logo.svg")
+	rec = httptest.NewRecorder()
+	h.HandleGetLogo(rec, httptest.NewRequest(http.MethodGet, "/static/logo.png", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://cdn.example.com/logo.svg" {
+		t.Errorf("expected a redirect to the custom logo, got status %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
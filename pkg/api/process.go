@@ -0,0 +1,107 @@
+// Package api - Sending signals to the subprocess
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/auth"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+// ProcessKillPath is the signal endpoint, relative to the interim base path
+// Reachable while the app is running, unlike the rest of the interim API
+const ProcessKillPath = "/api/process/kill"
+
+// allowedSignals are the signals the kill endpoint may send, by name
+// SIGKILL and SIGSTOP are left out: the restart endpoint is the way to replace a wedged app.
+var allowedSignals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// ProcessHandler provides HTTP endpoints acting on the subprocess itself
+type ProcessHandler struct {
+	manager *process.ManagerWithLogs
+	logger  *logger.Logger
+}
+
+// NewProcessHandler creates a new process API handler
+func NewProcessHandler(manager *process.ManagerWithLogs, log *logger.Logger) *ProcessHandler {
+	return &ProcessHandler{
+		manager: manager,
+		logger:  log.WithComponent("process-api"),
+	}
+}
+
+// HandleSignalProcess sends a signal to the subprocess, e.g. SIGUSR1 to make it reopen its logs
+// DELETE /api/process/kill?signal=SIGUSR1
+//
+// signal is one of SIGHUP, SIGTERM, SIGUSR1 or SIGUSR2 (the SIG prefix is optional);
+// other names get 400. Returns 409 Conflict if no process is running. A SIGTERM stops the
+// app like any other exit, so --max-restarts decides whether it comes back.
+func (h *ProcessHandler) HandleSignalProcess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.ToUpper(r.URL.Query().Get("signal"))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, ok := allowedSignals[name]
+	if !ok {
+		http.Error(w, "signal must be one of SIGHUP, SIGTERM, SIGUSR1, SIGUSR2", http.StatusBadRequest)
+		return
+	}
+
+	userName := ""
+	if data := r.Header.Get("X-Forwarded-User-Data"); data != "" {
+		var user auth.User
+		if err := json.Unmarshal([]byte(data), &user); err == nil {
+			userName = user.Name
+		}
+	}
+
+	pid, err := h.manager.Signal(sig)
+	if errors.Is(err, process.ErrNotRunning) {
+		http.Error(w, "Process is not running", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to signal process", err, "signal", name, "user_name", userName)
+		http.Error(w, "Failed to signal process", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("process signalled via API",
+		"signal", name,
+		"pid", pid,
+		"user_name", userName)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"signal": name,
+		"pid":    pid,
+	}); err != nil {
+		h.logger.Error("failed to encode response", err)
+	}
+}
+
+// RegisterRoutesWithAuth registers the process API under the interim path with authentication
+// There is no unauthenticated variant: signalling the app is never public.
+func (h *ProcessHandler) RegisterRoutesWithAuth(mux *http.ServeMux, basePath string, authMW auth.Middleware) {
+	mux.Handle(basePath+ProcessKillPath, authMW.Wrap(http.HandlerFunc(h.HandleSignalProcess)))
+
+	h.logger.Info("process API routes registered with authentication",
+		"endpoints", []string{
+			"DELETE " + basePath + ProcessKillPath,
+		})
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+// signalCatcher traps every allowed signal and echoes its name, exiting only on TERM
+const signalCatcher = `
+trap 'echo caught HUP' HUP
+trap 'echo caught USR1' USR1
+trap 'echo caught USR2' USR2
+trap 'echo caught TERM; exit 0' TERM
+echo ready
+while true; do sleep 0.05; done
+`
+
+// waitForLog waits until a captured log line contains want
+func waitForLog(t *testing.T, mgr *process.ManagerWithLogs, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, entry := range mgr.GetRecentLogs(-1) {
+			if strings.Contains(entry.Line, want) {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a log line containing %q", want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHandleSignalProcess(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+
+	for _, tt := range []struct {
+		query string
+		want  string
+	}{
+		{query: "SIGHUP", want: "caught HUP"},
+		{query: "SIGUSR1", want: "caught USR1"},
+		{query: "SIGUSR2", want: "caught USR2"},
+		{query: "usr1", want: "caught USR1"},
+		{query: "SIGTERM", want: "caught TERM"},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			mgr, err := process.NewManagerWithLogs(process.Config{
+				Command: []string{"sh", "-c", signalCatcher},
+			}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+			if err != nil {
+				t.Fatalf("failed to create manager: %v", err)
+			}
+			defer func() { _ = mgr.Stop() }()
+			if err := mgr.Start(context.Background()); err != nil {
+				t.Fatalf("failed to start process: %v", err)
+			}
+			waitForLog(t, mgr, "ready")
+
+			h := NewProcessHandler(mgr, log)
+			req := httptest.NewRequest(http.MethodDelete, ProcessKillPath+"?signal="+tt.query, nil)
+			req.Header.Set("X-Forwarded-User-Data", `{"name":"alice"}`)
+			rec := httptest.NewRecorder()
+			h.HandleSignalProcess(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var resp struct {
+				Signal string `json:"signal"`
+				PID    int    `json:"pid"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if resp.PID != mgr.GetPID() || !strings.HasPrefix(resp.Signal, "SIG") {
+				t.Errorf("expected signal name and pid %d, got %+v", mgr.GetPID(), resp)
+			}
+			waitForLog(t, mgr, tt.want)
+		})
+	}
+}
+
+func TestHandleSignalProcess_Rejected(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	h := NewProcessHandler(mgr, log)
+
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		wantStatus int
+	}{
+		{name: "unknown signal", method: http.MethodDelete, query: "?signal=SIGKILL", wantStatus: http.StatusBadRequest},
+		{name: "missing signal", method: http.MethodDelete, query: "", wantStatus: http.StatusBadRequest},
+		{name: "wrong method", method: http.MethodPost, query: "?signal=SIGUSR1", wantStatus: http.StatusMethodNotAllowed},
+		{name: "not running", method: http.MethodDelete, query: "?signal=SIGUSR1", wantStatus: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.HandleSignalProcess(rec, httptest.NewRequest(tt.method, ProcessKillPath+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Package api - Manual process restart
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ProcessRestartPath is the restart endpoint, relative to the interim base path
+// Reachable while the app is running, unlike the rest of the interim API
+const ProcessRestartPath = "/api/process/restart"
+
+// DeploymentTracker is the interim page's record of whether the app has been deployed
+// Implemented by interim.Handler
+type DeploymentTracker interface {
+	MarkAppDeployed()
+	ResetDeployment()
+	GracePeriodState() (active bool, expiresAt time.Time) // expiresAt is zero until the app is deployed
+}
+
+// ErrRestartInProgress is returned by RestartProcess while another restart is running
+var ErrRestartInProgress = errors.New("restart already in progress")
+
+// SetDeploymentTracker lets a manual restart bring the interim page back while the app restarts
+func (h *LogsHandler) SetDeploymentTracker(tracker DeploymentTracker) {
+	h.deployment = tracker
+}
+
+// HandleRestartProcess restarts the subprocess without restarting the proxy
+// POST /api/process/restart
+//
+// Returns 202 Accepted immediately; the restart runs in the background. Clients poll
+// GET /api/logs/stats and observe process_state.state go:
+//
+//	running -> stopped -> starting -> running (or failed if the ready check fails)
+//
+// While the app is not running, app URLs serve the interim page again. Returns 409
+// Conflict if a restart is already in progress.
+func (h *LogsHandler) HandleRestartProcess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.restarting.CompareAndSwap(false, true) {
+		http.Error(w, "Restart already in progress", http.StatusConflict)
+		return
+	}
+
+	h.logger.Info("process restart requested via API", "pid", h.manager.GetPID())
+
+	go func() {
+		defer h.restarting.Store(false)
+		_ = h.restart("")
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"status": "restarting",
+	}); err != nil {
+		h.logger.Error("failed to encode response", err)
+	}
+}
+
+// RestartProcess restarts the subprocess like the restart API, e.g. after its command changed
+// reason, if set, starts the fresh logs. Blocks until the process is launched and returns
+// ErrRestartInProgress if another restart is running.
+func (h *LogsHandler) RestartProcess(reason string) error {
+	if !h.restarting.CompareAndSwap(false, true) {
+		return ErrRestartInProgress
+	}
+	defer h.restarting.Store(false)
+	return h.restart(reason)
+}
+
+// restart brings the interim page back, clears the logs and restarts the process
+// The caller holds h.restarting.
+func (h *LogsHandler) restart(reason string) error {
+	if h.deployment != nil {
+		h.deployment.ResetDeployment()
+	}
+	h.manager.ClearLogs()
+	if reason != "" {
+		h.manager.AddInfoLog(reason)
+	}
+
+	if err := h.manager.Restart(); err != nil {
+		h.logger.Error("failed to restart process", err)
+		h.manager.AddErrorLog("ERROR: Failed to restart process: " + err.Error())
+		return err
+	}
+
+	if h.deployment != nil {
+		h.deployment.MarkAppDeployed()
+	}
+	return nil
+}
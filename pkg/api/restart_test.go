@@ -0,0 +1,145 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+// recordingTracker records deployment state changes made by a restart
+type recordingTracker struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (t *recordingTracker) MarkAppDeployed() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, "deployed")
+}
+
+func (t *recordingTracker) ResetDeployment() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, "reset")
+}
+
+func (t *recordingTracker) GracePeriodState() (bool, time.Time) {
+	return false, time.Time{}
+}
+
+func (t *recordingTracker) Events() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.events...)
+}
+
+func TestHandleRestartProcess(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sh", "-c", "echo launched; exec sleep 30"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+	}()
+
+	tracker := &recordingTracker{}
+	h := NewLogsHandler(mgr, log)
+	h.SetDeploymentTracker(tracker)
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.HandleRestartProcess(rec, httptest.NewRequest(http.MethodGet, ProcessRestartPath, nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+		}
+	})
+
+	if err := mgr.Start(t.Context()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	mgr.AddInfoLog("before restart")
+	firstPID := mgr.GetPID()
+
+	rec := httptest.NewRecorder()
+	h.HandleRestartProcess(rec, httptest.NewRequest(http.MethodPost, ProcessRestartPath, nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, rec.Code)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for (mgr.GetPID() == firstPID || !mgr.IsRunning()) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if mgr.GetPID() == firstPID || !mgr.IsRunning() {
+		t.Fatalf("expected process to be running with a new PID, state %s pid %d", mgr.GetState(), mgr.GetPID())
+	}
+
+	// The restart goroutine marks the app deployed after Start returns
+	deadline = time.Now().Add(time.Second)
+	for len(tracker.Events()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if events := tracker.Events(); len(events) != 2 || events[0] != "reset" || events[1] != "deployed" {
+		t.Errorf("expected deployment reset then deployed, got %v", events)
+	}
+
+	for _, entry := range mgr.GetRecentLogs(-1) {
+		if entry.Line == "before restart" {
+			t.Error("expected log buffer to be cleared by the restart")
+		}
+	}
+}
+
+func TestRestartProcess(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+	}()
+
+	tracker := &recordingTracker{}
+	h := NewLogsHandler(mgr, log)
+	h.SetDeploymentTracker(tracker)
+
+	if err := mgr.Start(t.Context()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	firstPID := mgr.GetPID()
+
+	// A restart through the API is running: the other path must not start a second one
+	h.restarting.Store(true)
+	if err := h.RestartProcess("Command file changed"); !errors.Is(err, ErrRestartInProgress) {
+		t.Errorf("expected %v, got %v", ErrRestartInProgress, err)
+	}
+	h.restarting.Store(false)
+
+	if err := h.RestartProcess("Command file changed"); err != nil {
+		t.Fatalf("failed to restart: %v", err)
+	}
+	if mgr.GetPID() == firstPID {
+		t.Error("expected a new process")
+	}
+	if events := tracker.Events(); len(events) != 2 || events[0] != "reset" || events[1] != "deployed" {
+		t.Errorf("expected deployment reset then deployed, got %v", events)
+	}
+	logs := mgr.GetRecentLogs(-1)
+	if len(logs) == 0 || logs[0].Line != "Command file changed" {
+		t.Errorf("expected the reason to start the fresh logs, got %v", logs)
+	}
+}
@@ -0,0 +1,217 @@
+// Package api - Server-side log search
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Log search limits
+const (
+	searchDefaultLimit = 100
+	searchMaxLimit     = 1000
+)
+
+// SearchMatch is a log line matching a search
+// Matches holds the [start, end) byte offsets of each match within Line.
+type SearchMatch struct {
+	LineNumber int        `json:"line_number"`         // Buffer: line number since start (as in /api/logs/context); file: line in the log file
+	Timestamp  *time.Time `json:"timestamp,omitempty"` // Unset for file lines, whose timestamp is part of Line
+	Stream     string     `json:"stream,omitempty"`
+	Line       string     `json:"line"`
+	Matches    [][]int    `json:"matches"`
+}
+
+// lineMatcher returns the [start, end) offsets of every match in a line (nil = no match)
+type lineMatcher func(line string) [][]int
+
+// newLineMatcher builds a matcher for a plain substring or an RE2 regular expression
+func newLineMatcher(query string, isRegex bool) (lineMatcher, error) {
+	if isRegex {
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, err
+		}
+		return func(line string) [][]int {
+			return re.FindAllStringIndex(line, -1)
+		}, nil
+	}
+
+	return func(line string) [][]int {
+		var matches [][]int
+		for start := 0; start <= len(line); {
+			i := strings.Index(line[start:], query)
+			if i < 0 {
+				break
+			}
+			matches = append(matches, []int{start + i, start + i + len(query)})
+			start += i + len(query)
+		}
+		return matches
+	}, nil
+}
+
+// HandleSearchLogs returns the log lines matching a query, like grep
+// Searches the memory buffer by default, or the whole persistent log file with source=file.
+// The query is a plain substring, or an RE2 regular expression with regex=true. At most
+// limit matches are returned, the most recent ones in log order; truncated is set if
+// older matches were left out.
+// GET /api/logs/search?q=Traceback&regex=false&stream=stderr&source=buffer&limit=100
+func (h *LogsHandler) HandleSearchLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		http.Error(w, "q parameter required", http.StatusBadRequest)
+		return
+	}
+
+	isRegex := r.URL.Query().Get("regex") == "true"
+	match, err := newLineMatcher(query, isRegex)
+	if err != nil {
+		http.Error(w, "invalid regex: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stream := r.URL.Query().Get("stream") // "stdout", "stderr", or "" for all
+	if stream != "" && stream != "stdout" && stream != "stderr" {
+		http.Error(w, "stream must be stdout or stderr", http.StatusBadRequest)
+		return
+	}
+
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		source = "buffer"
+	}
+	if source != "buffer" && source != "file" {
+		http.Error(w, "source must be buffer or file", http.StatusBadRequest)
+		return
+	}
+
+	limit := searchDefaultLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, searchMaxLimit)
+	}
+
+	var results []SearchMatch
+	var truncated bool
+	if source == "file" {
+		lines, err := h.manager.GetAllLogsFromFile()
+		if err != nil {
+			h.logger.Error("failed to read logs from file", err)
+			http.Error(w, "Failed to read logs", http.StatusInternalServerError)
+			return
+		}
+		results, truncated = searchFileLines(lines, match, stream, limit)
+	} else {
+		results, truncated = h.searchBuffer(match, stream, limit)
+	}
+
+	response := map[string]interface{}{
+		"matches":   results,
+		"count":     len(results),
+		"truncated": truncated,
+		"query": map[string]interface{}{
+			"q":      query,
+			"regex":  isRegex,
+			"stream": stream,
+			"source": source,
+			"limit":  limit,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode search response", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// searchBuffer finds the last limit matching buffered entries, returned oldest first
+func (h *LogsHandler) searchBuffer(match lineMatcher, stream string, limit int) ([]SearchMatch, bool) {
+	entries, firstLine := h.manager.GetLogsRange(1, math.MaxInt)
+
+	results := make([]SearchMatch, 0)
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if stream != "" && entry.Stream != stream {
+			continue
+		}
+		positions := match(entry.Line)
+		if positions == nil {
+			continue
+		}
+		if len(results) == limit {
+			slices.Reverse(results)
+			return results, true
+		}
+		timestamp := entry.Timestamp
+		results = append(results, SearchMatch{
+			LineNumber: firstLine + i,
+			Timestamp:  &timestamp,
+			Stream:     entry.Stream,
+			Line:       entry.Line,
+			Matches:    positions,
+		})
+	}
+	slices.Reverse(results)
+	return results, false
+}
+
+// searchFileLines finds the last limit matching log file lines, returned oldest first
+// File lines look like "[timestamp] [stream] line"; the query is matched against the
+// whole line, so offsets include the prefix.
+func searchFileLines(lines []string, match lineMatcher, stream string, limit int) ([]SearchMatch, bool) {
+	results := make([]SearchMatch, 0)
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		lineStream := fileLineStream(line)
+		if stream != "" && lineStream != stream {
+			continue
+		}
+		positions := match(line)
+		if positions == nil {
+			continue
+		}
+		if len(results) == limit {
+			slices.Reverse(results)
+			return results, true
+		}
+		results = append(results, SearchMatch{
+			LineNumber: i + 1,
+			Stream:     lineStream,
+			Line:       line,
+			Matches:    positions,
+		})
+	}
+	slices.Reverse(results)
+	return results, false
+}
+
+// fileLineStream returns the stream of a "[timestamp] [stream] line" log file line ("" if unknown)
+func fileLineStream(line string) string {
+	_, rest, ok := strings.Cut(line, "] [")
+	if !ok {
+		return ""
+	}
+	stream, _, ok := strings.Cut(rest, "] ")
+	if !ok || (stream != "stdout" && stream != "stderr") {
+		return ""
+	}
+	return stream
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+type searchResponse struct {
+	Matches   []SearchMatch `json:"matches"`
+	Count     int           `json:"count"`
+	Truncated bool          `json:"truncated"`
+}
+
+// newSearchHandler runs a process printing known lines and returns a handler over its logs
+func newSearchHandler(t *testing.T) *LogsHandler {
+	t.Helper()
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sh", "-c", `echo "starting app"; sleep 0.05; echo "error: port 8080 in use" >&2; sleep 0.05; echo "retry port 8081"; sleep 0.05; echo "error: giving up" >&2`},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	t.Cleanup(func() { _ = mgr.CloseLogFile() })
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(mgr.GetRecentLogs(-1)) < 4 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the process output to be captured")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return NewLogsHandler(mgr, log)
+}
+
+func search(t *testing.T, h *LogsHandler, query string) searchResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.HandleSearchLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs/search"+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: expected status 200, got %d: %s", query, rec.Code, rec.Body.String())
+	}
+	var resp searchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid search response: %v", err)
+	}
+	return resp
+}
+
+func TestHandleSearchLogs_Substring(t *testing.T) {
+	h := newSearchHandler(t)
+
+	resp := search(t, h, "?q=port")
+	if resp.Count != 2 || len(resp.Matches) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", resp.Matches)
+	}
+	// Matches come in log order, with stable line numbers and match offsets
+	first := resp.Matches[0]
+	if first.Line != "error: port 8080 in use" || first.Stream != "stderr" {
+		t.Errorf("expected the stderr port line first, got %+v", first)
+	}
+	if len(first.Matches) != 1 || first.Matches[0][0] != 7 || first.Matches[0][1] != 11 {
+		t.Errorf("expected match at [7 11], got %v", first.Matches)
+	}
+	if resp.Matches[1].Line != "retry port 8081" {
+		t.Errorf("expected the stdout port line second, got %+v", resp.Matches[1])
+	}
+	if resp.Matches[1].LineNumber <= first.LineNumber {
+		t.Errorf("expected increasing line numbers, got %d then %d", first.LineNumber, resp.Matches[1].LineNumber)
+	}
+
+	t.Run("stream filter", func(t *testing.T) {
+		resp := search(t, h, "?q=port&stream=stdout")
+		if resp.Count != 1 || resp.Matches[0].Line != "retry port 8081" {
+			t.Errorf("expected only the stdout match, got %+v", resp.Matches)
+		}
+	})
+
+	t.Run("limit keeps the most recent matches", func(t *testing.T) {
+		resp := search(t, h, "?q=error&limit=1")
+		if resp.Count != 1 || resp.Matches[0].Line != "error: giving up" || !resp.Truncated {
+			t.Errorf("expected the last error and truncated, got %+v (truncated %v)", resp.Matches, resp.Truncated)
+		}
+	})
+
+	t.Run("regex characters are literal", func(t *testing.T) {
+		if resp := search(t, h, "?q=port.808"); resp.Count != 0 {
+			t.Errorf("expected no matches for a literal dot, got %+v", resp.Matches)
+		}
+	})
+}
+
+func TestHandleSearchLogs_Regex(t *testing.T) {
+	h := newSearchHandler(t)
+
+	resp := search(t, h, "?q=port+808[0-9]&regex=true")
+	if resp.Count != 2 {
+		t.Fatalf("expected 2 matches, got %+v", resp.Matches)
+	}
+	if got := resp.Matches[1].Matches; len(got) != 1 || got[0][0] != 6 || got[0][1] != 15 {
+		t.Errorf("expected match at [6 15], got %v", got)
+	}
+
+	resp = search(t, h, "?q=^error&regex=true&stream=stderr")
+	if resp.Count != 2 {
+		t.Errorf("expected both stderr errors, got %+v", resp.Matches)
+	}
+}
+
+func TestHandleSearchLogs_File(t *testing.T) {
+	h := newSearchHandler(t)
+
+	resp := search(t, h, "?q=port&source=file&stream=stderr")
+	if resp.Count != 1 {
+		t.Fatalf("expected 1 match from the log file, got %+v", resp.Matches)
+	}
+	if m := resp.Matches[0]; m.Stream != "stderr" || m.Timestamp != nil || m.Line[m.Matches[0][0]:m.Matches[0][1]] != "port" {
+		t.Errorf("expected a stderr file line with offsets into the full line, got %+v", m)
+	}
+}
+
+func TestHandleSearchLogs_InvalidParameters(t *testing.T) {
+	h := newSearchHandler(t)
+
+	for _, query := range []string{
+		"",
+		"?q=(unclosed&regex=true",
+		"?q=port&stream=both",
+		"?q=port&source=disk",
+		"?q=port&limit=0",
+	} {
+		rec := httptest.NewRecorder()
+		h.HandleSearchLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs/search"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package api - Process state change stream
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// StateStreamPath is the process state event stream, relative to the API base path
+const StateStreamPath = "/api/logs/state"
+
+// stateStreamPingInterval keeps idle state streams open through proxies with short read timeouts
+const stateStreamPingInterval = 15 * time.Second
+
+// HandleStateStream streams process state changes as Server-Sent Events
+// GET /api/logs/state
+//
+// The first "state" event carries the current state; every later one a transition, e.g.
+// {"previous": "starting", "state": "running", "timestamp": "..."}. A "ping" event is
+// sent every 15 seconds. The stream stays open until the client disconnects.
+func (h *LogsHandler) HandleStateStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop nginx-style proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	h.logger.Debug("state event stream opened", "remote_addr", r.RemoteAddr)
+
+	ctx := r.Context()
+	changes := h.manager.SubscribeState(ctx)
+	ping := time.NewTicker(stateStreamPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case change := <-changes:
+			data, err := json.Marshal(change)
+			if err != nil {
+				h.logger.Error("failed to encode state event", err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: state\ndata: %s\n\n", data); err != nil {
+				h.logger.Debug("state event stream write failed", "error", err)
+				return
+			}
+			flusher.Flush()
+
+		case <-ping.C:
+			if _, err := io.WriteString(w, "event: ping\ndata: {}\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+// stateEvents reads "state" events from an SSE response body into a channel
+func stateEvents(t *testing.T, body io.Reader) <-chan process.StateChange {
+	t.Helper()
+	events := make(chan process.StateChange, 10)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(body)
+		event := ""
+		for scanner.Scan() {
+			line := scanner.Text()
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+				continue
+			}
+			data, ok := strings.CutPrefix(line, "data: ")
+			if !ok || event != "state" {
+				continue
+			}
+			var change process.StateChange
+			if err := json.Unmarshal([]byte(data), &change); err != nil {
+				t.Errorf("invalid state event %q: %v", data, err)
+				return
+			}
+			events <- change
+		}
+	}()
+	return events
+}
+
+func nextState(t *testing.T, events <-chan process.StateChange) process.StateChange {
+	t.Helper()
+	select {
+	case change, ok := <-events:
+		if !ok {
+			t.Fatal("state stream closed unexpectedly")
+		}
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a state event")
+	}
+	return process.StateChange{}
+}
+
+func TestHandleStateStream(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	ready := make(chan struct{})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+		ReadyCheck: func(ctx context.Context) error {
+			select {
+			case <-ready:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() { _ = mgr.CloseLogFile() }()
+	defer func() { _ = mgr.Stop() }()
+
+	h := NewLogsHandler(mgr, log)
+	handlerDone := make(chan struct{}, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.HandleStateStream(w, r)
+		handlerDone <- struct{}{}
+	}))
+	defer srv.Close()
+
+	// Two clients watch concurrently
+	var clients []<-chan process.StateChange
+	var cancels []context.CancelFunc
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+StateStreamPath, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to open state stream: %v", err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("expected Content-Type text/event-stream, got %q", ct)
+		}
+		events := stateEvents(t, resp.Body)
+		if got := nextState(t, events); got.State != process.StateInitializing || got.Previous != "" {
+			t.Fatalf("client %d: expected the current state first, got %+v", i, got)
+		}
+		clients = append(clients, events)
+		cancels = append(cancels, cancel)
+	}
+
+	startErr := make(chan error, 1)
+	go func() { startErr <- mgr.Start(context.Background()) }()
+	for i, events := range clients {
+		if got := nextState(t, events); got.Previous != process.StateInitializing || got.State != process.StateStarting {
+			t.Errorf("client %d: expected initializing -> starting, got %+v", i, got)
+		}
+	}
+
+	close(ready)
+	if err := <-startErr; err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	for i, events := range clients {
+		if got := nextState(t, events); got.Previous != process.StateStarting || got.State != process.StateRunning {
+			t.Errorf("client %d: expected starting -> running, got %+v", i, got)
+		}
+	}
+
+	// A disconnecting client ends its handler
+	cancels[0]()
+	select {
+	case <-handlerDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the handler to return once the client disconnected")
+	}
+}
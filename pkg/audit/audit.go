@@ -0,0 +1,88 @@
+// Package audit records an audit trail of authenticated requests
+//
+// Each request made by a user authenticated through JupyterHub OAuth is written
+// as one JSON line (user, method, path, status, timestamp) to a dedicated file,
+// separate from the operational logs.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/auth"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
+)
+
+// Entry is a single audit record
+type Entry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	User       string    `json:"user"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	RemoteAddr string    `json:"remote_addr"`
+}
+
+// Logger writes audit entries as JSON lines
+type Logger struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFileLogger opens (or creates) path for appending audit entries
+func NewFileLogger(path string) (*Logger, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log file: %w", err)
+	}
+	return &Logger{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Record appends an entry to the audit log
+func (l *Logger) Record(entry Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(entry); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Close closes the audit log file
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// Wrap records an audit entry once next has handled the request
+// Must run inside auth.OAuthMiddleware.Wrap so the user is known; requests
+// without an authenticated user are passed through unrecorded.
+func (l *Logger) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Capture path before handlers (e.g. prefix stripping) modify the request
+		path := r.URL.Path
+		sw := middleware.NewStatusRecorder(w)
+		next.ServeHTTP(sw, r)
+
+		_ = l.Record(Entry{
+			Timestamp:  time.Now().UTC(),
+			User:       user.Name,
+			Method:     r.Method,
+			Path:       path,
+			Status:     sw.Status(),
+			RemoteAddr: r.RemoteAddr,
+		})
+	})
+}
@@ -0,0 +1,189 @@
+// Package auth - HTTP Basic authentication for deployments without JupyterHub
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Middleware authenticates requests before they reach next
+// Implemented by OAuthMiddleware and BasicAuthMiddleware.
+type Middleware interface {
+	Wrap(next http.Handler) http.Handler
+}
+
+// BasicAuthRealm is the realm announced in the WWW-Authenticate challenge
+const BasicAuthRealm = "jhub-app-proxy"
+
+// BasicAuthConfig holds the accepted credentials
+// User and Password add a single account; File is an htpasswd file with more. At least one must be set.
+type BasicAuthConfig struct {
+	User     string
+	Password string
+	File     string // htpasswd file with bcrypt ($2y$) or {SHA} hashes
+}
+
+// BasicAuthMiddleware handles HTTP Basic authentication
+type BasicAuthMiddleware struct {
+	users  map[string]passwordCheck
+	logger *logger.Logger
+
+	// Checked for unknown users when some users have bcrypt hashes, at the highest cost among
+	// them, so a failed login takes as long whether or not the user exists (nil = no bcrypt users)
+	dummyHash []byte
+}
+
+// passwordCheck reports whether a password is correct for one user
+type passwordCheck func(password string) bool
+
+// NewBasicAuthMiddleware creates a Basic auth middleware accepting the configured credentials
+func NewBasicAuthMiddleware(cfg BasicAuthConfig, log *logger.Logger) (*BasicAuthMiddleware, error) {
+	users := make(map[string]passwordCheck)
+
+	var dummyHash []byte
+	if cfg.File != "" {
+		bcryptCost, err := loadHtpasswd(cfg.File, users)
+		if err != nil {
+			return nil, err
+		}
+		if bcryptCost > 0 {
+			if dummyHash, err = bcrypt.GenerateFromPassword([]byte("jhub-app-proxy"), bcryptCost); err != nil {
+				return nil, fmt.Errorf("failed to create dummy bcrypt hash: %w", err)
+			}
+		}
+	}
+
+	if cfg.User != "" || cfg.Password != "" {
+		if cfg.User == "" || cfg.Password == "" {
+			return nil, fmt.Errorf("--basic-auth-user and --basic-auth-pass must be set together")
+		}
+		want := []byte(cfg.Password)
+		users[cfg.User] = func(password string) bool {
+			return subtle.ConstantTimeCompare([]byte(password), want) == 1
+		}
+	}
+
+	if len(users) == 0 {
+		return nil, fmt.Errorf("basic auth needs --basic-auth-user and --basic-auth-pass, or --basic-auth-file")
+	}
+
+	return &BasicAuthMiddleware{
+		users:     users,
+		logger:    log.WithComponent("basic-auth"),
+		dummyHash: dummyHash,
+	}, nil
+}
+
+// loadHtpasswd adds the users of an htpasswd file to users and returns the highest bcrypt cost (0 = none)
+// Only bcrypt and {SHA} hashes are supported; other formats (e.g. $apr1$ MD5) fail the load
+// rather than locking the user out silently.
+func loadHtpasswd(path string, users map[string]passwordCheck) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open --basic-auth-file: %w", err)
+	}
+	defer file.Close()
+
+	var maxCost int
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" || hash == "" {
+			return 0, fmt.Errorf("%s:%d: expected user:hash", path, lineNum)
+		}
+		check, err := htpasswdCheck(hash)
+		if err != nil {
+			return 0, fmt.Errorf("%s:%d: user %q: %w", path, lineNum, user, err)
+		}
+		users[user] = check
+		if cost, err := bcrypt.Cost([]byte(hash)); err == nil {
+			maxCost = max(maxCost, cost)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read --basic-auth-file: %w", err)
+	}
+	return maxCost, nil
+}
+
+// htpasswdCheck returns the password check for an htpasswd hash
+func htpasswdCheck(hash string) (passwordCheck, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2y$"), strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"):
+		hashed := []byte(hash)
+		if _, err := bcrypt.Cost(hashed); err != nil {
+			return nil, fmt.Errorf("invalid bcrypt hash: %w", err)
+		}
+		return func(password string) bool {
+			return bcrypt.CompareHashAndPassword(hashed, []byte(password)) == nil
+		}, nil
+	case strings.HasPrefix(hash, "{SHA}"):
+		want := strings.TrimPrefix(hash, "{SHA}")
+		return func(password string) bool {
+			sum := sha1.Sum([]byte(password))
+			got := base64.StdEncoding.EncodeToString(sum[:])
+			return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported hash format (use bcrypt: htpasswd -B)")
+	}
+}
+
+// Wrap wraps an HTTP handler with Basic authentication
+// Unauthenticated requests get 401 with a WWW-Authenticate challenge, so browsers prompt
+// for credentials. The credentials are not forwarded to next; the user name is, in the
+// same X-Forwarded-User and X-Forwarded-User-Data headers (and request context) as with OAuth.
+func (m *BasicAuthMiddleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, password, ok := r.BasicAuth()
+		if !ok || !m.authenticate(name, password) {
+			if ok {
+				m.logger.Warn("basic auth failed", "user_name", name, "path", r.URL.Path)
+			}
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, BasicAuthRealm))
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		middleware.SetAuthenticatedUser(r.Context(), name)
+		user := &User{Name: name}
+		pr := r.WithContext(context.WithValue(r.Context(), userContextKey{}, user))
+		pr.Header.Del("Authorization")
+		pr.Header.Set("X-Forwarded-User", name)
+		pr.Header.Del("X-Forwarded-Groups")
+		userData, _ := json.Marshal(user)
+		pr.Header.Set("X-Forwarded-User-Data", string(userData))
+
+		next.ServeHTTP(w, pr)
+	})
+}
+
+// authenticate reports whether password is correct for name
+// An unknown user costs a bcrypt comparison too when known ones do, so the response
+// time doesn't tell which user names exist.
+func (m *BasicAuthMiddleware) authenticate(name, password string) bool {
+	check, ok := m.users[name]
+	if !ok {
+		if m.dummyHash != nil {
+			_ = bcrypt.CompareHashAndPassword(m.dummyHash, []byte(password))
+		}
+		return false
+	}
+	return check(password)
+}
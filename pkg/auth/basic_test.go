@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBasicAuthMiddleware(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("file-secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	htpasswd := filepath.Join(t.TempDir(), "htpasswd")
+	content := "# users\nbob:" + string(hash) + "\n" +
+		"carol:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n" // sha1("password")
+	if err := os.WriteFile(htpasswd, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write htpasswd: %v", err)
+	}
+
+	mw, err := NewBasicAuthMiddleware(BasicAuthConfig{
+		User:     "alice",
+		Password: "flag-secret",
+		File:     htpasswd,
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+	handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := UserFromContext(r.Context())
+		_, _ = io.WriteString(w, user.Name+"|"+r.Header.Get("X-Forwarded-User")+"|"+r.Header.Get("Authorization"))
+	}))
+
+	tests := []struct {
+		name       string
+		user       string
+		password   string
+		noAuth     bool
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "flag user", user: "alice", password: "flag-secret", wantStatus: http.StatusOK, wantBody: "alice|alice|"},
+		{name: "bcrypt user", user: "bob", password: "file-secret", wantStatus: http.StatusOK, wantBody: "bob|bob|"},
+		{name: "sha user", user: "carol", password: "password", wantStatus: http.StatusOK, wantBody: "carol|carol|"},
+		{name: "wrong password", user: "alice", password: "file-secret", wantStatus: http.StatusUnauthorized},
+		{name: "wrong bcrypt password", user: "bob", password: "flag-secret", wantStatus: http.StatusUnauthorized},
+		{name: "unknown user", user: "mallory", password: "flag-secret", wantStatus: http.StatusUnauthorized},
+		{name: "no credentials", noAuth: true, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/app/", nil)
+			if !tt.noAuth {
+				req.SetBasicAuth(tt.user, tt.password)
+			}
+			req.Header.Set("X-Forwarded-User", "admin") // spoofed, must be overwritten
+			var accessLog bytes.Buffer
+			rec := httptest.NewRecorder()
+			middleware.AccessLog(logger.New(logger.Config{Output: &accessLog, Format: logger.FormatJSON}), handler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			// Only an authenticated user is logged, never the spoofed header
+			var event map[string]interface{}
+			if err := json.Unmarshal(accessLog.Bytes(), &event); err != nil {
+				t.Fatalf("expected one access log event, got %q: %v", accessLog.String(), err)
+			}
+			wantUser := tt.user
+			if tt.wantStatus != http.StatusOK {
+				wantUser = ""
+			}
+			if got, _ := event["user_name"].(string); got != wantUser {
+				t.Errorf("expected access log user %q, got %q", wantUser, got)
+			}
+			if tt.wantStatus == http.StatusUnauthorized {
+				challenge := rec.Header().Get("WWW-Authenticate")
+				if !strings.HasPrefix(challenge, `Basic realm="jhub-app-proxy"`) {
+					t.Errorf("expected a Basic challenge, got %q", challenge)
+				}
+				return
+			}
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewBasicAuthMiddleware_Invalid(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name string
+		cfg  BasicAuthConfig
+	}{
+		{name: "no credentials", cfg: BasicAuthConfig{}},
+		{name: "user without password", cfg: BasicAuthConfig{User: "alice"}},
+		{name: "missing file", cfg: BasicAuthConfig{File: filepath.Join(dir, "missing")}},
+		{name: "md5 hash", cfg: BasicAuthConfig{File: write("md5", "alice:$apr1$abc$def\n")}},
+		{name: "malformed line", cfg: BasicAuthConfig{File: write("malformed", "alice\n")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBasicAuthMiddleware(tt.cfg, log); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestBasicAuthMiddleware_UnknownUserHash(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	dir := t.TempDir()
+
+	// The dummy hash checked for unknown users costs as much as the slowest real one
+	var lines strings.Builder
+	for user, cost := range map[string]int{"bob": bcrypt.MinCost, "dave": bcrypt.MinCost + 1} {
+		hash, err := bcrypt.GenerateFromPassword([]byte("secret"), cost)
+		if err != nil {
+			t.Fatalf("failed to hash password: %v", err)
+		}
+		lines.WriteString(user + ":" + string(hash) + "\n")
+	}
+	htpasswd := filepath.Join(dir, "htpasswd")
+	if err := os.WriteFile(htpasswd, []byte(lines.String()), 0o600); err != nil {
+		t.Fatalf("failed to write htpasswd: %v", err)
+	}
+
+	mw, err := NewBasicAuthMiddleware(BasicAuthConfig{File: htpasswd}, log)
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+	if cost, err := bcrypt.Cost(mw.dummyHash); err != nil || cost != bcrypt.MinCost+1 {
+		t.Errorf("expected a dummy hash of cost %d, got %d (%v)", bcrypt.MinCost+1, cost, err)
+	}
+	if mw.authenticate("mallory", "secret") {
+		t.Error("expected an unknown user to be rejected")
+	}
+
+	// Without bcrypt users every check is fast, so an unknown user must be too
+	flagOnly, err := NewBasicAuthMiddleware(BasicAuthConfig{User: "alice", Password: "secret"}, log)
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+	if flagOnly.dummyHash != nil {
+		t.Error("expected no dummy hash without bcrypt users")
+	}
+}
@@ -2,17 +2,23 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
+	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
 )
 
 // OAuthMiddleware handles JupyterHub OAuth authentication
@@ -26,7 +32,105 @@ type OAuthMiddleware struct {
 	cookieName   string
 	headerName   string
 	callbackPath string // Custom callback path (e.g., "oauth_callback" or "_temp/jhub-app-proxy/oauth_callback")
+	logoutURL    string // Where HandleLogout redirects (empty = the Hub home page)
+	authz        AuthConfig
 	logger       *logger.Logger
+
+	// Hub user lookups cached by token (nil = every request asks the Hub)
+	userCache     *lru.Cache[string, cachedUser]
+	userCacheTTL  time.Duration
+	onCacheLookup func(hit bool) // Called on each cache lookup (optional, e.g. metrics)
+	now           func() time.Time
+}
+
+// cachedUser is a Hub user lookup result kept until expires
+type cachedUser struct {
+	user    *User
+	expires time.Time
+}
+
+// AuthConfig restricts which authenticated users may access wrapped handlers
+// With both lists empty every user with a valid token is allowed. Otherwise a user
+// is allowed if they are listed in AllowedUsers or belong to any of AllowedGroups.
+// On top of that, the user's token must carry every one of RequiredScopes.
+type AuthConfig struct {
+	AllowedGroups  []string // JupyterHub group names
+	AllowedUsers   []string // JupyterHub user names
+	RequiredScopes []string // JupyterHub scopes, matched exactly (e.g. "custom:dashboard:view")
+}
+
+// Restricted reports whether the config limits access beyond holding a valid token
+func (c AuthConfig) Restricted() bool {
+	return len(c.AllowedGroups) > 0 || len(c.AllowedUsers) > 0 || len(c.RequiredScopes) > 0
+}
+
+// deny returns the rule the user fails, for logging, or "" if they are allowed
+func (c AuthConfig) deny(user *User) string {
+	if !c.allows(user) {
+		return "--allowed-users/--allowed-groups"
+	}
+	for _, scope := range c.RequiredScopes {
+		if !slices.Contains(user.Scopes, scope) {
+			return "--required-scope " + scope
+		}
+	}
+	return ""
+}
+
+// allows reports whether the user passes the allowed users and groups lists
+func (c AuthConfig) allows(user *User) bool {
+	if len(c.AllowedUsers) == 0 && len(c.AllowedGroups) == 0 {
+		return true
+	}
+	for _, name := range c.AllowedUsers {
+		if user.Name == name {
+			return true
+		}
+	}
+	for _, allowed := range c.AllowedGroups {
+		for _, group := range user.Groups {
+			if group == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetAuthConfig restricts access to the given users and groups
+func (m *OAuthMiddleware) SetAuthConfig(cfg AuthConfig) {
+	m.authz = cfg
+}
+
+// LogoutPath is the logout endpoint, relative to the interim base path
+// Not at the app root, where it would shadow an app's own /logout (e.g. JupyterLab)
+const LogoutPath = "/logout"
+
+// SetLogoutRedirect sets where HandleLogout sends the browser (empty = the Hub home page)
+func (m *OAuthMiddleware) SetLogoutRedirect(url string) {
+	m.logoutURL = url
+}
+
+// SetUserCache caches up to size Hub user lookups by token for ttl, so repeated requests
+// with the same token skip the Hub API. A ttl or size <= 0 disables the cache.
+// A token revoked in the Hub keeps working here until its cache entry expires.
+func (m *OAuthMiddleware) SetUserCache(size int, ttl time.Duration) error {
+	if size <= 0 || ttl <= 0 {
+		m.userCache = nil
+		return nil
+	}
+	cache, err := lru.New[string, cachedUser](size)
+	if err != nil {
+		return fmt.Errorf("failed to create user cache: %w", err)
+	}
+	m.userCache = cache
+	m.userCacheTTL = ttl
+	return nil
+}
+
+// SetCacheObserver sets a callback run on each user cache lookup (e.g. to count hits and misses)
+func (m *OAuthMiddleware) SetCacheObserver(fn func(hit bool)) {
+	m.onCacheLookup = fn
 }
 
 // NewOAuthMiddleware creates a new OAuth middleware with default callback path
@@ -87,6 +191,7 @@ func NewOAuthMiddlewareWithCallbackPath(log *logger.Logger, callbackPath string)
 		headerName:   "X-Jupyterhub-Api-Token",
 		callbackPath: callbackPath,
 		logger:       log.WithComponent("oauth"),
+		now:          time.Now,
 	}, nil
 }
 
@@ -109,13 +214,34 @@ func (m *OAuthMiddleware) Wrap(next http.Handler) http.Handler {
 			if err != nil {
 				return false
 			}
+			middleware.SetAuthenticatedUser(r.Context(), user.Name)
+
+			// A valid token is not enough when access is limited to some users, groups or scopes
+			if rule := m.authz.deny(user); rule != "" {
+				m.logger.Warn("user not allowed",
+					"user_name", user.Name,
+					"rule", rule,
+					"user_groups", user.Groups,
+					"user_scopes", user.Scopes,
+					"path", r.URL.Path)
+				http.Error(w, "Forbidden: you are not allowed to access this app", http.StatusForbidden)
+				return true
+			}
 
-			pr := new(http.Request)
-			*pr = *r
+			// Make the resolved user available to downstream handlers (e.g. audit logging)
+			pr := r.WithContext(context.WithValue(r.Context(), userContextKey{}, user))
 
 			userData, _ := json.Marshal(user)
 			pr.Header.Set("X-Forwarded-User-Data", string(userData))
 
+			// Identity headers for the backend, always overwriting anything the client sent
+			pr.Header.Set("X-Forwarded-User", user.Name)
+			if len(user.Groups) > 0 {
+				pr.Header.Set("X-Forwarded-Groups", strings.Join(user.Groups, ","))
+			} else {
+				pr.Header.Del("X-Forwarded-Groups")
+			}
+
 			m.logger.Info("setting user data in headers",
 				"header", "X-Forwarded-User-Data",
 				"user_name", user.Name,
@@ -133,6 +259,10 @@ func (m *OAuthMiddleware) Wrap(next http.Handler) http.Handler {
 			return
 		}
 
+		if maybeProxy(tokenFromAuthorization(r.Header.Get("Authorization"))) {
+			return
+		}
+
 		cookie, err := r.Cookie(m.cookieName)
 		if err == nil && maybeProxy(cookie.Value) {
 			return
@@ -143,6 +273,30 @@ func (m *OAuthMiddleware) Wrap(next http.Handler) http.Handler {
 	})
 }
 
+// tokenFromAuthorization extracts the token from an "Authorization: Bearer <token>"
+// or "Authorization: token <token>" header (the scheme JupyterHub itself uses)
+// Returns "" for other schemes such as Basic
+func tokenFromAuthorization(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok {
+		return ""
+	}
+	if !strings.EqualFold(scheme, "bearer") && !strings.EqualFold(scheme, "token") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// userContextKey is the request context key holding the authenticated *User
+type userContextKey struct{}
+
+// UserFromContext returns the user authenticated by OAuthMiddleware.Wrap, if any
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(*User)
+	return user, ok && user != nil
+}
+
+// User is a JupyterHub user as returned by the Hub's /user API
 type User struct {
 	Name   string   `json:"name"`
 	Admin  bool     `json:"admin"`
@@ -151,7 +305,39 @@ type User struct {
 	Scopes []string `json:"scopes"`
 }
 
+// getUser resolves the user owning token, from the cache if possible
 func (m *OAuthMiddleware) getUser(token string) (*User, error) {
+	if m.userCache == nil {
+		return m.fetchUser(token)
+	}
+
+	entry, ok := m.userCache.Get(token)
+	hit := ok && m.now().Before(entry.expires)
+	if m.onCacheLookup != nil {
+		m.onCacheLookup(hit)
+	}
+	if hit {
+		return entry.user, nil
+	}
+
+	user, err := m.fetchUser(token)
+	if err != nil {
+		// Don't keep a user the Hub no longer accepts the token for; a Hub that is down or
+		// failing (network errors, 5xx) says nothing about the token, so its entry stays
+		if errors.Is(err, errTokenRejected) {
+			m.userCache.Remove(token)
+		}
+		return nil, err
+	}
+	m.userCache.Add(token, cachedUser{user: user, expires: m.now().Add(m.userCacheTTL)})
+	return user, nil
+}
+
+// errTokenRejected is wrapped by fetchUser errors for a token the Hub refused (401 or 403)
+var errTokenRejected = errors.New("token rejected by the hub")
+
+// fetchUser asks the Hub API for the user owning token
+func (m *OAuthMiddleware) fetchUser(token string) (*User, error) {
 	req, err := http.NewRequest("GET", m.apiURL+"/user", nil)
 	if err != nil {
 		return nil, err
@@ -164,6 +350,9 @@ func (m *OAuthMiddleware) getUser(token string) (*User, error) {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("request to %s returned status %d: %w", req.URL.String(), resp.StatusCode, errTokenRejected)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("request to %s returned status %d", req.URL.String(), resp.StatusCode)
 	}
@@ -237,38 +426,12 @@ func (m *OAuthMiddleware) handleCallback(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Exchange code for token
-	redirectURI := m.baseURL + m.callbackPath
-	data := url.Values{}
-	data.Set("client_id", m.clientID)
-	data.Set("client_secret", m.apiToken)
-	data.Set("grant_type", "authorization_code")
-	data.Set("code", code)
-	data.Set("redirect_uri", redirectURI)
-
-	req, _ := http.NewRequest("POST", m.apiURL+"/oauth2/token", strings.NewReader(data.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	resp, err := http.DefaultClient.Do(req)
+	accessToken, err := m.exchangeCode(r.Context(), code)
 	if err != nil {
+		m.logger.Error("token exchange failed", err)
 		http.Error(w, "Token exchange failed", http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		m.logger.Error("token exchange failed", fmt.Errorf("status %d: %s", resp.StatusCode, string(body)))
-		http.Error(w, "Token exchange failed", http.StatusInternalServerError)
-		return
-	}
-
-	var tokenResp struct {
-		AccessToken string `json:"access_token"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		http.Error(w, "Failed to parse token", http.StatusInternalServerError)
-		return
-	}
 
 	// Clear state cookie
 	http.SetCookie(w, &http.Cookie{
@@ -281,7 +444,7 @@ func (m *OAuthMiddleware) handleCallback(w http.ResponseWriter, r *http.Request)
 	// Set token cookie
 	http.SetCookie(w, &http.Cookie{
 		Name:     m.cookieName,
-		Value:    tokenResp.AccessToken,
+		Value:    accessToken,
 		Path:     m.baseURL,
 		HttpOnly: true,
 		Secure:   r.TLS != nil,
@@ -303,3 +466,104 @@ func (m *OAuthMiddleware) handleCallback(w http.ResponseWriter, r *http.Request)
 
 	http.Redirect(w, r, redirectURL, http.StatusFound)
 }
+
+// HandleLogout ends the OAuth session: it expires the token, state and next cookies and
+// redirects to the logout URL, the Hub home page by default
+// Only this app's session ends; the user stays logged in to JupyterHub, so the next visit
+// signs them in again without a prompt.
+func (m *OAuthMiddleware) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	for _, name := range []string{m.cookieName, m.cookieName + "-oauth-state", m.cookieName + "-oauth-next"} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     m.baseURL,
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	redirectURL := m.logoutURL
+	if redirectURL == "" {
+		redirectURL = m.hubHost + m.hubPrefix + "home"
+	}
+	m.logger.Info("OAuth session cleared", "path", r.URL.Path, "redirect", redirectURL)
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+// Token exchange retry policy
+// Bounded so a struggling hub fails the login quickly instead of hanging the browser
+const (
+	tokenExchangeAttempts = 3
+	tokenExchangeBackoff  = 250 * time.Millisecond // Doubled after each failed attempt
+	tokenExchangeTimeout  = 10 * time.Second       // Overall, across all attempts
+)
+
+// exchangeCode exchanges an OAuth authorization code for an access token
+// Network errors and 5xx responses are retried with backoff; 4xx responses (e.g. an
+// expired or already used code) fail immediately since retrying can't fix them.
+func (m *OAuthMiddleware) exchangeCode(ctx context.Context, code string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, tokenExchangeTimeout)
+	defer cancel()
+
+	data := url.Values{}
+	data.Set("client_id", m.clientID)
+	data.Set("client_secret", m.apiToken)
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", code)
+	data.Set("redirect_uri", m.baseURL+m.callbackPath)
+
+	backoff := tokenExchangeBackoff
+	var lastErr error
+	for attempt := 1; attempt <= tokenExchangeAttempts; attempt++ {
+		if attempt > 1 {
+			m.logger.Warn("retrying token exchange", "attempt", attempt, "backoff", backoff, "error", lastErr)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return "", fmt.Errorf("token exchange cancelled after %d attempts: %w", attempt-1, lastErr)
+			}
+			backoff *= 2
+		}
+
+		token, retry, err := m.requestToken(ctx, data)
+		if err == nil {
+			return token, nil
+		}
+		lastErr = err
+		if !retry {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("token exchange failed after %d attempts: %w", tokenExchangeAttempts, lastErr)
+}
+
+// requestToken makes a single token exchange request
+// retry reports whether the failure is transient (network error or 5xx)
+func (m *OAuthMiddleware) requestToken(ctx context.Context, data url.Values) (token string, retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.apiURL+"/oauth2/token", strings.NewReader(data.Encode()))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", true, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", resp.StatusCode >= 500, fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", false, fmt.Errorf("failed to parse token: %w", err)
+	}
+	return tokenResp.AccessToken, false, nil
+}
@@ -0,0 +1,500 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+func TestTokenFromAuthorization(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "Bearer abc123", want: "abc123"},
+		{header: "bearer abc123", want: "abc123"},
+		{header: "token abc123", want: "abc123"},
+		{header: "Token  abc123 ", want: "abc123"},
+		{header: "Basic YWxpY2U6c2VjcmV0", want: ""},
+		{header: "abc123", want: ""},
+		{header: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := tokenFromAuthorization(tt.header); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.header, tt.want, got)
+		}
+	}
+}
+
+func TestOAuthMiddleware_AuthorizationHeader(t *testing.T) {
+	// Mock hub accepting only "valid-token"
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" || r.Header.Get("Authorization") != "token valid-token" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_, _ = io.WriteString(w, `{"name":"alice"}`)
+	}))
+	defer hub.Close()
+	t.Setenv("JUPYTERHUB_API_URL", hub.URL)
+	t.Setenv("JUPYTERHUB_API_TOKEN", "service-token")
+	t.Setenv("JUPYTERHUB_CLIENT_ID", "service-app")
+
+	mw, err := NewOAuthMiddleware(logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+	handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := UserFromContext(r.Context())
+		_, _ = io.WriteString(w, user.Name)
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantBody      string
+	}{
+		{name: "bearer", authorization: "Bearer valid-token", wantStatus: http.StatusOK, wantBody: "alice"},
+		{name: "token", authorization: "token valid-token", wantStatus: http.StatusOK, wantBody: "alice"},
+		{name: "invalid token", authorization: "Bearer wrong-token", wantStatus: http.StatusFound},
+		{name: "basic", authorization: "Basic YWxpY2U6c2VjcmV0", wantStatus: http.StatusFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/app/", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthConfig_Allows(t *testing.T) {
+	alice := &User{Name: "alice", Groups: []string{"analysts", "staff"}}
+	bob := &User{Name: "bob"}
+
+	tests := []struct {
+		name  string
+		cfg   AuthConfig
+		user  *User
+		allow bool
+	}{
+		{name: "no restrictions", cfg: AuthConfig{}, user: bob, allow: true},
+		{name: "allowed group", cfg: AuthConfig{AllowedGroups: []string{"staff"}}, user: alice, allow: true},
+		{name: "no allowed group", cfg: AuthConfig{AllowedGroups: []string{"admins"}}, user: alice, allow: false},
+		{name: "groups required, user has none", cfg: AuthConfig{AllowedGroups: []string{"staff"}}, user: bob, allow: false},
+		{name: "allowed user", cfg: AuthConfig{AllowedUsers: []string{"bob"}}, user: bob, allow: true},
+		{name: "user not listed", cfg: AuthConfig{AllowedUsers: []string{"bob"}}, user: alice, allow: false},
+		{name: "user listed, not in group", cfg: AuthConfig{AllowedGroups: []string{"admins"}, AllowedUsers: []string{"bob"}}, user: bob, allow: true},
+		{name: "in group, user not listed", cfg: AuthConfig{AllowedGroups: []string{"analysts"}, AllowedUsers: []string{"bob"}}, user: alice, allow: true},
+		{name: "neither listed nor in group", cfg: AuthConfig{AllowedGroups: []string{"admins"}, AllowedUsers: []string{"carol"}}, user: alice, allow: false},
+		{name: "names are case sensitive", cfg: AuthConfig{AllowedUsers: []string{"Alice"}}, user: alice, allow: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.allows(tt.user); got != tt.allow {
+				t.Errorf("expected allows %v, got %v", tt.allow, got)
+			}
+		})
+	}
+}
+
+func TestOAuthMiddleware_AllowedGroups(t *testing.T) {
+	// Mock hub mapping tokens to users
+	users := map[string]string{
+		"token alice-token": `{"name":"alice","groups":["analysts","staff"]}`,
+		"token bob-token":   `{"name":"bob","groups":[]}`,
+	}
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := users[r.Header.Get("Authorization")]
+		if r.URL.Path != "/user" || !ok {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_, _ = io.WriteString(w, user)
+	}))
+	defer hub.Close()
+	t.Setenv("JUPYTERHUB_API_URL", hub.URL)
+	t.Setenv("JUPYTERHUB_API_TOKEN", "service-token")
+	t.Setenv("JUPYTERHUB_CLIENT_ID", "service-app")
+
+	mw, err := NewOAuthMiddleware(logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+	mw.SetAuthConfig(AuthConfig{AllowedGroups: []string{"staff"}})
+
+	handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Header.Get("X-Forwarded-User")+"|"+r.Header.Get("X-Forwarded-Groups"))
+	}))
+
+	tests := []struct {
+		name       string
+		token      string
+		spoofed    bool
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "member of allowed group", token: "alice-token", wantStatus: http.StatusOK, wantBody: "alice|analysts,staff"},
+		{name: "client headers overwritten", token: "alice-token", spoofed: true, wantStatus: http.StatusOK, wantBody: "alice|analysts,staff"},
+		{name: "not a member", token: "bob-token", wantStatus: http.StatusForbidden},
+		{name: "invalid token", token: "wrong-token", wantStatus: http.StatusFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/app/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			if tt.spoofed {
+				req.Header.Set("X-Forwarded-User", "admin")
+				req.Header.Set("X-Forwarded-Groups", "admins")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthConfig_Deny(t *testing.T) {
+	alice := &User{Name: "alice", Groups: []string{"staff"}, Scopes: []string{"read:users", "custom:dashboard:view"}}
+	bob := &User{Name: "bob", Scopes: []string{"read:users"}}
+
+	tests := []struct {
+		name     string
+		cfg      AuthConfig
+		user     *User
+		wantRule string
+	}{
+		{name: "no restrictions", cfg: AuthConfig{}, user: bob},
+		{name: "has required scope", cfg: AuthConfig{RequiredScopes: []string{"custom:dashboard:view"}}, user: alice},
+		{name: "missing required scope", cfg: AuthConfig{RequiredScopes: []string{"custom:dashboard:view"}}, user: bob, wantRule: "--required-scope custom:dashboard:view"},
+		{name: "all scopes required", cfg: AuthConfig{RequiredScopes: []string{"read:users", "admin:users"}}, user: alice, wantRule: "--required-scope admin:users"},
+		{name: "scopes match exactly", cfg: AuthConfig{RequiredScopes: []string{"custom:dashboard"}}, user: alice, wantRule: "--required-scope custom:dashboard"},
+		{name: "group checked before scope", cfg: AuthConfig{AllowedGroups: []string{"admins"}, RequiredScopes: []string{"read:users"}}, user: bob, wantRule: "--allowed-users/--allowed-groups"},
+		{name: "in group but missing scope", cfg: AuthConfig{AllowedGroups: []string{"staff"}, RequiredScopes: []string{"admin:users"}}, user: alice, wantRule: "--required-scope admin:users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.deny(tt.user); got != tt.wantRule {
+				t.Errorf("expected rule %q, got %q", tt.wantRule, got)
+			}
+		})
+	}
+}
+
+func TestOAuthMiddleware_RequiredScope(t *testing.T) {
+	// Mock hub mapping tokens to users
+	users := map[string]string{
+		"token alice-token": `{"name":"alice","groups":["staff"],"scopes":["access:servers!server=alice/","custom:dashboard:view"]}`,
+		"token bob-token":   `{"name":"bob","groups":["staff"],"scopes":["access:servers!server=alice/"]}`,
+		"token carol-token": `{"name":"carol","groups":[],"scopes":["custom:dashboard:view"]}`,
+	}
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := users[r.Header.Get("Authorization")]
+		if r.URL.Path != "/user" || !ok {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_, _ = io.WriteString(w, user)
+	}))
+	defer hub.Close()
+	t.Setenv("JUPYTERHUB_API_URL", hub.URL)
+	t.Setenv("JUPYTERHUB_API_TOKEN", "service-token")
+	t.Setenv("JUPYTERHUB_CLIENT_ID", "service-app")
+
+	mw, err := NewOAuthMiddleware(logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+	mw.SetAuthConfig(AuthConfig{AllowedGroups: []string{"staff"}, RequiredScopes: []string{"custom:dashboard:view"}})
+
+	handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Header.Get("X-Forwarded-User"))
+	}))
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "in group with scope", token: "alice-token", wantStatus: http.StatusOK},
+		{name: "in group without scope", token: "bob-token", wantStatus: http.StatusForbidden},
+		{name: "scope without group", token: "carol-token", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/app/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestOAuthMiddleware_TokenExchangeRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32 // Token requests failing before the hub succeeds
+		failStatus   int
+		wantStatus   int
+		wantAttempts int32
+	}{
+		{name: "succeeds after transient failure", failures: 1, failStatus: http.StatusBadGateway, wantStatus: http.StatusFound, wantAttempts: 2},
+		{name: "gives up after max attempts", failures: 10, failStatus: http.StatusServiceUnavailable, wantStatus: http.StatusInternalServerError, wantAttempts: tokenExchangeAttempts},
+		{name: "client error not retried", failures: 10, failStatus: http.StatusBadRequest, wantStatus: http.StatusInternalServerError, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/oauth2/token" {
+					http.NotFound(w, r)
+					return
+				}
+				if n := attempts.Add(1); n <= tt.failures {
+					http.Error(w, "hub unavailable", tt.failStatus)
+					return
+				}
+				if r.FormValue("code") != "the-code" {
+					http.Error(w, "invalid code", http.StatusBadRequest)
+					return
+				}
+				_, _ = io.WriteString(w, `{"access_token":"user-token"}`)
+			}))
+			defer hub.Close()
+			t.Setenv("JUPYTERHUB_API_URL", hub.URL)
+			t.Setenv("JUPYTERHUB_API_TOKEN", "service-token")
+			t.Setenv("JUPYTERHUB_CLIENT_ID", "service-app")
+			t.Setenv("JUPYTERHUB_SERVICE_PREFIX", "/user/alice/app/")
+
+			mw, err := NewOAuthMiddleware(logger.New(logger.Config{Output: io.Discard}))
+			if err != nil {
+				t.Fatalf("failed to create middleware: %v", err)
+			}
+			handler := mw.Wrap(http.NotFoundHandler())
+
+			req := httptest.NewRequest(http.MethodGet, "/user/alice/app/oauth_callback?code=the-code&state=the-state", nil)
+			req.AddCookie(&http.Cookie{Name: mw.cookieName + "-oauth-state", Value: "the-state"})
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("expected %d token exchange attempts, got %d", tt.wantAttempts, got)
+			}
+			if tt.wantStatus != http.StatusFound {
+				return
+			}
+
+			var token string
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == mw.cookieName {
+					token = c.Value
+				}
+			}
+			if token != "user-token" {
+				t.Errorf("expected token cookie %q, got %q", "user-token", token)
+			}
+		})
+	}
+}
+
+func TestOAuthMiddleware_UserCache(t *testing.T) {
+	var lookups atomic.Int32
+	var revoked, down atomic.Bool
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if revoked.Load() {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Authorization") != "token valid-token" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_, _ = io.WriteString(w, `{"name":"alice"}`)
+	}))
+	defer hub.Close()
+	t.Setenv("JUPYTERHUB_API_URL", hub.URL)
+	t.Setenv("JUPYTERHUB_API_TOKEN", "service-token")
+	t.Setenv("JUPYTERHUB_CLIENT_ID", "service-app")
+
+	mw, err := NewOAuthMiddleware(logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+	if err := mw.SetUserCache(10, time.Minute); err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	now := time.Now()
+	mw.now = func() time.Time { return now }
+	var hits, misses int
+	mw.SetCacheObserver(func(hit bool) {
+		if hit {
+			hits++
+		} else {
+			misses++
+		}
+	})
+
+	handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/app/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := request("valid-token"); code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i, code)
+		}
+	}
+	if got := lookups.Load(); got != 1 {
+		t.Errorf("expected 1 hub lookup within the TTL, got %d", got)
+	}
+
+	// Past the TTL the cached user is ignored and the hub is asked again
+	now = now.Add(time.Minute + time.Second)
+	if code := request("valid-token"); code != http.StatusOK {
+		t.Fatalf("expected status 200 after expiry, got %d", code)
+	}
+	if got := lookups.Load(); got != 2 {
+		t.Errorf("expected a hub lookup after TTL expiry, got %d lookups", got)
+	}
+	if hits != 2 || misses != 2 {
+		t.Errorf("expected 2 hits and 2 misses, got %d hits and %d misses", hits, misses)
+	}
+
+	// Rejected tokens are never cached
+	request("wrong-token")
+	request("wrong-token")
+	if got := lookups.Load(); got != 4 {
+		t.Errorf("expected rejected tokens to reach the hub every time, got %d lookups", got)
+	}
+
+	// A failing hub says nothing about the token, so its entry is kept
+	down.Store(true)
+	now = now.Add(time.Minute + time.Second)
+	if code := request("valid-token"); code == http.StatusOK {
+		t.Fatal("expected the lookup to fail while the hub returns 503")
+	}
+	if !mw.userCache.Contains("valid-token") {
+		t.Error("expected a 503 from the hub to keep the cached user")
+	}
+	down.Store(false)
+
+	// A token the hub stops accepting is evicted once its entry is due for a lookup
+	revoked.Store(true)
+	now = now.Add(time.Minute + time.Second)
+	if code := request("valid-token"); code == http.StatusOK {
+		t.Fatal("expected a revoked token to be rejected after expiry")
+	}
+	if mw.userCache.Contains("valid-token") {
+		t.Error("expected the revoked token to be removed from the cache")
+	}
+}
+
+func TestOAuthMiddleware_UserCacheDisabled(t *testing.T) {
+	t.Setenv("JUPYTERHUB_API_URL", "http://hub.invalid")
+	t.Setenv("JUPYTERHUB_API_TOKEN", "service-token")
+
+	mw, err := NewOAuthMiddleware(logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+	if err := mw.SetUserCache(1000, 0); err != nil {
+		t.Fatalf("expected no error for a zero TTL, got %v", err)
+	}
+	if mw.userCache != nil {
+		t.Error("expected a zero TTL to disable the cache")
+	}
+}
+
+func TestOAuthMiddleware_HandleLogout(t *testing.T) {
+	t.Setenv("JUPYTERHUB_API_URL", "http://hub.invalid/hub/api")
+	t.Setenv("JUPYTERHUB_API_TOKEN", "service-token")
+	t.Setenv("JUPYTERHUB_CLIENT_ID", "service-app")
+	t.Setenv("JUPYTERHUB_SERVICE_PREFIX", "/user/alice/app/")
+	t.Setenv("JUPYTERHUB_BASE_URL", "/jupyter/")
+
+	tests := []struct {
+		name         string
+		redirect     string
+		wantLocation string
+	}{
+		{name: "hub home", wantLocation: "/jupyter/hub/home"},
+		{name: "configured", redirect: "https://example.com/goodbye", wantLocation: "https://example.com/goodbye"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, err := NewOAuthMiddleware(logger.New(logger.Config{Output: io.Discard}))
+			if err != nil {
+				t.Fatalf("failed to create middleware: %v", err)
+			}
+			mw.SetLogoutRedirect(tt.redirect)
+
+			req := httptest.NewRequest(http.MethodGet, "/user/alice/app/_temp/jhub-app-proxy/logout", nil)
+			req.AddCookie(&http.Cookie{Name: "service-app", Value: "stale-token"})
+			rec := httptest.NewRecorder()
+			mw.HandleLogout(rec, req)
+
+			if rec.Code != http.StatusFound {
+				t.Fatalf("expected status 302, got %d", rec.Code)
+			}
+			if location := rec.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("expected redirect to %q, got %q", tt.wantLocation, location)
+			}
+
+			cleared := make(map[string]bool)
+			for _, cookie := range rec.Result().Cookies() {
+				if cookie.MaxAge >= 0 || cookie.Value != "" || cookie.Path != "/user/alice/app/" {
+					t.Errorf("expected cookie %s to be expired on the service prefix, got %+v", cookie.Name, cookie)
+				}
+				cleared[cookie.Name] = true
+			}
+			for _, name := range []string{"service-app", "service-app-oauth-state", "service-app-oauth-next"} {
+				if !cleared[name] {
+					t.Errorf("expected cookie %s to be cleared", name)
+				}
+			}
+		})
+	}
+}
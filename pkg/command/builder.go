@@ -12,8 +12,10 @@ import (
 
 // Builder helps construct and manipulate commands for subprocess execution
 type Builder struct {
-	logger         *logger.Logger
-	condaWarning   string // Stores conda activation warning if any
+	logger                *logger.Logger
+	condaWarning          string // Stores conda activation warning if any
+	failOnMissingCondaEnv bool   // Return conda activation errors instead of running without conda
+	condaEnvPreferRoot    string // Directory whose env wins when several conda envs share a name
 }
 
 // NewBuilder creates a new command builder
@@ -23,6 +25,18 @@ func NewBuilder(log *logger.Logger) *Builder {
 	}
 }
 
+// SetFailOnMissingCondaEnv controls what Build does when conda activation fails
+// When true, Build returns the error; when false (default), it warns and runs without conda
+func (b *Builder) SetFailOnMissingCondaEnv(fail bool) {
+	b.failOnMissingCondaEnv = fail
+}
+
+// SetCondaEnvPreferRoot sets the directory whose environment is used when several conda
+// environments have the requested name
+func (b *Builder) SetCondaEnvPreferRoot(root string) {
+	b.condaEnvPreferRoot = root
+}
+
 // Build constructs the final command with conda activation if needed
 func (b *Builder) Build(command []string, condaEnv string) ([]string, error) {
 	if len(command) == 0 {
@@ -32,8 +46,13 @@ func (b *Builder) Build(command []string, condaEnv string) ([]string, error) {
 	// Apply conda activation if specified
 	if condaEnv != "" {
 		condaMgr := conda.NewManager(b.logger)
+		condaMgr.SetPreferRoot(b.condaEnvPreferRoot)
 		activatedCommand, err := condaMgr.BuildActivationCommand(condaEnv, command)
 		if err != nil {
+			if b.failOnMissingCondaEnv {
+				return nil, fmt.Errorf("conda environment activation failed for %q: %w", condaEnv, err)
+			}
+
 			// Store warning message for later display in interim UI
 			b.condaWarning = fmt.Sprintf("WARNING: Conda environment activation failed: %s. Running command without conda activation.", err.Error())
 
@@ -55,9 +74,12 @@ func (b *Builder) GetCondaWarning() string {
 	return b.condaWarning
 }
 
-// GetRootPath constructs the root path from JUPYTERHUB_SERVICE_PREFIX
-// by prepending /hub and ensuring proper path formatting (no double slashes, proper trailing slash handling)
-func GetRootPath() string {
+// DefaultRootPathPrefix is prepended to the service prefix for {root_path} (--root-path-prefix)
+const DefaultRootPathPrefix = "/hub"
+
+// GetBaseURL returns JUPYTERHUB_SERVICE_PREFIX with a leading and without a trailing slash
+// Returns "" when the service prefix is not set
+func GetBaseURL() string {
 	servicePrefix := os.Getenv("JUPYTERHUB_SERVICE_PREFIX")
 	if servicePrefix == "" {
 		return ""
@@ -69,20 +91,36 @@ func GetRootPath() string {
 	}
 
 	// Remove trailing slash from service prefix for consistent joining
-	servicePrefix = strings.TrimSuffix(servicePrefix, "/")
+	return strings.TrimSuffix(servicePrefix, "/")
+}
 
-	// Construct root path: /hub + service_prefix
-	rootPath := "/hub" + servicePrefix
+// GetRootPath constructs the root path from JUPYTERHUB_SERVICE_PREFIX
+// by prepending prefix (e.g. /hub) and ensuring proper path formatting (no double slashes,
+// proper trailing slash handling). An empty prefix gives the bare service prefix.
+func GetRootPath(prefix string) string {
+	baseURL := GetBaseURL()
+	if baseURL == "" {
+		return ""
+	}
+
+	// Normalize prefix to /segment form so "hub", "/hub" and "/hub/" all give /hub
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return baseURL
+	}
 
-	return rootPath
+	// Construct root path: prefix + service_prefix
+	return "/" + prefix + baseURL
 }
 
 // SubstitutePort replaces jhsingle-native-proxy style placeholders in command arguments
-// Handles: {port} → actual port, {root_path} → JupyterHub root path, {-} → -, {--} → --, and strips surrounding quotes
-func SubstitutePort(command []string, allocatedPort int) []string {
+// Handles: {port} → actual port, {root_path} → rootPathPrefix + service prefix, {base_url} → service prefix,
+// {-} → -, {--} → --, and strips surrounding quotes
+func SubstitutePort(command []string, allocatedPort int, rootPathPrefix string) []string {
 	result := make([]string, len(command))
 	portStr := fmt.Sprintf("%d", allocatedPort)
-	rootPath := GetRootPath()
+	rootPath := GetRootPath(rootPathPrefix)
+	baseURL := GetBaseURL()
 
 	for i, arg := range command {
 		processed := arg
@@ -93,6 +131,9 @@ func SubstitutePort(command []string, allocatedPort int) []string {
 		// Replace root_path placeholder
 		processed = strings.ReplaceAll(processed, "{root_path}", rootPath)
 
+		// Replace base_url placeholder
+		processed = strings.ReplaceAll(processed, "{base_url}", baseURL)
+
 		// Replace dash placeholders (jhsingle-native-proxy compatibility)
 		processed = strings.ReplaceAll(processed, "{-}", "-")
 		processed = strings.ReplaceAll(processed, "{--}", "--")
@@ -113,6 +154,39 @@ func SubstitutePort(command []string, allocatedPort int) []string {
 	return result
 }
 
+// SubstituteNamedPorts replaces {name} placeholders with ports allocated for --route backends
+// Run before SubstitutePort, which strips quotes around arguments
+func SubstituteNamedPorts(command []string, ports map[string]int) []string {
+	result := make([]string, len(command))
+	for i, arg := range command {
+		for name, port := range ports {
+			arg = strings.ReplaceAll(arg, "{"+name+"}", fmt.Sprintf("%d", port))
+		}
+		result[i] = arg
+	}
+	return result
+}
+
+// SubstituteSocket replaces {socket} placeholders with the Unix socket path the app listens on
+// Run before SubstitutePort, which strips quotes around arguments
+func SubstituteSocket(command []string, socketPath string) []string {
+	result := make([]string, len(command))
+	for i, arg := range command {
+		result[i] = strings.ReplaceAll(arg, "{socket}", socketPath)
+	}
+	return result
+}
+
+// UsesPort reports whether any argument contains the {port} placeholder
+func UsesPort(command []string) bool {
+	for _, arg := range command {
+		if strings.Contains(arg, "{port}") {
+			return true
+		}
+	}
+	return false
+}
+
 // BuildEnv creates environment variables map for the subprocess
 // Passes through JupyterHub environment variables
 func BuildEnv() map[string]string {
@@ -1,41 +1,81 @@
 package command
 
 import (
+	"io"
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
 )
 
 func TestGetRootPath(t *testing.T) {
 	tests := []struct {
 		name          string
 		servicePrefix string
+		rootPrefix    string
 		expected      string
 	}{
 		{
 			name:          "standard service prefix",
 			servicePrefix: "/user/fakeuser/myapp/",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      "/hub/user/fakeuser/myapp",
 		},
 		{
 			name:          "service prefix without trailing slash",
 			servicePrefix: "/user/testuser/app",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      "/hub/user/testuser/app",
 		},
 		{
 			name:          "service prefix without leading slash",
 			servicePrefix: "user/demouser/app/",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      "/hub/user/demouser/app",
 		},
 		{
 			name:          "empty service prefix",
 			servicePrefix: "",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      "",
 		},
 		{
 			name:          "simple service prefix",
 			servicePrefix: "/user/alice/",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      "/hub/user/alice",
 		},
+		{
+			name:          "custom prefix",
+			servicePrefix: "/user/alice/app/",
+			rootPrefix:    "/jupyter",
+			expected:      "/jupyter/user/alice/app",
+		},
+		{
+			name:          "prefix with slashes trimmed",
+			servicePrefix: "/user/alice/app/",
+			rootPrefix:    "jupyter/",
+			expected:      "/jupyter/user/alice/app",
+		},
+		{
+			name:          "empty prefix",
+			servicePrefix: "/user/alice/app/",
+			rootPrefix:    "",
+			expected:      "/user/alice/app",
+		},
+		{
+			name:          "slash-only prefix",
+			servicePrefix: "/user/alice/app/",
+			rootPrefix:    "/",
+			expected:      "/user/alice/app",
+		},
+		{
+			name:          "empty service prefix with custom prefix",
+			servicePrefix: "",
+			rootPrefix:    "/jupyter",
+			expected:      "",
+		},
 	}
 
 	for _, tt := range tests {
@@ -48,9 +88,9 @@ func TestGetRootPath(t *testing.T) {
 			}
 			defer os.Unsetenv("JUPYTERHUB_SERVICE_PREFIX")
 
-			result := GetRootPath()
+			result := GetRootPath(tt.rootPrefix)
 			if result != tt.expected {
-				t.Errorf("GetRootPath() = %q, want %q", result, tt.expected)
+				t.Errorf("GetRootPath(%q) = %q, want %q", tt.rootPrefix, result, tt.expected)
 			}
 		})
 	}
@@ -62,6 +102,7 @@ func TestSubstitutePort(t *testing.T) {
 		command       []string
 		port          int
 		servicePrefix string
+		rootPrefix    string
 		expected      []string
 	}{
 		{
@@ -69,6 +110,7 @@ func TestSubstitutePort(t *testing.T) {
 			command:       []string{"python", "-m", "http.server", "{port}"},
 			port:          8080,
 			servicePrefix: "",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      []string{"python", "-m", "http.server", "8080"},
 		},
 		{
@@ -76,6 +118,7 @@ func TestSubstitutePort(t *testing.T) {
 			command:       []string{"myapp", "--root-path", "{root_path}"},
 			port:          8080,
 			servicePrefix: "/user/test/app/",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      []string{"myapp", "--root-path", "/hub/user/test/app"},
 		},
 		{
@@ -83,6 +126,7 @@ func TestSubstitutePort(t *testing.T) {
 			command:       []string{"myapp", "--port", "{port}", "--root-path", "{root_path}"},
 			port:          9000,
 			servicePrefix: "/user/bob/dashboard/",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      []string{"myapp", "--port", "9000", "--root-path", "/hub/user/bob/dashboard"},
 		},
 		{
@@ -90,6 +134,7 @@ func TestSubstitutePort(t *testing.T) {
 			command:       []string{"myapp", "{-}p", "{port}", "{--}root-path", "{root_path}"},
 			port:          8888,
 			servicePrefix: "/user/test/",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      []string{"myapp", "-p", "8888", "--root-path", "/hub/user/test"},
 		},
 		{
@@ -97,6 +142,7 @@ func TestSubstitutePort(t *testing.T) {
 			command:       []string{"'myapp --port {port}'"},
 			port:          3000,
 			servicePrefix: "",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      []string{"myapp --port 3000"},
 		},
 		{
@@ -104,6 +150,7 @@ func TestSubstitutePort(t *testing.T) {
 			command:       []string{`"myapp --root-path {root_path}"`},
 			port:          3000,
 			servicePrefix: "/user/demo/",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      []string{"myapp --root-path /hub/user/demo"},
 		},
 		{
@@ -111,8 +158,49 @@ func TestSubstitutePort(t *testing.T) {
 			command:       []string{"myapp", "--root-path", "{root_path}"},
 			port:          5000,
 			servicePrefix: "",
+			rootPrefix:    DefaultRootPathPrefix,
 			expected:      []string{"myapp", "--root-path", ""},
 		},
+		{
+			name:          "substitute base_url",
+			command:       []string{"uvicorn", "main:app", "--root-path", "{base_url}"},
+			port:          8000,
+			servicePrefix: "/user/alice/api/",
+			rootPrefix:    DefaultRootPathPrefix,
+			expected:      []string{"uvicorn", "main:app", "--root-path", "/user/alice/api"},
+		},
+		{
+			name:          "root_path and base_url with custom prefix",
+			command:       []string{"myapp", "--prefix", "{root_path}", "--base-url", "{base_url}"},
+			port:          8000,
+			servicePrefix: "/user/alice/app/",
+			rootPrefix:    "/jupyter",
+			expected:      []string{"myapp", "--prefix", "/jupyter/user/alice/app", "--base-url", "/user/alice/app"},
+		},
+		{
+			name:          "empty root path prefix",
+			command:       []string{"panel", "serve", "app.py", "--prefix", "{root_path}"},
+			port:          5006,
+			servicePrefix: "/user/alice/app/",
+			rootPrefix:    "",
+			expected:      []string{"panel", "serve", "app.py", "--prefix", "/user/alice/app"},
+		},
+		{
+			name:          "base_url inside quoted argument",
+			command:       []string{`"--url={base_url}/"`},
+			port:          5006,
+			servicePrefix: "user/alice/app",
+			rootPrefix:    DefaultRootPathPrefix,
+			expected:      []string{"--url=/user/alice/app/"},
+		},
+		{
+			name:          "empty base_url when no service prefix",
+			command:       []string{"myapp", "--base-url", "{base_url}"},
+			port:          5000,
+			servicePrefix: "",
+			rootPrefix:    "/jupyter",
+			expected:      []string{"myapp", "--base-url", ""},
+		},
 	}
 
 	for _, tt := range tests {
@@ -125,7 +213,7 @@ func TestSubstitutePort(t *testing.T) {
 			}
 			defer os.Unsetenv("JUPYTERHUB_SERVICE_PREFIX")
 
-			result := SubstitutePort(tt.command, tt.port)
+			result := SubstitutePort(tt.command, tt.port, tt.rootPrefix)
 			if len(result) != len(tt.expected) {
 				t.Fatalf("SubstitutePort() returned %d args, want %d", len(result), len(tt.expected))
 			}
@@ -137,3 +225,68 @@ func TestSubstitutePort(t *testing.T) {
 		})
 	}
 }
+
+func TestSubstituteNamedPorts(t *testing.T) {
+	command := []string{"sh", "-c", "api --port {api_port} & app --port {port} --api http://127.0.0.1:{api_port}"}
+	result := SubstituteNamedPorts(command, map[string]int{"api_port": 8502})
+
+	expected := "api --port 8502 & app --port {port} --api http://127.0.0.1:8502"
+	if result[2] != expected {
+		t.Errorf("SubstituteNamedPorts()[2] = %q, want %q", result[2], expected)
+	}
+	if command[2] == expected {
+		t.Error("SubstituteNamedPorts() modified its input")
+	}
+}
+
+func TestSubstituteSocket(t *testing.T) {
+	command := []string{"uvicorn", "app:app", "--uds", "{socket}", "'--root-path={root_path}'"}
+	result := SubstituteSocket(command, "/tmp/app.sock")
+
+	if result[3] != "/tmp/app.sock" {
+		t.Errorf("SubstituteSocket()[3] = %q, want %q", result[3], "/tmp/app.sock")
+	}
+	if result[4] != command[4] {
+		t.Errorf("SubstituteSocket()[4] = %q, want it unchanged", result[4])
+	}
+	if UsesPort(result) {
+		t.Error("UsesPort() = true for a command without {port}")
+	}
+	if !UsesPort([]string{"app", "--port={port}"}) {
+		t.Error("UsesPort() = false for a command with {port}")
+	}
+}
+
+func TestBuild_MissingCondaEnv(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	command := []string{"python", "app.py"}
+	missingEnv := "jhub-app-proxy-test-env-that-does-not-exist"
+
+	t.Run("falls back to plain command by default", func(t *testing.T) {
+		builder := NewBuilder(log)
+
+		result, err := builder.Build(command, missingEnv)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(result) != len(command) || result[0] != command[0] {
+			t.Errorf("expected original command %v, got %v", command, result)
+		}
+		if builder.GetCondaWarning() == "" {
+			t.Error("expected conda warning to be recorded")
+		}
+	})
+
+	t.Run("fails when fail-on-missing-conda-env is set", func(t *testing.T) {
+		builder := NewBuilder(log)
+		builder.SetFailOnMissingCondaEnv(true)
+
+		result, err := builder.Build(command, missingEnv)
+		if err == nil {
+			t.Fatalf("expected error, got command %v", result)
+		}
+		if !strings.Contains(err.Error(), missingEnv) {
+			t.Errorf("expected error to name the missing env, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+)
+
+// StdinCommandFile is the --command-file value that reads the command from standard input
+const StdinCommandFile = "-"
+
+// CommandFile is the content of a --command-file:
+//
+//	{"command": ["python", "app.py"], "env": {"KEY": "VALUE"}}
+type CommandFile struct {
+	Command []string          `json:"command"`
+	Env     map[string]string `json:"env,omitempty"` // Added to the app's environment, overriding --env
+}
+
+// ReadCommandFile reads and validates a --command-file; path "-" reads standard input
+func ReadCommandFile(path string) (*CommandFile, error) {
+	if path == StdinCommandFile {
+		return ParseCommandFile(os.Stdin, "stdin")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open --command-file: %w", err)
+	}
+	defer file.Close()
+	return ParseCommandFile(file, path)
+}
+
+// ParseCommandFile parses a command file; name is used in error messages
+// The command must not be empty and env keys must be valid variable names.
+func ParseCommandFile(r io.Reader, name string) (*CommandFile, error) {
+	var cf CommandFile
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cf); err != nil {
+		return nil, fmt.Errorf("invalid command file %s: %w", name, err)
+	}
+	if len(cf.Command) == 0 {
+		return nil, fmt.Errorf("invalid command file %s: command is empty", name)
+	}
+	for key := range cf.Env {
+		if !validEnvKey(key) {
+			return nil, fmt.Errorf("invalid command file %s: bad env name %q", name, key)
+		}
+	}
+	return &cf, nil
+}
+
+// Equal reports whether two command files run the same command with the same environment
+func (cf *CommandFile) Equal(other *CommandFile) bool {
+	return slices.Equal(cf.Command, other.Command) && maps.Equal(cf.Env, other.Env)
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCommandFile(t *testing.T) {
+	cf, err := ParseCommandFile(strings.NewReader(`{"command": ["python", "app.py"], "env": {"MODE": "prod"}}`), "cmd.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &CommandFile{Command: []string{"python", "app.py"}, Env: map[string]string{"MODE": "prod"}}
+	if !reflect.DeepEqual(cf, want) {
+		t.Errorf("expected %+v, got %+v", want, cf)
+	}
+	if !cf.Equal(want) {
+		t.Error("expected Equal to match an identical command file")
+	}
+	if cf.Equal(&CommandFile{Command: []string{"python", "app.py"}}) {
+		t.Error("expected Equal to notice the env change")
+	}
+}
+
+func TestParseCommandFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "not json", content: `python app.py`},
+		{name: "bare array", content: `["python", "app.py"]`},
+		{name: "empty command", content: `{"command": []}`},
+		{name: "unknown field", content: `{"command": ["app"], "cwd": "/tmp"}`},
+		{name: "bad env name", content: `{"command": ["app"], "env": {"1BAD": "x"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCommandFile(strings.NewReader(tt.content), "cmd.json"); err == nil {
+				t.Error("expected an error")
+			} else if !strings.Contains(err.Error(), "cmd.json") {
+				t.Errorf("expected the error to name the file, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LoadEnv returns the subprocess variables set with --env-file and --env
+// Files are read in order, later files overriding earlier ones, and the KEY=VALUE
+// assignments (taken literally, without quote handling) override them all.
+func LoadEnv(files, assignments []string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open --env-file: %w", err)
+		}
+		vars, err := ParseEnvFile(file, path)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range vars {
+			env[key] = value
+		}
+	}
+
+	for _, assignment := range assignments {
+		key, value, ok := strings.Cut(assignment, "=")
+		if !ok || !validEnvKey(key) {
+			return nil, fmt.Errorf("invalid --env %q: expected KEY=VALUE", assignment)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
+
+// ParseEnvFile parses a .env file of KEY=VALUE lines; name is used in error messages
+// Blank lines and lines starting with # are skipped, as is an "export " prefix.
+// Values may be double-quoted (with \n, \t, \" and \\ escapes), single-quoted (taken
+// literally) or bare, where a # preceded by whitespace starts a comment. Values can't
+// span lines.
+func ParseEnvFile(r io.Reader, name string) (map[string]string, error) {
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, rawValue, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validEnvKey(key) {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", name, lineNum)
+		}
+		value, err := parseEnvValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %w", name, lineNum, key, err)
+		}
+		env[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return env, nil
+}
+
+// parseEnvValue unquotes the value part of a .env line
+func parseEnvValue(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	switch raw[0] {
+	case '"':
+		var value strings.Builder
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			switch {
+			case c == '"':
+				return value.String(), checkEnvTrailer(raw[i+1:])
+			case c == '\\' && i+1 < len(raw):
+				i++
+				switch raw[i] {
+				case 'n':
+					value.WriteByte('\n')
+				case 't':
+					value.WriteByte('\t')
+				case '"', '\\':
+					value.WriteByte(raw[i])
+				default:
+					value.WriteByte('\\')
+					value.WriteByte(raw[i])
+				}
+			default:
+				value.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double quote")
+	case '\'':
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quote")
+		}
+		return raw[1 : end+1], checkEnvTrailer(raw[end+2:])
+	}
+
+	// Bare value: strip an inline comment
+	for i := 1; i < len(raw); i++ {
+		if raw[i] == '#' && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+			return strings.TrimSpace(raw[:i]), nil
+		}
+	}
+	return raw, nil
+}
+
+// checkEnvTrailer allows only whitespace and a comment after a closing quote
+func checkEnvTrailer(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected %q after closing quote", rest)
+	}
+	return nil
+}
+
+// validEnvKey reports whether key is a portable environment variable name
+func validEnvKey(key string) bool {
+	if key == "" || (key[0] >= '0' && key[0] <= '9') {
+		return false
+	}
+	for _, c := range key {
+		if c != '_' && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
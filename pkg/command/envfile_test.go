@@ -0,0 +1,101 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	content := `# database settings
+DB_HOST=db.internal
+export DB_PORT=5432
+
+DB_PASSWORD="p@ss word # not a comment"
+GREETING="line one\nline two \"quoted\" \\ done"   # trailing comment
+RAW='no $expansion or \n escapes'
+EMPTY=
+SPACED =  padded value   # comment
+URL=http://example.com/#anchor
+UNICODE="héllo"
+`
+	env, err := ParseEnvFile(strings.NewReader(content), ".env")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"DB_HOST":     "db.internal",
+		"DB_PORT":     "5432",
+		"DB_PASSWORD": "p@ss word # not a comment",
+		"GREETING":    "line one\nline two \"quoted\" \\ done",
+		"RAW":         `no $expansion or \n escapes`,
+		"EMPTY":       "",
+		"SPACED":      "padded value",
+		"URL":         "http://example.com/#anchor",
+		"UNICODE":     "héllo",
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("expected %q, got %q", want, env)
+	}
+}
+
+func TestParseEnvFile_Malformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "no equals sign", content: "FOO=bar\nJUSTAKEY\n", wantErr: ".env:2:"},
+		{name: "empty key", content: "=value\n", wantErr: ".env:1:"},
+		{name: "key with dash", content: "MY-VAR=1\n", wantErr: ".env:1:"},
+		{name: "key starting with digit", content: "1VAR=1\n", wantErr: ".env:1:"},
+		{name: "unterminated double quote", content: "A=\"open\n", wantErr: "unterminated double quote"},
+		{name: "unterminated single quote", content: "A='open\n", wantErr: "unterminated single quote"},
+		{name: "text after closing quote", content: "A=\"one\" two\n", wantErr: "after closing quote"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseEnvFile(strings.NewReader(tt.content), ".env")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, ".env")
+	local := filepath.Join(dir, ".env.local")
+	if err := os.WriteFile(base, []byte("MODE=dev\nDB_HOST=localhost\nTOKEN=base\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, []byte("DB_HOST=db.internal\nTOKEN=local\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Later files override earlier ones, and --env overrides all files
+	env, err := LoadEnv([]string{base, local}, []string{"TOKEN=flag=with=equals", "EXTRA="})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"MODE":    "dev",
+		"DB_HOST": "db.internal",
+		"TOKEN":   "flag=with=equals",
+		"EXTRA":   "",
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("expected %q, got %q", want, env)
+	}
+
+	if _, err := LoadEnv([]string{filepath.Join(dir, "missing")}, nil); err == nil {
+		t.Error("expected an error for a missing env file")
+	}
+	if _, err := LoadEnv(nil, []string{"NOEQUALS"}); err == nil {
+		t.Error("expected an error for --env without =")
+	}
+}
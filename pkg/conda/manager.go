@@ -3,7 +3,9 @@ package conda
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -18,18 +20,45 @@ type CondaInfo struct {
 	Envs        []string `json:"envs"`
 }
 
+// Executor runs conda commands and returns their standard output
+// Replaced in tests to fake conda.
+type Executor interface {
+	Output(name string, args ...string) ([]byte, error)
+}
+
+// execExecutor runs commands with os/exec
+type execExecutor struct{}
+
+func (execExecutor) Output(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).Output()
+}
+
 // Manager handles conda environment operations
 type Manager struct {
-	logger *logger.Logger
+	logger     *logger.Logger
+	executor   Executor
+	preferRoot string // Directory whose environment wins when several share a name (empty = none)
 }
 
 // NewManager creates a new conda manager
 func NewManager(log *logger.Logger) *Manager {
 	return &Manager{
-		logger: log.WithComponent("conda-manager"),
+		logger:   log.WithComponent("conda-manager"),
+		executor: execExecutor{},
 	}
 }
 
+// SetExecutor replaces how conda commands are run
+func (m *Manager) SetExecutor(executor Executor) {
+	m.executor = executor
+}
+
+// SetPreferRoot sets the directory whose environment GetEnvPath picks when several
+// environments have the requested name
+func (m *Manager) SetPreferRoot(root string) {
+	m.preferRoot = root
+}
+
 // GetCondaPrefix returns the conda installation prefix
 func (m *Manager) GetCondaPrefix() (string, error) {
 	// Try CONDA_PREFIX env var first
@@ -37,15 +66,11 @@ func (m *Manager) GetCondaPrefix() (string, error) {
 		return prefix, nil
 	}
 
-	// Try to find conda executable
-	condaPath, err := exec.LookPath("conda")
-	if err != nil {
+	// Get conda prefix from conda info
+	output, err := m.executor.Output("conda", "info", "--base")
+	if errors.Is(err, exec.ErrNotFound) {
 		return "", fmt.Errorf("conda not found in PATH: %w", err)
 	}
-
-	// Get conda prefix from conda info
-	cmd := exec.Command(condaPath, "info", "--base")
-	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get conda base: %w", err)
 	}
@@ -61,8 +86,7 @@ func (m *Manager) GetCondaInfo() (*CondaInfo, error) {
 	}
 
 	m.logger.Debug("calling conda info", "conda_exe", condaExe)
-	cmd := exec.Command(condaExe, "info", "--json")
-	output, err := cmd.Output()
+	output, err := m.executor.Output(condaExe, "info", "--json")
 	if err != nil {
 		m.logger.Warn("failed to run conda info", "error", err.Error())
 		return nil, fmt.Errorf("failed to run conda info: %w", err)
@@ -114,15 +138,11 @@ func (m *Manager) GetEnvPath(envName string) (string, error) {
 	envPath := filepath.Join(condaInfo.CondaPrefix, "envs", envName)
 
 	// Search through all environments by name
-	for _, env := range condaInfo.Envs {
-		lastName := filepath.Base(env)
-		if lastName == envName {
-			envPath = env
-			m.logger.Debug("matched conda environment",
-				"env_name", envName,
-				"env_path", envPath)
-			break
-		}
+	if env := m.matchEnv(envName, condaInfo); env != "" {
+		envPath = env
+		m.logger.Debug("matched conda environment",
+			"env_name", envName,
+			"env_path", envPath)
 	}
 
 	// Verify the path exists
@@ -142,6 +162,69 @@ func (m *Manager) GetEnvPath(envName string) (string, error) {
 	return envPath, nil
 }
 
+// matchEnv picks the environment named envName from those conda knows about
+// Environments in different roots (e.g. a user's ~/.conda/envs and a shared install) can
+// share a name. When they do, the first of these wins and a warning lists the candidates:
+// an environment whose full path is envName, one under the preferred root, one under the
+// conda installation, then the first in conda's own order.
+// Returns "" if no environment has the name.
+func (m *Manager) matchEnv(envName string, info *CondaInfo) string {
+	var candidates []string
+	for _, env := range info.Envs {
+		if env == envName || filepath.Base(env) == envName {
+			candidates = append(candidates, env)
+		}
+	}
+	if len(candidates) <= 1 {
+		if len(candidates) == 1 {
+			return candidates[0]
+		}
+		return ""
+	}
+
+	chosen, reason := candidates[0], "first listed by conda"
+	for _, tiebreak := range []struct {
+		match  func(env string) bool
+		reason string
+	}{
+		{func(env string) bool { return env == envName }, "exact path"},
+		{func(env string) bool { return isUnder(env, m.preferRoot) }, "under preferred root"},
+		{func(env string) bool { return isUnder(env, info.CondaPrefix) }, "under conda prefix"},
+	} {
+		if env := firstMatch(candidates, tiebreak.match); env != "" {
+			chosen, reason = env, tiebreak.reason
+			break
+		}
+	}
+
+	m.logger.Warn("several conda environments match the name, use --conda-env-prefer-root or a full path to choose",
+		"env_name", envName,
+		"candidates", candidates,
+		"chosen", chosen,
+		"reason", reason)
+	return chosen
+}
+
+// firstMatch returns the first env for which match is true, or ""
+func firstMatch(envs []string, match func(env string) bool) string {
+	for _, env := range envs {
+		if match(env) {
+			return env
+		}
+	}
+	return ""
+}
+
+// isUnder reports whether path is inside the directory root
+// An empty root contains nothing.
+func isUnder(path, root string) bool {
+	if root == "" {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // BuildActivationCommand creates a command that activates a conda environment
 // and runs the target command within it
 func (m *Manager) BuildActivationCommand(envName string, command []string) ([]string, error) {
@@ -188,17 +271,42 @@ func (m *Manager) BuildActivationCommand(envName string, command []string) ([]st
 	return activationCmd, nil
 }
 
+// DryRun writes the command BuildActivationCommand resolves for envName, without running it
+// The arguments are shell-quoted, so the line can be pasted into a terminal to debug activation.
+func (m *Manager) DryRun(w io.Writer, envName string, command []string) error {
+	activationCmd, err := m.BuildActivationCommand(envName, command)
+	if err != nil {
+		return err
+	}
+	quoted := make([]string, len(activationCmd))
+	for i, arg := range activationCmd {
+		quoted[i] = shellQuote(arg)
+	}
+	_, err = fmt.Fprintln(w, strings.Join(quoted, " "))
+	return err
+}
+
+// shellQuote quotes arg for a POSIX shell unless it only has safe characters
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@,+%") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
 // ValidateEnvironment checks if a conda environment exists and is valid
-func (m *Manager) ValidateEnvironment(envName string) error {
+// Returns the environment's path, which BuildActivationCommand and DryRun accept in place
+// of envName without looking it up again.
+func (m *Manager) ValidateEnvironment(envName string) (string, error) {
 	envPath, err := m.GetEnvPath(envName)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Check if python exists in the environment
 	pythonPath := filepath.Join(envPath, "bin", "python")
 	if _, err := os.Stat(pythonPath); err != nil {
-		return fmt.Errorf("python not found in conda environment %s: %w", envName, err)
+		return "", fmt.Errorf("python not found in conda environment %s: %w", envName, err)
 	}
 
 	m.logger.Debug("conda environment validated",
@@ -206,5 +314,5 @@ func (m *Manager) ValidateEnvironment(envName string) error {
 		"env_path", envPath,
 		"python_path", pythonPath)
 
-	return nil
+	return envPath, nil
 }
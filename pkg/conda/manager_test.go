@@ -0,0 +1,244 @@
+package conda
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+// fakeExecutor answers conda commands from info instead of running conda
+type fakeExecutor struct {
+	info  CondaInfo
+	calls []string
+}
+
+func (f *fakeExecutor) Output(name string, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, strings.Join(append([]string{name}, args...), " "))
+	switch strings.Join(args, " ") {
+	case "info --json":
+		return json.Marshal(f.info)
+	case "info --base":
+		return []byte(f.info.CondaPrefix + "\n"), nil
+	}
+	return nil, fmt.Errorf("unexpected conda command: %s %v", name, args)
+}
+
+// newFakeConda returns a manager whose conda knows info, with the env directories created
+func newFakeConda(t *testing.T, info CondaInfo, log *logger.Logger) (*Manager, *fakeExecutor) {
+	t.Helper()
+	t.Setenv("CONDA_PREFIX", "")
+	t.Setenv("CONDA_EXE", "")
+	for _, env := range info.Envs {
+		if err := os.MkdirAll(env, 0o755); err != nil {
+			t.Fatalf("failed to create env dir: %v", err)
+		}
+	}
+	executor := &fakeExecutor{info: info}
+	m := NewManager(log)
+	m.SetExecutor(executor)
+	return m, executor
+}
+
+func TestGetEnvPath_SameName(t *testing.T) {
+	root := t.TempDir()
+	prefix := filepath.Join(root, "opt", "conda")
+	userEnv := filepath.Join(root, "home", "alice", ".conda", "envs", "analytics")
+	sharedEnv := filepath.Join(root, "shared", "envs", "analytics")
+	baseEnv := filepath.Join(prefix, "envs", "analytics")
+
+	tests := []struct {
+		name       string
+		envs       []string
+		envName    string
+		preferRoot string
+		want       string
+		wantWarn   bool
+	}{
+		{
+			name:    "single match",
+			envs:    []string{prefix, userEnv},
+			envName: "analytics",
+			want:    userEnv,
+		},
+		{
+			name:     "conda prefix wins",
+			envs:     []string{prefix, userEnv, baseEnv},
+			envName:  "analytics",
+			want:     baseEnv,
+			wantWarn: true,
+		},
+		{
+			name:       "preferred root wins",
+			envs:       []string{prefix, baseEnv, userEnv},
+			envName:    "analytics",
+			preferRoot: filepath.Dir(userEnv),
+			want:       userEnv,
+			wantWarn:   true,
+		},
+		{
+			name:     "first listed when no tiebreak applies",
+			envs:     []string{prefix, sharedEnv, userEnv},
+			envName:  "analytics",
+			want:     sharedEnv,
+			wantWarn: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			log := logger.New(logger.Config{Output: buf, Format: logger.FormatJSON})
+			m, _ := newFakeConda(t, CondaInfo{CondaPrefix: prefix, Envs: tt.envs}, log)
+			m.SetPreferRoot(tt.preferRoot)
+
+			// Repeated lookups resolve the same way
+			for i := 0; i < 3; i++ {
+				got, err := m.GetEnvPath(tt.envName)
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if got != tt.want {
+					t.Fatalf("expected %s, got %s", tt.want, got)
+				}
+			}
+
+			warned := strings.Contains(buf.String(), "several conda environments match the name")
+			if warned != tt.wantWarn {
+				t.Errorf("expected warning %v, got %v: %s", tt.wantWarn, warned, buf.String())
+			}
+			for _, env := range tt.envs {
+				if tt.wantWarn && filepath.Base(env) == tt.envName && !strings.Contains(buf.String(), env) {
+					t.Errorf("expected the warning to list candidate %s: %s", env, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestIsUnder(t *testing.T) {
+	tests := []struct {
+		path string
+		root string
+		want bool
+	}{
+		{path: "/opt/conda/envs/a", root: "/opt/conda", want: true},
+		{path: "/opt/conda", root: "/opt/conda", want: false},
+		{path: "/opt/conda-other/envs/a", root: "/opt/conda", want: false},
+		{path: "/opt/conda/envs/a", root: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := isUnder(tt.path, tt.root); got != tt.want {
+			t.Errorf("isUnder(%q, %q): expected %v, got %v", tt.path, tt.root, tt.want, got)
+		}
+	}
+}
+
+func TestValidateEnvironment(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "conda")
+	withPython := filepath.Join(prefix, "envs", "analytics")
+	withoutPython := filepath.Join(prefix, "envs", "empty")
+	log := logger.New(logger.Config{Output: io.Discard})
+	m, _ := newFakeConda(t, CondaInfo{CondaPrefix: prefix, Envs: []string{prefix, withPython, withoutPython}}, log)
+
+	if err := os.MkdirAll(filepath.Join(withPython, "bin"), 0o755); err != nil {
+		t.Fatalf("failed to create bin dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(withPython, "bin", "python"), nil, 0o755); err != nil {
+		t.Fatalf("failed to create python: %v", err)
+	}
+
+	tests := []struct {
+		envName string
+		wantErr bool
+	}{
+		{envName: "analytics", wantErr: false},
+		{envName: withPython, wantErr: false},
+		{envName: "empty", wantErr: true},
+		{envName: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.envName), func(t *testing.T) {
+			envPath, err := m.ValidateEnvironment(tt.envName)
+			if tt.wantErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.wantErr && envPath != withPython {
+				t.Errorf("expected env path %s, got %s", withPython, envPath)
+			}
+		})
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "conda")
+	env := filepath.Join(prefix, "envs", "analytics")
+	log := logger.New(logger.Config{Output: io.Discard})
+	m, executor := newFakeConda(t, CondaInfo{CondaPrefix: prefix, Envs: []string{prefix, env}}, log)
+
+	buf := &bytes.Buffer{}
+	if err := m.DryRun(buf, "analytics", []string{"streamlit", "run", "app.py", "--server.headless", "it's true"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// conda isn't installed under the fake prefix, so the one on PATH is used
+	want := "conda run -p " + env + " --no-capture-output streamlit run app.py --server.headless 'it'\\''s true'\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+	for _, call := range executor.calls {
+		if strings.Contains(call, " run ") {
+			t.Errorf("expected the command not to be run, got call %q", call)
+		}
+	}
+
+	t.Run("resolved env path", func(t *testing.T) {
+		executor.calls = nil
+		if err := m.DryRun(io.Discard, env, []string{"app"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for _, call := range executor.calls {
+			if strings.HasSuffix(call, "info --json") {
+				t.Errorf("expected no environment lookup for a resolved path, got call %q", call)
+			}
+		}
+	})
+
+	t.Run("unknown env", func(t *testing.T) {
+		if err := m.DryRun(io.Discard, "missing", []string{"app"}); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestGetCondaPrefix_NotInstalled(t *testing.T) {
+	t.Setenv("CONDA_PREFIX", "")
+	m := NewManager(logger.New(logger.Config{Output: io.Discard}))
+	m.SetExecutor(notFoundExecutor{})
+
+	_, err := m.GetCondaPrefix()
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("expected exec.ErrNotFound, got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "not found in PATH") {
+		t.Errorf("expected a conda not found error, got %v", err)
+	}
+}
+
+// notFoundExecutor behaves as if conda isn't installed
+type notFoundExecutor struct{}
+
+func (notFoundExecutor) Output(name string, _ ...string) ([]byte, error) {
+	return nil, &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
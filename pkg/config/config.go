@@ -4,51 +4,161 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/nebari-dev/jhub-app-proxy/pkg/command"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/health"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/redact"
 	"github.com/spf13/cobra"
 )
 
 // Config holds application configuration
 type Config struct {
 	// Authentication
-	AuthType        string // "oauth", "none"
-	InterimPageAuth bool   // If true, protect interim pages/logs API even when AuthType is "none"
+	AuthType        string   `json:"auth_type" yaml:"auth_type"`                 // "oauth", "basic", "none"
+	InterimPageAuth bool     `json:"interim_page_auth" yaml:"interim_page_auth"` // If true, protect interim pages/logs API even when AuthType is "none"
+	AllowedGroups   []string `json:"allowed_groups" yaml:"allowed_groups"`       // JupyterHub groups allowed through OAuth (empty = any user)
+	AllowedUsers    []string `json:"allowed_users" yaml:"allowed_users"`         // JupyterHub users allowed through OAuth (empty = any user)
+	RequiredScopes  []string `json:"required_scopes" yaml:"required_scopes"`     // JupyterHub scopes users need on top of AllowedUsers/AllowedGroups
+	OAuthCacheTTL   int      `json:"oauth_cache_ttl" yaml:"oauth_cache_ttl"`     // seconds a token's Hub user lookup is reused (0 = no cache)
+	OAuthCacheSize  int      `json:"oauth_cache_size" yaml:"oauth_cache_size"`   // Tokens kept in the user lookup cache
+	LogoutRedirect  string   `json:"logout_redirect" yaml:"logout_redirect"`     // Where the logout endpoint redirects (empty = the Hub home page)
+	BasicAuthUser   string   `json:"basic_auth_user" yaml:"basic_auth_user"`     // User accepted with AuthType "basic"
+	BasicAuthPass   string   `json:"basic_auth_pass" yaml:"basic_auth_pass"`     // Password of BasicAuthUser
+	BasicAuthFile   string   `json:"basic_auth_file" yaml:"basic_auth_file"`     // htpasswd file of users accepted with AuthType "basic"
 
 	// Process
-	Command     []string
-	DestPort    int
-	CondaEnv    string
-	WorkDir    string
-	KeepAlive  bool
-	StripPrefix bool // Strip service prefix before forwarding (default: true for most apps)
+	Command               []string `json:"command" yaml:"command"`
+	CommandFile           string   `json:"command_file" yaml:"command_file"`           // JSON file with the command and extra env, instead of Command ("-" = stdin)
+	CommandFilePoll       int      `json:"command_file_poll" yaml:"command_file_poll"` // seconds between checks of CommandFile for a new command (0 = read once)
+	RootPathPrefix        string   `json:"root_path_prefix" yaml:"root_path_prefix"`   // Prepended to the service prefix for {root_path}
+	DestPort              int      `json:"dest_port" yaml:"dest_port"`
+	DestSocket            string   `json:"dest_socket" yaml:"dest_socket"`     // Unix domain socket the app listens on instead of a port
+	DestPortMin           int      `json:"dest_port_min" yaml:"dest_port_min"` // Lowest port of the subprocess port range (0 = random port)
+	DestPortMax           int      `json:"dest_port_max" yaml:"dest_port_max"` // Highest port of the subprocess port range
+	CondaEnv              string   `json:"conda_env" yaml:"conda_env"`
+	FailOnMissingCondaEnv bool     `json:"fail_on_missing_conda_env" yaml:"fail_on_missing_conda_env"` // Fail startup instead of running without conda when activation fails
+	CondaEnvPreferRoot    string   `json:"conda_env_prefer_root" yaml:"conda_env_prefer_root"`         // Directory whose env wins when several conda envs share CondaEnv's name
+	ValidateAtStartup     bool     `json:"conda_validate" yaml:"conda_validate"`                       // Check the conda env exists and has python before anything starts
+	WorkDir               string   `json:"work_dir" yaml:"work_dir"`
+	WorkDirCreate         bool     `json:"work_dir_create" yaml:"work_dir_create"` // Create a missing WorkDir instead of failing
+	EnvFiles              []string `json:"env_files" yaml:"env_files"`             // .env files merged into the app's environment, later ones winning
+	Env                   []string `json:"env" yaml:"env"`                         // KEY=VALUE variables for the app, overriding EnvFiles
+	KeepAlive             bool     `json:"keep_alive" yaml:"keep_alive"`
+	KeepAliveInterval     int      `json:"keep_alive_interval" yaml:"keep_alive_interval"`               // seconds between activity reports to JupyterHub
+	KeepAliveJitter       int      `json:"keep_alive_jitter" yaml:"keep_alive_jitter"`                   // seconds of random offset (±) applied to each report
+	SkipActivityIfCulled  bool     `json:"skip_activity_if_culled" yaml:"skip_activity_if_culled"`       // stop activity reports once the Hub reports the server stopped
+	Nice                  int      `json:"nice" yaml:"nice"`                                             // Scheduling niceness of the app, -20..19 (0 = inherit)
+	NoProcessGroup        bool     `json:"no_process_group" yaml:"no_process_group"`                     // Keep the app in the proxy's process group so Ctrl+C reaches it
+	RunAsUser             string   `json:"run_as_user" yaml:"run_as_user"`                               // User name the app runs as when the proxy is root (empty = ours)
+	RunAsUID              int      `json:"run_as_uid" yaml:"run_as_uid"`                                 // uid the app runs as when the proxy is root (-1 = ours)
+	RunAsGID              int      `json:"run_as_gid" yaml:"run_as_gid"`                                 // gid the app runs as (-1 = primary group of the run-as user)
+	StripPrefix           bool     `json:"strip_prefix" yaml:"strip_prefix"`                             // Strip service prefix before forwarding (default: true for most apps)
+	MaxRestarts           int      `json:"max_restarts" yaml:"max_restarts"`                             // Automatic restarts after a non-zero exit (0 = never restart)
+	RestartBackoff        int      `json:"restart_backoff" yaml:"restart_backoff"`                       // seconds before the first restart
+	RestartBackoffMax     int      `json:"restart_backoff_max" yaml:"restart_backoff_max"`               // seconds
+	RestartBackoffFactor  float64  `json:"restart_backoff_multiplier" yaml:"restart_backoff_multiplier"` // Backoff growth per restart
+	CrashLoopThreshold    int      `json:"crash_loop_threshold" yaml:"crash_loop_threshold"`             // Restarts within CrashLoopWindow before giving up (0 = no crash-loop detection)
+	CrashLoopWindow       int      `json:"crash_loop_window" yaml:"crash_loop_window"`                   // seconds
+
+	// Proxy
+	AllowedMethods     []string `json:"allowed_methods" yaml:"allowed_methods"`           // HTTP methods forwarded to the backend (empty = all)
+	Routes             []string `json:"routes" yaml:"routes"`                             // Additional backends: <prefix>=<port> or <prefix>={name}
+	CORSOrigins        []string `json:"cors_origins" yaml:"cors_origins"`                 // Origins allowed cross-origin access, "*" for any (empty = CORS disabled)
+	PreserveHost       bool     `json:"preserve_host" yaml:"preserve_host"`               // Forward the client's Host header to the backend
+	BackendH2C         bool     `json:"backend_h2c" yaml:"backend_h2c"`                   // Speak HTTP/2 cleartext (h2c) to the backend
+	GRPC               bool     `json:"grpc" yaml:"grpc"`                                 // Proxy gRPC: HTTP/2 end-to-end with trailers, implies BackendH2C
+	BackendDialTimeout int      `json:"backend_dial_timeout" yaml:"backend_dial_timeout"` // seconds, TCP connect timeout to the backend
+	BackendRetry       int      `json:"backend_retry" yaml:"backend_retry"`               // retries of GET/HEAD requests whose backend connection is refused or reset
+	ProxyTimeout       int      `json:"proxy_timeout" yaml:"proxy_timeout"`               // seconds, backend response deadline (0 = unlimited, WebSockets exempt)
+	WSMaxMessageSize   int64    `json:"ws_max_message_size" yaml:"ws_max_message_size"`   // bytes, larger client WebSocket messages close the connection (0 = unlimited)
+	TrustProxyHeaders  bool     `json:"trust_proxy_headers" yaml:"trust_proxy_headers"`   // Keep X-Forwarded-For/X-Real-IP/X-Forwarded-Host from an upstream proxy
+	MaxURLLength       int      `json:"max_url_length" yaml:"max_url_length"`             // bytes, longer request URIs get 414 (0 = unlimited)
+	NoIndex            bool     `json:"no_index" yaml:"no_index"`                         // Serve a disallow-all robots.txt and send X-Robots-Tag: noindex
+	AuditLogFile       string   `json:"audit_log_file" yaml:"audit_log_file"`             // File receiving a JSON-lines audit trail of authenticated requests
+	Compress           bool     `json:"compress" yaml:"compress"`                         // gzip/deflate backend responses for clients that accept it
+
+	// JupyterHub
+	HubConnectTimeout int `json:"hub_connect_timeout" yaml:"hub_connect_timeout"` // seconds, DNS + connect timeout for Hub API calls
 
 	// Git
-	Repo       string
-	RepoFolder string
-	RepoBranch string
+	Repo             StringList `json:"repo" yaml:"repo"` // URL[,branch=B][,folder=F][,optional] per repository
+	RepoFolder       string     `json:"repo_folder" yaml:"repo_folder"`
+	RepoBranch       string     `json:"repo_branch" yaml:"repo_branch"`
+	RepoCloneTimeout int        `json:"repo_clone_timeout" yaml:"repo_clone_timeout"` // seconds, 0 = no limit
+
+	RepoVerifyCommits bool `json:"repo_verify_commits" yaml:"repo_verify_commits"` // Require a valid GPG signature on each cloned HEAD
+	RepoUnshallow     bool `json:"repo_unshallow" yaml:"repo_unshallow"`           // Fetch the full history after the shallow clone
 
 	// Health Check
-	ReadyCheckPath string
-	ReadyTimeout   int // seconds
+	ReadyCheckPath        string `json:"ready_check_path" yaml:"ready_check_path"`
+	ReadyCheckType        string `json:"ready_check_type" yaml:"ready_check_type"`                 // "http" or "tcp" (empty = http)
+	ReadyCheckMethod      string `json:"ready_check_method" yaml:"ready_check_method"`             // HTTP method of the ready check
+	ReadyCheckBody        string `json:"ready_check_body" yaml:"ready_check_body"`                 // Body sent with the ready check (empty = none)
+	ReadyCheckContentType string `json:"ready_check_content_type" yaml:"ready_check_content_type"` // Content-Type of the ready check body
+	ReadyTimeout          int    `json:"ready_timeout" yaml:"ready_timeout"`                       // seconds
+	ReadyStabilization    int    `json:"ready_stabilization" yaml:"ready_stabilization"`           // seconds to keep probing after the first ready result (0 = disabled)
+
+	LivenessInterval         int `json:"liveness_interval" yaml:"liveness_interval"`                   // seconds between checks once the app runs (0 = disabled)
+	LivenessFailureThreshold int `json:"liveness_failure_threshold" yaml:"liveness_failure_threshold"` // Consecutive failed checks before the app is degraded
 
 	// Logging
-	LogLevel      string
-	LogFormat     string
-	LogBufferSize int
-	ShowCaller    bool
+	LogLevel        string   `json:"log_level" yaml:"log_level"`
+	LogFormat       string   `json:"log_format" yaml:"log_format"`
+	LogTimeFormat   string   `json:"log_timestamp_format" yaml:"log_timestamp_format"` // Go time layout for pretty logs and the subprocess log file
+	LogTimezone     string   `json:"log_timezone" yaml:"log_timezone"`                 // IANA zone name, "UTC" or "Local"
+	LogBufferSize   int      `json:"log_buffer_size" yaml:"log_buffer_size"`
+	LogMaxAge       int      `json:"log_max_age" yaml:"log_max_age"`               // Seconds before subprocess log lines expire (0 = never)
+	LogFileMaxBytes int64    `json:"log_file_max_bytes" yaml:"log_file_max_bytes"` // Size at which the subprocess log file is truncated (0 = unbounded)
+	ShowCaller      bool     `json:"log_caller" yaml:"log_caller"`
+	LogFields       []string `json:"log_fields" yaml:"log_fields"`           // Static key=value fields attached to every log line
+	LogHubFields    bool     `json:"log_hub_fields" yaml:"log_hub_fields"`   // Attach JupyterHub deployment metadata (user, server, prefix) to every log line
+	LogSinkURL      string   `json:"log_sink_url" yaml:"log_sink_url"`       // HTTP endpoint receiving batches of subprocess logs (empty = disabled)
+	LogSinkFormat   string   `json:"log_sink_format" yaml:"log_sink_format"` // Payload format for the log sink (json, loki)
+	RedactEnv       []string `json:"redact_env" yaml:"redact_env"`           // Env vars whose values are masked in logs, API responses and command display
+	StripANSI       bool     `json:"strip_ansi" yaml:"strip_ansi"`           // Remove ANSI escape codes from captured subprocess output
+
+	RedactPatterns []string `json:"redact_patterns" yaml:"redact_patterns"` // Regexes whose matches are masked, in addition to redact.DefaultPatterns
+
+	OTelLogsEndpoint string `json:"otel_logs_endpoint" yaml:"otel_logs_endpoint"` // OTLP/HTTP endpoint receiving subprocess logs (empty = disabled)
+
+	LogNoSync bool `json:"log_no_sync" yaml:"log_no_sync"` // Don't fsync the subprocess log file after every line
+
+	FailureLogLines int `json:"failure_log_lines" yaml:"failure_log_lines"` // Recent log lines the logs API returns by default once the app has failed
+
+	APIRateLimit float64 `json:"api_rate_limit" yaml:"api_rate_limit"` // Logs API requests per second per client IP (0 = unlimited)
+	APIRateBurst int     `json:"api_rate_burst" yaml:"api_rate_burst"` // Logs API requests a client may make at once
 
 	// Server
-	Port       int // Port for proxy server (what JupyterHub expects)
-	ListenPort int // Deprecated: use Port instead
+	Port        int    `json:"port" yaml:"port"`                   // Port for proxy server (what JupyterHub expects)
+	ListenPort  int    `json:"listen_port" yaml:"listen_port"`     // Deprecated: use Port instead
+	TLSCertFile string `json:"tls_cert_file" yaml:"tls_cert_file"` // PEM certificate for serving HTTPS (requires TLSKeyFile)
+	TLSKeyFile  string `json:"tls_key_file" yaml:"tls_key_file"`   // PEM private key for TLSCertFile
+
+	TLSMinVersion   string   `json:"tls_min_version" yaml:"tls_min_version"`     // Oldest TLS version accepted: 1.0, 1.1, 1.2 or 1.3
+	TLSCipherSuites []string `json:"tls_cipher_suites" yaml:"tls_cipher_suites"` // Allowed TLS 1.0-1.2 cipher suites (empty = Go defaults)
+
+	// Observability
+	Metrics bool `json:"metrics" yaml:"metrics"` // Expose Prometheus metrics at /_metrics
 
 	// Voila-specific
-	Progressive bool
+	Progressive bool `json:"progressive" yaml:"progressive"`
+	HTTP2Push   bool `json:"http2_push" yaml:"http2_push"` // Push interim page assets over HTTP/2 (only with TLS)
+
+	// Interim page branding
+	BrandTitle        string `json:"brand_title" yaml:"brand_title"`                 // Interim page title (empty = default)
+	BrandLogoURL      string `json:"brand_logo_url" yaml:"brand_logo_url"`           // Logo shown instead of the Nebari logo (empty = default)
+	BrandPrimaryColor string `json:"brand_primary_color" yaml:"brand_primary_color"` // CSS color of the progress bar and toggles (empty = default)
 }
 
 // NewFromFlags creates a Config from command line flags using cobra
 // Returns the cobra command and config, or error
 func NewFromFlags(version, buildTime string) (*cobra.Command, *Config, error) {
 	cfg := &Config{}
+	var configFile string
 
 	rootCmd := &cobra.Command{
 		Use:     "jhub-app-proxy [flags] -- command [args...]",
@@ -58,72 +168,277 @@ func NewFromFlags(version, buildTime string) (*cobra.Command, *Config, error) {
 health monitoring, log capture, and JupyterHub integration.
 
 Framework-agnostic - works with any web application (Streamlit, Voila, Panel, etc).`,
+		// Runs after flag parsing, so flags given on the command line take precedence
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return applyConfigFile(cmd, cfg, configFile)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Remaining args are the command to run, overriding the config file's command
+			if len(args) > 0 {
+				cfg.Command = args
+			}
 			// If no command provided, show help
-			if len(args) == 0 {
+			if len(cfg.Command) == 0 && cfg.CommandFile == "" {
 				return cmd.Help()
 			}
-			// Remaining args are the command to run
-			cfg.Command = args
 			return nil
 		},
 	}
 
+	// Config file flag
+	rootCmd.Flags().StringVar(&configFile, "config", "",
+		"YAML file with configuration values (keys as printed by `jhub-app-proxy config`); flags override file values")
+
 	// Core flags
 	rootCmd.Flags().StringVar(&cfg.AuthType, "authtype", "oauth",
-		"Authentication type (oauth, none)")
+		"Authentication type (oauth, basic, none)")
 	rootCmd.Flags().BoolVar(&cfg.InterimPageAuth, "interim-page-auth", false,
 		"Protect interim pages and logs API with OAuth even when --authtype=none (allows public app with protected logs)")
+	rootCmd.Flags().StringSliceVar(&cfg.AllowedGroups, "allowed-groups", nil,
+		"Comma-separated JupyterHub groups allowed through OAuth, other users get 403 (default: any authenticated user)")
+	rootCmd.Flags().StringSliceVar(&cfg.AllowedUsers, "allowed-users", nil,
+		"Comma-separated JupyterHub users allowed through OAuth, in addition to --allowed-groups (default: any authenticated user)")
+	rootCmd.Flags().StringSliceVar(&cfg.RequiredScopes, "required-scope", nil,
+		"JupyterHub scope users must hold to pass OAuth, e.g. custom:dashboard:view; repeatable or comma-separated, all are required (default: none)")
+	rootCmd.Flags().StringVar(&cfg.BasicAuthUser, "basic-auth-user", "",
+		"User name accepted with --authtype=basic (requires --basic-auth-pass)")
+	rootCmd.Flags().StringVar(&cfg.BasicAuthPass, "basic-auth-pass", "",
+		"Password of --basic-auth-user; visible in the process list, prefer --basic-auth-file or the config file")
+	rootCmd.Flags().StringVar(&cfg.BasicAuthFile, "basic-auth-file", "",
+		"htpasswd file (bcrypt or {SHA} hashes) with the users accepted with --authtype=basic")
+	rootCmd.Flags().IntVar(&cfg.OAuthCacheTTL, "oauth-cache-ttl", 60,
+		"Seconds to reuse the Hub's answer for an OAuth token before asking again; a revoked token works until then (0 = ask on every request)")
+	rootCmd.Flags().IntVar(&cfg.OAuthCacheTTL, "auth-cache-ttl", 60,
+		"Alias of --oauth-cache-ttl")
+	rootCmd.Flags().IntVar(&cfg.OAuthCacheSize, "oauth-cache-size", 1000,
+		"Maximum tokens kept in the OAuth user cache, least recently used are evicted first")
+	rootCmd.Flags().StringVar(&cfg.LogoutRedirect, "logout-redirect", "",
+		"URL the OAuth logout endpoint redirects to after clearing the session (default: the JupyterHub home page)")
 	rootCmd.Flags().IntVar(&cfg.Port, "port", 0,
 		"Port for proxy server to listen on (what JupyterHub expects)")
 	rootCmd.Flags().IntVar(&cfg.ListenPort, "listen-port", 0,
 		"Deprecated: use --port instead")
 	rootCmd.Flags().IntVar(&cfg.DestPort, "destport", 0,
 		"Internal subprocess port (0 = random)")
+	rootCmd.Flags().IntVar(&cfg.DestPortMin, "destport-min", 0,
+		"Lowest subprocess port; with --destport-max, the first free port in the range is used instead of a random one (0 = disabled)")
+	rootCmd.Flags().IntVar(&cfg.DestPortMax, "destport-max", 0,
+		"Highest subprocess port of the --destport-min range")
+	rootCmd.Flags().StringVar(&cfg.DestSocket, "dest-socket", "",
+		"Unix domain socket the app listens on instead of a port; substituted for {socket} in the command")
+	rootCmd.Flags().StringVar(&cfg.CommandFile, "command-file", "",
+		`JSON file giving the command instead of the command line, as {"command": [...], "env": {...}}; "-" reads it from stdin`)
+	rootCmd.Flags().IntVar(&cfg.CommandFilePoll, "command-file-poll", 30,
+		"Seconds between checks of --command-file; when its command or env changes, the app is restarted with the new one (0 = read once)")
+	rootCmd.Flags().StringVar(&cfg.RootPathPrefix, "root-path-prefix", command.DefaultRootPathPrefix,
+		"Path prepended to JUPYTERHUB_SERVICE_PREFIX for {root_path} in the command (empty = the service prefix alone, like {base_url})")
+	rootCmd.Flags().StringVar(&cfg.TLSCertFile, "tls-cert", "",
+		"PEM certificate file to serve HTTPS with (requires --tls-key; reloaded on change or SIGHUP)")
+	rootCmd.Flags().StringVar(&cfg.TLSKeyFile, "tls-key", "",
+		"PEM private key file for --tls-cert")
+	rootCmd.Flags().StringVar(&cfg.TLSMinVersion, "tls-min-version", "1.2",
+		"Oldest TLS version accepted when serving HTTPS: 1.0, 1.1, 1.2 or 1.3")
+	rootCmd.Flags().StringSliceVar(&cfg.TLSCipherSuites, "tls-cipher-suites", nil,
+		"Comma-separated cipher suites allowed for TLS 1.2 and below, e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 (default: Go's secure defaults)")
 
 	// Process management flags
 	rootCmd.Flags().StringVar(&cfg.CondaEnv, "conda-env", "",
 		"Conda environment to activate")
+	rootCmd.Flags().BoolVar(&cfg.FailOnMissingCondaEnv, "fail-on-missing-conda-env", false,
+		"Fail startup if the conda environment cannot be activated (default: warn and run without conda)")
+	rootCmd.Flags().BoolVar(&cfg.FailOnMissingCondaEnv, "conda-strict", false,
+		"Same as --fail-on-missing-conda-env")
+	rootCmd.Flags().StringVar(&cfg.CondaEnvPreferRoot, "conda-env-prefer-root", "",
+		"Directory whose environment is used when several conda environments have the --conda-env name, e.g. /opt/conda/envs")
+	rootCmd.Flags().BoolVar(&cfg.ValidateAtStartup, "conda-validate", true,
+		"Check the conda environment at startup and report the result in the startup banner")
 	rootCmd.Flags().StringVar(&cfg.WorkDir, "workdir", "",
-		"Working directory for the process")
+		"Working directory for the process; startup fails if it doesn't exist, unless --workdir-create is set")
+	rootCmd.Flags().BoolVar(&cfg.WorkDirCreate, "workdir-create", false,
+		"Create --workdir (mode 0750) if it doesn't exist")
+	rootCmd.Flags().StringArrayVar(&cfg.EnvFiles, "env-file", nil,
+		"File of KEY=VALUE lines (# comments and quoted values allowed) added to the app's environment; later files override earlier ones (repeatable)")
+	rootCmd.Flags().StringArrayVar(&cfg.Env, "env", nil,
+		"KEY=VALUE variable added to the app's environment, overriding --env-file (repeatable)")
 	rootCmd.Flags().BoolVar(&cfg.KeepAlive, "keep-alive", false,
 		"Always report activity to prevent idle culling (default: false, report actual activity)")
+	rootCmd.Flags().IntVar(&cfg.KeepAliveInterval, "keep-alive-interval", 300,
+		"Seconds between activity reports to JupyterHub")
+	rootCmd.Flags().IntVar(&cfg.KeepAliveJitter, "keep-alive-jitter", 30,
+		"Random offset in seconds (±) applied to each activity report so apps started together don't report at once; capped at half the interval (0 = none)")
+	rootCmd.Flags().BoolVar(&cfg.SkipActivityIfCulled, "skip-activity-if-culled", false,
+		"Check the server's status in JupyterHub before each activity report and stop reporting once it is no longer running, e.g. after being culled")
+	rootCmd.Flags().IntVar(&cfg.Nice, "nice", 0,
+		"Scheduling niceness of the app, from -20 (highest priority) to 19 (lowest); negative values need CAP_SYS_NICE (0 = inherit, Linux only)")
+	rootCmd.Flags().BoolVar(&cfg.NoProcessGroup, "no-process-group", false,
+		"Run the app in the proxy's process group instead of its own, so Ctrl+C in a terminal reaches it directly; stopping the app then no longer signals the processes it spawned")
+	rootCmd.Flags().StringVar(&cfg.RunAsUser, "run-as-user", "",
+		"User the app runs as when the proxy runs as root, to drop privileges (default: the proxy's user)")
+	rootCmd.Flags().IntVar(&cfg.RunAsUID, "run-as-uid", -1,
+		"uid the app runs as when the proxy runs as root; the user must exist (-1 = the proxy's)")
+	rootCmd.Flags().IntVar(&cfg.RunAsGID, "gid", -1,
+		"gid the app runs as with --run-as-user or --run-as-uid (-1 = the user's primary group)")
+	rootCmd.Flags().IntVar(&cfg.MaxRestarts, "max-restarts", 0,
+		"Restart the app up to this many times when it exits with a non-zero code (0 = never restart)")
+	rootCmd.Flags().IntVar(&cfg.RestartBackoff, "restart-backoff", 1,
+		"Seconds to wait before the first automatic restart (0 = restart immediately)")
+	rootCmd.Flags().IntVar(&cfg.RestartBackoffMax, "restart-backoff-max", 30,
+		"Maximum seconds to wait between automatic restarts")
+	rootCmd.Flags().Float64Var(&cfg.RestartBackoffFactor, "restart-backoff-multiplier", 2,
+		"Factor the restart delay grows by after each restart")
+	rootCmd.Flags().IntVar(&cfg.CrashLoopThreshold, "crash-loop-threshold", 5,
+		"Stop restarting after this many restarts within --crash-loop-window (0 = disabled)")
+	rootCmd.Flags().IntVar(&cfg.CrashLoopWindow, "crash-loop-window", 60,
+		"Time window in seconds for crash-loop detection")
 
 	// Prefix handling (default: strip prefix like jhsingle-native-proxy)
 	rootCmd.Flags().BoolVar(&cfg.StripPrefix, "strip-prefix", true,
 		"Strip service prefix before forwarding to backend (default: true, use false for JupyterLab)")
+	rootCmd.Flags().StringArrayVar(&cfg.Routes, "route", nil,
+		"Route a path prefix to another backend port: <prefix>=<port>, or <prefix>={name} to allocate a free port substituted for {name} in the command (repeatable, longest prefix wins)")
+	rootCmd.Flags().StringSliceVar(&cfg.CORSOrigins, "cors-origins", nil,
+		"Comma-separated origins allowed to call the app cross-origin with cookies, e.g. https://dashboards.example.com; * allows any origin without credentials (default: CORS disabled)")
+	rootCmd.Flags().StringSliceVar(&cfg.AllowedMethods, "allowed-methods", nil,
+		"Comma-separated HTTP methods forwarded to the backend, others get 405 (default: all methods)")
+	rootCmd.Flags().BoolVar(&cfg.PreserveHost, "preserve-host", true,
+		"Forward the client's Host header to the backend (false rewrites it to the backend address)")
+	rootCmd.Flags().BoolVar(&cfg.BackendH2C, "backend-h2c", false,
+		"Forward requests to the backend over HTTP/2 cleartext (h2c) instead of HTTP/1.1 (no WebSocket support)")
+	rootCmd.Flags().BoolVar(&cfg.GRPC, "grpc", false,
+		"Proxy a gRPC backend: accept HTTP/2 cleartext (h2c) from clients, forward over h2c (implies --backend-h2c) and pass trailers through")
+	rootCmd.Flags().IntVar(&cfg.BackendDialTimeout, "backend-dial-timeout", 10,
+		"Timeout in seconds for connecting to the backend; a backend that is bound but not accepting fails with 504")
+	rootCmd.Flags().IntVar(&cfg.BackendRetry, "backend-retry", 0,
+		"Times to retry GET and HEAD requests whose connection to the backend is refused or reset, with a short backoff (0 = never); other methods are never retried")
+	rootCmd.Flags().IntVar(&cfg.ProxyTimeout, "proxy-timeout", 0,
+		"Timeout in seconds for backend requests, returning 504 when exceeded; WebSocket connections are exempt (0 = unlimited)")
+	rootCmd.Flags().Int64Var(&cfg.WSMaxMessageSize, "ws-max-message-size", 0,
+		"Maximum size in bytes of a WebSocket message sent by a client; larger messages close the connection with code 1009 (0 = unlimited)")
+	rootCmd.Flags().BoolVar(&cfg.Compress, "compress", false,
+		"Compress app responses with gzip (or deflate) for clients that accept it, unless the app already did; images and other compressed types are skipped")
+	rootCmd.Flags().BoolVar(&cfg.TrustProxyHeaders, "trust-proxy-headers", false,
+		"Append the client IP to an incoming X-Forwarded-For chain and keep X-Real-IP/X-Forwarded-Host set by an upstream proxy (default: replace them)")
+	rootCmd.Flags().IntVar(&cfg.MaxURLLength, "max-url-length", 32768,
+		"Maximum request URL length in bytes, including the query string; longer URLs get 414 (0 = unlimited)")
+	rootCmd.Flags().BoolVar(&cfg.NoIndex, "no-index", false,
+		"Ask search engines not to index the app: serve a disallow-all robots.txt and add X-Robots-Tag: noindex to responses")
+	rootCmd.Flags().StringVar(&cfg.AuditLogFile, "audit-log-file", "",
+		"Append an audit trail (user, method, path, status, timestamp) of authenticated app requests to this file as JSON lines")
+
+	// JupyterHub API flags
+	rootCmd.Flags().IntVar(&cfg.HubConnectTimeout, "hub-connect-timeout", 5,
+		"Timeout in seconds for DNS resolution and connecting to the JupyterHub API")
 
 	// Git repository flags
-	rootCmd.Flags().StringVar(&cfg.Repo, "repo", "",
-		"Git repository URL to clone")
+	rootCmd.Flags().StringArrayVar((*[]string)(&cfg.Repo), "repo", nil,
+		"Git repository to clone: URL[,branch=B][,folder=F][,optional] (repeatable, cloned in parallel)")
 	rootCmd.Flags().StringVar(&cfg.RepoFolder, "repofolder", "",
-		"Destination folder for git clone")
+		"Destination folder for git clone (single --repo only)")
 	rootCmd.Flags().StringVar(&cfg.RepoBranch, "repobranch", "main",
-		"Git branch to checkout")
+		"Git branch to checkout for --repo entries without branch=")
+	rootCmd.Flags().IntVar(&cfg.RepoCloneTimeout, "repo-clone-timeout", 300,
+		"Maximum time in seconds to wait for git clone before giving up (0 = no limit)")
+	rootCmd.Flags().BoolVar(&cfg.RepoVerifyCommits, "repo-verify-commits", false,
+		"Refuse to start the app unless the HEAD commit of each cloned repository has a valid GPG signature from a key in the keyring")
+	rootCmd.Flags().BoolVar(&cfg.RepoUnshallow, "repo-unshallow", false,
+		"Fetch the full history of each cloned repository (git fetch --unshallow) before starting the app, so git log and similar work; the clone stays shallow if it fails")
 
 	// Health check flags
 	rootCmd.Flags().StringVar(&cfg.ReadyCheckPath, "ready-check-path", "/",
 		"Health check path (e.g., /, /health, /voila/static/)")
+	rootCmd.Flags().StringVar(&cfg.ReadyCheckType, "ready-check-type", "",
+		"Health check type: http (GET --ready-check-path, 2xx/3xx is ready) or tcp (ready once the port accepts connections) (default: http)")
+	rootCmd.Flags().StringVar(&cfg.ReadyCheckType, "ready-check-protocol", "",
+		"Alias of --ready-check-type")
+	rootCmd.Flags().StringVar(&cfg.ReadyCheckMethod, "ready-check-method", "GET",
+		"HTTP method of the ready check: GET, HEAD, POST, PUT or PATCH")
+	rootCmd.Flags().StringVar(&cfg.ReadyCheckBody, "ready-check-body", "",
+		"Body sent with the ready check, e.g. a JSON document; needs --ready-check-method POST, PUT or PATCH (default: none)")
+	rootCmd.Flags().StringVar(&cfg.ReadyCheckContentType, "ready-check-content-type", "application/json",
+		"Content-Type of --ready-check-body")
 	rootCmd.Flags().IntVar(&cfg.ReadyTimeout, "ready-timeout", 300,
 		"Health check timeout in seconds")
+	rootCmd.Flags().IntVar(&cfg.ReadyStabilization, "ready-stabilization", 0,
+		"Keep probing for this many seconds after the ready check first passes; a failure in that window reverts to not-ready, catching apps that answer briefly and then crash (0 = disabled)")
+	rootCmd.Flags().IntVar(&cfg.LivenessInterval, "liveness-interval", 0,
+		"Keep probing the ready check every this many seconds once the app runs, marking it degraded (and restarting it if --max-restarts allows) when it stops answering (0 = disabled)")
+	rootCmd.Flags().IntVar(&cfg.LivenessFailureThreshold, "liveness-failure-threshold", health.DefaultLivenessFailureThreshold,
+		"Consecutive failed liveness checks before the app is marked degraded")
 
 	// Logging flags
 	rootCmd.Flags().StringVar(&cfg.LogLevel, "log-level", "info",
 		"Log level (debug, info, warn, error)")
 	rootCmd.Flags().StringVar(&cfg.LogFormat, "log-format", "json",
 		"Log format (json, pretty)")
+	rootCmd.Flags().StringVar(&cfg.LogTimeFormat, "log-timestamp-format", "2006-01-02 15:04:05.000",
+		"Go time layout for timestamps in pretty logs and the subprocess log file (JSON logs always use RFC 3339)")
+	rootCmd.Flags().StringVar(&cfg.LogTimezone, "log-timezone", "UTC",
+		"Time zone for log timestamps: UTC, Local (the host's zone) or an IANA name such as Europe/Berlin")
 	rootCmd.Flags().IntVar(&cfg.LogBufferSize, "log-buffer-size", 1000,
 		"Number of subprocess log lines to keep in memory")
+	rootCmd.Flags().IntVar(&cfg.LogMaxAge, "log-max-age", 0,
+		"Seconds to keep subprocess log lines; older lines are hidden from the logs API and pruned from the log file (0 = no expiry)")
+	rootCmd.Flags().Int64Var(&cfg.LogFileMaxBytes, "log-file-max-bytes", 100*1024*1024,
+		"Size in bytes at which the oldest lines are cut from the subprocess log file (0 = unbounded)")
+	rootCmd.Flags().BoolVar(&cfg.LogNoSync, "log-no-sync", false,
+		"Don't fsync the subprocess log file after every line; much faster for verbose apps, but lines not yet flushed by the OS are lost if the machine crashes")
+	rootCmd.Flags().IntVar(&cfg.FailureLogLines, "failure-log-lines", 200,
+		"Recent subprocess log lines the failure page shows, and /api/logs returns by default, once the app has failed (0 = 100 like a running app)")
 	rootCmd.Flags().BoolVar(&cfg.ShowCaller, "log-caller", false,
 		"Show file:line in logs")
+	rootCmd.Flags().StringArrayVar(&cfg.LogFields, "log-field", nil,
+		"Static key=value field attached to every log line (repeatable)")
+	rootCmd.Flags().BoolVar(&cfg.LogHubFields, "log-hub-fields", false,
+		"Attach JupyterHub deployment metadata (user, server name, service prefix) to every log line")
+	rootCmd.Flags().StringVar(&cfg.LogSinkURL, "log-sink-url", "",
+		"HTTP endpoint receiving batches of subprocess logs, e.g. http://loki:3100/loki/api/v1/push (default: disabled)")
+	rootCmd.Flags().StringVar(&cfg.LogSinkFormat, "log-sink-format", "json",
+		"Payload format for --log-sink-url (json, loki, otlp)")
+	rootCmd.Flags().StringVar(&cfg.OTelLogsEndpoint, "otel-logs-endpoint", "",
+		"OTLP/HTTP endpoint receiving subprocess logs as OpenTelemetry log records, e.g. http://otel-collector:4318/v1/logs (default: disabled)")
+	rootCmd.Flags().StringArrayVar(&cfg.RedactEnv, "redact-env", nil,
+		"Name of an environment variable whose value is masked in logs, API responses and the displayed command (repeatable)")
+	rootCmd.Flags().StringArrayVar(&cfg.RedactPatterns, "redact-pattern", nil,
+		"Regular expression whose matches are masked like --redact-env values; only the first capture group is masked if there is one (repeatable)")
+	rootCmd.Flags().BoolVar(&cfg.StripANSI, "strip-ansi", false,
+		"Remove ANSI escape codes (colors, cursor movement) from captured subprocess output; /api/logs?raw=true still returns the original lines")
+	rootCmd.Flags().Float64Var(&cfg.APIRateLimit, "api-rate-limit", middleware.DefaultAPIRateLimit,
+		"Requests per second each client IP may make to the logs API (/api/logs/*) and /api/health, more get 429 (0 = unlimited)")
+	rootCmd.Flags().IntVar(&cfg.APIRateBurst, "api-rate-burst", middleware.DefaultAPIRateBurst,
+		"Requests a client IP may make to the logs API at once before --api-rate-limit applies")
+
+	// Observability flags
+	rootCmd.Flags().BoolVar(&cfg.Metrics, "metrics", false,
+		"Expose Prometheus metrics for the proxy and subprocess at /_metrics (unauthenticated)")
 
 	// Optional flags
 	rootCmd.Flags().BoolVar(&cfg.Progressive, "progressive", false,
 		"Enable progressive response streaming (for Voila)")
+	rootCmd.Flags().BoolVar(&cfg.HTTP2Push, "http2-push", true,
+		"Push the interim page's CSS and JS with the HTML to HTTP/2 clients (only applies when serving TLS with --tls-cert)")
+	rootCmd.Flags().StringVar(&cfg.BrandTitle, "brand-title", "",
+		"Title of the interim page shown during startup (default: JHub Apps Proxy)")
+	rootCmd.Flags().StringVar(&cfg.BrandLogoURL, "brand-logo-url", "",
+		"Logo shown on the interim page instead of the Nebari logo: an http(s) URL or an absolute path (default: Nebari logo)")
+	rootCmd.Flags().StringVar(&cfg.BrandPrimaryColor, "brand-primary-color", "",
+		"Color of the interim page's progress bar and toggles: a hex color like #1e40af or a CSS color name (default: built-in colors)")
+
+	// The app command is passed as positional args, so they must not be taken for unknown subcommands
+	rootCmd.Args = cobra.ArbitraryArgs
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(newConfigCommand(rootCmd, cfg))
+	rootCmd.AddCommand(newVersionCommand(version, buildTime))
 
 	return rootCmd, cfg, nil
 }
 
+// Default returns a Config holding the command-line flag defaults
+// Used when embedding the proxy: set Command and override fields, then pass it to server.Run
+func Default() *Config {
+	_, cfg, _ := NewFromFlags("", "")
+	return cfg
+}
+
 // NormalizePort handles backward compatibility and environment variable loading
 func (c *Config) NormalizePort() {
 	// Handle backward compatibility: --listen-port → --port
@@ -144,3 +459,161 @@ func (c *Config) NormalizePort() {
 		c.Port = 8888
 	}
 }
+
+// TLSEnabled reports whether the proxy serves HTTPS itself
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// LogLocation returns the time zone log timestamps are rendered in (--log-timezone)
+func (c *Config) LogLocation() (*time.Location, error) {
+	if c.LogTimezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(c.LogTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --log-timezone %q: %w", c.LogTimezone, err)
+	}
+	return loc, nil
+}
+
+// StaticLogFields returns the fields to attach to every log line
+// Combines --log-field key=value pairs with JupyterHub deployment metadata when --log-hub-fields is set
+func (c *Config) StaticLogFields() (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+
+	if c.LogHubFields {
+		hubFields := map[string]string{
+			"hub_user":        "JUPYTERHUB_USER",
+			"hub_server_name": "JUPYTERHUB_SERVER_NAME",
+			"service_prefix":  "JUPYTERHUB_SERVICE_PREFIX",
+		}
+		for key, envVar := range hubFields {
+			if val := os.Getenv(envVar); val != "" {
+				fields[key] = val
+			}
+		}
+	}
+
+	for _, field := range c.LogFields {
+		key, value, ok := strings.Cut(field, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --log-field %q: expected key=value", field)
+		}
+		fields[key] = value
+	}
+
+	return fields, nil
+}
+
+// Redactor builds the redactor for --redact-env values, the Hub API token and --redact-pattern matches
+// Also returns the --redact-env names that were skipped. An invalid pattern is reported in the
+// error, but the returned redactor still masks everything else so it can be used regardless.
+// --redact-env names set with --env-file or --env are masked with the value the app gets;
+// the proxy's own Hub API token is masked even if the app is given another one.
+func (c *Config) Redactor() (*redact.Redactor, []string, error) {
+	// An unreadable env file is reported when the app's environment is built
+	appEnv, _ := command.LoadEnv(c.EnvFiles, c.Env)
+	redactor, skipped := redact.FromLookup(c.RedactEnv, func(name string) string {
+		if value, ok := appEnv[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	})
+	patterns, err := redact.CompilePatterns(c.RedactPatterns)
+	return redactor.WithPatterns(patterns), skipped, err
+}
+
+// RepoSpec is a parsed --repo: a repository cloned before the app starts
+type RepoSpec struct {
+	URL      string
+	Branch   string
+	Folder   string
+	Optional bool // A failed clone is reported but doesn't stop the app from starting
+}
+
+// ParseRepos parses the --repo flags
+// Entries without branch= use --repobranch. folder= may only be left out with a single
+// --repo, which then clones into --repofolder.
+func (c *Config) ParseRepos() ([]RepoSpec, error) {
+	repos := make([]RepoSpec, 0, len(c.Repo))
+	seen := make(map[string]bool)
+	for _, spec := range c.Repo {
+		fields := strings.Split(spec, ",")
+		repo := RepoSpec{URL: strings.TrimSpace(fields[0]), Branch: c.RepoBranch}
+		if repo.URL == "" {
+			return nil, fmt.Errorf("invalid --repo %q: missing URL", spec)
+		}
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+			switch key {
+			case "branch":
+				repo.Branch = value
+			case "folder":
+				repo.Folder = value
+			case "optional":
+				repo.Optional = true
+			default:
+				return nil, fmt.Errorf("invalid --repo %q: unknown option %q (expected branch=, folder= or optional)", spec, field)
+			}
+		}
+		if repo.Folder == "" {
+			if len(c.Repo) > 1 {
+				return nil, fmt.Errorf("invalid --repo %q: folder= is required when cloning several repositories", spec)
+			}
+			repo.Folder = c.RepoFolder
+		}
+		if seen[repo.Folder] {
+			return nil, fmt.Errorf("invalid --repo %q: folder %s is already used by another repository", spec, repo.Folder)
+		}
+		seen[repo.Folder] = true
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+// Route is a parsed --route: requests under Prefix go to Port instead of the app
+// Placeholder is set for <prefix>={name} routes, whose port is allocated at startup.
+type Route struct {
+	Prefix      string
+	Port        int
+	Placeholder string // Name substituted in the command, without braces (empty = fixed port)
+}
+
+// reservedPlaceholders are command placeholders a route can't claim
+var reservedPlaceholders = map[string]bool{"port": true, "socket": true, "root_path": true, "base_url": true, "-": true, "--": true}
+
+// ParseRoutes parses the --route flags
+func (c *Config) ParseRoutes() ([]Route, error) {
+	routes := make([]Route, 0, len(c.Routes))
+	seen := make(map[string]bool)
+	for _, spec := range c.Routes {
+		prefix, target, ok := strings.Cut(spec, "=")
+		prefix = "/" + strings.Trim(strings.TrimSpace(prefix), "/")
+		target = strings.TrimSpace(target)
+		if !ok || prefix == "/" || target == "" {
+			return nil, fmt.Errorf("invalid --route %q: expected <prefix>=<port> or <prefix>={name}", spec)
+		}
+		if seen[prefix] {
+			return nil, fmt.Errorf("invalid --route %q: duplicate prefix %s", spec, prefix)
+		}
+		seen[prefix] = true
+
+		route := Route{Prefix: prefix}
+		if strings.HasPrefix(target, "{") && strings.HasSuffix(target, "}") {
+			route.Placeholder = target[1 : len(target)-1]
+			if route.Placeholder == "" || reservedPlaceholders[route.Placeholder] {
+				return nil, fmt.Errorf("invalid --route %q: placeholder %s is reserved or empty", spec, target)
+			}
+		} else {
+			port, err := strconv.Atoi(target)
+			if err != nil || port < 1 || port > 65535 {
+				return nil, fmt.Errorf("invalid --route %q: port must be 1-65535 or a {name} placeholder", spec)
+			}
+			route.Port = port
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
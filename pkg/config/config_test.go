@@ -0,0 +1,222 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStaticLogFields(t *testing.T) {
+	t.Setenv("JUPYTERHUB_USER", "alice")
+	t.Setenv("JUPYTERHUB_SERVER_NAME", "dashboard")
+	t.Setenv("JUPYTERHUB_SERVICE_PREFIX", "/user/alice/dashboard/")
+
+	tests := []struct {
+		name      string
+		config    Config
+		expected  map[string]interface{}
+		expectErr bool
+	}{
+		{
+			name:     "no fields",
+			config:   Config{},
+			expected: map[string]interface{}{},
+		},
+		{
+			name:   "static key=value fields",
+			config: Config{LogFields: []string{"team=data", "env=prod", "note=a=b"}},
+			expected: map[string]interface{}{
+				"team": "data",
+				"env":  "prod",
+				"note": "a=b",
+			},
+		},
+		{
+			name:   "hub fields with static override",
+			config: Config{LogHubFields: true, LogFields: []string{"hub_user=bob"}},
+			expected: map[string]interface{}{
+				"hub_user":        "bob",
+				"hub_server_name": "dashboard",
+				"service_prefix":  "/user/alice/dashboard/",
+			},
+		},
+		{
+			name:      "missing separator",
+			config:    Config{LogFields: []string{"team"}},
+			expectErr: true,
+		},
+		{
+			name:      "empty key",
+			config:    Config{LogFields: []string{"=data"}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := tt.config.StaticLogFields()
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got fields %v", fields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(fields) != len(tt.expected) {
+				t.Fatalf("expected %d fields, got %v", len(tt.expected), fields)
+			}
+			for k, v := range tt.expected {
+				if fields[k] != v {
+					t.Errorf("field %q: expected %v, got %v", k, v, fields[k])
+				}
+			}
+		})
+	}
+}
+
+func TestLogLocation(t *testing.T) {
+	tests := []struct {
+		timezone string
+		want     string
+		wantErr  bool
+	}{
+		{timezone: "", want: "UTC"},
+		{timezone: "UTC", want: "UTC"},
+		{timezone: "Local", want: "Local"},
+		{timezone: "Europe/Berlin", want: "Europe/Berlin"},
+		{timezone: "Mars/Olympus_Mons", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{LogTimezone: tt.timezone}
+		loc, err := cfg.LogLocation()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tt.timezone)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.timezone, err)
+			continue
+		}
+		if loc.String() != tt.want {
+			t.Errorf("%q: expected location %q, got %q", tt.timezone, tt.want, loc.String())
+		}
+	}
+}
+
+func TestParseRepos(t *testing.T) {
+	tests := []struct {
+		name      string
+		repos     []string
+		want      []RepoSpec
+		expectErr bool
+	}{
+		{name: "none", repos: nil, want: []RepoSpec{}},
+		{name: "single uses repofolder", repos: []string{"https://github.com/org/app"}, want: []RepoSpec{
+			{URL: "https://github.com/org/app", Branch: "main", Folder: "/srv/app"},
+		}},
+		{name: "several with options", repos: []string{
+			"https://github.com/org/app,folder=/srv/app",
+			"https://github.com/org/data, branch=v2, folder=/srv/data, optional",
+		}, want: []RepoSpec{
+			{URL: "https://github.com/org/app", Branch: "main", Folder: "/srv/app"},
+			{URL: "https://github.com/org/data", Branch: "v2", Folder: "/srv/data", Optional: true},
+		}},
+		{name: "several without folder", repos: []string{"https://github.com/org/app", "https://github.com/org/data,folder=/srv/data"}, expectErr: true},
+		{name: "duplicate folder", repos: []string{"https://a,folder=/srv/app", "https://b,folder=/srv/app"}, expectErr: true},
+		{name: "unknown option", repos: []string{"https://github.com/org/app,depth=1"}, expectErr: true},
+		{name: "missing URL", repos: []string{",folder=/srv/app"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Repo: tt.repos, RepoBranch: "main", RepoFolder: "/srv/app"}
+			repos, err := cfg.ParseRepos()
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got repos %v", repos)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(repos, tt.want) {
+				t.Errorf("expected repos %v, got %v", tt.want, repos)
+			}
+		})
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	tests := []struct {
+		name      string
+		routes    []string
+		want      []Route
+		expectErr bool
+	}{
+		{name: "none", routes: nil, want: []Route{}},
+		{name: "fixed port", routes: []string{"/api=8502"}, want: []Route{{Prefix: "/api", Port: 8502}}},
+		{name: "placeholder", routes: []string{"api/={api_port}"}, want: []Route{{Prefix: "/api", Placeholder: "api_port"}}},
+		{name: "several", routes: []string{"/api=8502", "/ws/events={events}"}, want: []Route{
+			{Prefix: "/api", Port: 8502},
+			{Prefix: "/ws/events", Placeholder: "events"},
+		}},
+		{name: "missing port", routes: []string{"/api"}, expectErr: true},
+		{name: "root prefix", routes: []string{"/=8502"}, expectErr: true},
+		{name: "bad port", routes: []string{"/api=http"}, expectErr: true},
+		{name: "port out of range", routes: []string{"/api=70000"}, expectErr: true},
+		{name: "reserved placeholder", routes: []string{"/api={port}"}, expectErr: true},
+		{name: "duplicate prefix", routes: []string{"/api=8502", "/api/=8503"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Routes: tt.routes}
+			routes, err := cfg.ParseRoutes()
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("expected error, got routes %v", routes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(routes, tt.want) {
+				t.Errorf("expected routes %v, got %v", tt.want, routes)
+			}
+		})
+	}
+}
+
+func TestReadyCheckProtocolAlias(t *testing.T) {
+	for _, flag := range []string{"--ready-check-type", "--ready-check-protocol"} {
+		t.Run(flag, func(t *testing.T) {
+			cfg, err := executeWithArgs(t, flag, "tcp", "--", "app")
+			if err != nil {
+				t.Fatalf("failed to parse flags: %v", err)
+			}
+			if cfg.ReadyCheckType != "tcp" {
+				t.Errorf("expected ready check type tcp, got %q", cfg.ReadyCheckType)
+			}
+		})
+	}
+}
+
+func TestAuthCacheTTLAlias(t *testing.T) {
+	for _, flag := range []string{"--oauth-cache-ttl", "--auth-cache-ttl"} {
+		t.Run(flag, func(t *testing.T) {
+			cfg, err := executeWithArgs(t, flag, "15", "--", "app")
+			if err != nil {
+				t.Fatalf("failed to parse flags: %v", err)
+			}
+			if cfg.OAuthCacheTTL != 15 {
+				t.Errorf("expected OAuth cache TTL 15, got %d", cfg.OAuthCacheTTL)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package config - Loading configuration from a YAML file (--config)
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile sets the fields present in a YAML config file, leaving the others untouched
+// Keys are the snake_case names printed by `jhub-app-proxy config` (auth_type, dest_port,
+// conda_env, command, ...). Unknown keys are an error so typos don't go unnoticed.
+// $VAR and ${VAR} in string values are replaced from the environment; $$ is a literal $.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	data, err = expandEnv(data)
+	if err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// expandEnv expands environment variables in the string values of a YAML document
+// Keys, comments and non-string values are left alone, so a variable can't inject YAML
+// structure or turn into an unknown key.
+func expandEnv(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		return data, nil // Empty document
+	}
+
+	expandNode(&doc)
+	return yaml.Marshal(&doc)
+}
+
+// expandNode expands environment variables in the string scalars under node, skipping mapping keys
+func expandNode(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			expandNode(child)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			expandNode(node.Content[i])
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!str" {
+			node.Value = os.Expand(node.Value, func(name string) string {
+				if name == "$" {
+					return "$"
+				}
+				return os.Getenv(name)
+			})
+			// Let an unquoted value resolve again, so `port: $PORT` still sets an int
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+	}
+}
+
+// applyConfigFile loads the --config file into cfg after flag parsing
+// Flags set on the command line are re-applied afterwards, so they override the file.
+func applyConfigFile(cmd *cobra.Command, cfg *Config, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	// Snapshot the flags given on the command line
+	type setFlag struct {
+		value  string
+		values []string // For slice flags, which append instead of replace on Set
+	}
+	changed := make(map[*pflag.Flag]setFlag)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			changed[f] = setFlag{values: sv.GetSlice()}
+		} else {
+			changed[f] = setFlag{value: f.Value.String()}
+		}
+	})
+
+	if err := cfg.LoadFile(path); err != nil {
+		return err
+	}
+
+	for f, set := range changed {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			if err := sv.Replace(set.values); err != nil {
+				return fmt.Errorf("failed to re-apply --%s over config file: %w", f.Name, err)
+			}
+		} else if err := f.Value.Set(set.value); err != nil {
+			return fmt.Errorf("failed to re-apply --%s over config file: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// StringList is a list of strings that a config file may also give as a single string
+// Keeps `repo: https://...` files working now that --repo is repeatable.
+type StringList []string
+
+// UnmarshalYAML accepts a sequence of strings or a single string
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = StringList{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// executeWithArgs parses args like the real binary and returns the resulting Config
+func executeWithArgs(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+	cmd, cfg, err := NewFromFlags("test", "now")
+	if err != nil {
+		t.Fatalf("failed to create command: %v", err)
+	}
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs(args)
+	return cfg, cmd.Execute()
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFile_RoundTrip(t *testing.T) {
+	want := Config{
+		AuthType:              "none",
+		InterimPageAuth:       true,
+		AllowedGroups:         []string{"analysts", "staff"},
+		AllowedUsers:          []string{"alice"},
+		RequiredScopes:        []string{"custom:dashboard:view"},
+		OAuthCacheTTL:         30,
+		OAuthCacheSize:        500,
+		LogoutRedirect:        "https://example.com/goodbye",
+		BasicAuthUser:         "admin",
+		BasicAuthPass:         "hunter2",
+		BasicAuthFile:         "/etc/jhub-app-proxy/htpasswd",
+		Command:               []string{"streamlit", "run", "app.py", "--server.port", "{port}"},
+		CommandFile:           "/run/app/command.json",
+		CommandFilePoll:       10,
+		DestPort:              8501,
+		CondaEnv:              "analytics",
+		FailOnMissingCondaEnv: true,
+		WorkDir:               "/home/jovyan/app",
+		KeepAlive:             true,
+		KeepAliveInterval:     120,
+		KeepAliveJitter:       10,
+		SkipActivityIfCulled:  true,
+		Nice:                  10,
+		NoProcessGroup:        true,
+		StripPrefix:           false,
+		MaxRestarts:           3,
+		RestartBackoff:        2,
+		RestartBackoffMax:     60,
+		RestartBackoffFactor:  1.5,
+		CrashLoopThreshold:    4,
+		CrashLoopWindow:       120,
+		AllowedMethods:        []string{"GET", "POST"},
+		Routes:                []string{"/api={api_port}"},
+		CORSOrigins:           []string{"https://dashboards.example.com"},
+		PreserveHost:          false,
+		BackendH2C:            true,
+		GRPC:                  true,
+		BackendDialTimeout:    3,
+		BackendRetry:          2,
+		ProxyTimeout:          30,
+		WSMaxMessageSize:      1048576,
+		TrustProxyHeaders:     true,
+		MaxURLLength:          4096,
+		NoIndex:               true,
+		AuditLogFile:          "/var/log/audit.jsonl",
+		Compress:              true,
+		HubConnectTimeout:     7,
+		Repo:                  StringList{"https://github.com/org/app,branch=main,folder=/srv/app", "https://github.com/org/data,optional"},
+		RepoFolder:            "/home/jovyan/app",
+		RepoBranch:            "develop",
+		RepoCloneTimeout:      600,
+		RepoVerifyCommits:     true,
+		RepoUnshallow:         true,
+		ReadyCheckPath:        "/healthz",
+		ReadyCheckType:        "tcp",
+		ReadyCheckMethod:      "POST",
+		ReadyCheckBody:        `{"probe": "ready"}`,
+		ReadyCheckContentType: "application/json",
+		ReadyTimeout:          120,
+		ReadyStabilization:    10,
+		LogLevel:              "debug",
+		LogFormat:             "pretty",
+		LogTimeFormat:         "15:04:05",
+		LogTimezone:           "Europe/Berlin",
+		LogBufferSize:         5000,
+		LogMaxAge:             86400,
+		LogFileMaxBytes:       10485760,
+		ShowCaller:            true,
+		LogFields:             []string{"team=data", "env=prod"},
+		LogHubFields:          true,
+		LogSinkURL:            "http://loki:3100/loki/api/v1/push",
+		LogSinkFormat:         "loki",
+		RedactEnv:             []string{"DB_PASSWORD"},
+		RedactPatterns:        []string{`sk-[A-Za-z0-9]+`},
+		StripANSI:             true,
+		Port:                  9000,
+		ListenPort:            9001,
+		TLSCertFile:           "/etc/tls/tls.crt",
+		TLSKeyFile:            "/etc/tls/tls.key",
+		TLSMinVersion:         "1.3",
+		TLSCipherSuites:       []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+		Metrics:               true,
+		Progressive:           true,
+		HTTP2Push:             false,
+
+		LivenessInterval:         15,
+		LivenessFailureThreshold: 4,
+
+		APIRateLimit: 2.5,
+		APIRateBurst: 5,
+
+		OTelLogsEndpoint: "http://otel-collector:4318/v1/logs",
+
+		LogNoSync: true,
+
+		BrandTitle:        "Acme Data Platform",
+		BrandLogoURL:      "https://example.com/logo.svg",
+		BrandPrimaryColor: "#1e40af",
+
+		WorkDirCreate: true,
+		EnvFiles:      []string{"/srv/app/.env", "/srv/app/.env.local"},
+		Env:           []string{"APP_MODE=production"},
+
+		DestSocket: "/tmp/app.sock",
+
+		CondaEnvPreferRoot: "/opt/conda/envs",
+
+		ValidateAtStartup: true,
+
+		DestPortMin: 20000,
+		DestPortMax: 20100,
+
+		FailureLogLines: 50,
+
+		RootPathPrefix: "/jupyter",
+
+		RunAsUser: "jovyan",
+		RunAsUID:  -1,
+		RunAsGID:  100,
+	}
+
+	data, err := yaml.Marshal(want)
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+	path := writeConfigFile(t, string(data))
+
+	got, err := executeWithArgs(t, "--config", path)
+	if err != nil {
+		t.Fatalf("failed to load config file: %v", err)
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("expected config loaded from file to match\nwant: %+v\ngot:  %+v", want, *got)
+	}
+}
+
+func TestLoadFile_FlagsOverrideFile(t *testing.T) {
+	path := writeConfigFile(t, `
+auth_type: none
+port: 9000
+dest_port: 8501
+conda_env: analytics
+log_fields: [team=data]
+allowed_methods: [GET]
+command: [python, app.py]
+`)
+
+	cfg, err := executeWithArgs(t,
+		"--config", path,
+		"--port", "9100",
+		"--log-field", "env=prod",
+		"--allowed-methods", "GET,HEAD",
+		"--", "streamlit", "run", "app.py")
+	if err != nil {
+		t.Fatalf("failed to load config file: %v", err)
+	}
+
+	if cfg.Port != 9100 {
+		t.Errorf("expected flag port 9100 to override file, got %d", cfg.Port)
+	}
+	if cfg.AuthType != "none" {
+		t.Errorf("expected auth_type none from file, got %q", cfg.AuthType)
+	}
+	if cfg.DestPort != 8501 {
+		t.Errorf("expected dest_port 8501 from file, got %d", cfg.DestPort)
+	}
+	if cfg.CondaEnv != "analytics" {
+		t.Errorf("expected conda_env analytics from file, got %q", cfg.CondaEnv)
+	}
+	if !reflect.DeepEqual(cfg.LogFields, []string{"env=prod"}) {
+		t.Errorf("expected --log-field to replace file log_fields, got %q", cfg.LogFields)
+	}
+	if !reflect.DeepEqual(cfg.AllowedMethods, []string{"GET", "HEAD"}) {
+		t.Errorf("expected --allowed-methods to replace file allowed_methods, got %q", cfg.AllowedMethods)
+	}
+	if strings.Join(cfg.Command, " ") != "streamlit run app.py" {
+		t.Errorf("expected args to override file command, got %q", cfg.Command)
+	}
+	// Unset in both: flag default
+	if cfg.ReadyCheckPath != "/" {
+		t.Errorf("expected default ready_check_path /, got %q", cfg.ReadyCheckPath)
+	}
+}
+
+func TestLoadFile_CommandFromFile(t *testing.T) {
+	path := writeConfigFile(t, "command: [python, -m, http.server, \"{port}\"]\n")
+
+	cfg, err := executeWithArgs(t, "--config", path)
+	if err != nil {
+		t.Fatalf("failed to load config file: %v", err)
+	}
+	if strings.Join(cfg.Command, " ") != "python -m http.server {port}" {
+		t.Errorf("expected command from file, got %q", cfg.Command)
+	}
+}
+
+func TestLoadFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "unknown key", content: "auth_type: none\nauthtyp: oauth\n", wantErr: "field authtyp not found"},
+		{name: "wrong type", content: "port: eighty\n", wantErr: "cannot unmarshal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.content)
+			_, err := executeWithArgs(t, "--config", path, "--", "python", "app.py")
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), path) {
+				t.Errorf("expected error mentioning %q and the file path, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := executeWithArgs(t, "--config", filepath.Join(t.TempDir(), "missing.yaml"), "--", "python", "app.py")
+		if err == nil {
+			t.Fatal("expected error for missing file, got nil")
+		}
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		path := writeConfigFile(t, "")
+		cfg, err := executeWithArgs(t, "--config", path, "--", "python", "app.py")
+		if err != nil {
+			t.Fatalf("expected empty file to be accepted, got %v", err)
+		}
+		if cfg.AuthType != "oauth" {
+			t.Errorf("expected default auth_type oauth, got %q", cfg.AuthType)
+		}
+	})
+}
+
+func TestLoadFile_RepoList(t *testing.T) {
+	path := writeConfigFile(t, `
+repo:
+  - https://github.com/org/app,folder=/srv/app
+  - https://github.com/org/data,folder=/srv/data,optional
+`)
+
+	cfg, err := executeWithArgs(t, "--config", path)
+	if err != nil {
+		t.Fatalf("failed to load config file: %v", err)
+	}
+	want := StringList{"https://github.com/org/app,folder=/srv/app", "https://github.com/org/data,folder=/srv/data,optional"}
+	if !reflect.DeepEqual(cfg.Repo, want) {
+		t.Errorf("expected repo %q, got %q", want, cfg.Repo)
+	}
+}
+
+func TestLoadFile_ExpandsEnv(t *testing.T) {
+	t.Setenv("APP_REPO", "https://github.com/org/app")
+	t.Setenv("APP_PORT", "9000")
+	t.Setenv("APP_DEBUG", "true")
+	path := writeConfigFile(t, `
+repo: ${APP_REPO}
+port: $APP_PORT
+keep_alive: $APP_DEBUG
+conda_env: "$APP_DEBUG"
+work_dir: /home/$UNSET_VARIABLE_FOR_TEST/app
+command: [bash, -c, "echo $$HOME $APP_PORT"]
+`)
+
+	cfg, err := executeWithArgs(t, "--config", path)
+	if err != nil {
+		t.Fatalf("failed to load config file: %v", err)
+	}
+
+	if len(cfg.Repo) != 1 || cfg.Repo[0] != "https://github.com/org/app" {
+		t.Errorf("expected repo from ${APP_REPO}, got %q", cfg.Repo)
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("expected port 9000 from $APP_PORT, got %d", cfg.Port)
+	}
+	if !cfg.KeepAlive {
+		t.Error("expected keep_alive true from $APP_DEBUG")
+	}
+	if cfg.CondaEnv != "true" {
+		t.Errorf("expected quoted conda_env to stay a string, got %q", cfg.CondaEnv)
+	}
+	if cfg.WorkDir != "/home//app" {
+		t.Errorf("expected unset variable to expand to empty, got %q", cfg.WorkDir)
+	}
+	if got := strings.Join(cfg.Command, " "); got != "bash -c echo $HOME 9000" {
+		t.Errorf("expected $$ to be a literal $, got %q", got)
+	}
+}
+
+func TestLoadFile_Example(t *testing.T) {
+	var cfg Config
+	if err := cfg.LoadFile("../../examples/config.yaml"); err != nil {
+		t.Fatalf("failed to load example config: %v", err)
+	}
+	if len(cfg.Command) == 0 {
+		t.Error("expected example config to set a command")
+	}
+}
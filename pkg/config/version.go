@@ -0,0 +1,48 @@
+// Package config - `jhub-app-proxy version` subcommand printing build information
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// VersionInfo identifies the proxy build
+type VersionInfo struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// newVersionCommand creates the `version` subcommand
+// `--json` gives tooling (CI, jhub-apps) the exact build without parsing the --version string.
+func newVersionCommand(version, buildTime string) *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "version [--json]",
+		Short: "Print the version and build information and exit",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := VersionInfo{
+				Version:   version,
+				BuildTime: buildTime,
+				GoVersion: runtime.Version(),
+			}
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				return enc.Encode(info)
+			}
+			_, err := fmt.Fprintf(cmd.OutOrStdout(), "jhub-app-proxy %s (built %s, %s)\n",
+				info.Version, info.BuildTime, info.GoVersion)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false,
+		`Print {"version", "build_time", "go_version"} as JSON`)
+
+	return cmd
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestVersionCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "json", args: []string{"version", "--json"}},
+		{name: "text", args: []string{"version"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, _, err := NewFromFlags("v1.2.3", "2026-10-01T12:00:00Z")
+			if err != nil {
+				t.Fatalf("failed to create command: %v", err)
+			}
+			out := &bytes.Buffer{}
+			cmd.SetOut(out)
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("version command failed: %v", err)
+			}
+
+			if tt.name == "text" {
+				if !strings.Contains(out.String(), "v1.2.3") {
+					t.Errorf("expected version in output, got %q", out.String())
+				}
+				return
+			}
+
+			var info map[string]string
+			if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+				t.Fatalf("expected well-formed JSON, got %q: %v", out.String(), err)
+			}
+			want := map[string]string{
+				"version":    "v1.2.3",
+				"build_time": "2026-10-01T12:00:00Z",
+				"go_version": runtime.Version(),
+			}
+			for key, value := range want {
+				if info[key] != value {
+					t.Errorf("expected %s %q, got %q", key, value, info[key])
+				}
+			}
+		})
+	}
+}
@@ -2,37 +2,49 @@
 package git
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
 )
 
 // Manager handles git operations
 type Manager struct {
-	logger *logger.Logger
+	logger  *logger.Logger
+	command func(ctx context.Context, name string, args ...string) *exec.Cmd // Builds git commands (exec.CommandContext; faked in tests)
 }
 
 // NewManager creates a new git manager
 func NewManager(log *logger.Logger) *Manager {
 	return &Manager{
-		logger: log.WithComponent("git-manager"),
+		logger:  log.WithComponent("git-manager"),
+		command: exec.CommandContext,
 	}
 }
 
+// OutputHandler receives git output line by line as it is produced
+type OutputHandler func(line string)
+
 // CloneConfig holds git clone configuration
 type CloneConfig struct {
-	RepoURL    string // Git repository URL
-	Branch     string // Branch or tag to checkout
-	DestPath   string // Destination path for the clone
-	Depth      int    // Clone depth (0 for full clone, 1 for shallow)
-	Submodules bool   // Whether to clone submodules
+	RepoURL       string        // Git repository URL
+	Branch        string        // Branch or tag to checkout
+	DestPath      string        // Destination path for the clone
+	Depth         int           // Clone depth (0 for full clone, 1 for shallow)
+	Submodules    bool          // Whether to clone submodules
+	OutputHandler OutputHandler // Optional handler for streaming git output (e.g., to the interim logs)
+
+	RequireSignedCommits bool // Verify fails unless HEAD carries a valid GPG signature
 }
 
 // Clone clones a git repository
-func (m *Manager) Clone(cfg CloneConfig) error {
+// The context bounds how long the clone (or pull) may take
+func (m *Manager) Clone(ctx context.Context, cfg CloneConfig) error {
 	m.logger.Progress("cloning git repository",
 		"repo", cfg.RepoURL,
 		"branch", cfg.Branch,
@@ -50,7 +62,7 @@ func (m *Manager) Clone(cfg CloneConfig) error {
 	if _, err := os.Stat(gitDir); err == nil {
 		m.logger.Info("git repository already exists, pulling latest changes",
 			"dest", cfg.DestPath)
-		return m.pull(cfg.DestPath, cfg.Branch)
+		return m.pull(ctx, cfg.DestPath, cfg.Branch, cfg.OutputHandler)
 	}
 
 	// Build clone command
@@ -74,15 +86,18 @@ func (m *Manager) Clone(cfg CloneConfig) error {
 	args = append(args, cfg.RepoURL, cfg.DestPath)
 
 	// Execute clone
-	cmd := exec.Command("git", args...)
-	output, err := cmd.CombinedOutput()
+	cmd := m.command(ctx, "git", args...)
+	output, err := runWithOutput(cmd, cfg.OutputHandler)
 
 	if err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("%w (%v)", ctx.Err(), err)
+		}
 		m.logger.GitOperation("clone", cfg.RepoURL, cfg.Branch, cfg.DestPath, err)
 		m.logger.Error("git clone failed", err,
-			"output", string(output),
+			"output", output,
 			"command", cmd.String())
-		return fmt.Errorf("git clone failed: %w: %s", err, string(output))
+		return fmt.Errorf("git clone failed: %w: %s", err, output)
 	}
 
 	m.logger.GitOperation("clone", cfg.RepoURL, cfg.Branch, cfg.DestPath, nil)
@@ -94,38 +109,38 @@ func (m *Manager) Clone(cfg CloneConfig) error {
 }
 
 // pull updates an existing git repository
-func (m *Manager) pull(repoPath string, branch string) error {
+func (m *Manager) pull(ctx context.Context, repoPath string, branch string, onLine OutputHandler) error {
 	m.logger.Progress("pulling git repository",
 		"path", repoPath,
 		"branch", branch)
 
 	// Fetch latest changes
-	fetchCmd := exec.Command("git", "fetch", "origin")
+	fetchCmd := m.command(ctx, "git", "fetch", "origin")
 	fetchCmd.Dir = repoPath
-	if output, err := fetchCmd.CombinedOutput(); err != nil {
-		m.logger.Error("git fetch failed", err, "output", string(output))
-		return fmt.Errorf("git fetch failed: %w: %s", err, string(output))
+	if output, err := runWithOutput(fetchCmd, onLine); err != nil {
+		m.logger.Error("git fetch failed", err, "output", output)
+		return fmt.Errorf("git fetch failed: %w: %s", err, output)
 	}
 
 	// Checkout specified branch
 	if branch != "" {
-		checkoutCmd := exec.Command("git", "checkout", branch)
+		checkoutCmd := m.command(ctx, "git", "checkout", branch)
 		checkoutCmd.Dir = repoPath
-		if output, err := checkoutCmd.CombinedOutput(); err != nil {
-			m.logger.Error("git checkout failed", err, "output", string(output))
-			return fmt.Errorf("git checkout failed: %w: %s", err, string(output))
+		if output, err := runWithOutput(checkoutCmd, onLine); err != nil {
+			m.logger.Error("git checkout failed", err, "output", output)
+			return fmt.Errorf("git checkout failed: %w: %s", err, output)
 		}
 	}
 
 	// Pull latest changes
-	pullCmd := exec.Command("git", "pull", "origin", branch)
+	pullCmd := m.command(ctx, "git", "pull", "origin", branch)
 	pullCmd.Dir = repoPath
-	output, err := pullCmd.CombinedOutput()
+	output, err := runWithOutput(pullCmd, onLine)
 
 	if err != nil {
 		m.logger.GitOperation("pull", repoPath, branch, repoPath, err)
-		m.logger.Error("git pull failed", err, "output", string(output))
-		return fmt.Errorf("git pull failed: %w: %s", err, string(output))
+		m.logger.Error("git pull failed", err, "output", output)
+		return fmt.Errorf("git pull failed: %w: %s", err, output)
 	}
 
 	m.logger.GitOperation("pull", repoPath, branch, repoPath, nil)
@@ -134,8 +149,143 @@ func (m *Manager) pull(repoPath string, branch string) error {
 	return nil
 }
 
+// Verify checks the integrity of the repository cloned at cfg.DestPath
+// Runs git fsck and, with cfg.RequireSignedCommits, checks the GPG signature of HEAD
+// against the keys in the user's keyring.
+func (m *Manager) Verify(ctx context.Context, cfg CloneConfig) error {
+	fsckCmd := m.command(ctx, "git", "fsck", "--no-dangling")
+	fsckCmd.Dir = cfg.DestPath
+	if output, err := runWithOutput(fsckCmd, nil); err != nil {
+		m.logger.Error("git fsck failed", err, "dest", cfg.DestPath, "output", output)
+		return fmt.Errorf("git fsck failed for %s: %w: %s", cfg.DestPath, err, output)
+	}
+
+	if !cfg.RequireSignedCommits {
+		m.logger.Info("git repository verified", "dest", cfg.DestPath)
+		return nil
+	}
+
+	// --raw prints machine-readable GnuPG status lines, including the key fingerprint
+	verifyCmd := m.command(ctx, "git", "verify-commit", "--raw", "HEAD")
+	verifyCmd.Dir = cfg.DestPath
+	output, err := runWithOutput(verifyCmd, nil)
+	if err != nil {
+		m.logger.Error("git verify-commit failed", err, "dest", cfg.DestPath, "output", output)
+		return fmt.Errorf("HEAD of %s has no valid GPG signature: %w: %s", cfg.DestPath, err, output)
+	}
+
+	m.logger.Info("git repository verified, HEAD signature is valid",
+		"dest", cfg.DestPath,
+		"gpg_fingerprint", signatureFingerprint(output))
+	return nil
+}
+
+// signatureFingerprint extracts the signing key fingerprint from GnuPG status output
+// Returns an empty string if there is no VALIDSIG line.
+func signatureFingerprint(status string) string {
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "[GNUPG:]" && fields[1] == "VALIDSIG" {
+			return fields[2]
+		}
+	}
+	return ""
+}
+
+// HeadCommit returns the SHA of the commit checked out in the repository at repoPath
+func (m *Manager) HeadCommit(ctx context.Context, repoPath string) (string, error) {
+	cmd := m.command(ctx, "git", "rev-parse", "HEAD")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse HEAD failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// IsShallow reports whether the repository at repoPath is a shallow clone
+func (m *Manager) IsShallow(ctx context.Context, repoPath string) (bool, error) {
+	cmd := m.command(ctx, "git", "rev-parse", "--is-shallow-repository")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("git rev-parse --is-shallow-repository failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)) == "true", nil
+}
+
+// Unshallow fetches the full history of a shallow clone, e.g. so git log works in the app
+// A repository that is already complete is left alone: git refuses --unshallow on it,
+// which is not an error here.
+func (m *Manager) Unshallow(ctx context.Context, repoPath string, onLine OutputHandler) error {
+	shallow, err := m.IsShallow(ctx, repoPath)
+	if err != nil {
+		return err
+	}
+	if !shallow {
+		m.logger.Info("git repository already has its full history", "path", repoPath)
+		return nil
+	}
+
+	m.logger.Progress("fetching full git history", "path", repoPath)
+	cmd := m.command(ctx, "git", "fetch", "--unshallow", "origin")
+	cmd.Dir = repoPath
+	output, err := runWithOutput(cmd, onLine)
+	if err != nil && strings.Contains(output, "on a complete repository") {
+		m.logger.Info("git repository already has its full history", "path", repoPath)
+		return nil
+	}
+	if err != nil {
+		m.logger.Error("git fetch --unshallow failed", err, "path", repoPath, "output", output)
+		return fmt.Errorf("git fetch --unshallow failed: %w: %s", err, output)
+	}
+
+	m.logger.Info("fetched full git history", "path", repoPath)
+	return nil
+}
+
 // IsGitInstalled checks if git is available
 func (m *Manager) IsGitInstalled() bool {
 	_, err := exec.LookPath("git")
 	return err == nil
 }
+
+// runWithOutput runs cmd and returns its combined output
+// Each output line is also passed to onLine (if set) as soon as it is written
+func runWithOutput(cmd *exec.Cmd, onLine OutputHandler) (string, error) {
+	w := &lineWriter{onLine: onLine}
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err := cmd.Run()
+	w.flush()
+	return w.output.String(), err
+}
+
+// lineWriter collects command output and emits complete lines to a handler
+// Carriage returns are treated as line breaks so git progress updates are surfaced too
+type lineWriter struct {
+	onLine  OutputHandler
+	output  bytes.Buffer
+	pending bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.output.Write(p)
+	for _, b := range p {
+		if b == '\n' || b == '\r' {
+			w.flush()
+			continue
+		}
+		w.pending.WriteByte(b)
+	}
+	return len(p), nil
+}
+
+// flush emits any buffered partial line
+func (w *lineWriter) flush() {
+	line := strings.TrimSpace(w.pending.String())
+	w.pending.Reset()
+	if line != "" && w.onLine != nil {
+		w.onLine(line)
+	}
+}
@@ -0,0 +1,267 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+// runGit runs a git command in dir, failing the test on error
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v: %s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+// setupGPG points gpg at a fresh keyring holding one signing key and returns its fingerprint
+func setupGPG(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+
+	// Short path: gpg-agent's socket path must fit in a Unix socket address
+	home, err := os.MkdirTemp("", "gpg")
+	if err != nil {
+		t.Fatalf("failed to create GNUPGHOME: %v", err)
+	}
+	t.Setenv("GNUPGHOME", home)
+	t.Cleanup(func() {
+		_ = exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+		_ = os.RemoveAll(home)
+	})
+
+	gen := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", "Test <test@example.com>", "ed25519", "sign", "never")
+	if output, err := gen.CombinedOutput(); err != nil {
+		t.Fatalf("failed to generate GPG key: %v: %s", err, output)
+	}
+	list, err := exec.Command("gpg", "--batch", "--with-colons", "--list-secret-keys").Output()
+	if err != nil {
+		t.Fatalf("failed to list GPG keys: %v", err)
+	}
+	for _, line := range strings.Split(string(list), "\n") {
+		if fields := strings.Split(line, ":"); fields[0] == "fpr" && len(fields) > 9 {
+			return fields[9]
+		}
+	}
+	t.Fatal("no fingerprint in GPG key listing")
+	return ""
+}
+
+// newSourceRepo creates a repository with a single commit, signed with the given key if set
+func newSourceRepo(t *testing.T, signingKey string) string {
+	t.Helper()
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-b", "main")
+	if err := os.WriteFile(filepath.Join(dir, "app.py"), []byte("print('hello')\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	runGit(t, dir, "add", "app.py")
+	if signingKey != "" {
+		runGit(t, dir, "-c", "user.signingkey="+signingKey, "commit", "-S", "-m", "signed")
+	} else {
+		runGit(t, dir, "commit", "-m", "unsigned")
+	}
+	return dir
+}
+
+func TestManager_Verify(t *testing.T) {
+	fingerprint := setupGPG(t)
+	log := logger.New(logger.Config{Output: io.Discard})
+	m := NewManager(log)
+
+	tests := []struct {
+		name       string
+		signingKey string
+		requireSig bool
+		expectErr  bool
+	}{
+		{name: "signed commit", signingKey: fingerprint, requireSig: true},
+		{name: "unsigned commit", requireSig: true, expectErr: true},
+		{name: "unsigned commit without signature check", requireSig: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := CloneConfig{
+				RepoURL:              "file://" + newSourceRepo(t, tt.signingKey),
+				Branch:               "main",
+				DestPath:             filepath.Join(t.TempDir(), "clone"),
+				Depth:                1,
+				RequireSignedCommits: tt.requireSig,
+			}
+			if err := m.Clone(context.Background(), cfg); err != nil {
+				t.Fatalf("failed to clone: %v", err)
+			}
+
+			err := m.Verify(context.Background(), cfg)
+			if tt.expectErr && err == nil {
+				t.Fatal("expected verification to fail")
+			}
+			if !tt.expectErr && err != nil {
+				t.Fatalf("expected verification to pass, got %v", err)
+			}
+		})
+	}
+}
+
+func TestManager_VerifyCorrupted(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	m := NewManager(log)
+
+	dir := newSourceRepo(t, "")
+	// Truncate every loose object so fsck finds them corrupt
+	objects := filepath.Join(dir, ".git", "objects")
+	err := filepath.Walk(objects, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && len(filepath.Base(filepath.Dir(path))) == 2 {
+			_ = os.Chmod(path, 0644)
+			return os.WriteFile(path, []byte("corrupt"), 0644)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to corrupt objects: %v", err)
+	}
+
+	err = m.Verify(context.Background(), CloneConfig{DestPath: dir})
+	if err == nil || !strings.Contains(err.Error(), "git fsck failed") {
+		t.Errorf("expected git fsck error, got %v", err)
+	}
+}
+
+func TestSignatureFingerprint(t *testing.T) {
+	status := "[GNUPG:] NEWSIG\n[GNUPG:] GOODSIG 1234 Test <test@example.com>\n" +
+		"[GNUPG:] VALIDSIG ABCDEF0123456789 2024-01-01 1704067200 0 4 0 22 10 00 ABCDEF0123456789\n"
+	if got := signatureFingerprint(status); got != "ABCDEF0123456789" {
+		t.Errorf("expected fingerprint ABCDEF0123456789, got %q", got)
+	}
+	if got := signatureFingerprint("gpg: no signature found"); got != "" {
+		t.Errorf("expected no fingerprint, got %q", got)
+	}
+}
+
+// fakeGit makes m run this test binary as git, answered by TestFakeGitProcess
+// shallow is printed by rev-parse ("error" fails it); fetch is "ok", "complete" (git's
+// refusal to unshallow a complete repository) or "fail". Returns the recorded git calls.
+func fakeGit(t *testing.T, m *Manager, shallow, fetch string) func() []string {
+	t.Helper()
+	logPath := filepath.Join(t.TempDir(), "calls")
+	m.command = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, os.Args[0], append([]string{"-test.run=TestFakeGitProcess", "--", name}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"JHUB_FAKE_GIT=1",
+			"JHUB_FAKE_GIT_LOG="+logPath,
+			"JHUB_FAKE_GIT_SHALLOW="+shallow,
+			"JHUB_FAKE_GIT_FETCH="+fetch)
+		return cmd
+	}
+	return func() []string {
+		data, _ := os.ReadFile(logPath)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+// TestFakeGitProcess is the fake git run by fakeGit; it does nothing as a regular test
+func TestFakeGitProcess(t *testing.T) {
+	if os.Getenv("JHUB_FAKE_GIT") != "1" {
+		return
+	}
+	args := os.Args[slices.Index(os.Args, "--")+2:] // After "--" and "git"
+	if f, err := os.OpenFile(os.Getenv("JHUB_FAKE_GIT_LOG"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644); err == nil {
+		fmt.Fprintln(f, strings.Join(args, " "))
+		f.Close()
+	}
+
+	switch args[0] {
+	case "rev-parse":
+		if os.Getenv("JHUB_FAKE_GIT_SHALLOW") == "error" {
+			fmt.Fprintln(os.Stderr, "fatal: not a git repository")
+			os.Exit(128)
+		}
+		fmt.Println(os.Getenv("JHUB_FAKE_GIT_SHALLOW"))
+	case "fetch":
+		switch os.Getenv("JHUB_FAKE_GIT_FETCH") {
+		case "complete":
+			fmt.Fprintln(os.Stderr, "fatal: --unshallow on a complete repository does not make sense")
+			os.Exit(128)
+		case "fail":
+			fmt.Fprintln(os.Stderr, "fatal: could not read from remote repository")
+			os.Exit(128)
+		}
+	}
+	os.Exit(0)
+}
+
+func TestManager_Unshallow(t *testing.T) {
+	tests := []struct {
+		name      string
+		shallow   string
+		fetch     string
+		wantErr   bool
+		wantCalls []string
+	}{
+		{name: "shallow clone", shallow: "true", fetch: "ok",
+			wantCalls: []string{"rev-parse --is-shallow-repository", "fetch --unshallow origin"}},
+		{name: "already complete", shallow: "false",
+			wantCalls: []string{"rev-parse --is-shallow-repository"}},
+		{name: "git refuses a complete repository", shallow: "true", fetch: "complete",
+			wantCalls: []string{"rev-parse --is-shallow-repository", "fetch --unshallow origin"}},
+		{name: "fetch fails", shallow: "true", fetch: "fail", wantErr: true,
+			wantCalls: []string{"rev-parse --is-shallow-repository", "fetch --unshallow origin"}},
+		{name: "not a repository", shallow: "error", wantErr: true,
+			wantCalls: []string{"rev-parse --is-shallow-repository"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(logger.New(logger.Config{Output: io.Discard}))
+			calls := fakeGit(t, m, tt.shallow, tt.fetch)
+
+			err := m.Unshallow(context.Background(), t.TempDir(), nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got := calls(); !slices.Equal(got, tt.wantCalls) {
+				t.Errorf("expected git calls %q, got %q", tt.wantCalls, got)
+			}
+		})
+	}
+}
+
+func TestManager_UnshallowRealClone(t *testing.T) {
+	m := NewManager(logger.New(logger.Config{Output: io.Discard}))
+	src := newSourceRepo(t, "")
+	runGit(t, src, "commit", "--allow-empty", "-m", "second")
+
+	dest := filepath.Join(t.TempDir(), "clone")
+	if err := m.Clone(context.Background(), CloneConfig{RepoURL: "file://" + src, DestPath: dest, Depth: 1}); err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	if shallow, err := m.IsShallow(context.Background(), dest); err != nil || !shallow {
+		t.Fatalf("expected a shallow clone, got shallow=%v err=%v", shallow, err)
+	}
+
+	if err := m.Unshallow(context.Background(), dest, nil); err != nil {
+		t.Fatalf("failed to unshallow: %v", err)
+	}
+	if shallow, err := m.IsShallow(context.Background(), dest); err != nil || shallow {
+		t.Errorf("expected the full history, got shallow=%v err=%v", shallow, err)
+	}
+	if log := runGit(t, dest, "log", "--oneline"); strings.Count(log, "\n") != 2 {
+		t.Errorf("expected 2 commits in git log, got %q", log)
+	}
+}
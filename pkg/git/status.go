@@ -0,0 +1,95 @@
+// Package git - Clone status of each --repo, reported by the git status API
+package git
+
+import (
+	"net/url"
+	"sync"
+)
+
+// RepoState is how far the clone of a repository has got
+type RepoState string
+
+const (
+	RepoPending RepoState = "pending" // Not cloned yet, or clone in progress
+	RepoDone    RepoState = "done"
+	RepoError   RepoState = "error"
+)
+
+// RepoStatus is the clone status of one repository
+type RepoStatus struct {
+	URL      string    `json:"url"` // Credentials in the URL are removed
+	Branch   string    `json:"branch"`
+	Folder   string    `json:"folder"`
+	Optional bool      `json:"optional"`
+	State    RepoState `json:"state"`
+	Commit   string    `json:"commit,omitempty"` // SHA of HEAD once cloned
+	Shallow  bool      `json:"shallow"`          // Cloned without full history (see --repo-unshallow)
+	Error    string    `json:"error,omitempty"`
+}
+
+// StatusTracker records the clone status of every repository
+// Safe for concurrent use by the parallel clones and the API.
+type StatusTracker struct {
+	mu    sync.RWMutex
+	repos []RepoStatus
+}
+
+// NewStatusTracker creates an empty tracker
+func NewStatusTracker() *StatusTracker {
+	return &StatusTracker{}
+}
+
+// Add registers a pending repository and returns its index for later updates
+func (t *StatusTracker) Add(repoURL, branch, folder string, optional bool) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.repos = append(t.repos, RepoStatus{
+		URL:      displayURL(repoURL),
+		Branch:   branch,
+		Folder:   folder,
+		Optional: optional,
+		State:    RepoPending,
+	})
+	return len(t.repos) - 1
+}
+
+// SetDone marks repository i as cloned at the given commit
+func (t *StatusTracker) SetDone(i int, commit string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.repos[i].State = RepoDone
+	t.repos[i].Commit = commit
+	t.repos[i].Error = ""
+}
+
+// SetShallow records whether repository i is a shallow clone
+func (t *StatusTracker) SetShallow(i int, shallow bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.repos[i].Shallow = shallow
+}
+
+// SetError marks the clone of repository i as failed
+func (t *StatusTracker) SetError(i int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.repos[i].State = RepoError
+	t.repos[i].Error = err.Error()
+}
+
+// Statuses returns a snapshot of every repository's status, in --repo order
+func (t *StatusTracker) Statuses() []RepoStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]RepoStatus{}, t.repos...)
+}
+
+// displayURL removes the userinfo from a repository URL, which commonly holds a token
+func displayURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	u.User = nil
+	return u.String()
+}
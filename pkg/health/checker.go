@@ -2,28 +2,55 @@
 package health
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
 )
 
+// Check types
+const (
+	CheckTypeHTTP = "http" // GET the URL, healthy on a 2xx or 3xx response
+	CheckTypeTCP  = "tcp"  // Connect to the URL's host:port, healthy once the port accepts connections
+)
+
 // CheckConfig holds configuration for health checking
 type CheckConfig struct {
 	URL              string        // URL to check (e.g., http://localhost:8501/health)
+	Socket           string        // Unix domain socket the app listens on; the URL's host is then ignored (empty = TCP)
+	CheckType        string        // CheckTypeHTTP or CheckTypeTCP (empty = CheckTypeHTTP)
 	Timeout          time.Duration // Overall timeout for ready state
 	Interval         time.Duration // Interval between checks
 	InitialDelay     time.Duration // Delay before first check
 	SuccessThreshold int           // Number of consecutive successes required
-	HTTPTimeout      time.Duration // Timeout for individual HTTP requests
+	Stabilization    time.Duration // Keep probing this long after SuccessThreshold is met; any failure reverts to not-ready (0 = disabled)
+	HTTPTimeout      time.Duration // Timeout for individual checks (HTTP request or TCP connect)
+	Method           string        // HTTP method of the check request (empty = GET)
+	RequestBody      []byte        // Body sent with the check request (nil = none)
+	ContentType      string        // Content-Type of RequestBody (empty = not set)
 }
 
 // DefaultCheckConfig returns sensible defaults for health checking
+// A tcp://host:port URL selects CheckTypeTCP and is stored as host:port.
 func DefaultCheckConfig(url string) CheckConfig {
+	checkType := CheckTypeHTTP
+	if addr, ok := strings.CutPrefix(url, "tcp://"); ok {
+		checkType = CheckTypeTCP
+		url = addr
+	}
+
 	return CheckConfig{
 		URL:              url,
+		CheckType:        checkType,
 		Timeout:          5 * time.Minute,
 		Interval:         1 * time.Second,
 		InitialDelay:     2 * time.Second,
@@ -32,11 +59,21 @@ func DefaultCheckConfig(url string) CheckConfig {
 	}
 }
 
+// Result is the outcome of a single health check
+type Result struct {
+	CheckedAt time.Time     // Zero if no check has run yet
+	Latency   time.Duration // How long the check took
+	Err       error         // Nil if the check passed
+}
+
 // Checker performs health checks on spawned processes
 type Checker struct {
 	config CheckConfig
 	logger *logger.Logger
 	client *http.Client
+
+	mu   sync.Mutex
+	last Result
 }
 
 // NewChecker creates a new health checker
@@ -50,12 +87,30 @@ func NewChecker(cfg CheckConfig, log *logger.Logger) *Checker {
 	if cfg.SuccessThreshold == 0 {
 		cfg.SuccessThreshold = 1
 	}
+	if cfg.CheckType == "" {
+		cfg.CheckType = CheckTypeHTTP
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	cfg.Method = strings.ToUpper(cfg.Method)
+
+	var transport http.RoundTripper
+	if cfg.Socket != "" {
+		socketTransport := http.DefaultTransport.(*http.Transport).Clone()
+		socketTransport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", cfg.Socket)
+		}
+		transport = socketTransport
+	}
 
 	return &Checker{
 		config: cfg,
 		logger: log.WithComponent("health-checker"),
 		client: &http.Client{
-			Timeout: cfg.HTTPTimeout,
+			Transport: transport,
+			Timeout:   cfg.HTTPTimeout,
 			// Don't follow redirects for health checks
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				return http.ErrUseLastResponse
@@ -69,6 +124,7 @@ func NewChecker(cfg CheckConfig, log *logger.Logger) *Checker {
 func (c *Checker) WaitUntilReady(ctx context.Context) error {
 	c.logger.Info("starting health check",
 		"url", c.config.URL,
+		"type", c.config.CheckType,
 		"timeout", c.config.Timeout,
 		"interval", c.config.Interval)
 
@@ -92,12 +148,18 @@ func (c *Checker) WaitUntilReady(ctx context.Context) error {
 
 	attempt := 0
 	consecutiveSuccesses := 0
+	var stableSince time.Time // When SuccessThreshold was met, zero outside the stabilization window
 	maxAttempts := int(c.config.Timeout / c.config.Interval)
 	logEveryNAttempts := 15 // Log failed checks every ~15 seconds
 
 	for {
 		select {
 		case <-timeoutCtx.Done():
+			// Cancelled by the caller (e.g. shutdown) rather than timed out
+			if ctx.Err() != nil {
+				c.logger.Info("health check cancelled", "attempts", attempt, "url", c.config.URL)
+				return fmt.Errorf("health check cancelled after %d attempts: %w", attempt, ctx.Err())
+			}
 			c.logger.Error("health check timeout",
 				timeoutCtx.Err(),
 				"attempts", attempt,
@@ -117,6 +179,19 @@ func (c *Checker) WaitUntilReady(ctx context.Context) error {
 				consecutiveSuccesses++
 				c.logger.HealthCheck(attempt, maxAttempts, c.config.URL, true, latency, nil)
 
+				if consecutiveSuccesses >= c.config.SuccessThreshold && c.config.Stabilization > 0 {
+					if stableSince.IsZero() {
+						stableSince = time.Now()
+						c.logger.Info("process passed health check, confirming stability",
+							"attempts", attempt,
+							"url", c.config.URL,
+							"stabilization", c.config.Stabilization)
+						continue
+					}
+					if time.Since(stableSince) < c.config.Stabilization {
+						continue
+					}
+				}
 				if consecutiveSuccesses >= c.config.SuccessThreshold {
 					c.logger.Info("process is ready",
 						"attempts", attempt,
@@ -126,6 +201,15 @@ func (c *Checker) WaitUntilReady(ctx context.Context) error {
 				}
 			} else {
 				consecutiveSuccesses = 0 // Reset on failure
+				if !stableSince.IsZero() {
+					// Flapping startup: the app answered, then failed before proving stable
+					c.logger.Warn("health check failed during stabilization window, process not ready",
+						"attempt", attempt,
+						"url", c.config.URL,
+						"stable_for", time.Since(stableSince),
+						"error", err)
+					stableSince = time.Time{}
+				}
 				// Log at debug level every attempt, and at info level every N attempts
 				c.logger.Debug("health check failed",
 					"attempt", attempt,
@@ -143,12 +227,64 @@ func (c *Checker) WaitUntilReady(ctx context.Context) error {
 	}
 }
 
-// check performs a single health check
+// check performs a single health check and records its result
 func (c *Checker) check(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.URL, nil)
+	start := time.Now()
+	err := c.probe(ctx)
+	latency := time.Since(start)
+
+	c.mu.Lock()
+	c.last = Result{CheckedAt: time.Now(), Latency: latency, Err: err}
+	c.mu.Unlock()
+
+	return err
+}
+
+// probe checks the health check target once
+func (c *Checker) probe(ctx context.Context) error {
+	if c.config.CheckType == CheckTypeTCP {
+		return c.probeTCP(ctx)
+	}
+	return c.probeHTTP(ctx)
+}
+
+// probeTCP connects to the health check URL's host and port
+// For backends that don't serve HTTP on the ready path and just need the port open
+// The URL may also be a bare host:port. With a Socket, connects to the socket instead.
+func (c *Checker) probeTCP(ctx context.Context) error {
+	network, addr := "tcp", c.config.URL
+	if c.config.Socket != "" {
+		network, addr = "unix", c.config.Socket
+	}
+	if network == "tcp" && strings.Contains(addr, "://") {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return fmt.Errorf("invalid health check URL: %w", err)
+		}
+		addr = u.Host
+	}
+
+	dialer := net.Dialer{Timeout: c.config.HTTPTimeout}
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return fmt.Errorf("connection failed: %w", err)
+	}
+	return conn.Close()
+}
+
+// probeHTTP requests the health check URL
+func (c *Checker) probeHTTP(ctx context.Context) error {
+	var body io.Reader
+	if c.config.RequestBody != nil {
+		body = bytes.NewReader(c.config.RequestBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, c.config.Method, c.config.URL, body)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
+	if body != nil && c.config.ContentType != "" {
+		req.Header.Set("Content-Type", c.config.ContentType)
+	}
 
 	// Add user agent to identify health checks
 	req.Header.Set("User-Agent", "jhub-app-proxy-health-check/1.0")
@@ -177,3 +313,84 @@ func (c *Checker) CheckOnce(ctx context.Context) error {
 	c.logger.HealthCheck(1, 1, c.config.URL, err == nil, latency, err)
 	return err
 }
+
+// Check performs a single health check without logging
+// Meant for callers that poll repeatedly, such as the health API
+func (c *Checker) Check(ctx context.Context) error {
+	return c.check(ctx)
+}
+
+// LastResult returns the result of the most recent health check
+func (c *Checker) LastResult() Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// URL returns the URL being checked
+func (c *Checker) URL() string {
+	return c.config.URL
+}
+
+// ValidateCheckType returns an error unless checkType is a supported check type;
+// empty is valid and leaves the type picked by DefaultCheckConfig
+func ValidateCheckType(checkType string) error {
+	switch checkType {
+	case "", CheckTypeHTTP, CheckTypeTCP:
+		return nil
+	default:
+		return fmt.Errorf("invalid ready check type %q: expected %s or %s", checkType, CheckTypeHTTP, CheckTypeTCP)
+	}
+}
+
+// ValidateCheckRequest returns an error unless method is a supported ready check method
+// that can carry body; GET and HEAD checks can't have a body
+func ValidateCheckRequest(method string, body []byte) error {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead:
+		if len(body) > 0 {
+			return fmt.Errorf("ready check body requires a method that takes a body (POST, PUT or PATCH), got %s", strings.ToUpper(method))
+		}
+		return nil
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return nil
+	default:
+		return fmt.Errorf("invalid ready check method %q: expected GET, HEAD, POST, PUT or PATCH", method)
+	}
+}
+
+// ValidateTarget ensures a health check URL reaches the subprocess rather than the proxy itself
+// If the check hit the proxy (same port, or a ready-check path that rewrites the URL's
+// host such as "@host:port/"), the interim page would answer 200 and the app would be
+// reported ready before it actually started.
+func ValidateTarget(checkURL string, subprocessPort, proxyPort int) error {
+	if subprocessPort == proxyPort {
+		return fmt.Errorf("subprocess port %d is the same as the proxy port: health checks would hit the proxy instead of the app (use a different --destport)", subprocessPort)
+	}
+
+	u, err := url.Parse(checkURL)
+	if err != nil {
+		return fmt.Errorf("invalid health check URL %q: %w", checkURL, err)
+	}
+	if u.User != nil {
+		return fmt.Errorf("health check URL %q must not contain userinfo: check --ready-check-path", checkURL)
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); (ip == nil || !ip.IsLoopback()) && host != "localhost" {
+		return fmt.Errorf("health check URL %q must target the local subprocess, got host %q: check --ready-check-path", checkURL, host)
+	}
+
+	targetPort, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return fmt.Errorf("health check URL %q has no valid port: check --ready-check-path", checkURL)
+	}
+	if targetPort == proxyPort {
+		return fmt.Errorf("health check URL %q targets the proxy port %d instead of the subprocess port %d: check --ready-check-path", checkURL, proxyPort, subprocessPort)
+	}
+	if targetPort != subprocessPort {
+		return fmt.Errorf("health check URL %q targets port %d instead of the subprocess port %d: check --ready-check-path", checkURL, targetPort, subprocessPort)
+	}
+
+	return nil
+}
@@ -2,8 +2,13 @@ package health
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -46,6 +51,36 @@ func TestChecker_CheckOnce_Failure(t *testing.T) {
 	}
 }
 
+func TestChecker_LastResult(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	log := logger.New(logger.DefaultConfig())
+	checker := NewChecker(DefaultCheckConfig(server.URL), log)
+
+	if result := checker.LastResult(); !result.CheckedAt.IsZero() {
+		t.Errorf("expected no result before any check, got %v", result)
+	}
+
+	if err := checker.Check(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result := checker.LastResult(); result.CheckedAt.IsZero() || result.Err != nil {
+		t.Errorf("expected passing result, got %v", result)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := checker.Check(context.Background()); err == nil {
+		t.Fatal("expected error for 503 status, got nil")
+	}
+	if result := checker.LastResult(); result.Err == nil {
+		t.Error("expected failing result to be recorded")
+	}
+}
+
 func TestChecker_WaitUntilReady_Success(t *testing.T) {
 	attempts := 0
 	// Create test server that succeeds after 2 attempts
@@ -141,6 +176,65 @@ func TestChecker_WaitUntilReady_SuccessThreshold(t *testing.T) {
 	}
 }
 
+func TestChecker_WaitUntilReady_Stabilization(t *testing.T) {
+	// Answers 200 during a boot phase, then crashes with 500s
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	cfg := CheckConfig{
+		URL:              server.URL,
+		Timeout:          1 * time.Second,
+		Interval:         50 * time.Millisecond,
+		SuccessThreshold: 1,
+		Stabilization:    500 * time.Millisecond,
+		HTTPTimeout:      100 * time.Millisecond,
+	}
+
+	checker := NewChecker(cfg, logger.New(logger.Config{Output: io.Discard}))
+	if err := checker.WaitUntilReady(context.Background()); err == nil {
+		t.Error("expected a backend failing within the stabilization window not to be marked ready")
+	}
+}
+
+func TestChecker_WaitUntilReady_StabilizationRecovers(t *testing.T) {
+	// Fails once right after the first success, then stays healthy
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	cfg := CheckConfig{
+		URL:              server.URL,
+		Timeout:          5 * time.Second,
+		Interval:         50 * time.Millisecond,
+		SuccessThreshold: 1,
+		Stabilization:    300 * time.Millisecond,
+		HTTPTimeout:      100 * time.Millisecond,
+	}
+
+	checker := NewChecker(cfg, logger.New(logger.Config{Output: io.Discard}))
+	start := time.Now()
+	if err := checker.WaitUntilReady(context.Background()); err != nil {
+		t.Fatalf("expected process to become ready, got error: %v", err)
+	}
+	// The window restarts after the failure, so readiness takes a full window past attempt 3
+	if elapsed := time.Since(start); elapsed < 3*cfg.Interval+cfg.Stabilization {
+		t.Errorf("expected the stabilization window to restart after the failure, ready after %v", elapsed)
+	}
+}
+
 func TestDefaultCheckConfig(t *testing.T) {
 	url := "http://localhost:8080/health"
 	cfg := DefaultCheckConfig(url)
@@ -154,4 +248,276 @@ func TestDefaultCheckConfig(t *testing.T) {
 	if cfg.Interval == 0 {
 		t.Error("expected non-zero interval")
 	}
+	if cfg.CheckType != CheckTypeHTTP {
+		t.Errorf("expected check type %s, got %s", CheckTypeHTTP, cfg.CheckType)
+	}
+
+	cfg = DefaultCheckConfig("tcp://127.0.0.1:8080")
+	if cfg.CheckType != CheckTypeTCP {
+		t.Errorf("expected check type %s for a tcp:// URL, got %s", CheckTypeTCP, cfg.CheckType)
+	}
+	if cfg.URL != "127.0.0.1:8080" {
+		t.Errorf("expected tcp:// prefix to be stripped, got %s", cfg.URL)
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	tests := []struct {
+		name           string
+		readyCheckPath string
+		subprocessPort int
+		proxyPort      int
+		wantErr        bool
+	}{
+		{name: "subprocess root", readyCheckPath: "/", subprocessPort: 9000, proxyPort: 8888},
+		{name: "subprocess health path", readyCheckPath: "/healthz?full=1", subprocessPort: 9000, proxyPort: 8888},
+		{name: "destport equals proxy port", readyCheckPath: "/", subprocessPort: 8888, proxyPort: 8888, wantErr: true},
+		{name: "path redirecting to proxy via userinfo", readyCheckPath: "@127.0.0.1:8888/", subprocessPort: 9000, proxyPort: 8888, wantErr: true},
+		{name: "path redirecting to remote host", readyCheckPath: "@example.com:9000/", subprocessPort: 9000, proxyPort: 8888, wantErr: true},
+		{name: "path appending a port", readyCheckPath: "0/", subprocessPort: 900, proxyPort: 8888, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Built the same way as in main
+			checkURL := fmt.Sprintf("http://127.0.0.1:%d%s", tt.subprocessPort, tt.readyCheckPath)
+			err := ValidateTarget(checkURL, tt.subprocessPort, tt.proxyPort)
+			if tt.wantErr && err == nil {
+				t.Errorf("expected misconfiguration to be caught for %q", checkURL)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("expected %q to be valid, got %v", checkURL, err)
+			}
+		})
+	}
+}
+
+func TestChecker_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	// Accept and close connections without ever speaking HTTP
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	log := logger.New(logger.DefaultConfig())
+
+	tests := []struct {
+		name    string
+		addr    string
+		wantErr bool
+	}{
+		{name: "listening port", addr: listener.Addr().String(), wantErr: false},
+		{name: "non-listening port", addr: closedAddr, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultCheckConfig("http://" + tt.addr + "/ready")
+			cfg.CheckType = CheckTypeTCP
+			checker := NewChecker(cfg, log)
+
+			err := checker.CheckOnce(context.Background())
+			if tt.wantErr && err == nil {
+				t.Error("expected error for non-listening port, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+
+		t.Run(tt.name+" tcp URL", func(t *testing.T) {
+			checker := NewChecker(DefaultCheckConfig("tcp://"+tt.addr), log)
+
+			err := checker.CheckOnce(context.Background())
+			if tt.wantErr && err == nil {
+				t.Error("expected error for non-listening port, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+
+	t.Run("wait until ready", func(t *testing.T) {
+		checker := NewChecker(CheckConfig{
+			URL:         "http://" + listener.Addr().String() + "/",
+			CheckType:   CheckTypeTCP,
+			Timeout:     2 * time.Second,
+			Interval:    50 * time.Millisecond,
+			HTTPTimeout: 500 * time.Millisecond,
+		}, log)
+		if err := checker.WaitUntilReady(context.Background()); err != nil {
+			t.Errorf("expected port to be ready, got error: %v", err)
+		}
+	})
+
+	t.Run("wait until ready times out", func(t *testing.T) {
+		checker := NewChecker(CheckConfig{
+			URL:         "http://" + closedAddr + "/",
+			CheckType:   CheckTypeTCP,
+			Timeout:     300 * time.Millisecond,
+			Interval:    50 * time.Millisecond,
+			HTTPTimeout: 100 * time.Millisecond,
+		}, log)
+		if err := checker.WaitUntilReady(context.Background()); err == nil {
+			t.Error("expected timeout error, got nil")
+		}
+	})
+}
+
+func TestChecker_Socket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("failed to listen on unix socket: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	missing := filepath.Join(t.TempDir(), "missing.sock")
+
+	tests := []struct {
+		name      string
+		socket    string
+		checkType string
+		wantErr   bool
+	}{
+		{name: "http over socket", socket: socketPath, checkType: CheckTypeHTTP, wantErr: false},
+		{name: "tcp check connects to socket", socket: socketPath, checkType: CheckTypeTCP, wantErr: false},
+		{name: "missing socket", socket: missing, checkType: CheckTypeHTTP, wantErr: true},
+		{name: "tcp check on missing socket", socket: missing, checkType: CheckTypeTCP, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The host is a placeholder; only the socket is dialed
+			cfg := DefaultCheckConfig("http://localhost/ready")
+			cfg.Socket = tt.socket
+			cfg.CheckType = tt.checkType
+			checker := NewChecker(cfg, log)
+
+			err := checker.CheckOnce(context.Background())
+			if tt.wantErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateCheckType(t *testing.T) {
+	for _, checkType := range []string{"", CheckTypeHTTP, CheckTypeTCP} {
+		if err := ValidateCheckType(checkType); err != nil {
+			t.Errorf("expected %q to be valid, got %v", checkType, err)
+		}
+	}
+	if err := ValidateCheckType("grpc"); err == nil {
+		t.Error("expected error for unsupported check type, got nil")
+	}
+}
+
+func TestChecker_RequestBody(t *testing.T) {
+	const wantBody = `{"probe": "ready"}`
+
+	// Ready only for a POST carrying the expected JSON body
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || string(body) != wantBody || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	log := logger.New(logger.Config{Output: io.Discard})
+
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		contentType string
+		wantErr     bool
+	}{
+		{name: "post with expected body", method: "post", body: wantBody, contentType: "application/json"},
+		{name: "post with other body", method: http.MethodPost, body: `{}`, contentType: "application/json", wantErr: true},
+		{name: "post with other content type", method: http.MethodPost, body: wantBody, contentType: "text/plain", wantErr: true},
+		{name: "default get", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultCheckConfig(server.URL)
+			cfg.Method = tt.method
+			if tt.body != "" {
+				cfg.RequestBody = []byte(tt.body)
+				cfg.ContentType = tt.contentType
+			}
+			checker := NewChecker(cfg, log)
+
+			// Checked twice: the body must be sent with every check, not just the first
+			for i := 0; i < 2; i++ {
+				err := checker.CheckOnce(context.Background())
+				if tt.wantErr && err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !tt.wantErr && err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCheckRequest(t *testing.T) {
+	tests := []struct {
+		method  string
+		body    string
+		wantErr bool
+	}{
+		{method: "GET"},
+		{method: "head"},
+		{method: "POST", body: "{}"},
+		{method: "patch", body: "{}"},
+		{method: "GET", body: "{}", wantErr: true},
+		{method: "DELETE", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.body, func(t *testing.T) {
+			err := ValidateCheckRequest(tt.method, []byte(tt.body))
+			if tt.wantErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
 }
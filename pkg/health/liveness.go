@@ -0,0 +1,85 @@
+// Package health - Ongoing liveness checks once the app is running
+package health
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultLivenessFailureThreshold is the number of consecutive failed checks before the app counts as unhealthy
+const DefaultLivenessFailureThreshold = 3
+
+// LivenessConfig controls WatchLiveness
+type LivenessConfig struct {
+	Interval         time.Duration // Time between checks
+	FailureThreshold int           // Consecutive failures before OnUnhealthy (0 = DefaultLivenessFailureThreshold)
+
+	// IsRunning gates the checks: failures only count while it reports true, and the
+	// failure count starts over whenever it doesn't (e.g. while the app restarts). Nil = always.
+	IsRunning func() bool
+
+	OnUnhealthy func(failures int, err error) // Called once when the threshold is reached
+	OnRecovered func()                        // Called when a check passes after OnUnhealthy
+}
+
+// WatchLiveness probes the check target every cfg.Interval until ctx is cancelled
+// Unlike WaitUntilReady it keeps running after the app is up, catching a backend that
+// is still alive but no longer answers. Results are recorded for LastResult.
+func (c *Checker) WatchLiveness(ctx context.Context, cfg LivenessConfig) {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultLivenessFailureThreshold
+	}
+
+	c.logger.Info("starting liveness checks",
+		"url", c.config.URL,
+		"interval", cfg.Interval,
+		"failure_threshold", cfg.FailureThreshold)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	failures := 0
+	unhealthy := false
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Debug("liveness checks stopped")
+			return
+		case <-ticker.C:
+		}
+
+		if cfg.IsRunning != nil && !cfg.IsRunning() {
+			failures = 0
+			unhealthy = false
+			continue
+		}
+
+		err := c.check(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			if unhealthy {
+				c.logger.Info("liveness check passed again, app recovered", "url", c.config.URL)
+				if cfg.OnRecovered != nil {
+					cfg.OnRecovered()
+				}
+			}
+			failures = 0
+			unhealthy = false
+			continue
+		}
+
+		failures++
+		c.logger.Warn("liveness check failed",
+			"url", c.config.URL,
+			"consecutive_failures", failures,
+			"error", err.Error())
+		if failures >= cfg.FailureThreshold && !unhealthy {
+			unhealthy = true
+			if cfg.OnUnhealthy != nil {
+				cfg.OnUnhealthy(failures, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+func TestChecker_WatchLiveness(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := NewChecker(DefaultCheckConfig(server.URL), logger.New(logger.Config{Output: io.Discard}))
+
+	unhealthy := make(chan int, 1)
+	recovered := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		checker.WatchLiveness(ctx, LivenessConfig{
+			Interval:         10 * time.Millisecond,
+			FailureThreshold: 3,
+			OnUnhealthy: func(failures int, err error) {
+				unhealthy <- failures
+			},
+			OnRecovered: func() {
+				recovered <- struct{}{}
+			},
+		})
+	}()
+
+	// Healthy backend: no callback
+	select {
+	case failures := <-unhealthy:
+		t.Fatalf("expected healthy backend, got unhealthy after %d failures", failures)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	failing.Store(true)
+	select {
+	case failures := <-unhealthy:
+		if failures != 3 {
+			t.Errorf("expected unhealthy after 3 failures, got %d", failures)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the backend to be reported unhealthy")
+	}
+	if checker.LastResult().Err == nil {
+		t.Error("expected the last result to record the failure")
+	}
+
+	// Reported only once while it stays unhealthy
+	select {
+	case <-unhealthy:
+		t.Error("expected a single unhealthy report")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	failing.Store(false)
+	select {
+	case <-recovered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the backend to be reported recovered")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected WatchLiveness to return once the context is cancelled")
+	}
+}
+
+func TestChecker_WatchLiveness_NotRunning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	checker := NewChecker(DefaultCheckConfig(server.URL), logger.New(logger.Config{Output: io.Discard}))
+
+	var reports atomic.Int32
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	checker.WatchLiveness(ctx, LivenessConfig{
+		Interval:         10 * time.Millisecond,
+		FailureThreshold: 1,
+		IsRunning:        func() bool { return false },
+		OnUnhealthy:      func(int, error) { reports.Add(1) },
+	})
+
+	if n := reports.Load(); n != 0 {
+		t.Errorf("expected no checks while the app is not running, got %d unhealthy reports", n)
+	}
+}
@@ -5,10 +5,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"os"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/nebari-dev/jhub-app-proxy/pkg/activity"
@@ -23,19 +28,48 @@ type Client struct {
 	servername string
 	logger     *logger.Logger
 	httpClient *http.Client
+
+	onActivityReport func(err error)
+	skipIfCulled     bool // Stop the activity reporter once the Hub reports the server stopped
+
+	mu         sync.Mutex
+	lastReport time.Time // Last successful activity report (zero if none)
 }
 
+// DefaultConnectTimeout bounds DNS resolution plus TCP connect for Hub API calls
+const DefaultConnectTimeout = 5 * time.Second
+
+// DefaultActivityInterval is how often StartActivityReporter reports activity when no interval is given
+const DefaultActivityInterval = 5 * time.Minute
+
 // Config holds JupyterHub client configuration
 type Config struct {
-	BaseURL    string // JupyterHub base URL (from JUPYTERHUB_BASE_URL or JUPYTERHUB_API_URL)
-	APIToken   string // API token (from JUPYTERHUB_API_TOKEN)
-	Username   string // Username (from JUPYTERHUB_USER)
-	ServerName string // Server name (from JUPYTERHUB_SERVER_NAME or empty for default)
+	BaseURL        string        // JupyterHub base URL (from JUPYTERHUB_BASE_URL or JUPYTERHUB_API_URL)
+	APIToken       string        // API token (from JUPYTERHUB_API_TOKEN)
+	Username       string        // Username (from JUPYTERHUB_USER)
+	ServerName     string        // Server name (from JUPYTERHUB_SERVER_NAME or empty for default)
+	ConnectTimeout time.Duration // DNS + connect timeout, separate from the overall request timeout (0 = DefaultConnectTimeout)
+
+	// OnActivityReport is called with the result of every report sent by StartActivityReporter (nil = none)
+	OnActivityReport func(err error)
+
+	// SkipActivityIfCulled makes StartActivityReporter check GetServerStatus before each report
+	// and stop once the server is no longer ready, e.g. after the Hub culled it
+	SkipActivityIfCulled bool
 }
 
+// ErrServerNotFound is returned by GetServerStatus when the Hub has no such server
+var ErrServerNotFound = errors.New("server not found")
+
 // NewClientFromEnv creates a Hub client from environment variables
 // This is the typical way to initialize in a spawned process
 func NewClientFromEnv(log *logger.Logger) (*Client, error) {
+	return NewClient(ConfigFromEnv(), log)
+}
+
+// ConfigFromEnv builds a client configuration from JupyterHub environment variables
+// Callers can adjust the result (e.g. ConnectTimeout) before passing it to NewClient
+func ConfigFromEnv() Config {
 	cfg := Config{
 		BaseURL:    os.Getenv("JUPYTERHUB_API_URL"),
 		APIToken:   os.Getenv("JUPYTERHUB_API_TOKEN"),
@@ -51,7 +85,7 @@ func NewClientFromEnv(log *logger.Logger) (*Client, error) {
 		}
 	}
 
-	return NewClient(cfg, log)
+	return cfg
 }
 
 // NewClient creates a new JupyterHub API client
@@ -66,14 +100,33 @@ func NewClient(cfg Config, log *logger.Logger) (*Client, error) {
 		return nil, fmt.Errorf("JUPYTERHUB_USER must be set")
 	}
 
+	connectTimeout := cfg.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = DefaultConnectTimeout
+	}
+
+	// Bound DNS resolution and TCP connect separately so a flaky resolver
+	// fails fast instead of consuming the whole request timeout
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+
 	return &Client{
 		baseURL:    cfg.BaseURL,
 		apiToken:   cfg.APIToken,
 		username:   cfg.Username,
 		servername: cfg.ServerName,
 		logger:     log.WithComponent("hub-client"),
+
+		onActivityReport: cfg.OnActivityReport,
+		skipIfCulled:     cfg.SkipActivityIfCulled,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: transport,
 		},
 	}, nil
 }
@@ -199,24 +252,33 @@ func (c *Client) NotifyActivityWithTime(ctx context.Context, timestamp time.Time
 // StartActivityReporter starts a background goroutine that periodically reports activity
 // Returns a cancel function to stop the reporter
 //
+// Each report is scheduled interval ± a random offset of up to jitter, so apps started together
+// don't all hit the hub at once. interval <= 0 uses DefaultActivityInterval; jitter is capped at
+// half the interval.
+//
 // If keepAlive is true: Always report current time (prevent idle culling)
 // If keepAlive is false: Only report when there's actual activity tracked by activityTracker
-func (c *Client) StartActivityReporter(ctx context.Context, interval time.Duration, keepAlive bool, activityTracker *activity.Tracker) context.CancelFunc {
+func (c *Client) StartActivityReporter(ctx context.Context, interval, jitter time.Duration, keepAlive bool, activityTracker *activity.Tracker) context.CancelFunc {
 	ctx, cancel := context.WithCancel(ctx)
 
+	if interval <= 0 {
+		interval = DefaultActivityInterval
+	}
+
 	go func() {
 		c.logger.Info("starting activity reporter",
 			"interval", interval,
+			"jitter", jitter,
 			"keep_alive", keepAlive,
 			"username", c.username,
 			"servername", c.servername)
 
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
+		timer := time.NewTimer(jitteredInterval(interval, jitter))
+		defer timer.Stop()
 
 		// Report activity immediately on start if keepAlive is enabled
-		if keepAlive {
-			if err := c.NotifyActivity(ctx); err != nil {
+		if keepAlive && !c.stopIfCulled(ctx, cancel) {
+			if err := c.reported(c.NotifyActivity(ctx)); err != nil {
 				c.logger.Error("failed to notify activity on start", err)
 			}
 		}
@@ -226,10 +288,13 @@ func (c *Client) StartActivityReporter(ctx context.Context, interval time.Durati
 			case <-ctx.Done():
 				c.logger.Info("activity reporter stopped")
 				return
-			case <-ticker.C:
+			case <-timer.C:
 				if keepAlive {
 					// Always report current time (keep alive forever)
-					if err := c.NotifyActivity(ctx); err != nil {
+					if c.stopIfCulled(ctx, cancel) {
+						return
+					}
+					if err := c.reported(c.NotifyActivity(ctx)); err != nil {
 						c.logger.Error("failed to notify activity", err,
 							"username", c.username,
 							"servername", c.servername)
@@ -238,7 +303,10 @@ func (c *Client) StartActivityReporter(ctx context.Context, interval time.Durati
 					// Only report if there was actual activity
 					lastActivity := activityTracker.GetLastActivity()
 					if lastActivity != nil {
-						if err := c.NotifyActivityWithTime(ctx, *lastActivity); err != nil {
+						if c.stopIfCulled(ctx, cancel) {
+							return
+						}
+						if err := c.reported(c.NotifyActivityWithTime(ctx, *lastActivity)); err != nil {
 							c.logger.Error("failed to notify activity", err,
 								"username", c.username,
 								"servername", c.servername,
@@ -249,6 +317,7 @@ func (c *Client) StartActivityReporter(ctx context.Context, interval time.Durati
 						c.logger.Debug("no activity to report yet")
 					}
 				}
+				timer.Reset(jitteredInterval(interval, jitter))
 			}
 		}
 	}()
@@ -256,6 +325,70 @@ func (c *Client) StartActivityReporter(ctx context.Context, interval time.Durati
 	return cancel
 }
 
+// stopIfCulled cancels the activity reporter if SkipActivityIfCulled is set and the server is culled
+// Reporting activity for a server the Hub has stopped can't bring it back, and keeps the
+// Hub's activity records for the user misleading.
+func (c *Client) stopIfCulled(ctx context.Context, cancel context.CancelFunc) bool {
+	if !c.skipIfCulled || !c.culled(ctx) {
+		return false
+	}
+	c.logger.Warn("server is no longer running in JupyterHub, stopping activity reporter",
+		"username", c.username,
+		"servername", c.servername)
+	cancel()
+	return true
+}
+
+// culled reports whether the Hub considers the server stopped
+// A starting server isn't ready either, but has a pending spawn. Only a definite answer
+// counts: if the status can't be read, activity is reported as usual.
+func (c *Client) culled(ctx context.Context) bool {
+	status, err := c.GetServerStatus(ctx)
+	if errors.Is(err, ErrServerNotFound) {
+		return true
+	}
+	if err != nil {
+		c.logger.Warn("failed to get server status, reporting activity anyway", "error", err)
+		return false
+	}
+	ready, _ := status["ready"].(bool)
+	pending, _ := status["pending"].(string)
+	return !ready && pending == ""
+}
+
+// jitteredInterval returns interval offset by a random amount within ±jitter
+// jitter is capped at half the interval so reports never come back to back
+func jitteredInterval(interval, jitter time.Duration) time.Duration {
+	if jitter > interval/2 {
+		jitter = interval / 2
+	}
+	if jitter <= 0 {
+		return interval
+	}
+	return interval - jitter + rand.N(2*jitter+1)
+}
+
+// reported records the result of an activity report, passes it to the OnActivityReport hook and returns it
+func (c *Client) reported(err error) error {
+	if err == nil {
+		c.mu.Lock()
+		c.lastReport = time.Now()
+		c.mu.Unlock()
+	}
+	if c.onActivityReport != nil {
+		c.onActivityReport(err)
+	}
+	return err
+}
+
+// LastActivityReport returns when StartActivityReporter last reported activity successfully
+// Zero if no report has succeeded yet
+func (c *Client) LastActivityReport() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastReport
+}
+
 // GetUser retrieves user information from JupyterHub
 func (c *Client) GetUser(ctx context.Context) (map[string]interface{}, error) {
 	endpoint := fmt.Sprintf("%s/users/%s", c.baseURL, c.username)
@@ -293,6 +426,67 @@ func (c *Client) GetUser(ctx context.Context) (map[string]interface{}, error) {
 	return user, nil
 }
 
+// GetServerStatus retrieves this server's model from JupyterHub (ready, pending, url, last_activity...)
+// Asks GET /users/{username}/servers/{servername}; Hub versions without that endpoint answer
+// 404 or 405, and the server is then taken from the user model. Returns ErrServerNotFound if
+// the Hub has no such server.
+func (c *Client) GetServerStatus(ctx context.Context) (map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("%s/users/%s/servers/%s", c.baseURL, c.username, c.servername)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", c.apiToken))
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	duration := time.Since(start)
+
+	if err != nil {
+		c.logger.HubAPICall("GET", endpoint, 0, duration, err)
+		return nil, fmt.Errorf("failed to get server status: %w", err)
+	}
+	defer resp.Body.Close()
+
+	c.logger.HubAPICall("GET", endpoint, resp.StatusCode, duration, nil)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var server map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&server); err != nil {
+			return nil, fmt.Errorf("failed to decode server response: %w", err)
+		}
+		return server, nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return c.serverFromUser(ctx)
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get server status failed with status %d: %s",
+			resp.StatusCode, string(body))
+	}
+}
+
+// serverFromUser returns this server's entry in the user model's servers map
+// The Hub leaves the map out when the token lacks read:servers; the status is unknown then,
+// which is an error but not ErrServerNotFound.
+func (c *Client) serverFromUser(ctx context.Context) (map[string]interface{}, error) {
+	user, err := c.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	servers, ok := user["servers"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("user model has no servers, the token may lack the read:servers scope")
+	}
+	server, ok := servers[c.servername].(map[string]interface{})
+	if !ok {
+		return nil, ErrServerNotFound
+	}
+	return server, nil
+}
+
 // Ping checks if the JupyterHub API is reachable
 func (c *Client) Ping(ctx context.Context) error {
 	endpoint := fmt.Sprintf("%s/", c.baseURL)
@@ -306,7 +500,9 @@ func (c *Client) Ping(ctx context.Context) error {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to ping hub: %w", err)
+		reason := classifyConnError(err)
+		c.logger.Warn("hub ping failed", "endpoint", endpoint, "reason", reason, "error", err)
+		return fmt.Errorf("failed to ping hub (%s): %w", reason, err)
 	}
 	defer resp.Body.Close()
 
@@ -317,3 +513,20 @@ func (c *Client) Ping(ctx context.Context) error {
 	c.logger.Debug("hub ping successful")
 	return nil
 }
+
+// classifyConnError describes why a Hub request failed to connect
+// Distinguishes DNS failures from refused or timed-out connections for easier debugging
+func classifyConnError(err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return "dns resolution failed"
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return "connection refused"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "connection timeout"
+	}
+	return "request failed"
+}
@@ -0,0 +1,317 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+// newTestClient creates a client for baseURL with the given connect timeout
+func newTestClient(t *testing.T, baseURL string, connectTimeout time.Duration) *Client {
+	t.Helper()
+	client, err := NewClient(Config{
+		BaseURL:        baseURL,
+		APIToken:       "test-token",
+		Username:       "alice",
+		ConnectTimeout: connectTimeout,
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return client
+}
+
+func TestPing_UnresolvableHostFailsWithinConnectTimeout(t *testing.T) {
+	connectTimeout := 2 * time.Second
+	client := newTestClient(t, "http://jhub-app-proxy-test.invalid/hub/api", connectTimeout)
+
+	start := time.Now()
+	err := client.Ping(context.Background())
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("expected ping to an unresolvable host to fail")
+	}
+	// Allow some slack for scheduling, but stay well below the 10s request timeout
+	if elapsed > connectTimeout+time.Second {
+		t.Errorf("expected ping to fail within %v, took %v", connectTimeout, elapsed)
+	}
+}
+
+func TestPing_ConnectionRefused(t *testing.T) {
+	// Reserve a port and close it so nothing is listening there
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client := newTestClient(t, "http://"+addr+"/hub/api", time.Second)
+	err = client.Ping(context.Background())
+	if err == nil {
+		t.Fatal("expected ping to a closed port to fail")
+	}
+	if got := classifyConnError(err); got != "connection refused" {
+		t.Errorf("expected connection refused, got %q (%v)", got, err)
+	}
+}
+
+func TestPing_Success(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer hub.Close()
+
+	client := newTestClient(t, hub.URL+"/hub/api", time.Second)
+	if err := client.Ping(context.Background()); err != nil {
+		t.Errorf("expected ping to succeed, got %v", err)
+	}
+}
+
+func TestClassifyConnError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "dns failure",
+			err:  &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "hub.invalid", IsNotFound: true}},
+			want: "dns resolution failed",
+		},
+		{
+			name: "dial timeout",
+			err:  &net.OpError{Op: "dial", Err: timeoutError{}},
+			want: "connection timeout",
+		},
+		{
+			name: "other",
+			err:  io.ErrUnexpectedEOF,
+			want: "request failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyConnError(tt.err); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// timeoutError is a net.Error that reports a timeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestStartActivityReporter_OnActivityReport(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer hub.Close()
+
+	reports := make(chan error, 1)
+	client, err := NewClient(Config{
+		BaseURL:          hub.URL,
+		APIToken:         "test-token",
+		Username:         "alice",
+		OnActivityReport: func(err error) { reports <- err },
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	// keepAlive reports immediately on start
+	cancel := client.StartActivityReporter(context.Background(), time.Hour, 0, true, nil)
+	defer cancel()
+
+	select {
+	case err := <-reports:
+		if err != nil {
+			t.Errorf("expected successful report, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected OnActivityReport to be called")
+	}
+}
+
+func TestGetServerStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverCode int
+		serverBody string
+		userBody   string
+		wantReady  bool
+		wantErr    error
+		wantAnyErr bool
+	}{
+		{name: "server endpoint", serverCode: http.StatusOK, serverBody: `{"ready": true}`, wantReady: true},
+		{name: "fallback to user model", serverCode: http.StatusMethodNotAllowed, userBody: `{"servers": {"": {"ready": true}}}`, wantReady: true},
+		{name: "server gone", serverCode: http.StatusNotFound, userBody: `{"servers": {}}`, wantErr: ErrServerNotFound},
+		// Without read:servers the Hub leaves the servers map out: unknown, not gone
+		{name: "no servers in user model", serverCode: http.StatusMethodNotAllowed, userBody: `{"name": "alice"}`, wantAnyErr: true},
+		{name: "hub error", serverCode: http.StatusInternalServerError, wantAnyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "token test-token" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				switch r.URL.Path {
+				case "/users/alice/servers/":
+					w.WriteHeader(tt.serverCode)
+					_, _ = io.WriteString(w, tt.serverBody)
+				case "/users/alice":
+					_, _ = io.WriteString(w, tt.userBody)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer hub.Close()
+
+			status, err := newTestClient(t, hub.URL, 0).GetServerStatus(context.Background())
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+			case tt.wantAnyErr:
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if errors.Is(err, ErrServerNotFound) {
+					t.Fatalf("expected an error other than %v", ErrServerNotFound)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			default:
+				if ready, _ := status["ready"].(bool); ready != tt.wantReady {
+					t.Errorf("expected ready=%v, got %v", tt.wantReady, status["ready"])
+				}
+			}
+		})
+	}
+}
+
+func TestStartActivityReporter_SkipIfCulled(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string // Server endpoint response (empty = 405, falling back to the user model)
+		userBody   string
+		wantReport bool
+	}{
+		{name: "culled", status: `{"ready": false, "pending": null}`, wantReport: false},
+		{name: "starting", status: `{"ready": false, "pending": "spawn"}`, wantReport: true},
+		{name: "running", status: `{"ready": true, "pending": null}`, wantReport: true},
+		{name: "status unknown without read:servers", userBody: `{"name": "alice"}`, wantReport: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reports atomic.Int32
+			checked := make(chan struct{}, 1)
+			hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/users/alice/servers/":
+					if tt.status == "" {
+						w.WriteHeader(http.StatusMethodNotAllowed)
+						return
+					}
+					_, _ = io.WriteString(w, tt.status)
+					select {
+					case checked <- struct{}{}:
+					default:
+					}
+				case "/users/alice":
+					_, _ = io.WriteString(w, tt.userBody)
+					select {
+					case checked <- struct{}{}:
+					default:
+					}
+				case "/users/alice/activity":
+					reports.Add(1)
+				}
+			}))
+			defer hub.Close()
+
+			client, err := NewClient(Config{
+				BaseURL:              hub.URL,
+				APIToken:             "test-token",
+				Username:             "alice",
+				SkipActivityIfCulled: true,
+			}, logger.New(logger.Config{Output: io.Discard}))
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+
+			// keepAlive checks and reports immediately on start
+			cancel := client.StartActivityReporter(context.Background(), time.Hour, 0, true, nil)
+			defer cancel()
+
+			select {
+			case <-checked:
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected the server status to be checked")
+			}
+			deadline := time.Now().Add(2 * time.Second)
+			for tt.wantReport && reports.Load() == 0 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if !tt.wantReport {
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			if got := reports.Load() > 0; got != tt.wantReport {
+				t.Errorf("expected report=%v, got %d reports", tt.wantReport, reports.Load())
+			}
+		})
+	}
+}
+
+func TestJitteredInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		jitter   time.Duration
+		min, max time.Duration
+	}{
+		{name: "no jitter", interval: 5 * time.Minute, jitter: 0, min: 5 * time.Minute, max: 5 * time.Minute},
+		{name: "jitter", interval: 5 * time.Minute, jitter: 30 * time.Second, min: 270 * time.Second, max: 330 * time.Second},
+		{name: "jitter capped at half the interval", interval: time.Minute, jitter: time.Hour, min: 30 * time.Second, max: 90 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := make(map[time.Duration]bool)
+			for i := 0; i < 1000; i++ {
+				got := jitteredInterval(tt.interval, tt.jitter)
+				if got < tt.min || got > tt.max {
+					t.Fatalf("expected interval within [%v, %v], got %v", tt.min, tt.max, got)
+				}
+				seen[got] = true
+			}
+			if tt.jitter > 0 && len(seen) < 2 {
+				t.Error("expected jitter to vary the interval")
+			}
+		})
+	}
+}
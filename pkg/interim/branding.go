@@ -0,0 +1,62 @@
+package interim
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// defaultPageTitle is the <title> of the embedded interim page
+const defaultPageTitle = "JHub Apps Proxy"
+
+// brandColorPattern accepts hex colors (#rgb, #rgba, #rrggbb, #rrggbbaa) and CSS color names
+var brandColorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3,4}|#[0-9a-fA-F]{6}|#[0-9a-fA-F]{8}|[a-zA-Z]+)$`)
+
+// Branding customizes the interim page, e.g. to show a deployment's own look during startup
+type Branding struct {
+	Title        string // Page title (empty = "JHub Apps Proxy")
+	LogoURL      string // Logo shown instead of the Nebari logo: http(s) URL or absolute path (empty = embedded logo)
+	PrimaryColor string // CSS color of the progress bar and toggles (empty = default colors)
+}
+
+// Validate checks the logo URL and primary color
+func (b Branding) Validate() error {
+	if b.LogoURL != "" {
+		u, err := url.Parse(b.LogoURL)
+		if err != nil {
+			return fmt.Errorf("invalid --brand-logo-url %q: %w", b.LogoURL, err)
+		}
+		absolute := (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+		path := u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/")
+		if !absolute && !path {
+			return fmt.Errorf("invalid --brand-logo-url %q: must be an http(s) URL or an absolute path", b.LogoURL)
+		}
+	}
+	if b.PrimaryColor != "" && !brandColorPattern.MatchString(b.PrimaryColor) {
+		return fmt.Errorf("invalid --brand-primary-color %q: must be a hex color like #1e40af or a CSS color name", b.PrimaryColor)
+	}
+	return nil
+}
+
+// apply injects the branding into the interim page HTML
+// Sets the <title> and adds meta tags the page's JavaScript reads
+func (b Branding) apply(page string) string {
+	if b.Title != "" {
+		page = strings.Replace(page, "<title>"+defaultPageTitle+"</title>",
+			"<title>"+html.EscapeString(b.Title)+"</title>", 1)
+	}
+
+	var tags strings.Builder
+	for _, tag := range []struct{ name, content string }{
+		{"brand-title", b.Title},
+		{"brand-logo-url", b.LogoURL},
+		{"brand-primary-color", b.PrimaryColor},
+	} {
+		if tag.content != "" {
+			fmt.Fprintf(&tags, "<meta name=\"%s\" content=\"%s\">\n    ", tag.name, html.EscapeString(tag.content))
+		}
+	}
+	return strings.Replace(page, "<title>", tags.String()+"<title>", 1)
+}
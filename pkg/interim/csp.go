@@ -0,0 +1,55 @@
+package interim
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// fontStylesheetOrigin and fontOrigin serve the IBM Plex fonts the interim page loads
+const (
+	fontStylesheetOrigin = "https://fonts.googleapis.com"
+	fontOrigin           = "https://fonts.gstatic.com"
+)
+
+// newNonce returns a random CSP nonce, unique to one response
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate CSP nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// contentSecurityPolicy returns the policy of an interim page served with nonce
+// Only scripts and stylesheets carrying the nonce run, so markup injected into the page
+// (e.g. through a log line) can't execute. Images may also come from the origin of a
+// custom logo, which /static/logo.png redirects to.
+func (b Branding) contentSecurityPolicy(nonce string) string {
+	imgSrc := "'self'"
+	if u, err := url.Parse(b.LogoURL); err == nil && u.Host != "" {
+		imgSrc += " " + u.Scheme + "://" + u.Host
+	}
+
+	return strings.Join([]string{
+		"default-src 'none'",
+		"script-src 'nonce-" + nonce + "'",
+		"style-src 'self' 'nonce-" + nonce + "' " + fontStylesheetOrigin,
+		"font-src " + fontOrigin,
+		"img-src " + imgSrc,
+		"connect-src 'self'",
+		"base-uri 'none'",
+		"form-action 'none'",
+		"object-src 'none'",
+	}, "; ")
+}
+
+// applyNonce marks the page's inline scripts and stylesheet links with nonce
+// The inline scripts pass it on to the CSS and JS they load.
+func applyNonce(page, nonce string) string {
+	attr := ` nonce="` + nonce + `"`
+	page = strings.ReplaceAll(page, "<script>", "<script"+attr+">")
+	return strings.ReplaceAll(page, `rel="stylesheet">`, `rel="stylesheet"`+attr+">")
+}
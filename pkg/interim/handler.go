@@ -11,6 +11,7 @@
 package interim
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -41,6 +42,8 @@ type Handler struct {
 	deploymentTime  time.Time
 	appURLPath      string // The path to redirect to after app is ready (e.g., "/" or "/user/admin/app/")
 	interimBasePath string // The full interim path including service prefix (e.g., "/user/alice/custom/_temp/jhub-app-proxy")
+	http2Push       bool   // Push the page's CSS and JS along with the HTML over HTTP/2
+	branding        Branding
 }
 
 // Config contains configuration for the interim handler
@@ -49,6 +52,8 @@ type Config struct {
 	Logger          *logger.Logger
 	AppURLPath      string // Path to redirect to (e.g., "/" or "/user/admin/app/")
 	InterimBasePath string // Full interim path including service prefix (e.g., "/user/alice/custom/_temp/jhub-app-proxy")
+	HTTP2Push       bool   // Push the page's CSS and JS along with the HTML when the client speaks HTTP/2
+	Branding        Branding
 }
 
 // NewHandler creates a new interim page handler
@@ -58,6 +63,8 @@ func NewHandler(cfg Config) *Handler {
 		logger:          cfg.Logger.WithComponent("interim-handler"),
 		appURLPath:      cfg.AppURLPath,
 		interimBasePath: cfg.InterimBasePath,
+		http2Push:       cfg.HTTP2Push,
+		branding:        cfg.Branding,
 	}
 }
 
@@ -83,15 +90,48 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"request_path", r.URL.Path,
 		"base_path", basePath,
 		"app_url", h.appURLPath)
+	if h.http2Push {
+		h.pushAssets(w, basePath)
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		h.logger.Error("failed to serve interim page", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Content-Security-Policy", h.branding.contentSecurityPolicy(nonce))
 	w.WriteHeader(http.StatusOK)
 
 	// Inject both the app URL and base path into the HTML via meta tags that JavaScript can read
 	html := strings.Replace(ui.LogsHTML, "<title>",
 		fmt.Sprintf("<meta name=\"app-redirect-url\" content=\"%s\">\n    <meta name=\"base-path\" content=\"%s\">\n    <title>",
 			h.appURLPath, basePath), 1)
-	fmt.Fprint(w, html)
+	fmt.Fprint(w, applyNonce(h.branding.apply(html), nonce))
+}
+
+// pushedAssets are pushed with the interim page, relative to the interim base path
+// The page would otherwise only request them after parsing the HTML
+var pushedAssets = []string{"/static/logs.css", "/static/logs.js"}
+
+// pushAssets pushes the interim page's CSS and JS over HTTP/2 before the HTML is written
+// Does nothing for clients on HTTP/1.x or writers that don't support push.
+func (h *Handler) pushAssets(w http.ResponseWriter, basePath string) {
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return
+	}
+	for _, asset := range pushedAssets {
+		if err := pusher.Push(basePath+asset, nil); err != nil {
+			if !errors.Is(err, http.ErrNotSupported) {
+				h.logger.Debug("failed to push interim page asset", "asset", basePath+asset, "error", err)
+			}
+			return
+		}
+	}
 }
 
 // MarkAppDeployed marks the timestamp when the app became ready
@@ -108,6 +148,16 @@ func (h *Handler) MarkAppDeployed() {
 	}
 }
 
+// ResetDeployment forgets the deployment so the interim page is shown again
+// Used when the app is restarted while the proxy keeps running
+func (h *Handler) ResetDeployment() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.deploymentTime = time.Time{}
+	h.logger.Info("app deployment reset, interim page enabled until the app is ready again")
+}
+
 // IsInGracePeriod returns true if we're within the grace period after deployment
 func (h *Handler) isInGracePeriod() bool {
 	h.mu.RLock()
@@ -121,6 +171,20 @@ func (h *Handler) isInGracePeriod() bool {
 	return elapsed < GracePeriod
 }
 
+// GracePeriodState reports whether the grace period is running and when it ends
+// expiresAt is zero until the app has been deployed
+func (h *Handler) GracePeriodState() (active bool, expiresAt time.Time) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.deploymentTime.IsZero() {
+		return false, time.Time{}
+	}
+
+	expiresAt = h.deploymentTime.Add(GracePeriod)
+	return time.Now().Before(expiresAt), expiresAt
+}
+
 // ShouldServeLogsAPI returns true if the logs API should still be accessible
 // This is true when either:
 // 1. App is not running yet, OR
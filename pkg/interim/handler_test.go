@@ -0,0 +1,211 @@
+package interim
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+// pushRecorder is a ResponseRecorder that supports HTTP/2 server push
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	if p.Body.Len() > 0 {
+		panic("push after the response was written")
+	}
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func newTestHandler(t *testing.T, http2Push bool) *Handler {
+	t.Helper()
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	return NewHandler(Config{
+		Manager:         mgr,
+		Logger:          log,
+		AppURLPath:      "/user/alice/app/",
+		InterimBasePath: "/user/alice/app" + InterimPath,
+		HTTP2Push:       http2Push,
+	})
+}
+
+func TestHandler_HTTP2Push(t *testing.T) {
+	t.Run("pushes assets", func(t *testing.T) {
+		rec := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		newTestHandler(t, true).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user/alice/app/", nil))
+
+		want := []string{
+			"/user/alice/app/_temp/jhub-app-proxy/static/logs.css",
+			"/user/alice/app/_temp/jhub-app-proxy/static/logs.js",
+		}
+		if !reflect.DeepEqual(rec.pushed, want) {
+			t.Errorf("expected pushes %v, got %v", want, rec.pushed)
+		}
+		if !strings.Contains(rec.Body.String(), "<html") {
+			t.Error("expected the interim page to be served")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		rec := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		newTestHandler(t, false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user/alice/app/", nil))
+		if len(rec.pushed) != 0 {
+			t.Errorf("expected no pushes, got %v", rec.pushed)
+		}
+	})
+
+	t.Run("writer without push support", func(t *testing.T) {
+		// httptest.ResponseRecorder does not implement http.Pusher
+		rec := httptest.NewRecorder()
+		newTestHandler(t, true).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user/alice/app/", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "<html") {
+			t.Error("expected the interim page to be served")
+		}
+	})
+}
+
+func TestHandler_Branding(t *testing.T) {
+	h := newTestHandler(t, false)
+	h.branding = Branding{
+		Title:        `Acme "Data" <Platform>`,
+		LogoURL:      "https://cdn.example.com/logo.svg",
+		PrimaryColor: "#1e40af",
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user/alice/app/", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`<meta name="brand-title" content="Acme &#34;Data&#34; &lt;Platform&gt;">`,
+		`<meta name="brand-logo-url" content="https://cdn.example.com/logo.svg">`,
+		`<meta name="brand-primary-color" content="#1e40af">`,
+		`<title>Acme &#34;Data&#34; &lt;Platform&gt;</title>`,
+		`<meta name="base-path" content="/user/alice/app/_temp/jhub-app-proxy">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected the interim page to contain %s", want)
+		}
+	}
+
+	t.Run("no branding", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestHandler(t, false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user/alice/app/", nil))
+		body := rec.Body.String()
+		if strings.Contains(body, `name="brand-`) {
+			t.Error("expected no branding meta tags")
+		}
+		if !strings.Contains(body, "<title>"+defaultPageTitle+"</title>") {
+			t.Error("expected the default title")
+		}
+	})
+}
+
+func TestHandler_CSP(t *testing.T) {
+	nonceAttr := regexp.MustCompile(`nonce="([^"]*)"`)
+
+	serve := func(h *Handler) (policy, body string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user/alice/app/", nil))
+		return rec.Header().Get("Content-Security-Policy"), rec.Body.String()
+	}
+
+	h := newTestHandler(t, false)
+	policy, body := serve(h)
+
+	matches := nonceAttr.FindAllStringSubmatch(body, -1)
+	// Two inline scripts and the font stylesheet
+	if len(matches) != 3 {
+		t.Fatalf("expected 3 nonce attributes, got %d", len(matches))
+	}
+	nonce := matches[0][1]
+	if len(nonce) < 22 {
+		t.Errorf("expected a nonce of at least 128 bits, got %q", nonce)
+	}
+	for _, m := range matches {
+		if m[1] != nonce {
+			t.Errorf("expected every nonce attribute to be %q, got %q", nonce, m[1])
+		}
+	}
+
+	for _, want := range []string{
+		"default-src 'none'",
+		"script-src 'nonce-" + nonce + "'",
+		"style-src 'self' 'nonce-" + nonce + "' https://fonts.googleapis.com",
+		"img-src 'self';",
+		"connect-src 'self'",
+	} {
+		if !strings.Contains(policy, want) {
+			t.Errorf("expected policy to contain %q, got %q", want, policy)
+		}
+	}
+	if strings.Contains(policy, "unsafe-inline") {
+		t.Errorf("expected no unsafe-inline in policy, got %q", policy)
+	}
+	// Style attributes would be blocked by the policy
+	if strings.Contains(body, "style=\"") {
+		t.Error("expected no inline style attributes in the interim page")
+	}
+
+	t.Run("fresh nonce per response", func(t *testing.T) {
+		next, _ := serve(h)
+		if next == policy {
+			t.Error("expected a different nonce for each response")
+		}
+	})
+
+	t.Run("custom logo origin", func(t *testing.T) {
+		branded := newTestHandler(t, false)
+		branded.branding = Branding{LogoURL: "https://cdn.example.com/logo.svg"}
+		policy, _ := serve(branded)
+		if !strings.Contains(policy, "img-src 'self' https://cdn.example.com;") {
+			t.Errorf("expected img-src to allow the logo origin, got %q", policy)
+		}
+	})
+}
+
+func TestBranding_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		branding Branding
+		wantErr  bool
+	}{
+		{"empty", Branding{}, false},
+		{"https logo", Branding{LogoURL: "https://example.com/logo.png"}, false},
+		{"path logo", Branding{LogoURL: "/hub/static/logo"}, false},
+		{"relative logo", Branding{LogoURL: "logo.png"}, true},
+		{"javascript logo", Branding{LogoURL: "javascript:alert(1)"}, true},
+		{"hex color", Branding{PrimaryColor: "#1E40AF"}, false},
+		{"short hex color", Branding{PrimaryColor: "#f80"}, false},
+		{"color name", Branding{PrimaryColor: "rebeccapurple"}, false},
+		{"bad hex color", Branding{PrimaryColor: "#12345"}, true},
+		{"css injection", Branding{PrimaryColor: "red; background: url(x)"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.branding.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
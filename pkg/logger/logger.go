@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/lmittmann/tint"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/redact"
 )
 
 // Level represents log levels
@@ -37,6 +40,8 @@ type Config struct {
 	Output     io.Writer
 	ShowCaller bool // Include file:line in logs
 	TimeFormat string
+	TimeZone   *time.Location   // Zone log timestamps are rendered in (nil = local time)
+	Redactor   *redact.Redactor // Masks secret values in every log record (nil = none)
 }
 
 // DefaultConfig returns production-ready logging configuration
@@ -67,6 +72,7 @@ func New(cfg Config) *Logger {
 	}
 
 	var handler slog.Handler
+	replaceAttr := timeZoneReplacer(cfg.TimeZone)
 
 	// Create handler based on format
 	if cfg.Format == FormatPretty {
@@ -77,15 +83,17 @@ func New(cfg Config) *Logger {
 		}
 
 		handler = tint.NewHandler(output, &tint.Options{
-			Level:      level,
-			TimeFormat: timeFormat,
-			NoColor:    false, // Always use colors
-			AddSource:  cfg.ShowCaller,
+			Level:       level,
+			TimeFormat:  timeFormat,
+			NoColor:     false, // Always use colors
+			AddSource:   cfg.ShowCaller,
+			ReplaceAttr: replaceAttr,
 		})
 	} else {
 		// JSON format for production
 		opts := &slog.HandlerOptions{
-			Level: level,
+			Level:       level,
+			ReplaceAttr: replaceAttr,
 		}
 		if cfg.ShowCaller {
 			opts.AddSource = true
@@ -93,6 +101,10 @@ func New(cfg Config) *Logger {
 		handler = slog.NewJSONHandler(output, opts)
 	}
 
+	if cfg.Redactor != nil {
+		handler = &redactHandler{next: handler, redactor: cfg.Redactor}
+	}
+
 	logger := slog.New(handler).With("service", "jhub-app-proxy")
 
 	return &Logger{
@@ -100,6 +112,19 @@ func New(cfg Config) *Logger {
 	}
 }
 
+// timeZoneReplacer converts record timestamps to loc (nil when loc is nil, leaving local time)
+func timeZoneReplacer(loc *time.Location) func([]string, slog.Attr) slog.Attr {
+	if loc == nil {
+		return nil
+	}
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime {
+			a.Value = slog.TimeValue(a.Value.Time().In(loc))
+		}
+		return a
+	}
+}
+
 // WithComponent creates a child logger with component context for modularity
 func (l *Logger) WithComponent(component string) *Logger {
 	return &Logger{
@@ -186,8 +211,10 @@ func (l *Logger) ProcessFailed(exitCode int, stderr, stdout string, err error) {
 }
 
 // ProcessStarted logs process start with full command visibility
+// Only the names of the environment variables are logged: values from --env-file and
+// --env may be secrets the redactor doesn't know about.
 func (l *Logger) ProcessStarted(pid int, command []string, env map[string]string) {
-	l.logger.Info("process started", "pid", pid, "command", command, "env", env)
+	l.logger.Info("process started", "pid", pid, "command", command, "env_keys", slices.Sorted(maps.Keys(env)))
 }
 
 // ProcessExited logs process exit with duration and exit code
@@ -6,6 +6,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/redact"
 )
 
 func TestNewLogger(t *testing.T) {
@@ -155,6 +157,25 @@ func TestLoggerWithFields(t *testing.T) {
 	}
 }
 
+func TestLoggerWithFieldsPropagatesToChildren(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := Config{
+		Level:  LevelInfo,
+		Format: FormatJSON,
+		Output: buf,
+	}
+
+	logger := New(cfg).WithFields(map[string]interface{}{
+		"deployment": "alice-dashboard",
+	})
+	logger.WithComponent("process-manager").ProcessOutput("stdout", "app started")
+
+	output := buf.String()
+	if !strings.Contains(output, `"deployment":"alice-dashboard"`) {
+		t.Errorf("expected static field on child logger output, got %q", output)
+	}
+}
+
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig()
 
@@ -168,3 +189,56 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("expected ShowCaller to be false, got %v", cfg.ShowCaller)
 	}
 }
+
+func TestLoggerRedactor(t *testing.T) {
+	const secret = "s3cr3t-t0ken"
+	t.Setenv("APP_TOKEN", secret)
+	redactor, _ := redact.FromEnv([]string{"APP_TOKEN"})
+
+	buf := &bytes.Buffer{}
+	logger := New(Config{Level: LevelDebug, Format: FormatJSON, Output: buf, Redactor: redactor})
+
+	logger.Info("connecting with "+secret, "token", secret)
+	logger.WithFields(map[string]interface{}{"auth": "Bearer " + secret}).Warn("field")
+	logger.WithComponent("process-manager").ProcessStarted(42,
+		[]string{"app", "--token", secret},
+		map[string]string{"APP_TOKEN": secret})
+	logger.Error("request failed", errors.New("401 for token "+secret))
+	logger.ProcessOutput("stdout", "using token "+secret)
+
+	output := buf.String()
+	if strings.Contains(output, secret) {
+		t.Errorf("expected secret to be redacted everywhere, got %q", output)
+	}
+	if n := strings.Count(output, redact.Placeholder); n < 6 {
+		t.Errorf("expected at least 6 redactions, got %d in %q", n, output)
+	}
+	// The env is logged by name only
+	if !strings.Contains(output, `"env_keys":["APP_TOKEN"]`) {
+		t.Errorf("expected the env names in the process started record, got %q", output)
+	}
+	// Values without secrets keep their structure
+	if !strings.Contains(output, `"pid":42`) {
+		t.Errorf("expected non-secret attributes to be unchanged, got %q", output)
+	}
+}
+
+func TestLoggerTimeZone(t *testing.T) {
+	// JSON logs keep RFC 3339, pretty logs use TimeFormat; both in the configured zone
+	for _, format := range []Format{FormatJSON, FormatPretty} {
+		t.Run(string(format), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			log := New(Config{
+				Format:     format,
+				Output:     buf,
+				TimeFormat: "2006-01-02 15:04 -07:00",
+				TimeZone:   time.FixedZone("X", 5*60*60),
+			})
+			log.Info("hello")
+
+			if !strings.Contains(buf.String(), "+05:00") {
+				t.Errorf("expected timestamp with +05:00 offset, got %q", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Package logger - slog handler masking secret values before they are written
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/redact"
+)
+
+// redactHandler replaces secret values in the message and attributes of every record
+type redactHandler struct {
+	next     slog.Handler
+	redactor *redact.Redactor
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.String(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactAttr(attr)
+	}
+	return &redactHandler{next: h.next.WithAttrs(redacted), redactor: h.redactor}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
+
+// redactAttr masks secrets in an attribute value
+// Non-string values (commands, env maps, errors) are checked through their printed
+// form and replaced by the redacted string only when they contain a secret.
+func (h *redactHandler) redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(h.redactor.String(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, a := range group {
+			redacted[i] = h.redactAttr(a)
+		}
+		attr.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		printed := fmt.Sprint(value.Any())
+		if masked := h.redactor.String(printed); masked != printed {
+			attr.Value = slog.StringValue(masked)
+		} else {
+			attr.Value = value
+		}
+	default:
+		attr.Value = value
+	}
+	return attr
+}
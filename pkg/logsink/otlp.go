@@ -0,0 +1,115 @@
+package logsink
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+// otlpScopeName identifies jhub-app-proxy as the instrumentation scope of shipped records
+const otlpScopeName = "jhub-app-proxy"
+
+// OTLP severity numbers of the detected log levels
+// https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-severitynumber
+var otlpSeverity = map[string]struct {
+	number int
+	text   string
+}{
+	process.LevelDebug: {5, "DEBUG"},
+	process.LevelInfo:  {9, "INFO"},
+	process.LevelWarn:  {13, "WARN"},
+	process.LevelError: {17, "ERROR"},
+}
+
+// otlpRequest is the body of an OTLP/HTTP logs export request in JSON encoding
+// (ExportLogsServiceRequest, with 64-bit integers as strings per the protobuf JSON mapping)
+type otlpRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber,omitempty"`
+	SeverityText         string         `json:"severityText,omitempty"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func otlpString(s string) otlpAnyValue {
+	return otlpAnyValue{StringValue: &s}
+}
+
+func otlpInt(i int64) otlpAnyValue {
+	s := strconv.FormatInt(i, 10)
+	return otlpAnyValue{IntValue: &s}
+}
+
+// otlpPayload wraps a batch in a single resource, described by the static labels
+// Severity comes from the level detected in each line; lines without a level marker
+// are sent with an unspecified severity.
+func otlpPayload(batch []process.LogEntry, labels map[string]string) otlpRequest {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	resource := make([]otlpKeyValue, 0, len(keys))
+	for _, key := range keys {
+		resource = append(resource, otlpKeyValue{Key: key, Value: otlpString(labels[key])})
+	}
+
+	records := make([]otlpLogRecord, 0, len(batch))
+	for _, entry := range batch {
+		timestamp := strconv.FormatInt(entry.Timestamp.UnixNano(), 10)
+		severity := otlpSeverity[process.DetectLevel(entry.Line)]
+		records = append(records, otlpLogRecord{
+			TimeUnixNano:         timestamp,
+			ObservedTimeUnixNano: timestamp,
+			SeverityNumber:       severity.number,
+			SeverityText:         severity.text,
+			Body:                 otlpString(entry.Line),
+			Attributes: []otlpKeyValue{
+				{Key: "log.iostream", Value: otlpString(entry.Stream)},
+				{Key: "process.pid", Value: otlpInt(int64(entry.PID))},
+			},
+		})
+	}
+
+	return otlpRequest{ResourceLogs: []otlpResourceLogs{{
+		Resource: otlpResource{Attributes: resource},
+		ScopeLogs: []otlpScopeLogs{{
+			Scope:      otlpScope{Name: otlpScopeName},
+			LogRecords: records,
+		}},
+	}}}
+}
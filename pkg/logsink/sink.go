@@ -0,0 +1,282 @@
+// Package logsink ships captured subprocess logs to an external HTTP endpoint
+//
+// Entries are queued in memory and POSTed in batches by a background goroutine,
+// in Loki push API format, as OTLP log records or as generic JSON. Shipping never blocks log
+// capture: when the endpoint is slow or down, the queue fills up and new entries
+// are dropped (and counted) instead of stalling the subprocess output readers.
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+// Supported payload formats
+const (
+	FormatJSON = "json" // {"labels": {...}, "entries": [LogEntry, ...]}
+	FormatLoki = "loki" // Loki push API (/loki/api/v1/push)
+	FormatOTLP = "otlp" // OTLP/HTTP logs export in JSON encoding (/v1/logs)
+)
+
+// Config configures the log sink
+type Config struct {
+	URL           string            // Endpoint receiving POSTed batches
+	Format        string            // FormatJSON, FormatLoki or FormatOTLP (default: FormatJSON)
+	Labels        map[string]string // Static labels attached to every batch (Loki stream labels, OTLP resource attributes)
+	BatchSize     int               // Max entries per request (default: 100)
+	FlushInterval time.Duration     // Max time an entry waits before being shipped (default: 1s)
+	QueueSize     int               // Entries buffered before new ones are dropped (default: 10000)
+	MaxRetries    int               // Retries per batch after the first attempt (default: 3, negative = no retries)
+	RetryBackoff  time.Duration     // Initial retry delay, doubled per attempt (default: 500ms)
+	Logger        *logger.Logger
+}
+
+// Sink batches log entries and ships them to an HTTP endpoint
+// Implements process.LogSink
+type Sink struct {
+	cfg        Config
+	httpClient *http.Client
+	logger     *logger.Logger
+	queue      chan process.LogEntry
+	done       chan struct{}
+
+	mu      sync.Mutex
+	dropped int // Entries dropped because the queue was full, reset when reported
+}
+
+// New creates a log sink; call Start to begin shipping
+func New(cfg Config) (*Sink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("log sink URL is required")
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatJSON
+	}
+	if cfg.Format != FormatJSON && cfg.Format != FormatLoki && cfg.Format != FormatOTLP {
+		return nil, fmt.Errorf("invalid log sink format %q: must be %q, %q or %q", cfg.Format, FormatJSON, FormatLoki, FormatOTLP)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 500 * time.Millisecond
+	}
+
+	return &Sink{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     cfg.Logger.WithComponent("log-sink"),
+		queue:      make(chan process.LogEntry, cfg.QueueSize),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// Send queues an entry for shipping without blocking
+// The entry is dropped if the queue is full (endpoint slow or unavailable)
+func (s *Sink) Send(entry process.LogEntry) {
+	select {
+	case s.queue <- entry:
+	default:
+		s.mu.Lock()
+		s.dropped++
+		s.mu.Unlock()
+	}
+}
+
+// Start runs the background shipper until ctx is cancelled
+// Remaining queued entries are flushed before it stops; Wait blocks until then
+func (s *Sink) Start(ctx context.Context) {
+	s.logger.Info("shipping logs to external sink",
+		"url", s.cfg.URL,
+		"format", s.cfg.Format,
+		"batch_size", s.cfg.BatchSize)
+
+	go s.run(ctx)
+}
+
+// Wait blocks until the shipper has flushed its queue after ctx cancellation
+func (s *Sink) Wait() {
+	<-s.done
+}
+
+// run collects entries into batches and ships them on size or interval
+func (s *Sink) run(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]process.LogEntry, 0, s.cfg.BatchSize)
+	flush := func(ctx context.Context) {
+		if len(batch) > 0 {
+			s.ship(ctx, batch)
+			batch = make([]process.LogEntry, 0, s.cfg.BatchSize)
+		}
+		s.reportDropped()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Drain what is already queued with a single best-effort attempt per batch
+			for {
+				select {
+				case entry := <-s.queue:
+					batch = append(batch, entry)
+					if len(batch) >= s.cfg.BatchSize {
+						flush(context.Background())
+					}
+				default:
+					flush(context.Background())
+					return
+				}
+			}
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) >= s.cfg.BatchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// ship POSTs one batch, retrying with exponential backoff
+// Gives up after MaxRetries so a dead endpoint cannot stall shipping forever;
+// retries stop early once ctx is cancelled
+func (s *Sink) ship(ctx context.Context, batch []process.LogEntry) {
+	body, err := s.encode(batch)
+	if err != nil {
+		s.logger.Error("failed to encode log batch", err, "entries", len(batch))
+		return
+	}
+
+	backoff := s.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = s.post(body)
+		if err == nil {
+			return
+		}
+		if attempt >= s.cfg.MaxRetries || ctx.Err() != nil {
+			break
+		}
+
+		s.logger.Debug("log batch delivery failed, retrying",
+			"attempt", attempt+1,
+			"backoff", backoff,
+			"error", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	s.logger.Warn("dropping log batch after failed delivery",
+		"entries", len(batch),
+		"attempts", s.cfg.MaxRetries+1,
+		"error", err)
+}
+
+// post sends an encoded batch to the sink URL
+func (s *Sink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send log batch: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("log sink returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// reportDropped logs how many entries were dropped since the last report
+func (s *Sink) reportDropped() {
+	s.mu.Lock()
+	dropped := s.dropped
+	s.dropped = 0
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		s.logger.Warn("log sink queue full, entries dropped", "dropped", dropped)
+	}
+}
+
+// encode renders a batch in the configured format
+func (s *Sink) encode(batch []process.LogEntry) ([]byte, error) {
+	switch s.cfg.Format {
+	case FormatLoki:
+		return json.Marshal(lokiPayload(batch, s.cfg.Labels))
+	case FormatOTLP:
+		return json.Marshal(otlpPayload(batch, s.cfg.Labels))
+	}
+	return json.Marshal(map[string]interface{}{
+		"labels":  s.cfg.Labels,
+		"entries": batch,
+	})
+}
+
+// lokiPush is the body of a Loki push API request
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"` // [unix epoch nanoseconds, line]
+}
+
+// lokiPayload groups a batch into one Loki stream per output stream (stdout/stderr)
+func lokiPayload(batch []process.LogEntry, labels map[string]string) lokiPush {
+	var push lokiPush
+	index := make(map[string]int)
+
+	for _, entry := range batch {
+		i, ok := index[entry.Stream]
+		if !ok {
+			streamLabels := make(map[string]string, len(labels)+1)
+			for k, v := range labels {
+				streamLabels[k] = v
+			}
+			streamLabels["stream"] = entry.Stream
+			push.Streams = append(push.Streams, lokiStream{Stream: streamLabels})
+			i = len(push.Streams) - 1
+			index[entry.Stream] = i
+		}
+		push.Streams[i].Values = append(push.Streams[i].Values,
+			[2]string{strconv.FormatInt(entry.Timestamp.UnixNano(), 10), entry.Line})
+	}
+
+	return push
+}
@@ -0,0 +1,382 @@
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+// mockSink records the body of every request and fails the first failures requests
+type mockSink struct {
+	mu       sync.Mutex
+	bodies   [][]byte
+	failures int
+}
+
+func (m *mockSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failures > 0 {
+		m.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	m.bodies = append(m.bodies, body)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *mockSink) received() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]byte(nil), m.bodies...)
+}
+
+func testEntry(i int) process.LogEntry {
+	stream := "stdout"
+	if i%2 == 0 {
+		stream = "stderr"
+	}
+	return process.LogEntry{
+		Timestamp: time.Unix(1700000000, int64(i)),
+		Stream:    stream,
+		Line:      fmt.Sprintf("line %d", i),
+	}
+}
+
+// runSink sends n entries through a sink and waits until it has flushed them
+func runSink(t *testing.T, cfg Config, n int) {
+	t.Helper()
+	cfg.Logger = logger.New(logger.Config{Output: io.Discard})
+	sink, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sink.Start(ctx)
+	for i := 1; i <= n; i++ {
+		sink.Send(testEntry(i))
+	}
+	cancel()
+	sink.Wait()
+}
+
+func TestSink_JSONBatches(t *testing.T) {
+	mock := &mockSink{}
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	runSink(t, Config{
+		URL:           srv.URL,
+		Format:        FormatJSON,
+		Labels:        map[string]string{"hub_user": "alice"},
+		BatchSize:     10,
+		FlushInterval: time.Hour, // Only size and shutdown trigger a flush
+	}, 25)
+
+	bodies := mock.received()
+	if len(bodies) != 3 {
+		t.Fatalf("expected 3 batches for 25 entries, got %d", len(bodies))
+	}
+
+	var lines []string
+	for i, body := range bodies {
+		var payload struct {
+			Labels  map[string]string  `json:"labels"`
+			Entries []process.LogEntry `json:"entries"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("batch %d: invalid JSON: %v", i, err)
+		}
+		if payload.Labels["hub_user"] != "alice" {
+			t.Errorf("batch %d: expected labels to be attached, got %v", i, payload.Labels)
+		}
+		if len(payload.Entries) > 10 {
+			t.Errorf("batch %d: expected at most 10 entries, got %d", i, len(payload.Entries))
+		}
+		for _, entry := range payload.Entries {
+			lines = append(lines, entry.Line)
+		}
+	}
+
+	if len(lines) != 25 {
+		t.Fatalf("expected 25 shipped entries, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if want := fmt.Sprintf("line %d", i+1); line != want {
+			t.Errorf("entry %d: expected %q, got %q", i, want, line)
+		}
+	}
+}
+
+func TestSink_LokiFormat(t *testing.T) {
+	mock := &mockSink{}
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	runSink(t, Config{
+		URL:    srv.URL,
+		Format: FormatLoki,
+		Labels: map[string]string{"job": "jhub-app-proxy"},
+	}, 4)
+
+	bodies := mock.received()
+	if len(bodies) != 1 {
+		t.Fatalf("expected 1 batch, got %d", len(bodies))
+	}
+
+	var push lokiPush
+	if err := json.Unmarshal(bodies[0], &push); err != nil {
+		t.Fatalf("invalid Loki payload: %v", err)
+	}
+	if len(push.Streams) != 2 {
+		t.Fatalf("expected one stream per output stream, got %d", len(push.Streams))
+	}
+	for _, stream := range push.Streams {
+		if stream.Stream["job"] != "jhub-app-proxy" {
+			t.Errorf("expected static labels on stream, got %v", stream.Stream)
+		}
+		if len(stream.Values) != 2 {
+			t.Errorf("stream %s: expected 2 values, got %d", stream.Stream["stream"], len(stream.Values))
+		}
+	}
+	if got := push.Streams[0].Values[0]; got[0] != "1700000000000000001" || got[1] != "line 1" {
+		t.Errorf("expected [nanosecond timestamp, line], got %v", got)
+	}
+}
+
+func TestSink_RetriesFailedBatch(t *testing.T) {
+	mock := &mockSink{failures: 2}
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	cfg := Config{
+		URL:           srv.URL,
+		BatchSize:     5,
+		FlushInterval: time.Hour,
+		RetryBackoff:  time.Millisecond,
+		Logger:        logger.New(logger.Config{Output: io.Discard}),
+	}
+	sink, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sink.Start(ctx)
+	for i := 1; i <= 5; i++ {
+		sink.Send(testEntry(i))
+	}
+
+	// The full batch is shipped (and retried) while the sink is running
+	deadline := time.Now().Add(5 * time.Second)
+	for len(mock.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	sink.Wait()
+
+	if got := len(mock.received()); got != 1 {
+		t.Fatalf("expected batch delivered after retries, got %d deliveries", got)
+	}
+}
+
+func TestSink_SendNeverBlocks(t *testing.T) {
+	// Endpoint that hangs until the test ends
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	sink, err := New(Config{
+		URL:       srv.URL,
+		BatchSize: 1,
+		QueueSize: 10,
+		Logger:    logger.New(logger.Config{Output: io.Discard}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sink.Start(ctx)
+
+	// The shipper is stuck on the first request; the queue must overflow instead of blocking
+	start := time.Now()
+	for i := 1; i <= 1000; i++ {
+		sink.Send(testEntry(i))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected Send to return immediately with a stuck endpoint, took %v", elapsed)
+	}
+
+	sink.mu.Lock()
+	dropped := sink.dropped
+	sink.mu.Unlock()
+	if dropped == 0 {
+		t.Error("expected entries to be dropped once the queue is full")
+	}
+}
+
+func TestNew_InvalidFormat(t *testing.T) {
+	_, err := New(Config{
+		URL:    "http://localhost:3100",
+		Format: "syslog",
+		Logger: logger.New(logger.Config{Output: io.Discard}),
+	})
+	if err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+// otlpReceiver is an in-memory OTLP/HTTP logs receiver decoding the JSON encoding
+type otlpReceiver struct {
+	mu      sync.Mutex
+	records []map[string]interface{}
+	attrs   map[string]string // Resource attributes of the last request
+	paths   []string
+}
+
+func (o *otlpReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string `json:"key"`
+					Value struct {
+						StringValue string `json:"stringValue"`
+					} `json:"value"`
+				} `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				LogRecords []map[string]interface{} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.paths = append(o.paths, r.URL.Path)
+	for _, rl := range req.ResourceLogs {
+		o.attrs = make(map[string]string)
+		for _, attr := range rl.Resource.Attributes {
+			o.attrs[attr.Key] = attr.Value.StringValue
+		}
+		for _, sl := range rl.ScopeLogs {
+			o.records = append(o.records, sl.LogRecords...)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestSink_OTLPFormat(t *testing.T) {
+	receiver := &otlpReceiver{}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	sink, err := New(Config{
+		URL:    srv.URL + "/v1/logs",
+		Format: FormatOTLP,
+		Labels: map[string]string{
+			"service.name":              "jhub-app-proxy",
+			"jupyterhub.user":           "alice",
+			"jupyterhub.service_prefix": "/user/alice/app/",
+		},
+		Logger: logger.New(logger.Config{Output: io.Discard}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+
+	// Entries reach the sink through log capture, like in the proxy
+	buffer := process.NewLogBuffer(10)
+	defer func() { _ = buffer.Close() }()
+	buffer.SetSink(process.MultiSink{sink})
+	ctx, cancel := context.WithCancel(context.Background())
+	sink.Start(ctx)
+	buffer.Append(process.LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: "INFO:     Uvicorn running on http://127.0.0.1:8000", PID: 42})
+	buffer.Append(process.LogEntry{Timestamp: time.Now(), Stream: "stderr", Line: "ERROR: something broke", PID: 42})
+	buffer.Append(process.LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: "plain output", PID: 42})
+	cancel()
+	sink.Wait()
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.paths) != 1 || receiver.paths[0] != "/v1/logs" {
+		t.Errorf("expected one export to /v1/logs, got %v", receiver.paths)
+	}
+	for key, want := range map[string]string{
+		"service.name":              "jhub-app-proxy",
+		"jupyterhub.user":           "alice",
+		"jupyterhub.service_prefix": "/user/alice/app/",
+	} {
+		if got := receiver.attrs[key]; got != want {
+			t.Errorf("expected resource attribute %s=%q, got %q", key, want, got)
+		}
+	}
+
+	if len(receiver.records) != 3 {
+		t.Fatalf("expected 3 log records, got %d", len(receiver.records))
+	}
+	tests := []struct {
+		body           string
+		severityNumber float64 // JSON numbers decode as float64
+		severityText   string
+		stream         string
+	}{
+		{"INFO:     Uvicorn running on http://127.0.0.1:8000", 9, "INFO", "stdout"},
+		{"ERROR: something broke", 17, "ERROR", "stderr"},
+		{"plain output", 0, "", "stdout"},
+	}
+	for i, tt := range tests {
+		record := receiver.records[i]
+		body, _ := record["body"].(map[string]interface{})
+		if body["stringValue"] != tt.body {
+			t.Errorf("record %d: expected body %q, got %v", i, tt.body, record["body"])
+		}
+		severity, _ := record["severityNumber"].(float64)
+		text, _ := record["severityText"].(string)
+		if severity != tt.severityNumber || text != tt.severityText {
+			t.Errorf("record %d: expected severity %v %q, got %v %q", i, tt.severityNumber, tt.severityText, severity, text)
+		}
+		if ts, _ := record["timeUnixNano"].(string); ts == "" || ts == "0" {
+			t.Errorf("record %d: expected a timestamp, got %v", i, record["timeUnixNano"])
+		}
+
+		attrs := make(map[string]interface{})
+		list, _ := record["attributes"].([]interface{})
+		for _, a := range list {
+			kv, _ := a.(map[string]interface{})
+			value, _ := kv["value"].(map[string]interface{})
+			for _, v := range value {
+				attrs[kv["key"].(string)] = v
+			}
+		}
+		if attrs["log.iostream"] != tt.stream {
+			t.Errorf("record %d: expected log.iostream %q, got %v", i, tt.stream, attrs["log.iostream"])
+		}
+		if attrs["process.pid"] != "42" {
+			t.Errorf("record %d: expected process.pid 42, got %v", i, attrs["process.pid"])
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// Package metrics - HTTP request instrumentation
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
+)
+
+// Instrument counts and times the requests handled by next
+// pathPrefix must come from a small fixed set (e.g. "/", the interim path) to
+// keep label cardinality bounded; never pass the raw request path.
+func (m *Metrics) Instrument(pathPrefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := middleware.NewStatusRecorder(w)
+		next.ServeHTTP(sw, r)
+
+		m.requests.WithLabelValues(r.Method, strconv.Itoa(sw.Status()), pathPrefix).Inc()
+		m.requestDuration.WithLabelValues(pathPrefix).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,160 @@
+// Package metrics exposes Prometheus metrics for the proxy and its managed subprocess
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// SubprocessSource provides the subprocess information exported as metrics
+// Implemented by *process.ManagerWithLogs
+type SubprocessSource interface {
+	GetState() process.ProcessState
+	GetResourceUsage() (process.ResourceUsage, error)
+	GetRestartCount() int
+	GetLogStats() process.LogStats
+}
+
+// processStates lists every state so the state gauge always exports all series
+// The index is the value of the numeric jhub_subprocess_state gauge
+var processStates = []process.ProcessState{
+	process.StateInitializing,
+	process.StateStarting,
+	process.StateRunning,
+	process.StateFailed,
+	process.StateStopped,
+}
+
+// subprocessCollector samples subprocess state and resource usage at scrape time
+type subprocessCollector struct {
+	source SubprocessSource
+
+	memoryDesc         *prometheus.Desc
+	cpuDesc            *prometheus.Desc
+	stateDesc          *prometheus.Desc
+	stateCodeDesc      *prometheus.Desc
+	restartsDesc       *prometheus.Desc
+	logBufferLinesDesc *prometheus.Desc
+}
+
+func newSubprocessCollector(source SubprocessSource) *subprocessCollector {
+	return &subprocessCollector{
+		source: source,
+		memoryDesc: prometheus.NewDesc("jhub_app_subprocess_memory_bytes",
+			"Resident memory of the subprocess and its children in bytes", nil, nil),
+		cpuDesc: prometheus.NewDesc("jhub_app_subprocess_cpu_seconds_total",
+			"Cumulative user and system CPU time of the subprocess and its children in seconds", nil, nil),
+		stateDesc: prometheus.NewDesc("jhub_app_subprocess_state",
+			"Current subprocess state (1 for the active state, 0 otherwise)", []string{"state"}, nil),
+		stateCodeDesc: prometheus.NewDesc("jhub_subprocess_state",
+			"Current subprocess state as a number: 0 initializing, 1 starting, 2 running, 3 failed, 4 stopped", nil, nil),
+		restartsDesc: prometheus.NewDesc("jhub_subprocess_restarts_total",
+			"Automatic subprocess restarts since it was last started (resets on a manual restart)", nil, nil),
+		logBufferLinesDesc: prometheus.NewDesc("jhub_log_buffer_lines",
+			"Subprocess log lines currently held in the log buffer", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *subprocessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.memoryDesc
+	ch <- c.cpuDesc
+	ch <- c.stateDesc
+	ch <- c.stateCodeDesc
+	ch <- c.restartsDesc
+	ch <- c.logBufferLinesDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *subprocessCollector) Collect(ch chan<- prometheus.Metric) {
+	current := c.source.GetState()
+	for i, state := range processStates {
+		value := 0.0
+		if state == current {
+			value = 1
+			ch <- prometheus.MustNewConstMetric(c.stateCodeDesc, prometheus.GaugeValue, float64(i))
+		}
+		ch <- prometheus.MustNewConstMetric(c.stateDesc, prometheus.GaugeValue, value, string(state))
+	}
+	ch <- prometheus.MustNewConstMetric(c.restartsDesc, prometheus.CounterValue, float64(c.source.GetRestartCount()))
+	ch <- prometheus.MustNewConstMetric(c.logBufferLinesDesc, prometheus.GaugeValue, float64(c.source.GetLogStats().BufferedLines))
+
+	// Resource usage is only available while the process is alive
+	usage, err := c.source.GetResourceUsage()
+	if err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.memoryDesc, prometheus.GaugeValue, float64(usage.MemoryRSSBytes))
+	ch <- prometheus.MustNewConstMetric(c.cpuDesc, prometheus.CounterValue, usage.CPUSeconds)
+}
+
+// Metrics holds the Prometheus registry for jhub-app-proxy
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	activityReports *prometheus.CounterVec
+	oauthCacheHits  prometheus.Counter
+	oauthCacheMiss  prometheus.Counter
+}
+
+// New creates the metrics registry with the subprocess collector registered
+func New(source SubprocessSource) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "jhub_proxy_requests_total",
+			Help: "HTTP requests handled by the proxy",
+		}, []string{"method", "status_code", "path_prefix"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "jhub_proxy_request_duration_seconds",
+			Help:    "Time to handle HTTP requests, including the backend response",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"path_prefix"}),
+		activityReports: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "jhub_hub_activity_reports_total",
+			Help: "Activity reports sent to the JupyterHub API",
+		}, []string{"success"}),
+		oauthCacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "jhub_oauth_cache_hits_total",
+			Help: "OAuth user lookups answered from the token cache",
+		}),
+		oauthCacheMiss: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "jhub_oauth_cache_misses_total",
+			Help: "OAuth user lookups that missed the token cache (or found an expired entry) and called the Hub API",
+		}),
+	}
+
+	m.registry.MustRegister(newSubprocessCollector(source), m.requests, m.requestDuration, m.activityReports,
+		m.oauthCacheHits, m.oauthCacheMiss)
+
+	// Export both success series from the start so rate() works before the first failure
+	m.activityReports.WithLabelValues("true")
+	m.activityReports.WithLabelValues("false")
+
+	return m
+}
+
+// Handler returns the HTTP handler serving metrics in Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveActivityReport counts one activity report to the Hub (err == nil means success)
+func (m *Metrics) ObserveActivityReport(err error) {
+	m.activityReports.WithLabelValues(strconv.FormatBool(err == nil)).Inc()
+}
+
+// ObserveOAuthCacheLookup counts one OAuth user cache lookup
+func (m *Metrics) ObserveOAuthCacheLookup(hit bool) {
+	if hit {
+		m.oauthCacheHits.Inc()
+	} else {
+		m.oauthCacheMiss.Inc()
+	}
+}
@@ -0,0 +1,210 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+// scrape fetches the metrics page and returns its body
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/_metrics", nil))
+	if rec.Code != 200 {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+// metricValue extracts the value of an unlabeled metric from Prometheus text output
+func metricValue(t *testing.T, body, name string) float64 {
+	t.Helper()
+	match := regexp.MustCompile(`(?m)^` + name + ` (\S+)$`).FindStringSubmatch(body)
+	if match == nil {
+		t.Fatalf("metric %s not found in:\n%s", name, body)
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		t.Fatalf("metric %s has invalid value %q", name, match[1])
+	}
+	return value
+}
+
+func TestSubprocessMetrics_RunningProcess(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(
+		process.Config{Command: []string{"sleep", "30"}},
+		process.LogCaptureConfig{Enabled: true},
+		log,
+	)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = syscall.Kill(mgr.GetPID(), syscall.SIGKILL)
+		_ = mgr.CloseLogFile()
+	})
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	body := scrape(t, New(mgr))
+
+	if memory := metricValue(t, body, "jhub_app_subprocess_memory_bytes"); memory <= 0 {
+		t.Errorf("expected positive memory usage, got %v", memory)
+	}
+	if cpu := metricValue(t, body, "jhub_app_subprocess_cpu_seconds_total"); cpu < 0 {
+		t.Errorf("expected non-negative CPU time, got %v", cpu)
+	}
+	if !strings.Contains(body, `jhub_app_subprocess_state{state="running"} 1`) {
+		t.Errorf("expected running state gauge, got:\n%s", body)
+	}
+	if !strings.Contains(body, `jhub_app_subprocess_state{state="failed"} 0`) {
+		t.Errorf("expected failed state gauge to be 0, got:\n%s", body)
+	}
+	if state := metricValue(t, body, "jhub_subprocess_state"); state != 2 {
+		t.Errorf("expected numeric state 2 (running), got %v", state)
+	}
+	if restarts := metricValue(t, body, "jhub_subprocess_restarts_total"); restarts != 0 {
+		t.Errorf("expected 0 restarts, got %v", restarts)
+	}
+}
+
+func TestMetrics_LogBufferLines(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(
+		process.Config{Command: []string{"sleep", "30"}},
+		process.LogCaptureConfig{Enabled: true, BufferSize: 10},
+		log,
+	)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	t.Cleanup(func() { _ = mgr.CloseLogFile() })
+
+	mgr.AddInfoLog("one")
+	mgr.AddInfoLog("two")
+
+	if lines := metricValue(t, scrape(t, New(mgr)), "jhub_log_buffer_lines"); lines != 2 {
+		t.Errorf("expected 2 buffered lines, got %v", lines)
+	}
+}
+
+func TestMetrics_Instrument(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(
+		process.Config{Command: []string{"sleep", "30"}},
+		process.LogCaptureConfig{Enabled: false},
+		log,
+	)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	m := New(mgr)
+
+	handler := m.Instrument("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	for _, path := range []string{"/", "/page", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, m)
+	if !strings.Contains(body, `jhub_proxy_requests_total{method="GET",path_prefix="/",status_code="200"} 2`) {
+		t.Errorf("expected 2 successful requests, got:\n%s", body)
+	}
+	if !strings.Contains(body, `jhub_proxy_requests_total{method="GET",path_prefix="/",status_code="404"} 1`) {
+		t.Errorf("expected 1 not found request, got:\n%s", body)
+	}
+	if !strings.Contains(body, `jhub_proxy_request_duration_seconds_count{path_prefix="/"} 3`) {
+		t.Errorf("expected 3 timed requests, got:\n%s", body)
+	}
+}
+
+func TestMetrics_ActivityReports(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(
+		process.Config{Command: []string{"sleep", "30"}},
+		process.LogCaptureConfig{Enabled: false},
+		log,
+	)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	m := New(mgr)
+
+	m.ObserveActivityReport(nil)
+	m.ObserveActivityReport(nil)
+	m.ObserveActivityReport(errors.New("hub unreachable"))
+
+	body := scrape(t, m)
+	if !strings.Contains(body, `jhub_hub_activity_reports_total{success="true"} 2`) {
+		t.Errorf("expected 2 successful reports, got:\n%s", body)
+	}
+	if !strings.Contains(body, `jhub_hub_activity_reports_total{success="false"} 1`) {
+		t.Errorf("expected 1 failed report, got:\n%s", body)
+	}
+}
+
+func TestMetrics_OAuthCacheLookups(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(
+		process.Config{Command: []string{"sleep", "30"}},
+		process.LogCaptureConfig{Enabled: false},
+		log,
+	)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	m := New(mgr)
+
+	m.ObserveOAuthCacheLookup(false)
+	m.ObserveOAuthCacheLookup(true)
+	m.ObserveOAuthCacheLookup(true)
+
+	body := scrape(t, m)
+	if !strings.Contains(body, "jhub_oauth_cache_hits_total 2") {
+		t.Errorf("expected 2 cache hits, got:\n%s", body)
+	}
+	if !strings.Contains(body, "jhub_oauth_cache_misses_total 1") {
+		t.Errorf("expected 1 cache miss, got:\n%s", body)
+	}
+}
+
+func TestSubprocessMetrics_NotStarted(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(
+		process.Config{Command: []string{"sleep", "30"}},
+		process.LogCaptureConfig{Enabled: false},
+		log,
+	)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	body := scrape(t, New(mgr))
+
+	if !strings.Contains(body, `jhub_app_subprocess_state{state="initializing"} 1`) {
+		t.Errorf("expected initializing state gauge, got:\n%s", body)
+	}
+	if strings.Contains(body, "jhub_app_subprocess_memory_bytes") {
+		t.Errorf("expected no memory metric before the process starts, got:\n%s", body)
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+// AccessLog logs one structured "request completed" event per request at info level
+// Logs method, path, status, duration, the request ID from RequestID (wrap AccessLog in
+// it) and the user the auth middleware authenticated (SetAuthenticatedUser). Headers the
+// client sent are never used for the user, so requests that didn't authenticate have none.
+func AccessLog(log *logger.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := NewStatusRecorder(w)
+		user := &atomic.Value{}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), authenticatedUserKey{}, user)))
+
+		args := []interface{}{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", sw.Status(),
+			"duration", time.Since(start),
+			"request_id", RequestIDFromContext(r.Context()),
+		}
+		if name, _ := user.Load().(string); name != "" {
+			args = append(args, "user_name", name)
+		}
+		log.Info("request completed", args...)
+	})
+}
+
+type authenticatedUserKey struct{}
+
+// SetAuthenticatedUser records the user a request was authenticated as, for the access log
+// Called by the auth middleware; does nothing for requests that didn't go through AccessLog.
+func SetAuthenticatedUser(ctx context.Context, name string) {
+	if user, ok := ctx.Value(authenticatedUserKey{}).(*atomic.Value); ok {
+		user.Store(name)
+	}
+}
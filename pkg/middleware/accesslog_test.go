@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.Config{Output: &buf, Format: logger.FormatJSON, Level: logger.LevelInfo})
+
+	// Stands in for the auth middleware, which records the user it authenticated
+	handler := RequestID(AccessLog(log, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetAuthenticatedUser(r.Context(), "alice")
+		w.WriteHeader(http.StatusTeapot)
+		w.WriteHeader(http.StatusOK) // superfluous, must not change the logged status
+	})))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/user/alice/app/api", nil))
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("expected one JSON log event, got %q: %v", buf.String(), err)
+	}
+	want := map[string]interface{}{
+		"msg":        "request completed",
+		"level":      "INFO",
+		"method":     "POST",
+		"path":       "/user/alice/app/api",
+		"status":     float64(http.StatusTeapot),
+		"request_id": rec.Header().Get(RequestIDHeader),
+		"user_name":  "alice",
+	}
+	for key, value := range want {
+		if event[key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, event[key])
+		}
+	}
+	if _, ok := event["duration"]; !ok {
+		t.Error("expected a duration")
+	}
+}
+
+func TestAccessLog_Anonymous(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.Config{Output: &buf, Format: logger.FormatJSON})
+
+	handler := AccessLog(log, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	// A client-supplied identity header must not show up as the user
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Forwarded-User", "mallory")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("expected one JSON log event, got %q: %v", buf.String(), err)
+	}
+	if event["status"] != float64(http.StatusOK) {
+		t.Errorf("expected an implicit 200, got %v", event["status"])
+	}
+	if _, ok := event["user_name"]; ok {
+		t.Errorf("expected no user_name without authentication, got %v", event["user_name"])
+	}
+}
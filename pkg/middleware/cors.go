@@ -0,0 +1,101 @@
+// Package middleware provides HTTP middleware shared by every route of the proxy
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCORSMethods are announced to preflight requests when no methods are configured
+var DefaultCORSMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// corsMaxAge is how long browsers may cache a preflight response, in seconds
+const corsMaxAge = 600
+
+// CORSConfig configures cross-origin access to the app
+type CORSConfig struct {
+	AllowedOrigins []string // Exact origins such as "https://dashboards.example.com", or "*" for any
+	AllowedMethods []string // Methods announced to preflight requests (empty = DefaultCORSMethods)
+}
+
+// CORS answers cross-origin requests from allowed origins, e.g. a page embedding the app in an iframe
+// Allowed origins get credentialed access (cookies), except with the "*" wildcard, which
+// browsers only accept for requests without credentials.
+type CORS struct {
+	origins   map[string]bool
+	anyOrigin bool
+	methods   string
+}
+
+// NewCORS creates the CORS middleware; returns nil if no origins are configured
+func NewCORS(cfg CORSConfig) *CORS {
+	c := &CORS{origins: make(map[string]bool)}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		switch origin {
+		case "":
+		case "*":
+			c.anyOrigin = true
+		default:
+			c.origins[strings.ToLower(origin)] = true
+		}
+	}
+	if !c.anyOrigin && len(c.origins) == 0 {
+		return nil
+	}
+
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	c.methods = strings.ToUpper(strings.Join(methods, ", "))
+	return c
+}
+
+// Wrap adds CORS headers for allowed origins and answers their preflight requests with 204
+// Runs before authentication, so a preflight never gets the OAuth login redirect, which
+// browsers treat as a failed preflight. Requests from other origins pass through unchanged.
+func (c *CORS) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if !c.allows(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c.anyOrigin {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		// Preflight: answered here, never forwarded to the app
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", c.methods)
+			if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				h.Set("Access-Control-Allow-Headers", requested)
+			}
+			h.Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allows reports whether origin may access the app
+func (c *CORS) allows(origin string) bool {
+	return c.anyOrigin || c.origins[strings.ToLower(origin)]
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	// Stands in for the OAuth middleware: anything reaching it without a cookie is redirected
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err != nil {
+			http.Redirect(w, r, "/hub/login", http.StatusFound)
+			return
+		}
+		_, _ = io.WriteString(w, "app")
+	})
+
+	credentialed := NewCORS(CORSConfig{AllowedOrigins: []string{"https://dashboards.example.com/", "https://Other.example.com"}}).Wrap(next)
+	wildcard := NewCORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"get", "post"}}).Wrap(next)
+
+	tests := []struct {
+		name        string
+		handler     http.Handler
+		method      string
+		origin      string
+		preflight   bool
+		cookie      bool
+		wantStatus  int
+		wantOrigin  string
+		wantCreds   string
+		wantMethods string
+	}{
+		{
+			name: "preflight from allowed origin", handler: credentialed,
+			method: http.MethodOptions, origin: "https://dashboards.example.com", preflight: true,
+			wantStatus: http.StatusNoContent, wantOrigin: "https://dashboards.example.com", wantCreds: "true",
+			wantMethods: "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS",
+		},
+		{
+			name: "credentialed request", handler: credentialed,
+			method: http.MethodGet, origin: "https://other.example.com", cookie: true,
+			wantStatus: http.StatusOK, wantOrigin: "https://other.example.com", wantCreds: "true",
+		},
+		{
+			name: "unauthenticated request still gets CORS headers on the redirect", handler: credentialed,
+			method: http.MethodGet, origin: "https://dashboards.example.com",
+			wantStatus: http.StatusFound, wantOrigin: "https://dashboards.example.com", wantCreds: "true",
+		},
+		{
+			name: "preflight from other origin passes through", handler: credentialed,
+			method: http.MethodOptions, origin: "https://evil.example.com", preflight: true,
+			wantStatus: http.StatusFound,
+		},
+		{
+			name: "same-origin request", handler: credentialed,
+			method: http.MethodGet, cookie: true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "wildcard preflight", handler: wildcard,
+			method: http.MethodOptions, origin: "https://anywhere.example.com", preflight: true,
+			wantStatus: http.StatusNoContent, wantOrigin: "*", wantMethods: "GET, POST",
+		},
+		{
+			name: "wildcard request without credentials", handler: wildcard,
+			method: http.MethodGet, origin: "https://anywhere.example.com", cookie: true,
+			wantStatus: http.StatusOK, wantOrigin: "*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/app/", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+				req.Header.Set("Access-Control-Request-Headers", "content-type, x-requested-with")
+			}
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: "session", Value: "token"})
+			}
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+
+			h := rec.Header()
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tt.wantOrigin, got)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+				t.Errorf("expected Access-Control-Allow-Credentials %q, got %q", tt.wantCreds, got)
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("expected Access-Control-Allow-Methods %q, got %q", tt.wantMethods, got)
+			}
+			if tt.wantMethods != "" {
+				if got := h.Get("Access-Control-Allow-Headers"); got != "content-type, x-requested-with" {
+					t.Errorf("expected requested headers to be allowed, got %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestNewCORS_Disabled(t *testing.T) {
+	if c := NewCORS(CORSConfig{AllowedOrigins: []string{"", " "}}); c != nil {
+		t.Errorf("expected nil middleware without origins, got %+v", c)
+	}
+}
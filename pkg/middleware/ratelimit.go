@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Default rate limit of the logs API, per client IP
+const (
+	DefaultAPIRateLimit = 10.0 // Requests per second
+	DefaultAPIRateBurst = 30
+)
+
+// rateLimiterIdleTTL is how long a client's bucket is kept after its last request
+const rateLimiterIdleTTL = 10 * time.Minute
+
+// RateLimitConfig configures the per-client token bucket
+type RateLimitConfig struct {
+	Rate       float64 // Requests per second a client may sustain
+	Burst      int     // Requests a client may make at once
+	TrustProxy bool    // Take the client IP from X-Forwarded-For set by an upstream proxy
+}
+
+// RateLimiter answers 429 Too Many Requests once a client IP exceeds its token bucket
+// Buckets of clients that have been idle for a while are dropped, so the map can't grow
+// without bound.
+type RateLimiter struct {
+	limit      rate.Limit
+	burst      int
+	trustProxy bool
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates the rate limiting middleware; returns nil if the rate is not positive
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	if cfg.Rate <= 0 {
+		return nil
+	}
+	burst := cfg.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		limit:      rate.Limit(cfg.Rate),
+		burst:      burst,
+		trustProxy: cfg.TrustProxy,
+		clients:    make(map[string]*clientLimiter),
+		lastSweep:  time.Now(),
+	}
+}
+
+// Wrap rejects requests over the client's limit with 429 and a Retry-After header
+// A nil RateLimiter passes every request through.
+func (rl *RateLimiter) Wrap(next http.Handler) http.Handler {
+	if rl == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait, ok := rl.allow(rl.clientIP(r), time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow takes a token from the client's bucket, or reports how long until one is available
+func (rl *RateLimiter) allow(client string, now time.Time) (time.Duration, bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) > rateLimiterIdleTTL {
+		for ip, c := range rl.clients {
+			if now.Sub(c.lastSeen) > rateLimiterIdleTTL {
+				delete(rl.clients, ip)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	c, ok := rl.clients[client]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.clients[client] = c
+	}
+	c.lastSeen = now
+
+	res := c.limiter.ReserveN(now, 1)
+	if delay := res.DelayFrom(now); delay > 0 {
+		// Don't hold on to a token the request won't use
+		res.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
+
+// clientIP returns the IP a request is counted against
+// Behind a trusted proxy that is the rightmost X-Forwarded-For entry, the one the proxy
+// appended: entries to its left come from the client and can be anything.
+func (rl *RateLimiter) clientIP(r *http.Request) string {
+	if rl.trustProxy {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			last := values[len(values)-1]
+			if i := strings.LastIndexByte(last, ','); i >= 0 {
+				last = last[i+1:]
+			}
+			if ip := strings.TrimSpace(last); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	get := func(h http.Handler, remoteAddr, forwarded string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/logs/all", nil)
+		req.RemoteAddr = remoteAddr
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("burst above the limit", func(t *testing.T) {
+		h := NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 3}).Wrap(next)
+
+		for i := 0; i < 3; i++ {
+			if rec := get(h, "10.0.0.1:1234", ""); rec.Code != http.StatusOK {
+				t.Fatalf("request %d: expected status 200 within the burst, got %d", i+1, rec.Code)
+			}
+		}
+
+		rec := get(h, "10.0.0.1:5678", "")
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status 429 above the burst, got %d", rec.Code)
+		}
+		retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+		if err != nil || retryAfter < 1 {
+			t.Errorf("expected a Retry-After of at least 1 second, got %q", rec.Header().Get("Retry-After"))
+		}
+
+		// Other clients have their own bucket
+		if rec := get(h, "10.0.0.2:1234", ""); rec.Code != http.StatusOK {
+			t.Errorf("expected status 200 for another client, got %d", rec.Code)
+		}
+	})
+
+	t.Run("requests within the limit", func(t *testing.T) {
+		h := NewRateLimiter(RateLimitConfig{Rate: 100, Burst: 1}).Wrap(next)
+
+		for i := 0; i < 5; i++ {
+			if rec := get(h, "10.0.0.1:1234", ""); rec.Code != http.StatusOK {
+				t.Fatalf("request %d: expected status 200, got %d", i+1, rec.Code)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+
+	t.Run("forwarded client IP", func(t *testing.T) {
+		trusted := NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 1, TrustProxy: true}).Wrap(next)
+		untrusted := NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 1}).Wrap(next)
+
+		// Everyone arrives through the same upstream proxy
+		for _, client := range []string{"192.0.2.1", "198.51.100.7, 192.0.2.2"} {
+			if rec := get(trusted, "10.0.0.9:1234", client); rec.Code != http.StatusOK {
+				t.Errorf("expected status 200 for forwarded client %q, got %d", client, rec.Code)
+			}
+		}
+		if rec := get(untrusted, "10.0.0.9:1234", "192.0.2.1"); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		if rec := get(untrusted, "10.0.0.9:1234", "192.0.2.2"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("expected X-Forwarded-For to be ignored without TrustProxy, got %d", rec.Code)
+		}
+	})
+
+	t.Run("spoofed forwarded entries", func(t *testing.T) {
+		h := NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 1, TrustProxy: true}).Wrap(next)
+
+		// The client makes up the left entry; the proxy appends the address it saw
+		if rec := get(h, "10.0.0.9:1234", "203.0.113.1, 192.0.2.1"); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		if rec := get(h, "10.0.0.9:1234", "203.0.113.2, 192.0.2.1"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("expected a new spoofed entry not to escape the limit, got %d", rec.Code)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		rl := NewRateLimiter(RateLimitConfig{Rate: 0, Burst: 30})
+		if rl != nil {
+			t.Fatal("expected no rate limiter for a zero rate")
+		}
+		h := rl.Wrap(next)
+		for i := 0; i < 50; i++ {
+			if rec := get(h, "10.0.0.1:1234", ""); rec.Code != http.StatusOK {
+				t.Fatalf("request %d: expected status 200 without a limit, got %d", i+1, rec.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID on the request, the response and the upstream request
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds incoming request IDs, which end up in every log line of the request
+const maxRequestIDLength = 128
+
+type requestIDContextKey struct{}
+
+// RequestID gives every request a correlation ID, e.g. to trace it from the access log to the app's logs
+// An X-Request-ID set by an ingress in front of the proxy is kept so the ID spans both hops;
+// otherwise, or if it is too long or not printable, a UUID v4 is generated. The ID is stored
+// in the request context and set on the request and response headers.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), requestIDContextKey{}, id))
+		r.Header.Set(RequestIDHeader, id)
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestIDFromContext returns the ID set by RequestID, or "" outside of it
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// validRequestID reports whether an incoming ID is safe to reuse: non-empty, bounded, printable ASCII
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+		if got := r.Header.Get(RequestIDHeader); got != seen {
+			t.Errorf("expected request header %q to match the context ID %q", got, seen)
+		}
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		wantKept bool
+	}{
+		{name: "generated", incoming: ""},
+		{name: "kept from ingress", incoming: "ingress-1234", wantKept: true},
+		{name: "too long", incoming: strings.Repeat("a", maxRequestIDLength+1)},
+		{name: "not printable", incoming: "id\x00with\nnewline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(RequestIDHeader); got != seen {
+				t.Errorf("expected response header %q to match the context ID %q", got, seen)
+			}
+			if tt.wantKept {
+				if seen != tt.incoming {
+					t.Errorf("expected incoming ID %q to be kept, got %q", tt.incoming, seen)
+				}
+				return
+			}
+			if parsed, err := uuid.Parse(seen); err != nil || parsed.Version() != 4 {
+				t.Errorf("expected a generated UUID v4, got %q", seen)
+			}
+		})
+	}
+}
+
+func TestRequestIDFromContext_Missing(t *testing.T) {
+	if id := RequestIDFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()); id != "" {
+		t.Errorf("expected no request ID outside the middleware, got %q", id)
+	}
+}
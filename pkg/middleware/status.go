@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// StatusRecorder wraps a ResponseWriter to capture the response status code
+// Implements Hijacker, Flusher and Pusher so WebSocket upgrades, streaming and server push keep working
+type StatusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// NewStatusRecorder wraps w; the status is 200 until the handler writes another one
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Status returns the status code written by the handler
+func (sr *StatusRecorder) Status() int {
+	return sr.status
+}
+
+func (sr *StatusRecorder) WriteHeader(status int) {
+	if !sr.wroteHeader {
+		sr.status = status
+		sr.wroteHeader = true
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (sr *StatusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// Hijack implements http.Hijacker; a hijacked connection is recorded as 101 Switching Protocols
+func (sr *StatusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("StatusRecorder: underlying ResponseWriter does not implement http.Hijacker")
+	}
+	sr.status = http.StatusSwitchingProtocols
+	sr.wroteHeader = true
+	return hijacker.Hijack()
+}
+
+// Flush implements http.Flusher
+func (sr *StatusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Push implements http.Pusher so HTTP/2 server push (interim page assets) passes through
+func (sr *StatusRecorder) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := sr.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
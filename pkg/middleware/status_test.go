@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusRecorder(t *testing.T) {
+	t.Run("defaults to 200", func(t *testing.T) {
+		sr := NewStatusRecorder(httptest.NewRecorder())
+		_, _ = sr.Write([]byte("ok"))
+		if sr.Status() != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, sr.Status())
+		}
+	})
+
+	t.Run("first status wins", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		sr := NewStatusRecorder(rec)
+		sr.WriteHeader(http.StatusNotFound)
+		sr.WriteHeader(http.StatusInternalServerError)
+		if sr.Status() != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, sr.Status())
+		}
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status %d written through, got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("flush through ResponseController", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		sr := NewStatusRecorder(rec)
+		if err := http.NewResponseController(sr).Flush(); err != nil {
+			t.Fatalf("expected flush to pass through, got %v", err)
+		}
+		if !rec.Flushed {
+			t.Error("expected the underlying writer to be flushed")
+		}
+	})
+
+	t.Run("push not supported", func(t *testing.T) {
+		sr := NewStatusRecorder(httptest.NewRecorder())
+		if err := sr.Push("/static/app.js", nil); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("expected http.ErrNotSupported, got %v", err)
+		}
+	})
+
+	t.Run("hijack not supported", func(t *testing.T) {
+		sr := NewStatusRecorder(httptest.NewRecorder())
+		if _, _, err := sr.Hijack(); err == nil {
+			t.Error("expected error for a writer that can't be hijacked, got nil")
+		}
+	})
+}
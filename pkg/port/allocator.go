@@ -32,6 +32,30 @@ func Allocate(preferredPort int) (int, error) {
 	return preferredPort, nil
 }
 
+// ScanRange returns the first available port from low to high, inclusive
+// For deployments that reserve a port range per user instead of using random ports.
+func ScanRange(low, high int) (int, error) {
+	if low < 1 || high > 65535 || low > high {
+		return 0, fmt.Errorf("invalid port range %d-%d: must be within 1-65535 with min <= max", low, high)
+	}
+	for port := low; port <= high; port++ {
+		if IsAvailable(port) {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no available port in range %d-%d", low, high)
+}
+
+// AllocateFromRange returns preferredPort if it is available, otherwise the first
+// available port in low..high
+// A preferredPort of 0 goes straight to the range.
+func AllocateFromRange(low, high, preferredPort int) (int, error) {
+	if preferredPort != 0 && IsAvailable(preferredPort) {
+		return preferredPort, nil
+	}
+	return ScanRange(low, high)
+}
+
 // IsAvailable checks if a port is available for listening
 func IsAvailable(port int) bool {
 	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
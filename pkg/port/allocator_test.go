@@ -0,0 +1,102 @@
+package port
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// occupyRange listens on n consecutive ports and returns the first
+// The listeners are closed when the test ends.
+func occupyRange(t *testing.T, n int) int {
+	t.Helper()
+	for attempt := 0; attempt < 20; attempt++ {
+		first, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		low := first.Addr().(*net.TCPAddr).Port
+		listeners := []net.Listener{first}
+		for port := low + 1; port < low+n; port++ {
+			l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+			if err != nil {
+				break
+			}
+			listeners = append(listeners, l)
+		}
+		if len(listeners) == n {
+			t.Cleanup(func() {
+				for _, l := range listeners {
+					l.Close()
+				}
+			})
+			return low
+		}
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+	t.Fatalf("failed to occupy %d consecutive ports", n)
+	return 0
+}
+
+func TestScanRange(t *testing.T) {
+	t.Run("full range occupied", func(t *testing.T) {
+		low := occupyRange(t, 3)
+		_, err := ScanRange(low, low+2)
+		if err == nil {
+			t.Fatal("expected error for a fully occupied range, got nil")
+		}
+		if !strings.Contains(err.Error(), "no available port") {
+			t.Errorf("expected a no available port error, got %v", err)
+		}
+	})
+
+	t.Run("skips occupied ports", func(t *testing.T) {
+		low := occupyRange(t, 2)
+		// low+2 is free unless something else grabbed it since
+		if !IsAvailable(low + 2) {
+			t.Skip("port after the occupied range is in use")
+		}
+		port, err := ScanRange(low, low+2)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if port != low+2 {
+			t.Errorf("expected port %d, got %d", low+2, port)
+		}
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		for _, r := range [][2]int{{0, 10}, {9000, 8000}, {65000, 70000}} {
+			if _, err := ScanRange(r[0], r[1]); err == nil {
+				t.Errorf("expected error for range %d-%d, got nil", r[0], r[1])
+			}
+		}
+	})
+}
+
+func TestAllocateFromRange(t *testing.T) {
+	low := occupyRange(t, 2)
+
+	t.Run("preferred port", func(t *testing.T) {
+		preferred, err := Allocate(0)
+		if err != nil {
+			t.Fatalf("failed to find a free port: %v", err)
+		}
+		port, err := AllocateFromRange(low, low+1, preferred)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if port != preferred {
+			t.Errorf("expected preferred port %d, got %d", preferred, port)
+		}
+	})
+
+	t.Run("preferred port taken and range full", func(t *testing.T) {
+		if _, err := AllocateFromRange(low, low+1, low); err == nil {
+			t.Error("expected error when the preferred port and the whole range are taken, got nil")
+		}
+	})
+}
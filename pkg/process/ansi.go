@@ -0,0 +1,27 @@
+// Package process - Stripping ANSI escape codes from captured output (--strip-ansi)
+package process
+
+import "regexp"
+
+// ansiEscape matches ANSI escape sequences: CSI sequences such as colors and cursor movement,
+// OSC sequences such as terminal titles and hyperlinks, and the remaining short escapes
+// (character set selection, cursor save/restore, ...)
+var ansiEscape = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[ -/]*[0-~])`)
+
+// StripANSI removes ANSI escape sequences from s
+func StripANSI(s string) string {
+	if !containsESC(s) {
+		return s
+	}
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+// containsESC reports whether s contains an escape character, skipping the regexp for plain lines
+func containsESC(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x1b {
+			return true
+		}
+	}
+	return false
+}
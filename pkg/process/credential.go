@@ -0,0 +1,83 @@
+// Package process - User the subprocess runs as
+package process
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// RunAs is a user the subprocess runs as instead of the proxy's own
+type RunAs struct {
+	User       *user.User          // Account of the user, for HOME and USER
+	Credential *syscall.Credential // Set as the process credential (see Config.Credential)
+}
+
+// LookupRunAs resolves the user the subprocess should run as, by name or by uid
+// uid and gid are -1 when not set; without gid the user's primary group is used.
+// The user must exist, so the app can't end up under a uid without a home or groups.
+// Supplementary groups are the user's own, not inherited from the proxy.
+func LookupRunAs(name string, uid, gid int) (*RunAs, error) {
+	if name == "" && uid < 0 {
+		if gid >= 0 {
+			return nil, fmt.Errorf("--gid requires --run-as-user or --run-as-uid")
+		}
+		return nil, nil
+	}
+	if name != "" && uid >= 0 {
+		return nil, fmt.Errorf("--run-as-user and --run-as-uid are mutually exclusive")
+	}
+
+	var u *user.User
+	var err error
+	if name != "" {
+		u, err = user.Lookup(name)
+	} else {
+		u, err = user.LookupId(strconv.Itoa(uid))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("run-as user not found: %w", err)
+	}
+
+	cred := &syscall.Credential{}
+	userID, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid %q for user %s: %w", u.Uid, u.Username, err)
+	}
+	cred.Uid = uint32(userID)
+
+	if gid >= 0 {
+		if _, err := user.LookupGroupId(strconv.Itoa(gid)); err != nil {
+			return nil, fmt.Errorf("run-as group not found: %w", err)
+		}
+		cred.Gid = uint32(gid)
+	} else {
+		groupID, err := strconv.ParseUint(u.Gid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gid %q for user %s: %w", u.Gid, u.Username, err)
+		}
+		cred.Gid = uint32(groupID)
+	}
+
+	// Without group membership data (e.g. no /etc/group entry) the app gets no supplementary groups
+	if groups, err := u.GroupIds(); err == nil {
+		for _, g := range groups {
+			if id, err := strconv.ParseUint(g, 10, 32); err == nil {
+				cred.Groups = append(cred.Groups, uint32(id))
+			}
+		}
+	}
+
+	return &RunAs{User: u, Credential: cred}, nil
+}
+
+// Env returns the environment variables describing the user, so the app doesn't use the
+// proxy's HOME (e.g. /root) for caches and config
+func (r *RunAs) Env() map[string]string {
+	return map[string]string{
+		"HOME":    r.User.HomeDir,
+		"USER":    r.User.Username,
+		"LOGNAME": r.User.Username,
+	}
+}
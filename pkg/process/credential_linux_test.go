@@ -0,0 +1,140 @@
+//go:build linux
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+// lookupNobody returns the nobody account, which the tests run the subprocess as
+func lookupNobody(t *testing.T) *user.User {
+	t.Helper()
+	u, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skipf("no nobody user: %v", err)
+	}
+	return u
+}
+
+// readIDs returns the real uid and gid of a process from /proc/<pid>/status
+func readIDs(t *testing.T, pid int) (string, string) {
+	t.Helper()
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		t.Fatalf("failed to read process status: %v", err)
+	}
+	var uid, gid string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "Uid:":
+			uid = fields[1]
+		case "Gid:":
+			gid = fields[1]
+		}
+	}
+	return uid, gid
+}
+
+func TestLookupRunAs(t *testing.T) {
+	nobody := lookupNobody(t)
+
+	t.Run("not set", func(t *testing.T) {
+		runAs, err := LookupRunAs("", -1, -1)
+		if err != nil || runAs != nil {
+			t.Errorf("expected nil without a user, got %+v, %v", runAs, err)
+		}
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		runAs, err := LookupRunAs("nobody", -1, -1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got := fmt.Sprint(runAs.Credential.Uid); got != nobody.Uid {
+			t.Errorf("expected uid %s, got %s", nobody.Uid, got)
+		}
+		if got := fmt.Sprint(runAs.Credential.Gid); got != nobody.Gid {
+			t.Errorf("expected primary gid %s, got %s", nobody.Gid, got)
+		}
+		if env := runAs.Env(); env["HOME"] != nobody.HomeDir || env["USER"] != "nobody" {
+			t.Errorf("expected the user's HOME and USER, got %v", env)
+		}
+	})
+
+	t.Run("by uid with gid", func(t *testing.T) {
+		uid, err := strconv.Atoi(nobody.Uid)
+		if err != nil {
+			t.Fatalf("invalid uid %q: %v", nobody.Uid, err)
+		}
+		runAs, err := LookupRunAs("", uid, 0)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if runAs.User.Username != "nobody" {
+			t.Errorf("expected user nobody, got %s", runAs.User.Username)
+		}
+		if runAs.Credential.Gid != 0 {
+			t.Errorf("expected gid 0, got %d", runAs.Credential.Gid)
+		}
+	})
+
+	for _, tt := range []struct {
+		name     string
+		user     string
+		uid, gid int
+	}{
+		{name: "unknown user", user: "no-such-user-jhub", uid: -1, gid: -1},
+		{name: "unknown uid", uid: 2000000000, gid: -1},
+		{name: "user and uid", user: "nobody", uid: 0, gid: -1},
+		{name: "gid alone", uid: -1, gid: 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LookupRunAs(tt.user, tt.uid, tt.gid); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestManager_RunAsCredential(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("switching users needs root")
+	}
+	nobody := lookupNobody(t)
+	runAs, err := LookupRunAs("nobody", -1, -1)
+	if err != nil {
+		t.Fatalf("failed to look up nobody: %v", err)
+	}
+
+	mgr, err := NewManager(Config{
+		Command:    []string{"sleep", "30"},
+		Credential: runAs.Credential,
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+	}()
+
+	uid, gid := readIDs(t, mgr.GetPID())
+	if uid != nobody.Uid || gid != nobody.Gid {
+		t.Errorf("expected the app to run as %s:%s, got %s:%s", nobody.Uid, nobody.Gid, uid, gid)
+	}
+}
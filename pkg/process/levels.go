@@ -0,0 +1,96 @@
+// Package process - Log level detection for subprocess output
+package process
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Log levels detected in subprocess output
+const (
+	LevelError   = "error"
+	LevelWarn    = "warn"
+	LevelInfo    = "info"
+	LevelDebug   = "debug"
+	LevelUnknown = "unknown" // No recognizable level marker
+)
+
+var (
+	// Structured loggers: level=info, "level": "error", lvl=warn
+	structuredLevelPattern = regexp.MustCompile(`(?i)\b(?:level|lvl|severity)["']?\s*[=:]\s*["']?(fatal|critical|error|err|warning|warn|info|debug|trace)\b`)
+
+	// Plain-text loggers (Python logging, uvicorn, tornado): "ERROR:", "[WARNING]", "INFO     "
+	// Only upper-case markers count so words like "info" in prose don't match
+	plainLevelPattern = regexp.MustCompile(`\b(FATAL|CRITICAL|ERROR|WARNING|WARN|INFO|DEBUG|TRACE)\b`)
+
+	// Single-letter prefixes used by tornado/Jupyter: "[E 2025-01-15 10:30:00.000 ServerApp]"
+	tornadoLevelPattern = regexp.MustCompile(`^\[([EWID]) \d`)
+)
+
+// DetectLevel guesses the log level of a subprocess output line
+// Structured level fields win over plain-text markers; among plain-text markers the
+// leftmost one is used, so "INFO: retrying after error" counts as info.
+// Python tracebacks count as errors. Returns LevelUnknown when no marker is found.
+func DetectLevel(line string) string {
+	if match := structuredLevelPattern.FindStringSubmatch(line); match != nil {
+		return normalizeLevel(match[1])
+	}
+	if match := tornadoLevelPattern.FindStringSubmatch(line); match != nil {
+		return normalizeLevel(match[1])
+	}
+	if match := plainLevelPattern.FindStringSubmatch(line); match != nil {
+		return normalizeLevel(match[1])
+	}
+	if strings.HasPrefix(line, "Traceback (most recent call last)") {
+		return LevelError
+	}
+	return LevelUnknown
+}
+
+// normalizeLevel maps level spellings onto the four detected levels
+func normalizeLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "fatal", "critical", "error", "err", "e":
+		return LevelError
+	case "warning", "warn", "w":
+		return LevelWarn
+	case "info", "i":
+		return LevelInfo
+	case "debug", "trace", "d":
+		return LevelDebug
+	default:
+		return LevelUnknown
+	}
+}
+
+// LevelSummary counts buffered log lines by detected level and by stream
+type LevelSummary struct {
+	Levels  map[string]int `json:"levels"`  // error, warn, info, debug, unknown
+	Streams map[string]int `json:"streams"` // stdout, stderr
+	Total   int            `json:"total"`   // Lines scanned (currently buffered)
+}
+
+// GetLevelSummary scans the buffered entries and counts them by level and stream
+func (lb *LogBuffer) GetLevelSummary() LevelSummary {
+	summary := LevelSummary{
+		Levels: map[string]int{
+			LevelError:   0,
+			LevelWarn:    0,
+			LevelInfo:    0,
+			LevelDebug:   0,
+			LevelUnknown: 0,
+		},
+		Streams: map[string]int{
+			"stdout": 0,
+			"stderr": 0,
+		},
+	}
+
+	for _, entry := range lb.GetRecent(-1) {
+		summary.Levels[DetectLevel(entry.Line)]++
+		summary.Streams[entry.Stream]++
+		summary.Total++
+	}
+
+	return summary
+}
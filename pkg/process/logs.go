@@ -6,9 +6,14 @@ import (
 	"container/ring"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/redact"
 )
 
 // LogEntry represents a single log line from the subprocess
@@ -17,6 +22,7 @@ type LogEntry struct {
 	Stream    string    `json:"stream"` // "stdout" or "stderr"
 	Line      string    `json:"line"`
 	PID       int       `json:"pid"`
+	Raw       string    `json:"-"` // Line before ANSI escape codes were stripped (empty if nothing was stripped)
 }
 
 // LogBuffer is a thread-safe circular buffer for subprocess logs
@@ -29,6 +35,270 @@ type LogBuffer struct {
 	lines    int // Total lines captured (for stats)
 	logFile  *os.File
 	logPath  string
+	sink     LogSink          // Optional external sink receiving every appended entry
+	redactor *redact.Redactor // Masks secret values before entries are stored or shipped
+
+	timeFormat string         // Layout of timestamps in the log file
+	timeZone   *time.Location // Zone of timestamps in the log file
+	stripANSI  bool           // Remove ANSI escape codes before entries are stored or shipped
+
+	maxAge       time.Duration // Entries older than this are hidden and pruned from the file (0 = keep forever)
+	fileSize     int64         // Bytes written to the log file
+	fileMarks    []fileMark    // End of each unexpired file line, oldest first (only tracked with maxAge)
+	expiredLines int           // Lines pruned from the log file for age (lifetime)
+	stopPrune    chan struct{} // Closed to stop the prune goroutine (nil if not running)
+
+	maxFileBytes   int64 // Log file size that triggers truncation (0 = unbounded)
+	truncatedBytes int64 // Bytes cut from the log file for size (lifetime)
+
+	noSync bool // Leave flushing the log file to disk to the OS instead of syncing every line
+}
+
+// fileMark records where a log file line ends, so expired lines can be cut without parsing timestamps
+type fileMark struct {
+	timestamp time.Time
+	end       int64
+}
+
+// DefaultLogPruneInterval is how often expired lines are pruned from the log file
+const DefaultLogPruneInterval = time.Minute
+
+// DefaultLogTimestampFormat is the layout of timestamps in the persistent log file
+const DefaultLogTimestampFormat = "2006-01-02 15:04:05.000"
+
+// LogSink receives a copy of every captured log entry (e.g. to ship it to Loki)
+// Send is called while capturing output, so it must never block
+type LogSink interface {
+	Send(entry LogEntry)
+}
+
+// MultiSink forwards every entry to each of its sinks, e.g. Loki and an OTLP collector
+type MultiSink []LogSink
+
+// Send implements LogSink
+func (m MultiSink) Send(entry LogEntry) {
+	for _, sink := range m {
+		sink.Send(entry)
+	}
+}
+
+// SetSink forwards every subsequently appended entry to sink
+func (lb *LogBuffer) SetSink(sink LogSink) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.sink = sink
+}
+
+// SetRedactor masks secret values in every subsequently appended entry
+// Applied before the entry reaches the buffer, the log file and the sink
+func (lb *LogBuffer) SetRedactor(redactor *redact.Redactor) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.redactor = redactor
+}
+
+// SetStripANSI removes ANSI escape codes (colors, cursor movement) from every subsequently appended entry
+// The original line stays available as LogEntry.Raw in the memory buffer.
+func (lb *LogBuffer) SetStripANSI(strip bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.stripANSI = strip
+}
+
+// SetTimestampFormat sets the layout and zone of timestamps written to the log file
+// An empty format keeps DefaultLogTimestampFormat; a nil zone means local time
+func (lb *LogBuffer) SetTimestampFormat(format string, loc *time.Location) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if format == "" {
+		format = DefaultLogTimestampFormat
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	lb.timeFormat = format
+	lb.timeZone = loc
+}
+
+// SetRetention hides entries older than maxAge and prunes them from the log file every pruneInterval
+// maxAge <= 0 keeps entries until they are evicted by capacity; pruneInterval <= 0 uses
+// DefaultLogPruneInterval. Pruning stops when the buffer is closed.
+func (lb *LogBuffer) SetRetention(maxAge, pruneInterval time.Duration) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if lb.stopPrune != nil {
+		close(lb.stopPrune)
+		lb.stopPrune = nil
+	}
+	if maxAge <= 0 {
+		lb.maxAge = 0
+		lb.fileMarks = nil
+		return
+	}
+	if pruneInterval <= 0 {
+		pruneInterval = DefaultLogPruneInterval
+	}
+
+	lb.maxAge = maxAge
+	lb.stopPrune = make(chan struct{})
+	go lb.pruneLoop(pruneInterval, lb.stopPrune)
+}
+
+// SetMaxFileBytes caps the size of the persistent log file
+// Once the file grows past maxBytes, its oldest lines are cut so that about half of the
+// cap is left, which keeps a chatty app from rewriting the file on every line.
+// maxBytes <= 0 lets the file grow without bound.
+func (lb *LogBuffer) SetMaxFileBytes(maxBytes int64) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+	lb.maxFileBytes = maxBytes
+}
+
+// SetNoSync skips the fsync after every line written to the log file
+// Lines still reach the file (and readers) right away; only lines the OS hasn't
+// flushed yet are lost if the machine crashes, not when the proxy or app does.
+func (lb *LogBuffer) SetNoSync(noSync bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.noSync = noSync
+}
+
+// pruneLoop prunes expired lines from the log file until stop is closed
+func (lb *LogBuffer) pruneLoop(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := lb.Prune(); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to prune log file: %v\n", err)
+			}
+		}
+	}
+}
+
+// Prune rewrites the log file without the lines older than the retention max age
+// Lines are cut by the byte offsets recorded when they were written, so any timestamp format works.
+func (lb *LogBuffer) Prune() error {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if lb.maxAge <= 0 || lb.logFile == nil {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-lb.maxAge)
+	expired := 0
+	for expired < len(lb.fileMarks) && lb.fileMarks[expired].timestamp.Before(cutoff) {
+		expired++
+	}
+	if expired == 0 {
+		return nil
+	}
+	offset := lb.fileMarks[expired-1].end
+
+	if err := lb.rewriteFileLocked(offset); err != nil {
+		return err
+	}
+
+	lb.fileMarks = append(lb.fileMarks[:0], lb.fileMarks[expired:]...)
+	for i := range lb.fileMarks {
+		lb.fileMarks[i].end -= offset
+	}
+	lb.fileSize -= offset
+	lb.expiredLines += expired
+	return nil
+}
+
+// truncateFileLocked cuts the oldest lines from the log file, keeping about half of the
+// size cap; the caller must hold lb.mu
+// The cut is made at a line boundary so the file always starts with a whole line.
+func (lb *LogBuffer) truncateFileLocked() error {
+	start := lb.fileSize - lb.maxFileBytes/2
+	if start < 0 {
+		start = 0
+	}
+
+	file, err := os.Open(lb.logPath)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to seek log file: %w", err)
+	}
+	// Skip the rest of the line the cut falls into; a single line longer than the cap is dropped whole
+	partial, err := bufio.NewReader(file).ReadString('\n')
+	file.Close()
+	offset := start + int64(len(partial))
+	if err != nil {
+		offset = lb.fileSize
+	}
+
+	if err := lb.rewriteFileLocked(offset); err != nil {
+		return err
+	}
+
+	cut := 0
+	for cut < len(lb.fileMarks) && lb.fileMarks[cut].end <= offset {
+		cut++
+	}
+	lb.fileMarks = append(lb.fileMarks[:0], lb.fileMarks[cut:]...)
+	for i := range lb.fileMarks {
+		lb.fileMarks[i].end -= offset
+	}
+	lb.fileSize -= offset
+	lb.truncatedBytes += offset
+	return nil
+}
+
+// rewriteFileLocked replaces the log file with its content from offset on; the caller must hold lb.mu
+// The new file is written next to the old one and renamed over it, so readers never see a partial file.
+func (lb *LogBuffer) rewriteFileLocked(offset int64) error {
+	src, err := os.Open(lb.logPath)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer src.Close()
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek log file: %w", err)
+	}
+
+	dst, err := os.CreateTemp(filepath.Dir(lb.logPath), "jhub-app-proxy-*.log")
+	if err != nil {
+		return fmt.Errorf("failed to create pruned log file: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return fmt.Errorf("failed to write pruned log file: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return fmt.Errorf("failed to write pruned log file: %w", err)
+	}
+	if err := os.Rename(dst.Name(), lb.logPath); err != nil {
+		os.Remove(dst.Name())
+		return fmt.Errorf("failed to replace log file: %w", err)
+	}
+
+	// Keep appending to the pruned file
+	logFile, err := os.OpenFile(lb.logPath, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		lb.logFile.Close()
+		lb.logFile = nil
+		return fmt.Errorf("failed to reopen log file: %w", err)
+	}
+	lb.logFile.Close()
+	lb.logFile = logFile
+	return nil
 }
 
 // NewLogBuffer creates a new log buffer with the specified capacity
@@ -46,10 +316,12 @@ func NewLogBuffer(capacity int) *LogBuffer {
 	}
 
 	return &LogBuffer{
-		buffer:   ring.New(capacity),
-		capacity: capacity,
-		logFile:  logFile,
-		logPath:  logPath,
+		buffer:     ring.New(capacity),
+		capacity:   capacity,
+		logFile:    logFile,
+		logPath:    logPath,
+		timeFormat: DefaultLogTimestampFormat,
+		timeZone:   time.Local,
 	}
 }
 
@@ -58,25 +330,49 @@ func (lb *LogBuffer) Append(entry LogEntry) {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
 
+	entry.Line = lb.redactor.String(entry.Line)
+	if lb.stripANSI {
+		if stripped := StripANSI(entry.Line); stripped != entry.Line {
+			entry.Raw = entry.Line
+			entry.Line = stripped
+		}
+	}
+
 	// Add to memory buffer
 	lb.buffer.Value = entry
 	lb.buffer = lb.buffer.Next()
 	lb.lines++
 
+	if lb.sink != nil {
+		lb.sink.Send(entry)
+	}
+
 	// Write to persistent log file
 	if lb.logFile != nil {
 		// Format: [timestamp] [stream] line
 		logLine := fmt.Sprintf("[%s] [%s] %s\n",
-			entry.Timestamp.Format("2006-01-02 15:04:05.000"),
+			entry.Timestamp.In(lb.timeZone).Format(lb.timeFormat),
 			entry.Stream,
 			entry.Line)
-		if _, err := lb.logFile.WriteString(logLine); err != nil {
+		n, err := lb.logFile.WriteString(logLine)
+		if err != nil {
 			// Log write errors are logged but don't stop execution
 			fmt.Fprintf(os.Stderr, "failed to write log to file: %v\n", err)
 		}
-		if err := lb.logFile.Sync(); err != nil {
-			// Sync errors are logged but don't stop execution
-			fmt.Fprintf(os.Stderr, "failed to sync log file: %v\n", err)
+		lb.fileSize += int64(n)
+		if lb.maxAge > 0 {
+			lb.fileMarks = append(lb.fileMarks, fileMark{timestamp: entry.Timestamp, end: lb.fileSize})
+		}
+		if !lb.noSync {
+			if err := lb.logFile.Sync(); err != nil {
+				// Sync errors are logged but don't stop execution
+				fmt.Fprintf(os.Stderr, "failed to sync log file: %v\n", err)
+			}
+		}
+		if lb.maxFileBytes > 0 && lb.fileSize > lb.maxFileBytes {
+			if err := lb.truncateFileLocked(); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to truncate log file: %v\n", err)
+			}
 		}
 	}
 }
@@ -87,53 +383,64 @@ func (lb *LogBuffer) GetRecent(n int) []LogEntry {
 	lb.mu.RLock()
 	defer lb.mu.RUnlock()
 
-	if n <= 0 || n > lb.capacity {
-		n = lb.capacity
-	}
-
-	// Calculate actual available entries
-	available := lb.lines
-	if available > lb.capacity {
-		available = lb.capacity
-	}
+	return lb.getRecentLocked(n)
+}
 
-	if n > available {
+// getRecentLocked implements GetRecent; the caller must hold lb.mu
+func (lb *LogBuffer) getRecentLocked(n int) []LogEntry {
+	oldest, available := lb.unexpiredLocked()
+	if n <= 0 || n > available {
 		n = available
 	}
 
 	entries := make([]LogEntry, 0, n)
 
-	// Start from the oldest entry in the ring
-	start := lb.buffer
-	if available < lb.capacity {
-		// If we haven't filled the buffer yet, find the first non-nil entry
-		for i := 0; i < lb.capacity; i++ {
-			if start.Value == nil {
-				start = start.Next()
-			} else {
-				break
-			}
+	// Skip the older entries and collect the last n
+	current := oldest.Move(available - n)
+	for i := 0; i < n; i++ {
+		if entry, ok := current.Value.(LogEntry); ok {
+			entries = append(entries, entry)
 		}
+		current = current.Next()
 	}
 
-	// Collect entries
-	current := start
-	for i := 0; i < available; i++ {
-		if current.Value != nil {
-			entry, ok := current.Value.(LogEntry)
-			if ok {
-				entries = append(entries, entry)
-			}
-		}
-		current = current.Next()
+	return entries
+}
+
+// availableLocked returns the number of entries held in the ring; the caller must hold lb.mu
+func (lb *LogBuffer) availableLocked() int {
+	if lb.lines > lb.capacity {
+		return lb.capacity
 	}
+	return lb.lines
+}
 
-	// Return only the last N entries
-	if len(entries) > n {
-		entries = entries[len(entries)-n:]
+// oldestLocked returns the ring element holding the oldest buffered entry; the caller must hold lb.mu
+// lb.buffer always points at the next slot to write, so the oldest entry sits
+// exactly `available` slots behind it (for a full ring that is lb.buffer itself,
+// including the single-slot ring where every append overwrites the same element)
+func (lb *LogBuffer) oldestLocked() *ring.Ring {
+	return lb.buffer.Move(-lb.availableLocked())
+}
+
+// unexpiredLocked returns the oldest buffered entry within the retention max age and the
+// number of entries from there on; the caller must hold lb.mu
+// Entries arrive in time order, so expired entries are always at the start of the buffer.
+func (lb *LogBuffer) unexpiredLocked() (*ring.Ring, int) {
+	oldest, available := lb.oldestLocked(), lb.availableLocked()
+	if lb.maxAge <= 0 {
+		return oldest, available
 	}
 
-	return entries
+	cutoff := time.Now().Add(-lb.maxAge)
+	for available > 0 {
+		if entry, ok := oldest.Value.(LogEntry); ok && !entry.Timestamp.Before(cutoff) {
+			break
+		}
+		oldest = oldest.Next()
+		available--
+	}
+	return oldest, available
 }
 
 // GetSince returns all log entries since the given timestamp
@@ -143,31 +450,11 @@ func (lb *LogBuffer) GetSince(since time.Time) []LogEntry {
 
 	entries := make([]LogEntry, 0)
 
-	available := lb.lines
-	if available > lb.capacity {
-		available = lb.capacity
-	}
-
-	// Find the starting point
-	start := lb.buffer
-	if available < lb.capacity {
-		for i := 0; i < lb.capacity; i++ {
-			if start.Value == nil {
-				start = start.Next()
-			} else {
-				break
-			}
-		}
-	}
-
-	// Collect entries after the timestamp
-	current := start
+	// Collect entries after the timestamp, oldest first
+	current, available := lb.unexpiredLocked()
 	for i := 0; i < available; i++ {
-		if current.Value != nil {
-			entry, ok := current.Value.(LogEntry)
-			if ok && entry.Timestamp.After(since) {
-				entries = append(entries, entry)
-			}
+		if entry, ok := current.Value.(LogEntry); ok && entry.Timestamp.After(since) {
+			entries = append(entries, entry)
 		}
 		current = current.Next()
 	}
@@ -175,6 +462,72 @@ func (lb *LogBuffer) GetSince(since time.Time) []LogEntry {
 	return entries
 }
 
+// GetRange returns buffered entries whose line numbers fall within [from, to]
+// Line numbers are 1-based and count every line captured since start (see LogStats.TotalLines),
+// so they stay stable as the ring buffer wraps. The range is clamped to the lines
+// still held in memory; the returned int is the line number of the first entry.
+func (lb *LogBuffer) GetRange(from, to int) ([]LogEntry, int) {
+	lb.mu.RLock()
+	all := lb.getRecentLocked(-1)
+	firstLine := lb.lines - len(all) + 1
+	lb.mu.RUnlock()
+
+	if from < firstLine {
+		from = firstLine
+	}
+	lastLine := firstLine + len(all) - 1
+	if to > lastLine {
+		to = lastLine
+	}
+	if from > to {
+		return []LogEntry{}, from
+	}
+
+	return all[from-firstLine : to-firstLine+1], from
+}
+
+// GetPage returns up to limit buffered entries starting at offset, oldest first
+// offset counts every line captured since start (0 = first line, see LogStats.TotalLines),
+// so a page holds the same lines as the ring buffer wraps; lines already evicted are
+// skipped. If limit <= 0, returns everything from offset on. Also returns the offset of
+// the line after the page, to request next, and whether buffered lines remain beyond it.
+func (lb *LogBuffer) GetPage(offset, limit int) ([]LogEntry, int, bool) {
+	lb.mu.RLock()
+	all := lb.getRecentLocked(-1)
+	total := lb.lines
+	lb.mu.RUnlock()
+
+	firstOffset := total - len(all)
+	if offset < firstOffset {
+		offset = firstOffset
+	}
+	if offset >= total {
+		return []LogEntry{}, total, false
+	}
+
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return all[offset-firstOffset : end-firstOffset], end, end < total
+}
+
+// FindLastMatch returns the line number of the most recent buffered entry containing query
+// Returns 0 if no buffered entry matches
+func (lb *LogBuffer) FindLastMatch(query string) int {
+	lb.mu.RLock()
+	all := lb.getRecentLocked(-1)
+	firstLine := lb.lines - len(all) + 1
+	lb.mu.RUnlock()
+
+	for i := len(all) - 1; i >= 0; i-- {
+		if strings.Contains(all[i].Line, query) {
+			return firstLine + i
+		}
+	}
+	return 0
+}
+
 // GetByStream returns recent entries filtered by stream (stdout/stderr)
 func (lb *LogBuffer) GetByStream(stream string, n int) []LogEntry {
 	all := lb.GetRecent(-1) // Get all
@@ -208,25 +561,24 @@ func (lb *LogBuffer) GetStats() LogStats {
 	lb.mu.RLock()
 	defer lb.mu.RUnlock()
 
-	available := lb.lines
-	if available > lb.capacity {
-		available = lb.capacity
-	}
-
 	return LogStats{
-		TotalLines:    lb.lines,
-		BufferedLines: available,
-		Capacity:      lb.capacity,
-		BufferFull:    lb.lines >= lb.capacity,
+		TotalLines:     lb.lines,
+		BufferedLines:  lb.availableLocked(),
+		Capacity:       lb.capacity,
+		BufferFull:     lb.lines >= lb.capacity,
+		ExpiredLines:   lb.expiredLines,
+		TruncatedBytes: lb.truncatedBytes,
 	}
 }
 
 // LogStats represents statistics about the log buffer
 type LogStats struct {
-	TotalLines    int  `json:"total_lines"`    // Total lines captured (lifetime)
-	BufferedLines int  `json:"buffered_lines"` // Currently buffered lines
-	Capacity      int  `json:"capacity"`       // Buffer capacity
-	BufferFull    bool `json:"buffer_full"`    // Whether buffer has wrapped
+	TotalLines     int   `json:"total_lines"`     // Total lines captured (lifetime)
+	BufferedLines  int   `json:"buffered_lines"`  // Currently buffered lines
+	Capacity       int   `json:"capacity"`        // Buffer capacity
+	BufferFull     bool  `json:"buffer_full"`     // Whether buffer has wrapped
+	ExpiredLines   int   `json:"expired_lines"`   // Lines pruned from the log file for age (lifetime)
+	TruncatedBytes int64 `json:"truncated_bytes"` // Bytes cut from the log file for size (lifetime)
 }
 
 // ToJSON converts log entries to JSON for easy API responses
@@ -264,6 +616,48 @@ func (lb *LogBuffer) GetAllFromFile() ([]string, error) {
 	return lines, scanner.Err()
 }
 
+// Rotate closes and reopens the log file at its path, for external log rotation
+// Once the file has been moved away (e.g. by logrotate), new lines go to a fresh file at the
+// same path; if it hasn't, appending continues. The in-memory buffer is kept either way.
+func (lb *LogBuffer) Rotate() error {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if lb.logPath == "" {
+		return fmt.Errorf("no log file available")
+	}
+
+	logFile, err := os.OpenFile(lb.logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to reopen log file: %w", err)
+	}
+	info, err := logFile.Stat()
+	if err != nil {
+		logFile.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	// Lines tracked for pruning stay valid only if this is still the same file
+	sameFile := false
+	if lb.logFile != nil {
+		if oldInfo, err := lb.logFile.Stat(); err == nil {
+			sameFile = os.SameFile(oldInfo, info)
+		}
+		// The rotated file is kept, so flush what wasn't synced line by line
+		if lb.noSync {
+			_ = lb.logFile.Sync()
+		}
+		lb.logFile.Close()
+	}
+	if !sameFile {
+		lb.fileMarks = nil
+	}
+
+	lb.logFile = logFile
+	lb.fileSize = info.Size()
+	return nil
+}
+
 // GetLogFilePath returns the path to the persistent log file
 func (lb *LogBuffer) GetLogFilePath() string {
 	lb.mu.RLock()
@@ -276,20 +670,39 @@ func (lb *LogBuffer) Close() error {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
 
+	if lb.stopPrune != nil {
+		close(lb.stopPrune)
+		lb.stopPrune = nil
+	}
+
 	if lb.logFile != nil {
 		lb.logFile.Close()
 		// Clean up the temporary file
 		if lb.logPath != "" {
 			os.Remove(lb.logPath)
 		}
+		lb.logFile = nil
+		lb.logPath = ""
 	}
 	return nil
 }
 
 // LogCaptureConfig configures log capture behavior
 type LogCaptureConfig struct {
-	Enabled    bool // Enable log capture
-	BufferSize int  // Number of log lines to keep in memory
+	Enabled    bool             // Enable log capture
+	BufferSize int              // Number of log lines to keep in memory
+	Sink       LogSink          // Optional external sink for captured entries (nil = none)
+	Redactor   *redact.Redactor // Masks secret values in captured entries (nil = none)
+	StripANSI  bool             // Remove ANSI escape codes from captured entries
+
+	TimestampFormat string         // Layout of log file timestamps (empty = DefaultLogTimestampFormat)
+	TimeZone        *time.Location // Zone of log file timestamps (nil = local time)
+
+	MaxAge        time.Duration // Hide and prune entries older than this (0 = no expiry)
+	PruneInterval time.Duration // How often to prune the log file (0 = DefaultLogPruneInterval)
+
+	MaxFileBytes int64 // Truncate the log file once it grows past this size (0 = unbounded)
+	NoSync       bool  // Don't fsync the log file after every line
 }
 
 // DefaultLogCaptureConfig returns sensible defaults
@@ -0,0 +1,758 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/redact"
+)
+
+// newTestLogBuffer creates a log buffer filled with lines "line 1" .. "line n"
+func newTestLogBuffer(t *testing.T, capacity, n int) *LogBuffer {
+	t.Helper()
+	lb := NewLogBuffer(capacity)
+	t.Cleanup(func() { lb.Close() })
+
+	for i := 1; i <= n; i++ {
+		lb.Append(LogEntry{
+			Timestamp: time.Now(),
+			Stream:    "stdout",
+			Line:      fmt.Sprintf("line %d", i),
+		})
+	}
+	return lb
+}
+
+func TestLogBuffer_GetRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		capacity  int
+		appended  int
+		from, to  int
+		wantFirst int
+		wantLines []string
+	}{
+		{
+			name:      "window centered on target",
+			capacity:  100,
+			appended:  20,
+			from:      8,
+			to:        12,
+			wantFirst: 8,
+			wantLines: []string{"line 8", "line 9", "line 10", "line 11", "line 12"},
+		},
+		{
+			name:      "clamped at start of buffer",
+			capacity:  100,
+			appended:  20,
+			from:      -3,
+			to:        3,
+			wantFirst: 1,
+			wantLines: []string{"line 1", "line 2", "line 3"},
+		},
+		{
+			name:      "clamped at end of buffer",
+			capacity:  100,
+			appended:  20,
+			from:      18,
+			to:        25,
+			wantFirst: 18,
+			wantLines: []string{"line 18", "line 19", "line 20"},
+		},
+		{
+			name:      "clamped to oldest line after wrap",
+			capacity:  10,
+			appended:  25,
+			from:      14,
+			to:        18,
+			wantFirst: 16,
+			wantLines: []string{"line 16", "line 17", "line 18"},
+		},
+		{
+			name:      "range fully evicted",
+			capacity:  10,
+			appended:  25,
+			from:      1,
+			to:        5,
+			wantLines: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := newTestLogBuffer(t, tt.capacity, tt.appended)
+
+			entries, first := lb.GetRange(tt.from, tt.to)
+			if len(entries) != len(tt.wantLines) {
+				t.Fatalf("expected %d entries, got %d", len(tt.wantLines), len(entries))
+			}
+			if len(entries) > 0 && first != tt.wantFirst {
+				t.Errorf("expected first line %d, got %d", tt.wantFirst, first)
+			}
+			for i, want := range tt.wantLines {
+				if entries[i].Line != want {
+					t.Errorf("entry %d: expected %q, got %q", i, want, entries[i].Line)
+				}
+			}
+		})
+	}
+}
+
+func TestLogBuffer_GetPage(t *testing.T) {
+	tests := []struct {
+		name        string
+		capacity    int
+		appended    int
+		offset      int
+		limit       int
+		wantLines   []string
+		wantNext    int
+		wantHasMore bool
+	}{
+		{
+			name:        "first page",
+			capacity:    100,
+			appended:    20,
+			offset:      0,
+			limit:       3,
+			wantLines:   []string{"line 1", "line 2", "line 3"},
+			wantNext:    3,
+			wantHasMore: true,
+		},
+		{
+			name:        "last page",
+			capacity:    100,
+			appended:    20,
+			offset:      18,
+			limit:       2,
+			wantLines:   []string{"line 19", "line 20"},
+			wantNext:    20,
+			wantHasMore: false,
+		},
+		{
+			name:        "limit larger than available",
+			capacity:    100,
+			appended:    20,
+			offset:      15,
+			limit:       50,
+			wantLines:   []string{"line 16", "line 17", "line 18", "line 19", "line 20"},
+			wantNext:    20,
+			wantHasMore: false,
+		},
+		{
+			name:        "no limit",
+			capacity:    100,
+			appended:    5,
+			offset:      2,
+			limit:       0,
+			wantLines:   []string{"line 3", "line 4", "line 5"},
+			wantNext:    5,
+			wantHasMore: false,
+		},
+		{
+			name:        "offset at end of buffer",
+			capacity:    100,
+			appended:    20,
+			offset:      20,
+			limit:       10,
+			wantLines:   []string{},
+			wantNext:    20,
+			wantHasMore: false,
+		},
+		{
+			name:        "offset beyond buffer",
+			capacity:    100,
+			appended:    20,
+			offset:      500,
+			limit:       10,
+			wantLines:   []string{},
+			wantNext:    20,
+			wantHasMore: false,
+		},
+		{
+			name:        "evicted offset skips to oldest line after wrap",
+			capacity:    10,
+			appended:    25,
+			offset:      3,
+			limit:       4,
+			wantLines:   []string{"line 16", "line 17", "line 18", "line 19"},
+			wantNext:    19,
+			wantHasMore: true,
+		},
+		{
+			name:        "empty buffer",
+			capacity:    10,
+			appended:    0,
+			offset:      0,
+			limit:       10,
+			wantLines:   []string{},
+			wantNext:    0,
+			wantHasMore: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := newTestLogBuffer(t, tt.capacity, tt.appended)
+
+			entries, next, hasMore := lb.GetPage(tt.offset, tt.limit)
+			if len(entries) != len(tt.wantLines) {
+				t.Fatalf("expected %d entries, got %d", len(tt.wantLines), len(entries))
+			}
+			for i, want := range tt.wantLines {
+				if entries[i].Line != want {
+					t.Errorf("entry %d: expected %q, got %q", i, want, entries[i].Line)
+				}
+			}
+			if next != tt.wantNext {
+				t.Errorf("expected next offset %d, got %d", tt.wantNext, next)
+			}
+			if hasMore != tt.wantHasMore {
+				t.Errorf("expected has more %v, got %v", tt.wantHasMore, hasMore)
+			}
+		})
+	}
+
+	t.Run("pages stay put as the buffer wraps", func(t *testing.T) {
+		lb := newTestLogBuffer(t, 10, 5)
+		first, next, _ := lb.GetPage(0, 3)
+		for i := 6; i <= 8; i++ {
+			lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: fmt.Sprintf("line %d", i)})
+		}
+		second, _, _ := lb.GetPage(next, 3)
+
+		var got []string
+		for _, e := range append(first, second...) {
+			got = append(got, e.Line)
+		}
+		want := []string{"line 1", "line 2", "line 3", "line 4", "line 5", "line 6"}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+}
+
+func TestLogBuffer_FindLastMatch(t *testing.T) {
+	lb := newTestLogBuffer(t, 10, 25)
+
+	if got := lb.FindLastMatch("line 2"); got != 25 {
+		t.Errorf("expected most recent match at line 25, got %d", got)
+	}
+	if got := lb.FindLastMatch("line 3"); got != 0 {
+		t.Errorf("expected evicted line to not match, got %d", got)
+	}
+}
+
+func TestLogBuffer_TinyCapacities(t *testing.T) {
+	for _, capacity := range []int{1, 2, 3} {
+		t.Run(fmt.Sprintf("capacity %d", capacity), func(t *testing.T) {
+			lb := NewLogBuffer(capacity)
+			t.Cleanup(func() { lb.Close() })
+
+			if got := lb.GetRecent(-1); len(got) != 0 {
+				t.Fatalf("expected empty buffer, got %d entries", len(got))
+			}
+
+			base := time.Now()
+			for i := 1; i <= 20; i++ {
+				lb.Append(LogEntry{
+					Timestamp: base.Add(time.Duration(i) * time.Millisecond),
+					Stream:    "stdout",
+					Line:      fmt.Sprintf("line %d", i),
+				})
+
+				// All buffered entries, oldest first
+				buffered := min(i, capacity)
+				assertLines(t, lb.GetRecent(-1), i-buffered+1, i)
+
+				// Every explicit n, including n larger than the buffer
+				for n := 1; n <= capacity+1; n++ {
+					want := min(n, buffered)
+					assertLines(t, lb.GetRecent(n), i-want+1, i)
+				}
+
+				// Only the newest entry is after the previous one's timestamp
+				assertLines(t, lb.GetSince(base.Add(time.Duration(i-1)*time.Millisecond)), i, i)
+
+				if stats := lb.GetStats(); stats.BufferedLines != buffered || stats.TotalLines != i {
+					t.Fatalf("after %d appends: expected %d buffered of %d total, got %+v", i, buffered, i, stats)
+				}
+			}
+		})
+	}
+}
+
+// assertLines checks that entries hold exactly "line first" .. "line last"
+func assertLines(t *testing.T, entries []LogEntry, first, last int) {
+	t.Helper()
+	if len(entries) != last-first+1 {
+		t.Fatalf("expected lines %d-%d, got %d entries", first, last, len(entries))
+	}
+	for i, entry := range entries {
+		if want := fmt.Sprintf("line %d", first+i); entry.Line != want {
+			t.Fatalf("entry %d: expected %q, got %q", i, want, entry.Line)
+		}
+	}
+}
+
+func TestDetectLevel(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "ERROR:root:database connection failed", want: LevelError},
+		{line: "2025-01-15 10:30:00 [WARNING] deprecated option", want: LevelWarn},
+		{line: "INFO:     Uvicorn running on http://0.0.0.0:8000", want: LevelInfo},
+		{line: "DEBUG - loading config", want: LevelDebug},
+		{line: `time=2025-01-15T10:30:00Z level=error msg="boom"`, want: LevelError},
+		{line: `{"level":"warn","msg":"slow request"}`, want: LevelWarn},
+		{line: "[E 2025-01-15 10:30:00.000 ServerApp] Uncaught exception", want: LevelError},
+		{line: "[I 2025-01-15 10:30:00.000 ServerApp] Serving notebooks", want: LevelInfo},
+		{line: "CRITICAL: out of memory", want: LevelError},
+		{line: "Traceback (most recent call last):", want: LevelError},
+		{line: "INFO: retrying after ERROR", want: LevelInfo},
+		{line: "  You can now view your Streamlit app in your browser.", want: LevelUnknown},
+		{line: "for more info see the docs", want: LevelUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := DetectLevel(tt.line); got != tt.want {
+			t.Errorf("DetectLevel(%q): expected %q, got %q", tt.line, tt.want, got)
+		}
+	}
+}
+
+func TestLogBuffer_GetLevelSummary(t *testing.T) {
+	lb := NewLogBuffer(100)
+	t.Cleanup(func() { lb.Close() })
+
+	lines := []struct {
+		stream string
+		line   string
+	}{
+		{"stderr", "ERROR: failed to bind"},
+		{"stderr", "Traceback (most recent call last):"},
+		{"stderr", "WARNING: falling back to default"},
+		{"stdout", "INFO: started"},
+		{"stdout", "INFO: ready"},
+		{"stdout", `level=info msg="request"`},
+		{"stdout", "DEBUG: cache miss"},
+		{"stdout", "plain output"},
+	}
+	for _, l := range lines {
+		lb.Append(LogEntry{Timestamp: time.Now(), Stream: l.stream, Line: l.line})
+	}
+
+	summary := lb.GetLevelSummary()
+
+	wantLevels := map[string]int{LevelError: 2, LevelWarn: 1, LevelInfo: 3, LevelDebug: 1, LevelUnknown: 1}
+	for level, want := range wantLevels {
+		if got := summary.Levels[level]; got != want {
+			t.Errorf("level %s: expected %d, got %d", level, want, got)
+		}
+	}
+	if summary.Streams["stdout"] != 5 || summary.Streams["stderr"] != 3 {
+		t.Errorf("expected 5 stdout and 3 stderr lines, got %v", summary.Streams)
+	}
+	if summary.Total != len(lines) {
+		t.Errorf("expected total %d, got %d", len(lines), summary.Total)
+	}
+}
+
+type recordingSink struct {
+	entries []LogEntry
+}
+
+func (s *recordingSink) Send(entry LogEntry) {
+	s.entries = append(s.entries, entry)
+}
+
+func TestLogBuffer_Redactor(t *testing.T) {
+	const secret = "hunter2-hunter2"
+	lb := NewLogBuffer(10)
+	t.Cleanup(func() { lb.Close() })
+	sink := &recordingSink{}
+	lb.SetSink(sink)
+	lb.SetRedactor(redact.New([]string{secret}))
+
+	lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stderr", Line: "connecting to postgres://app:" + secret + "@db"})
+
+	want := "connecting to postgres://app:REDACTED@db"
+	if got := lb.GetRecent(1); len(got) != 1 || got[0].Line != want {
+		t.Errorf("expected buffered line %q, got %v", want, got)
+	}
+	if len(sink.entries) != 1 || sink.entries[0].Line != want {
+		t.Errorf("expected shipped line %q, got %v", want, sink.entries)
+	}
+	fileLines, err := lb.GetAllFromFile()
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	for _, line := range fileLines {
+		if strings.Contains(line, secret) {
+			t.Errorf("expected secret to be redacted in log file, got %q", line)
+		}
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{name: "plain", line: "Listening on port 8501", want: "Listening on port 8501"},
+		{name: "color", line: "\x1b[32mINFO\x1b[0m started", want: "INFO started"},
+		{name: "bold 256 color", line: "\x1b[1;38;5;208mwarning\x1b[m: slow", want: "warning: slow"},
+		{name: "cursor movement", line: "\x1b[2K\x1b[1Gprogress 50%", want: "progress 50%"},
+		{name: "hyperlink", line: "see \x1b]8;;http://localhost:8501\x1b\\http://localhost:8501\x1b]8;;\x1b\\", want: "see http://localhost:8501"},
+		{name: "window title", line: "\x1b]0;streamlit\x07ready", want: "ready"},
+		{name: "charset", line: "\x1b(Bbox", want: "box"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripANSI(tt.line); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLogBuffer_StripANSI(t *testing.T) {
+	lb := NewLogBuffer(10)
+	t.Cleanup(func() { lb.Close() })
+	sink := &recordingSink{}
+	lb.SetSink(sink)
+	lb.SetStripANSI(true)
+
+	colored := "\x1b[33m  You can now view your Streamlit app in your browser.\x1b[0m"
+	want := "  You can now view your Streamlit app in your browser."
+	lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: colored})
+	lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: "plain"})
+
+	got := lb.GetRecent(-1)
+	if len(got) != 2 || got[0].Line != want || got[1].Line != "plain" {
+		t.Fatalf("expected cleaned lines, got %q", got)
+	}
+	if got[0].Raw != colored {
+		t.Errorf("expected raw line %q, got %q", colored, got[0].Raw)
+	}
+	if got[1].Raw != "" {
+		t.Errorf("expected no raw line when nothing was stripped, got %q", got[1].Raw)
+	}
+	if len(sink.entries) != 2 || sink.entries[0].Line != want {
+		t.Errorf("expected shipped line %q, got %v", want, sink.entries)
+	}
+	fileLines, err := lb.GetAllFromFile()
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if len(fileLines) != 2 || !strings.HasSuffix(fileLines[0], want) {
+		t.Errorf("expected cleaned line in log file, got %q", fileLines)
+	}
+}
+
+func TestLogBuffer_TimestampFormat(t *testing.T) {
+	berlin := time.FixedZone("CET", 60*60)
+	// 2024-03-05 23:30:15.250 UTC
+	ts := time.Date(2024, 3, 5, 23, 30, 15, 250_000_000, time.UTC)
+
+	tests := []struct {
+		name   string
+		format string
+		loc    *time.Location
+		want   string
+	}{
+		{name: "default format, UTC", loc: time.UTC, want: "[2024-03-05 23:30:15.250] [stdout] hello"},
+		{name: "default format, other zone", loc: berlin, want: "[2024-03-06 00:30:15.250] [stdout] hello"},
+		{name: "RFC 3339, UTC", format: time.RFC3339, loc: time.UTC, want: "[2024-03-05T23:30:15Z] [stdout] hello"},
+		{name: "RFC 3339, other zone", format: time.RFC3339, loc: berlin, want: "[2024-03-06T00:30:15+01:00] [stdout] hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLogBuffer(10)
+			t.Cleanup(func() { lb.Close() })
+			lb.SetTimestampFormat(tt.format, tt.loc)
+
+			lb.Append(LogEntry{Timestamp: ts, Stream: "stdout", Line: "hello"})
+
+			lines, err := lb.GetAllFromFile()
+			if err != nil {
+				t.Fatalf("failed to read log file: %v", err)
+			}
+			if len(lines) != 1 || lines[0] != tt.want {
+				t.Errorf("expected file line %q, got %q", tt.want, lines)
+			}
+		})
+	}
+}
+
+func TestLogBuffer_MaxAge(t *testing.T) {
+	lb := NewLogBuffer(100)
+	t.Cleanup(func() { lb.Close() })
+	lb.SetRetention(time.Hour, time.Hour) // Prune explicitly below
+
+	now := time.Now()
+	for i, age := range []time.Duration{3 * time.Hour, 2 * time.Hour, 30 * time.Minute, time.Minute} {
+		stream := "stdout"
+		if i%2 == 1 {
+			stream = "stderr"
+		}
+		lb.Append(LogEntry{Timestamp: now.Add(-age), Stream: stream, Line: fmt.Sprintf("line %d", i+1)})
+	}
+
+	lineText := func(entries []LogEntry) string {
+		lines := make([]string, len(entries))
+		for i, entry := range entries {
+			lines[i] = entry.Line
+		}
+		return strings.Join(lines, ",")
+	}
+
+	tests := []struct {
+		name    string
+		entries []LogEntry
+		want    string
+	}{
+		{name: "GetRecent all", entries: lb.GetRecent(-1), want: "line 3,line 4"},
+		{name: "GetRecent n", entries: lb.GetRecent(3), want: "line 3,line 4"},
+		{name: "GetSince", entries: lb.GetSince(now.Add(-4 * time.Hour)), want: "line 3,line 4"},
+		{name: "GetByStream stdout", entries: lb.GetByStream("stdout", 0), want: "line 3"},
+		{name: "GetByStream stderr", entries: lb.GetByStream("stderr", 0), want: "line 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineText(tt.entries); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("GetRange keeps line numbers", func(t *testing.T) {
+		entries, first := lb.GetRange(1, 4)
+		if first != 3 || lineText(entries) != "line 3,line 4" {
+			t.Errorf("expected lines 3-4 starting at 3, got %q starting at %d", lineText(entries), first)
+		}
+	})
+
+	t.Run("Prune drops expired file lines", func(t *testing.T) {
+		if err := lb.Prune(); err != nil {
+			t.Fatalf("failed to prune: %v", err)
+		}
+		lines, err := lb.GetAllFromFile()
+		if err != nil {
+			t.Fatalf("failed to read log file: %v", err)
+		}
+		if len(lines) != 2 || !strings.HasSuffix(lines[0], "line 3") || !strings.HasSuffix(lines[1], "line 4") {
+			t.Errorf("expected only lines 3 and 4 in the file, got %q", lines)
+		}
+		if got := lb.GetStats().ExpiredLines; got != 2 {
+			t.Errorf("expected 2 expired lines, got %d", got)
+		}
+
+		// Appending continues at the end of the pruned file
+		lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: "line 5"})
+		lines, _ = lb.GetAllFromFile()
+		if len(lines) != 3 || !strings.HasSuffix(lines[2], "line 5") {
+			t.Errorf("expected line 5 appended after pruning, got %q", lines)
+		}
+	})
+}
+
+func TestLogBuffer_PruneLoop(t *testing.T) {
+	lb := NewLogBuffer(100)
+	t.Cleanup(func() { lb.Close() })
+	lb.SetRetention(50*time.Millisecond, 10*time.Millisecond)
+
+	lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: "old"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for lb.GetStats().ExpiredLines != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the expired line to be pruned in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if lines, _ := lb.GetAllFromFile(); len(lines) != 0 {
+		t.Errorf("expected an empty log file, got %q", lines)
+	}
+	if entries := lb.GetRecent(-1); len(entries) != 0 {
+		t.Errorf("expected no unexpired entries, got %d", len(entries))
+	}
+}
+
+func TestLogBuffer_MaxFileBytes(t *testing.T) {
+	const maxBytes = 4096
+	lb := NewLogBuffer(10)
+	t.Cleanup(func() { lb.Close() })
+	lb.SetMaxFileBytes(maxBytes)
+
+	const total = 1000
+	for i := 1; i <= total; i++ {
+		lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: fmt.Sprintf("line %d", i)})
+
+		info, err := os.Stat(lb.GetLogFilePath())
+		if err != nil {
+			t.Fatalf("failed to stat log file: %v", err)
+		}
+		if info.Size() > maxBytes {
+			t.Fatalf("expected log file to stay within %d bytes, got %d after line %d", maxBytes, info.Size(), i)
+		}
+	}
+
+	lines, err := lb.GetAllFromFile()
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if len(lines) == 0 || len(lines) == total {
+		t.Fatalf("expected the file to keep only the most recent lines, got %d", len(lines))
+	}
+	// Whole lines only, newest last, with no gaps
+	first := total - len(lines) + 1
+	for i, line := range lines {
+		want := fmt.Sprintf("[stdout] line %d", first+i)
+		if !strings.HasSuffix(line, want) || !strings.HasPrefix(line, "[") {
+			t.Fatalf("expected line %d to end with %q, got %q", i, want, line)
+		}
+	}
+	if got := lb.GetStats().TruncatedBytes; got == 0 {
+		t.Error("expected truncated bytes to be reported")
+	}
+	if got := lb.GetRecent(-1); len(got) != 10 || got[9].Line != fmt.Sprintf("line %d", total) {
+		t.Errorf("expected the memory buffer to be unaffected, got %d entries", len(got))
+	}
+}
+
+func TestLogBuffer_Rotate(t *testing.T) {
+	lb := newTestLogBuffer(t, 100, 2)
+	path := lb.GetLogFilePath()
+
+	// Move the file away like logrotate, then reopen
+	rotated := path + ".1"
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatalf("failed to move log file: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(rotated) })
+	lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: "line 3"}) // Still goes to the moved file
+
+	if err := lb.Rotate(); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: "line 4"})
+
+	lines, err := lb.GetAllFromFile()
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], "line 4") {
+		t.Errorf("expected only line 4 in the fresh file, got %q", lines)
+	}
+
+	old, err := os.ReadFile(rotated)
+	if err != nil {
+		t.Fatalf("failed to read rotated file: %v", err)
+	}
+	if got := strings.Count(string(old), "\n"); got != 3 || !strings.Contains(string(old), "line 3") {
+		t.Errorf("expected lines 1-3 in the rotated file, got %q", old)
+	}
+
+	// The in-memory buffer spans the rotation
+	if got := len(lb.GetRecent(-1)); got != 4 {
+		t.Errorf("expected 4 buffered entries, got %d", got)
+	}
+
+	t.Run("without moving the file", func(t *testing.T) {
+		if err := lb.Rotate(); err != nil {
+			t.Fatalf("failed to rotate: %v", err)
+		}
+		lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: "line 5"})
+		lines, _ := lb.GetAllFromFile()
+		if len(lines) != 2 || !strings.HasSuffix(lines[1], "line 5") {
+			t.Errorf("expected line 5 appended to the same file, got %q", lines)
+		}
+	})
+}
+
+func TestLogBuffer_RotateConcurrentAppend(t *testing.T) {
+	lb := newTestLogBuffer(t, 100, 0)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: fmt.Sprintf("line %d", i)})
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if err := lb.Rotate(); err != nil {
+			t.Fatalf("failed to rotate: %v", err)
+		}
+	}
+	<-done
+
+	lines, err := lb.GetAllFromFile()
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if len(lines) != 200 {
+		t.Errorf("expected 200 lines in the unmoved file, got %d", len(lines))
+	}
+}
+
+func TestLogBuffer_NoSync(t *testing.T) {
+	lb := NewLogBuffer(10)
+	t.Cleanup(func() { lb.Close() })
+	lb.SetNoSync(true)
+
+	for i := 1; i <= 3; i++ {
+		lb.Append(LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: fmt.Sprintf("line %d", i)})
+	}
+
+	// Written lines are readable without waiting for the OS to flush them
+	lines, err := lb.GetAllFromFile()
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if len(lines) != 3 || !strings.HasSuffix(lines[2], "[stdout] line 3") {
+		t.Fatalf("expected 3 lines ending with line 3, got %q", lines)
+	}
+
+	// The rotated-out file keeps all its lines
+	path := lb.GetLogFilePath()
+	rotated := path + ".1"
+	t.Cleanup(func() { os.Remove(rotated) })
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatalf("failed to move log file: %v", err)
+	}
+	if err := lb.Rotate(); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	data, err := os.ReadFile(rotated)
+	if err != nil {
+		t.Fatalf("failed to read rotated file: %v", err)
+	}
+	if got := strings.Count(string(data), "\n"); got != 3 {
+		t.Errorf("expected 3 lines in the rotated file, got %d", got)
+	}
+}
+
+func BenchmarkLogBuffer_Append(b *testing.B) {
+	for _, noSync := range []bool{false, true} {
+		b.Run(fmt.Sprintf("no_sync=%v", noSync), func(b *testing.B) {
+			lb := NewLogBuffer(1000)
+			defer lb.Close()
+			lb.SetNoSync(noSync)
+			entry := LogEntry{Timestamp: time.Now(), Stream: "stdout", Line: "INFO: GET /api/data 200 OK in 12ms"}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				lb.Append(entry)
+			}
+		})
+	}
+}
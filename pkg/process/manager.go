@@ -5,6 +5,7 @@ package process
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -16,6 +17,9 @@ import (
 	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
 )
 
+// outputDrainTimeout bounds how long the exit monitor waits for remaining output after the process exits
+const outputDrainTimeout = 2 * time.Second
+
 // ProcessState represents the current state of a managed process
 type ProcessState string
 
@@ -30,11 +34,42 @@ const (
 // Config holds process configuration
 type Config struct {
 	Command       []string          // Command and arguments to execute
+	UserCommand   []string          // Command as the user gave it, before conda wrapping (nil = Command)
 	Env           map[string]string // Additional environment variables
 	WorkDir       string            // Working directory
 	ReadyTimeout  time.Duration     // How long to wait for process to be ready
 	ReadyCheck    ReadyChecker      // Function to check if process is ready
 	OutputHandler OutputHandler     // Handler for process output
+	RestartPolicy RestartPolicy     // Automatic restarts after the process fails
+	Phases        *PhaseTracker     // Startup phase tracking shared with main (nil = manager-owned)
+	Nice          int               // Scheduling niceness, -20 (highest priority) to 19 (0 = inherit ours)
+
+	// NoProcessGroup keeps the process in our process group instead of its own, so a
+	// terminal's Ctrl+C reaches it directly; stopping it then only signals the process
+	// itself, not what it spawned
+	NoProcessGroup bool
+
+	Credential *syscall.Credential // User and groups the process runs as, needs root (nil = ours, see LookupRunAs)
+
+	// OnStateChange is called on every state transition, with the manager's lock held,
+	// so it must not block or call back into the manager
+	OnStateChange func(from, to ProcessState)
+}
+
+// RestartPolicy controls relaunching the process when it exits with a non-zero code
+type RestartPolicy struct {
+	MaxRestarts int // Maximum number of automatic restarts (0 = never restart)
+
+	// Exponential backoff between restarts: the first restart waits BackoffInitial,
+	// each further one BackoffMultiplier times longer, capped at BackoffMax
+	BackoffInitial    time.Duration // Delay before the first restart (0 = restart immediately)
+	BackoffMax        time.Duration // Upper bound for the delay (0 = uncapped)
+	BackoffMultiplier float64       // Growth factor per restart (default: 2)
+
+	// Crash-loop detection: once the process has been restarted CrashLoopThreshold
+	// times within CrashLoopWindow, restarting stops and the process is marked failed
+	CrashLoopThreshold int           // Restarts allowed within the window (0 = disabled)
+	CrashLoopWindow    time.Duration // Sliding window for counting restarts (default: 60s)
 }
 
 // ReadyChecker is a function type that checks if a process is ready
@@ -49,16 +84,29 @@ type Manager struct {
 	logger *logger.Logger
 
 	// Process state
-	mu      sync.RWMutex
-	cmd     *exec.Cmd
-	state   ProcessState
-	pid     int
-	started time.Time
-	stopped time.Time
+	mu     sync.RWMutex
+	cmd    *exec.Cmd
+	exited chan struct{} // Closed once the current cmd has been waited for
+	// Closed once the current instance's monitor has finished, including any automatic restart
+	monitorDone chan struct{}
+	state       ProcessState
+	pid         int
+	started     time.Time
+	stopped     time.Time
+
+	// Restarts
+	generation    int         // Incremented on every launch so stale ready checks are ignored
+	stopping      bool        // Set by Stop to prevent restarts
+	restarts      int         // Automatic restarts performed so far
+	restartTimes  []time.Time // Restart times within the crash-loop window
+	crashLoop     bool        // Restarting stopped because of a crash loop
+	statusMessage string      // Human-readable reason for the current failed or degraded state
+	degraded      bool        // Running but failing liveness checks
 
 	// Cancellation
-	ctx    context.Context
-	cancel context.CancelFunc
+	ctx       context.Context
+	cancel    context.CancelFunc
+	parentCtx context.Context // Context passed to Start, reused by Restart
 }
 
 // NewManager creates a new process manager with the given configuration
@@ -66,10 +114,23 @@ func NewManager(cfg Config, log *logger.Logger) (*Manager, error) {
 	if len(cfg.Command) == 0 {
 		return nil, fmt.Errorf("command cannot be empty")
 	}
+	if err := ValidateNice(cfg.Nice); err != nil {
+		return nil, err
+	}
 
 	if cfg.ReadyTimeout == 0 {
 		cfg.ReadyTimeout = 5 * time.Minute
 	}
+	if cfg.RestartPolicy.CrashLoopThreshold > 0 && cfg.RestartPolicy.CrashLoopWindow == 0 {
+		cfg.RestartPolicy.CrashLoopWindow = 60 * time.Second
+	}
+	if cfg.RestartPolicy.BackoffMultiplier <= 0 {
+		cfg.RestartPolicy.BackoffMultiplier = 2
+	}
+
+	if cfg.Phases == nil {
+		cfg.Phases = NewPhaseTracker()
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -90,13 +151,67 @@ func (m *Manager) Start(ctx context.Context) error {
 		m.mu.Unlock()
 		return fmt.Errorf("process already running")
 	}
-	m.state = StateStarting
+	// Re-arm after Stop so the manager can be started again
+	if m.ctx.Err() != nil {
+		m.ctx, m.cancel = context.WithCancel(context.Background())
+	}
+	m.stopping = false
+	m.restarts = 0
+	m.restartTimes = nil
+	m.crashLoop = false
+	m.statusMessage = ""
+	m.degraded = false
+	m.parentCtx = ctx
+	m.setStateLocked(StateStarting)
 	m.mu.Unlock()
 
-	m.logger.Progress("starting process", "command", m.config.Command)
+	return m.launch(ctx)
+}
+
+// Restart stops the process and starts it again under the context of the original Start
+// The automatic restart budget and crash-loop detection start over. Like Start, it
+// returns once the process is launched; the ready check runs in the background.
+func (m *Manager) Restart() error {
+	m.mu.RLock()
+	parent := m.parentCtx
+	hasProcess := m.cmd != nil
+	monitorDone := m.monitorDone
+	m.mu.RUnlock()
+
+	if parent == nil {
+		return fmt.Errorf("process was never started")
+	}
+	if parent.Err() != nil {
+		return fmt.Errorf("shutting down: %w", parent.Err())
+	}
+
+	m.logger.Info("restarting process", "pid", m.GetPID())
+	if hasProcess {
+		if err := m.Stop(); err != nil {
+			return fmt.Errorf("failed to stop process: %w", err)
+		}
+		// The old monitor must be done before stopping is cleared, or it
+		// would take the SIGTERM exit for a crash and restart on its own
+		if monitorDone != nil {
+			<-monitorDone
+		}
+	}
+
+	return m.Start(parent)
+}
+
+// launch starts one instance of the process, its ready check and its exit monitor
+func (m *Manager) launch(ctx context.Context) error {
+	// SetCommand may replace these between launches
+	m.mu.RLock()
+	command, env := m.config.Command, m.config.Env
+	m.mu.RUnlock()
+
+	m.logger.Progress("starting process", "command", command)
+	m.config.Phases.Set(PhaseStartingProcess)
 
 	// Build command
-	cmd := exec.CommandContext(m.ctx, m.config.Command[0], m.config.Command[1:]...)
+	cmd := exec.CommandContext(m.ctx, command[0], command[1:]...)
 
 	// Set working directory
 	if m.config.WorkDir != "" {
@@ -105,43 +220,87 @@ func (m *Manager) Start(ctx context.Context) error {
 
 	// Set environment
 	cmd.Env = os.Environ()
-	for k, v := range m.config.Env {
+	for k, v := range env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
 	// Set process group so subprocess doesn't receive our signals
-	// This allows parent to handle Ctrl+C gracefully
+	// This allows parent to handle Ctrl+C gracefully (unless NoProcessGroup)
 	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
+		Setpgid:    !m.config.NoProcessGroup,
+		Credential: m.config.Credential,
+	}
+	// Kill the whole group when the context is cancelled, not just the process
+	cmd.Cancel = func() error {
+		return m.kill(cmd.Process, syscall.SIGKILL)
 	}
 
 	// Setup output pipes for streaming
-	stdout, err := cmd.StdoutPipe()
+	// Plain os.Pipe rather than cmd.StdoutPipe: Wait closes StdoutPipe readers as soon as
+	// the process exits, which drops the last lines of a fast-crashing process (often the
+	// traceback). With os.Pipe our readers see EOF only once every writer is gone.
+	stdout, stdoutW, err := os.Pipe()
 	if err != nil {
-		m.setState(StateFailed)
+		m.fail()
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
-	stderr, err := cmd.StderrPipe()
+	stderr, stderrW, err := os.Pipe()
 	if err != nil {
-		m.setState(StateFailed)
+		stdout.Close()
+		stdoutW.Close()
+		m.fail()
 		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
 
 	// Start the process
+	m.mu.Lock()
 	m.started = time.Now()
-	if err := cmd.Start(); err != nil {
-		m.setState(StateFailed)
-		m.logger.Error("failed to start process", err, "command", m.config.Command)
+	m.stopped = time.Time{}
+	m.mu.Unlock()
+	err = cmd.Start()
+	// The child has its own copies of the write ends now
+	stdoutW.Close()
+	stderrW.Close()
+	if err != nil {
+		stdout.Close()
+		stderr.Close()
+		m.fail()
+		m.logger.Error("failed to start process", err, "command", command)
 		return fmt.Errorf("failed to start process: %w", err)
 	}
 
+	exited := make(chan struct{})
+	monitorDone := make(chan struct{})
+
 	m.mu.Lock()
+	m.clearDegradedLocked()
 	m.cmd = cmd
+	m.exited = exited
+	m.monitorDone = monitorDone
 	m.pid = cmd.Process.Pid
+	m.generation++
+	generation := m.generation
 	m.mu.Unlock()
 
-	m.logger.ProcessStarted(m.pid, m.config.Command, m.config.Env)
+	// Lower (or raise) the app's CPU priority; the app still runs if this fails
+	if m.config.Nice != 0 {
+		if err := setPriority(cmd.Process.Pid, m.config.Nice); err != nil {
+			m.logger.Warn("failed to set process priority, running at default priority",
+				"pid", cmd.Process.Pid,
+				"nice", m.config.Nice,
+				"error", err)
+		} else {
+			m.logger.Info("set process priority", "pid", cmd.Process.Pid, "nice", m.config.Nice)
+		}
+	}
+
+	// Cancelled when this instance exits so its ready check doesn't outlive it
+	readyCtx, cancelReady := context.WithTimeout(ctx, m.config.ReadyTimeout)
+
+	m.logger.ProcessStarted(m.pid, command, env)
 
 	// Stream output in background
 	var wg sync.WaitGroup
@@ -152,28 +311,42 @@ func (m *Manager) Start(ctx context.Context) error {
 	// Wait for process to be ready (non-blocking - run in background)
 	if m.config.ReadyCheck != nil {
 		go func() {
-			readyCtx, cancel := context.WithTimeout(ctx, m.config.ReadyTimeout)
-			defer cancel()
+			defer cancelReady()
 
 			m.logger.Progress("waiting for process ready check",
 				"pid", m.pid,
 				"timeout", m.config.ReadyTimeout)
+			m.config.Phases.Set(PhaseWaitingForHealth)
 
 			if err := m.config.ReadyCheck(readyCtx); err != nil {
-				m.setState(StateFailed)
+				// Shutdown (e.g. SIGTERM) arrived before the process became ready: it is
+				// not a ready check failure, but the process must not be left running
+				if ctx.Err() != nil {
+					m.logger.Info("shutdown requested during ready check, stopping process", "pid", m.pid)
+					if err := m.Stop(); err != nil {
+						m.logger.Error("failed to stop process after shutdown during ready check", err)
+					}
+					return
+				}
+				if !m.setStateIfCurrent(generation, StateFailed) {
+					return // Process exited and was restarted, the new instance has its own check
+				}
+				m.config.Phases.Set(PhaseFailed)
 				m.logger.Error("process ready check failed", err,
 					"pid", m.pid,
 					"timeout", m.config.ReadyTimeout)
 				// Don't kill the process - let it run so logs are available
 				// Users can see the error in the log viewer
-			} else {
-				m.setState(StateRunning)
+			} else if m.setStateIfCurrent(generation, StateRunning) {
+				m.config.Phases.Set(PhaseReady)
 				m.logger.Info("process ready check passed", "pid", m.pid)
 			}
 		}()
 	} else {
 		// No ready check, mark as running immediately
+		cancelReady()
 		m.setState(StateRunning)
+		m.config.Phases.Set(PhaseReady)
 	}
 	m.logger.Info("process started successfully",
 		"pid", m.pid,
@@ -181,24 +354,206 @@ func (m *Manager) Start(ctx context.Context) error {
 
 	// Monitor process in background
 	go func() {
-		defer wg.Wait() // Wait for output streams to finish
-		if err := cmd.Wait(); err != nil {
-			m.setState(StateFailed)
-			exitCode := -1
+		defer close(monitorDone)
+		err := cmd.Wait()
+		close(exited) // Unblocks Stop, which holds m.mu while waiting
+		cancelReady()
+
+		// Let the readers drain the remaining output before reporting the exit.
+		// Bounded, since background children may keep the pipes open indefinitely.
+		drained := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-time.After(outputDrainTimeout):
+		}
+
+		m.mu.Lock()
+		m.stopped = time.Now()
+		stopping := m.stopping
+		m.mu.Unlock()
+
+		exitCode := 0
+		if err != nil {
+			exitCode = -1
 			if exitErr, ok := err.(*exec.ExitError); ok {
 				exitCode = exitErr.ExitCode()
 			}
-			m.logger.ProcessExited(m.pid, exitCode, time.Since(m.started))
+		}
+		// Exiting because Stop asked for it (SIGTERM/SIGKILL) is not a failure
+		if err != nil && !stopping {
+			m.fail()
 		} else {
 			m.setState(StateStopped)
-			m.logger.ProcessExited(m.pid, 0, time.Since(m.started))
 		}
-		m.stopped = time.Now()
+		m.logger.ProcessExited(m.pid, exitCode, time.Since(m.started))
+
+		if err != nil && !stopping {
+			m.restartAfterFailure(ctx)
+		}
 	}()
 
 	return nil
 }
 
+// restartAfterFailure relaunches the process according to the restart policy
+// Gives up when the restart budget is spent or a crash loop is detected
+func (m *Manager) restartAfterFailure(ctx context.Context) {
+	policy := m.config.RestartPolicy
+	if policy.MaxRestarts <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	if m.stopping || m.ctx.Err() != nil || ctx.Err() != nil {
+		m.mu.Unlock()
+		return
+	}
+	if m.restarts >= policy.MaxRestarts {
+		m.mu.Unlock()
+		m.logger.Warn("process failed and restart budget is exhausted", "max_restarts", policy.MaxRestarts)
+		return
+	}
+
+	now := time.Now()
+	if policy.CrashLoopThreshold > 0 {
+		recent := m.restartTimes[:0]
+		for _, t := range m.restartTimes {
+			if now.Sub(t) < policy.CrashLoopWindow {
+				recent = append(recent, t)
+			}
+		}
+		m.restartTimes = recent
+
+		if len(recent) >= policy.CrashLoopThreshold {
+			m.crashLoop = true
+			m.statusMessage = fmt.Sprintf("crash loop detected: process restarted %d times within %s, not restarting again",
+				len(recent), policy.CrashLoopWindow)
+			m.setStateLocked(StateFailed)
+			m.mu.Unlock()
+			m.config.Phases.Set(PhaseFailed)
+
+			m.logger.Error("crash loop detected, giving up on restarts", nil,
+				"restarts_in_window", len(recent),
+				"window", policy.CrashLoopWindow)
+			m.emit("stderr", "ERROR: "+m.GetStatusMessage())
+			return
+		}
+	}
+
+	m.restarts++
+	m.restartTimes = append(m.restartTimes, now)
+	restarts := m.restarts
+	m.setStateLocked(StateStarting)
+	m.mu.Unlock()
+
+	backoff := policy.backoff(restarts)
+	m.logger.Warn("restarting failed process",
+		"restart", restarts,
+		"backoff", backoff,
+		"remaining_restarts", policy.MaxRestarts-restarts)
+
+	if backoff > 0 {
+		timer := time.NewTimer(backoff)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-m.ctx.Done():
+		case <-ctx.Done():
+		}
+
+		// Stop may have been called while waiting
+		m.mu.Lock()
+		if m.stopping || m.ctx.Err() != nil || ctx.Err() != nil {
+			m.setStateLocked(StateStopped)
+			m.mu.Unlock()
+			return
+		}
+		m.mu.Unlock()
+	}
+
+	if err := m.launch(ctx); err != nil {
+		m.logger.Error("failed to restart process", err)
+	}
+}
+
+// backoff returns the delay before the given restart (1-based)
+func (p RestartPolicy) backoff(restart int) time.Duration {
+	if p.BackoffInitial <= 0 {
+		return 0
+	}
+	delay := float64(p.BackoffInitial)
+	for i := 1; i < restart; i++ {
+		delay *= p.BackoffMultiplier
+		if p.BackoffMax > 0 && delay >= float64(p.BackoffMax) {
+			return p.BackoffMax
+		}
+	}
+	if p.BackoffMax > 0 && delay > float64(p.BackoffMax) {
+		return p.BackoffMax
+	}
+	return time.Duration(delay)
+}
+
+// SetDegraded marks the running process as failing its liveness checks
+// The process keeps serving (it may recover); the reason is reported as the status message.
+func (m *Manager) SetDegraded(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.degraded = true
+	m.statusMessage = reason
+}
+
+// ClearDegraded marks the process as healthy again after SetDegraded
+func (m *Manager) ClearDegraded() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clearDegradedLocked()
+}
+
+// clearDegradedLocked clears the degraded flag and its message; m.mu must be held
+func (m *Manager) clearDegradedLocked() {
+	if m.degraded {
+		m.degraded = false
+		m.statusMessage = ""
+	}
+}
+
+// IsDegraded reports whether the process is running but failing its liveness checks
+func (m *Manager) IsDegraded() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.degraded
+}
+
+// RestartUnhealthy kills a wedged process so the restart policy relaunches it
+// The kill counts as a failure, so the restart budget, backoff and crash-loop detection
+// all apply. Returns an error without killing anything if no restart would follow.
+func (m *Manager) RestartUnhealthy() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	policy := m.config.RestartPolicy
+	if policy.MaxRestarts <= 0 {
+		return fmt.Errorf("automatic restarts are disabled")
+	}
+	if m.restarts >= policy.MaxRestarts {
+		return fmt.Errorf("restart budget of %d is exhausted", policy.MaxRestarts)
+	}
+	if m.cmd == nil || m.cmd.Process == nil || m.stopping {
+		return fmt.Errorf("no running process")
+	}
+
+	m.logger.Warn("killing unresponsive process for restart", "pid", m.pid)
+	if err := m.kill(m.cmd.Process, syscall.SIGKILL); err != nil {
+		return fmt.Errorf("failed to kill process: %w", err)
+	}
+	return nil
+}
+
 // Stop gracefully stops the process with SIGTERM, then SIGKILL if needed
 func (m *Manager) Stop() error {
 	m.mu.Lock()
@@ -207,41 +562,89 @@ func (m *Manager) Stop() error {
 	if m.cmd == nil || m.cmd.Process == nil {
 		return fmt.Errorf("no process to stop")
 	}
+	m.stopping = true
+
+	// Already exited (or stopped by a concurrent shutdown path), nothing to signal
+	select {
+	case <-m.exited:
+		m.cancel()
+		return nil
+	default:
+	}
 
 	m.logger.Info("stopping process", "pid", m.pid)
 
 	// Try graceful shutdown first (SIGTERM)
-	if err := m.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+	if err := m.kill(m.cmd.Process, syscall.SIGTERM); err != nil {
 		// Process might already be dead
 		m.logger.Warn("failed to send SIGTERM", "pid", m.pid, "error", err)
 	}
 
 	// Wait a bit for graceful shutdown
-	done := make(chan error, 1)
-	go func() {
-		done <- m.cmd.Wait()
-	}()
-
+	// The monitor goroutine owns cmd.Wait and closes exited once the process is reaped
 	select {
 	case <-time.After(10 * time.Second):
 		// Force kill if not stopped gracefully
 		m.logger.Warn("process did not stop gracefully, sending SIGKILL", "pid", m.pid)
-		if err := m.cmd.Process.Kill(); err != nil {
+		if err := m.kill(m.cmd.Process, syscall.SIGKILL); err != nil {
 			return fmt.Errorf("failed to kill process: %w", err)
 		}
-	case err := <-done:
-		if err != nil {
-			m.logger.Info("process stopped with error", "pid", m.pid, "error", err)
-		} else {
-			m.logger.Info("process stopped gracefully", "pid", m.pid)
-		}
+	case <-m.exited:
+		m.logger.Info("process stopped gracefully", "pid", m.pid)
 	}
 
 	m.cancel() // Cancel context
-	m.setState(StateStopped)
+
+	// m.mu is already held here (setState would deadlock)
+	m.setStateLocked(StateStopped)
 	return nil
 }
 
+// ErrNotRunning is returned by Signal when no process is alive
+var ErrNotRunning = errors.New("process is not running")
+
+// Signal sends sig to the running process and returns its PID
+// Only the process itself is signalled, not its group, so a wrapper (e.g. `conda run`)
+// receives it rather than the app it started. Fails if no process is alive.
+func (m *Manager) Signal(sig syscall.Signal) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.cmd == nil || m.cmd.Process == nil || m.exited == nil {
+		return 0, ErrNotRunning
+	}
+	select {
+	case <-m.exited:
+		return 0, ErrNotRunning
+	default:
+	}
+
+	if err := m.cmd.Process.Signal(sig); err != nil {
+		return 0, fmt.Errorf("failed to send %s: %w", sig, err)
+	}
+	return m.pid, nil
+}
+
+// kill sends sig to the process and, unless NoProcessGroup is set, to its whole group
+// Without its own group the process shares ours, which must not be signalled.
+func (m *Manager) kill(process *os.Process, sig syscall.Signal) error {
+	if m.config.NoProcessGroup {
+		return process.Signal(sig)
+	}
+	return signalGroup(process, sig)
+}
+
+// signalGroup sends sig to the process group led by process
+// The app runs in its own group (Setpgid), so this also reaches what wrappers like
+// `conda run` or shell scripts spawned, which would otherwise be orphaned. Falls back to
+// signalling the process alone if the group can't be signalled.
+func signalGroup(process *os.Process, sig syscall.Signal) error {
+	if err := syscall.Kill(-process.Pid, sig); err == nil {
+		return nil
+	}
+	return process.Signal(sig)
+}
+
 // GetState returns the current process state (thread-safe)
 func (m *Manager) GetState() ProcessState {
 	m.mu.RLock()
@@ -256,15 +659,75 @@ func (m *Manager) GetPID() int {
 	return m.pid
 }
 
+// MarkFailed marks the process as failed without it having been started
+// Used when a pre-start step (e.g. git clone) fails so the interim page can report it
+func (m *Manager) MarkFailed() {
+	m.fail()
+}
+
+// fail marks both the process state and the startup phase as failed
+func (m *Manager) fail() {
+	m.setState(StateFailed)
+	m.config.Phases.Set(PhaseFailed)
+}
+
+// GetStartupPhase returns the current startup phase
+func (m *Manager) GetStartupPhase() StartupPhase {
+	return m.config.Phases.Current()
+}
+
+// GetStartupPhases returns the startup phases entered so far, oldest first
+func (m *Manager) GetStartupPhases() []PhaseTransition {
+	return m.config.Phases.History()
+}
+
+// GetRestartCount returns the number of automatic restarts performed so far
+func (m *Manager) GetRestartCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.restarts
+}
+
+// CrashLoopDetected reports whether restarts were stopped because of a crash loop
+func (m *Manager) CrashLoopDetected() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.crashLoop
+}
+
+// GetStatusMessage returns a human-readable explanation of the current state
+// Empty unless the manager recorded a reason (e.g. a detected crash loop)
+func (m *Manager) GetStatusMessage() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.statusMessage
+}
+
 // IsRunning returns true if the process is currently running
 func (m *Manager) IsRunning() bool {
 	return m.GetState() == StateRunning
 }
 
+// IsAlive returns true if a launched process has not exited yet, whether or not it passed its ready check
+func (m *Manager) IsAlive() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.exited == nil {
+		return false
+	}
+	select {
+	case <-m.exited:
+		return false
+	default:
+		return true
+	}
+}
+
 // streamOutput reads from a pipe and logs each line
 // This ensures all subprocess output is visible for debugging
-func (m *Manager) streamOutput(wg *sync.WaitGroup, stream string, reader io.Reader) {
+func (m *Manager) streamOutput(wg *sync.WaitGroup, stream string, reader io.ReadCloser) {
 	defer wg.Done()
+	defer reader.Close()
 
 	scanner := bufio.NewScanner(reader)
 	// Increase buffer size for long log lines
@@ -289,16 +752,46 @@ func (m *Manager) streamOutput(wg *sync.WaitGroup, stream string, reader io.Read
 	}
 }
 
+// emit passes a manager-generated line to the output handler so it shows up with the process logs
+func (m *Manager) emit(stream, line string) {
+	if m.config.OutputHandler != nil {
+		m.config.OutputHandler(stream, line)
+	}
+}
+
+// setStateIfCurrent updates the state only if generation is still the latest launch
+// Returns false when the process has been relaunched since
+func (m *Manager) setStateIfCurrent(generation int, state ProcessState) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.generation != generation {
+		return false
+	}
+	m.setStateLocked(state)
+	return true
+}
+
 // setState safely updates the process state
 func (m *Manager) setState(state ProcessState) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.setStateLocked(state)
+}
+
+// setStateLocked updates the process state and reports actual transitions; the caller must hold m.mu
+func (m *Manager) setStateLocked(state ProcessState) {
 	oldState := m.state
 	m.state = state
+	if oldState == state {
+		return
+	}
 	m.logger.Debug("process state changed",
 		"from", oldState,
 		"to", state,
 		"pid", m.pid)
+	if m.config.OnStateChange != nil {
+		m.config.OnStateChange(oldState, state)
+	}
 }
 
 // GetUptime returns how long the process has been running
@@ -319,9 +812,35 @@ func (m *Manager) GetUptime() time.Duration {
 
 // GetCommand returns the command being executed
 func (m *Manager) GetCommand() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.config.Command
 }
 
+// GetUserCommand returns the command the user asked for, without the conda run wrapper
+func (m *Manager) GetUserCommand() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.config.UserCommand == nil {
+		return m.config.Command
+	}
+	return m.config.UserCommand
+}
+
+// SetCommand replaces the command, user command and environment used from the next launch on
+// The running process keeps its command; Restart switches to the new one.
+func (m *Manager) SetCommand(command, userCommand []string, env map[string]string) error {
+	if len(command) == 0 {
+		return fmt.Errorf("command cannot be empty")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config.Command = command
+	m.config.UserCommand = userCommand
+	m.config.Env = env
+	return nil
+}
+
 // GetWorkDir returns the working directory
 func (m *Manager) GetWorkDir() string {
 	return m.config.WorkDir
@@ -0,0 +1,694 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+func TestManager_CrashLoopStopsRestarts(t *testing.T) {
+	var mu sync.Mutex
+	launches := 0
+	var output []string
+
+	mgr, err := NewManager(Config{
+		// Exits immediately with an error, like an app with a broken import
+		Command: []string{"sh", "-c", "echo launched; exit 1"},
+		OutputHandler: func(stream, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if line == "launched" {
+				launches++
+			}
+			output = append(output, line)
+		},
+		RestartPolicy: RestartPolicy{
+			MaxRestarts:        100,
+			CrashLoopThreshold: 3,
+			CrashLoopWindow:    time.Minute,
+		},
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !mgr.CrashLoopDetected() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !mgr.CrashLoopDetected() {
+		t.Fatal("expected crash loop to be detected")
+	}
+
+	// Give a stray restart time to show up before counting
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Initial launch plus CrashLoopThreshold restarts, then no more
+	if launches != 4 {
+		t.Errorf("expected 4 launches (1 initial + 3 restarts), got %d", launches)
+	}
+	if state := mgr.GetState(); state != StateFailed {
+		t.Errorf("expected state %s, got %s", StateFailed, state)
+	}
+	if msg := mgr.GetStatusMessage(); !strings.Contains(msg, "crash loop detected") {
+		t.Errorf("expected crash loop status message, got %q", msg)
+	}
+	if last := output[len(output)-1]; !strings.Contains(last, "crash loop detected") {
+		t.Errorf("expected crash loop message in process output, got %q", last)
+	}
+}
+
+func TestManager_RestartBudget(t *testing.T) {
+	var mu sync.Mutex
+	launches := 0
+
+	mgr, err := NewManager(Config{
+		Command: []string{"sh", "-c", "echo launched; exit 1"},
+		OutputHandler: func(stream, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if line == "launched" {
+				launches++
+			}
+		},
+		RestartPolicy: RestartPolicy{MaxRestarts: 2},
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := launches
+		mu.Unlock()
+		if n >= 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if launches != 3 {
+		t.Errorf("expected 3 launches (1 initial + 2 restarts), got %d", launches)
+	}
+	if mgr.CrashLoopDetected() {
+		t.Error("expected no crash loop without a threshold")
+	}
+}
+
+func TestManager_StopIsNotAFailure(t *testing.T) {
+	var mu sync.Mutex
+	launches := 0
+
+	mgr, err := NewManager(Config{
+		Command: []string{"sh", "-c", "echo launched; exec sleep 30"},
+		OutputHandler: func(stream, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if line == "launched" {
+				launches++
+			}
+		},
+		RestartPolicy: RestartPolicy{MaxRestarts: 5},
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	start := time.Now()
+	if err := mgr.Stop(); err != nil {
+		t.Fatalf("failed to stop process: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected SIGTERM to stop the process promptly, took %v", elapsed)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if state := mgr.GetState(); state != StateStopped {
+		t.Errorf("expected state %s after Stop, got %s", StateStopped, state)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if launches > 1 {
+		t.Errorf("expected no restart after Stop, got %d launches", launches)
+	}
+}
+
+func TestManager_StopSignalsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	// A wrapper that backgrounds the real app, like `conda run` forking python
+	mgr, err := NewManager(Config{
+		Command: []string{"sh", "-c", "sleep 300 & echo $! > " + pidFile + "; wait"},
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	var childPID int
+	deadline := time.Now().Add(5 * time.Second)
+	for childPID == 0 {
+		if data, err := os.ReadFile(pidFile); err == nil && strings.HasSuffix(string(data), "\n") {
+			childPID, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("child process did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Don't leak the child if the test fails
+	defer func() { _ = syscall.Kill(childPID, syscall.SIGKILL) }()
+
+	if err := mgr.Stop(); err != nil {
+		t.Fatalf("failed to stop process: %v", err)
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for processAlive(childPID) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected child %d to be stopped with the process group", childPID)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestManager_NoProcessGroup(t *testing.T) {
+	ourGroup := syscall.Getpgrp()
+
+	tests := []struct {
+		name           string
+		noProcessGroup bool
+		wantOurGroup   bool
+	}{
+		{name: "own group by default", noProcessGroup: false, wantOurGroup: false},
+		{name: "shares our group", noProcessGroup: true, wantOurGroup: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr, err := NewManager(Config{
+				Command:        []string{"sleep", "300"},
+				NoProcessGroup: tt.noProcessGroup,
+			}, logger.New(logger.Config{Output: io.Discard}))
+			if err != nil {
+				t.Fatalf("failed to create manager: %v", err)
+			}
+			if err := mgr.Start(context.Background()); err != nil {
+				t.Fatalf("failed to start process: %v", err)
+			}
+
+			pid := mgr.GetPID()
+			pgid, err := syscall.Getpgid(pid)
+			if err != nil {
+				t.Fatalf("failed to get process group: %v", err)
+			}
+			if got := pgid == ourGroup; got != tt.wantOurGroup {
+				t.Errorf("process group %d, ours %d: expected shared = %v", pgid, ourGroup, tt.wantOurGroup)
+			}
+			if !tt.wantOurGroup && pgid != pid {
+				t.Errorf("expected the process to lead its own group, got pgid %d for pid %d", pgid, pid)
+			}
+
+			// Stopping must only signal the process, never the group it shares with us
+			if err := mgr.Stop(); err != nil {
+				t.Fatalf("failed to stop process: %v", err)
+			}
+			if state := mgr.GetState(); state != StateStopped {
+				t.Errorf("expected state %s, got %s", StateStopped, state)
+			}
+		})
+	}
+}
+
+// processAlive reports whether pid is running
+// An orphaned child that exited may stay a zombie until init reaps it, which counts as gone.
+func processAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		// No procfs (macOS): the signal probe is all there is
+		return true
+	}
+	// The state follows the parenthesised command name
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+func TestManager_SIGTERMDuringReadyCheck(t *testing.T) {
+	// Cancelled by a real SIGTERM, as main's signal handling does
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	checking := make(chan struct{})
+	mgr, err := NewManager(Config{
+		Command:      []string{"sleep", "30"},
+		ReadyTimeout: time.Minute,
+		// Never becomes ready, like an app still loading when the pod is terminated
+		ReadyCheck: func(ctx context.Context) error {
+			close(checking)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	pid := mgr.GetPID()
+
+	select {
+	case <-checking:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ready check did not start")
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("failed to send SIGTERM: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mgr.IsAlive() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if mgr.IsAlive() {
+		t.Fatal("expected subprocess to be terminated after SIGTERM during ready check")
+	}
+	if err := syscall.Kill(pid, 0); err == nil {
+		t.Errorf("expected process %d to be gone", pid)
+	}
+
+	// Stop may still be finishing up after the process was reaped
+	deadline = time.Now().Add(time.Second)
+	for mgr.GetState() != StateStopped && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if state := mgr.GetState(); state != StateStopped {
+		t.Errorf("expected state %s, got %s", StateStopped, state)
+	}
+}
+
+func TestRestartPolicy_Backoff(t *testing.T) {
+	policy := RestartPolicy{
+		BackoffInitial:    time.Second,
+		BackoffMax:        5 * time.Second,
+		BackoffMultiplier: 2,
+	}
+
+	tests := []struct {
+		restart int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{10, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.backoff(tt.restart); got != tt.want {
+			t.Errorf("restart %d: expected backoff %v, got %v", tt.restart, tt.want, got)
+		}
+	}
+
+	if got := (RestartPolicy{}).backoff(3); got != 0 {
+		t.Errorf("expected no backoff without BackoffInitial, got %v", got)
+	}
+}
+
+func TestManager_RestartBackoff(t *testing.T) {
+	var mu sync.Mutex
+	var launches []time.Time
+
+	mgr, err := NewManager(Config{
+		Command: []string{"sh", "-c", "echo launched; exit 1"},
+		OutputHandler: func(stream, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if line == "launched" {
+				launches = append(launches, time.Now())
+			}
+		},
+		RestartPolicy: RestartPolicy{
+			MaxRestarts:       2,
+			BackoffInitial:    200 * time.Millisecond,
+			BackoffMax:        300 * time.Millisecond,
+			BackoffMultiplier: 2,
+		},
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(launches)
+		mu.Unlock()
+		if n >= 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if count := mgr.GetRestartCount(); count != 2 {
+		t.Fatalf("expected restart count 2, got %d", count)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(launches) != 3 {
+		t.Fatalf("expected 3 launches (1 initial + 2 restarts), got %d", len(launches))
+	}
+	// 200ms before the first restart, then 400ms capped to 300ms
+	if gap := launches[1].Sub(launches[0]); gap < 200*time.Millisecond {
+		t.Errorf("expected at least 200ms before the first restart, got %v", gap)
+	}
+	if gap := launches[2].Sub(launches[1]); gap < 300*time.Millisecond {
+		t.Errorf("expected at least 300ms before the second restart, got %v", gap)
+	}
+}
+
+func TestManager_StopDuringBackoff(t *testing.T) {
+	var mu sync.Mutex
+	launches := 0
+
+	mgr, err := NewManager(Config{
+		Command: []string{"sh", "-c", "echo launched; exit 1"},
+		OutputHandler: func(stream, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if line == "launched" {
+				launches++
+			}
+		},
+		RestartPolicy: RestartPolicy{
+			MaxRestarts:    5,
+			BackoffInitial: time.Minute,
+		},
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mgr.GetRestartCount() < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := mgr.Stop(); err != nil {
+		t.Fatalf("failed to stop process: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if state := mgr.GetState(); state != StateStopped {
+		t.Errorf("expected state %s after Stop during backoff, got %s", StateStopped, state)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if launches != 1 {
+		t.Errorf("expected no restart after Stop during backoff, got %d launches", launches)
+	}
+}
+
+func TestManager_Restart(t *testing.T) {
+	var mu sync.Mutex
+	launches := 0
+
+	mgr, err := NewManager(Config{
+		Command: []string{"sh", "-c", "echo launched; exec sleep 30"},
+		OutputHandler: func(stream, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if line == "launched" {
+				launches++
+			}
+		},
+		// A budget that would wrongly be spent if the SIGTERM exit counted as a crash
+		RestartPolicy: RestartPolicy{MaxRestarts: 5},
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	if err := mgr.Restart(); err == nil {
+		t.Error("expected an error restarting a manager that was never started")
+	}
+
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+	}()
+	firstPID := mgr.GetPID()
+
+	waitForLaunches := func(n int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			got := launches
+			mu.Unlock()
+			if got >= n {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitForLaunches(1)
+
+	if err := mgr.Restart(); err != nil {
+		t.Fatalf("failed to restart process: %v", err)
+	}
+	waitForLaunches(2)
+	time.Sleep(200 * time.Millisecond)
+
+	if pid := mgr.GetPID(); pid == firstPID {
+		t.Errorf("expected a new PID after restart, still %d", pid)
+	}
+	if err := syscall.Kill(firstPID, 0); err == nil {
+		t.Errorf("expected original process %d to be gone", firstPID)
+	}
+	if state := mgr.GetState(); state != StateRunning {
+		t.Errorf("expected state %s after restart, got %s", StateRunning, state)
+	}
+	if count := mgr.GetRestartCount(); count != 0 {
+		t.Errorf("expected manual restart not to count as an automatic restart, got %d", count)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if launches != 2 {
+		t.Errorf("expected 2 launches, got %d", launches)
+	}
+}
+
+func TestManager_SetCommand(t *testing.T) {
+	lines := make(chan string, 10)
+	mgr, err := NewManager(Config{
+		Command:       []string{"sh", "-c", "echo first; exec sleep 30"},
+		OutputHandler: func(stream, line string) { lines <- line },
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+	}()
+
+	waitForLine := func(want string) {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case line := <-lines:
+				if line == want {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("expected output %q", want)
+			}
+		}
+	}
+	waitForLine("first")
+
+	if err := mgr.SetCommand(nil, nil, nil); err == nil {
+		t.Error("expected an error for an empty command")
+	}
+	newCommand := []string{"sh", "-c", "echo $GREETING; exec sleep 30"}
+	if err := mgr.SetCommand(newCommand, []string{"greet"}, map[string]string{"GREETING": "second"}); err != nil {
+		t.Fatalf("failed to set command: %v", err)
+	}
+	if got := mgr.GetUserCommand(); len(got) != 1 || got[0] != "greet" {
+		t.Errorf("expected user command [greet], got %v", got)
+	}
+
+	if err := mgr.Restart(); err != nil {
+		t.Fatalf("failed to restart process: %v", err)
+	}
+	waitForLine("second")
+}
+
+func TestNewManager_InvalidNice(t *testing.T) {
+	for _, nice := range []int{MinNice - 1, MaxNice + 1} {
+		_, err := NewManager(Config{
+			Command: []string{"sleep", "30"},
+			Nice:    nice,
+		}, logger.New(logger.Config{Output: io.Discard}))
+		if err == nil {
+			t.Errorf("expected error for nice %d, got nil", nice)
+		}
+	}
+}
+
+func TestManager_RestartUnhealthy(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+
+	t.Run("restart policy disabled", func(t *testing.T) {
+		mgr, err := NewManager(Config{Command: []string{"sleep", "30"}}, log)
+		if err != nil {
+			t.Fatalf("failed to create manager: %v", err)
+		}
+		if err := mgr.Start(context.Background()); err != nil {
+			t.Fatalf("failed to start process: %v", err)
+		}
+		defer func() { _ = mgr.Stop() }()
+
+		if err := mgr.RestartUnhealthy(); err == nil {
+			t.Error("expected an error without a restart policy")
+		}
+		if !mgr.IsAlive() {
+			t.Error("expected the process to be left running")
+		}
+	})
+
+	t.Run("killed and restarted", func(t *testing.T) {
+		mgr, err := NewManager(Config{
+			Command:       []string{"sleep", "30"},
+			RestartPolicy: RestartPolicy{MaxRestarts: 1},
+		}, log)
+		if err != nil {
+			t.Fatalf("failed to create manager: %v", err)
+		}
+		if err := mgr.Start(context.Background()); err != nil {
+			t.Fatalf("failed to start process: %v", err)
+		}
+		defer func() { _ = mgr.Stop() }()
+
+		firstPID := mgr.GetPID()
+		mgr.SetDegraded("not answering")
+		if !mgr.IsDegraded() || mgr.GetStatusMessage() != "not answering" {
+			t.Fatalf("expected degraded state with message, got %v %q", mgr.IsDegraded(), mgr.GetStatusMessage())
+		}
+
+		if err := mgr.RestartUnhealthy(); err != nil {
+			t.Fatalf("expected restart, got %v", err)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for mgr.GetRestartCount() < 1 || !mgr.IsRunning() || mgr.GetPID() == firstPID {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected the process to be restarted, state %s restarts %d", mgr.GetState(), mgr.GetRestartCount())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if mgr.IsDegraded() {
+			t.Error("expected the restarted process not to be degraded")
+		}
+
+		// The budget of one restart is spent
+		if err := mgr.RestartUnhealthy(); err == nil {
+			t.Error("expected an error once the restart budget is exhausted")
+		}
+	})
+}
+
+func TestManagerWithLogs_SubscribeState(t *testing.T) {
+	mgr, err := NewManagerWithLogs(Config{Command: []string{"true"}},
+		LogCaptureConfig{Enabled: true, BufferSize: 10}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() { _ = mgr.CloseLogFile() }()
+
+	subscribers := func() int {
+		n := 0
+		mgr.stateSubscribers.Range(func(_, _ any) bool { n++; return true })
+		return n
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := mgr.SubscribeState(ctx)
+	if got := <-changes; got.State != StateInitializing {
+		t.Fatalf("expected the current state first, got %+v", got)
+	}
+
+	// A subscriber that doesn't keep up loses the oldest transitions, not the latest
+	for i := 0; i < stateSubscriberBuffer+5; i++ {
+		mgr.publishState(StateStarting, StateRunning)
+	}
+	mgr.publishState(StateRunning, StateStopped)
+	var last StateChange
+	for len(changes) > 0 {
+		last = <-changes
+	}
+	if last.State != StateStopped {
+		t.Errorf("expected the latest transition to be kept, got %+v", last)
+	}
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for subscribers() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the subscriber to be removed once its context is cancelled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
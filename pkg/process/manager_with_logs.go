@@ -3,6 +3,7 @@ package process
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
@@ -12,8 +13,21 @@ import (
 type ManagerWithLogs struct {
 	*Manager
 	logBuffer *LogBuffer
+
+	stateSubscribers sync.Map // chan StateChange -> struct{}, fed by publishState
+}
+
+// StateChange is a process state transition delivered to SubscribeState subscribers
+type StateChange struct {
+	Previous  ProcessState `json:"previous,omitempty"` // Empty for the current state sent on subscription
+	State     ProcessState `json:"state"`
+	Timestamp time.Time    `json:"timestamp"`
 }
 
+// stateSubscriberBuffer is how many transitions a slow subscriber may fall behind
+// before the oldest pending one is dropped
+const stateSubscriberBuffer = 16
+
 // NewManagerWithLogs creates a process manager with log capture
 func NewManagerWithLogs(cfg Config, logCfg LogCaptureConfig, log *logger.Logger) (*ManagerWithLogs, error) {
 	var logBuffer *LogBuffer
@@ -21,6 +35,15 @@ func NewManagerWithLogs(cfg Config, logCfg LogCaptureConfig, log *logger.Logger)
 	// Create log buffer if enabled
 	if logCfg.Enabled {
 		logBuffer = NewLogBuffer(logCfg.BufferSize)
+		if logCfg.Sink != nil {
+			logBuffer.SetSink(logCfg.Sink)
+		}
+		logBuffer.SetRedactor(logCfg.Redactor)
+		logBuffer.SetStripANSI(logCfg.StripANSI)
+		logBuffer.SetTimestampFormat(logCfg.TimestampFormat, logCfg.TimeZone)
+		logBuffer.SetRetention(logCfg.MaxAge, logCfg.PruneInterval)
+		logBuffer.SetMaxFileBytes(logCfg.MaxFileBytes)
+		logBuffer.SetNoSync(logCfg.NoSync)
 
 		// Store original handler
 		originalHandler := cfg.OutputHandler
@@ -42,16 +65,70 @@ func NewManagerWithLogs(cfg Config, logCfg LogCaptureConfig, log *logger.Logger)
 		}
 	}
 
+	m := &ManagerWithLogs{logBuffer: logBuffer}
+
+	// Fan state transitions out to subscribers, keeping any existing hook
+	originalOnStateChange := cfg.OnStateChange
+	cfg.OnStateChange = func(from, to ProcessState) {
+		m.publishState(from, to)
+		if originalOnStateChange != nil {
+			originalOnStateChange(from, to)
+		}
+	}
+
 	// Create base manager
 	mgr, err := NewManager(cfg, log)
 	if err != nil {
 		return nil, err
 	}
+	m.Manager = mgr
+
+	return m, nil
+}
+
+// SubscribeState returns a channel receiving the current state followed by every
+// state transition, until ctx is cancelled
+// The channel is never closed; stop reading once ctx is done. A subscriber that
+// falls behind loses its oldest pending transitions, never the latest one.
+func (m *ManagerWithLogs) SubscribeState(ctx context.Context) <-chan StateChange {
+	ch := make(chan StateChange, stateSubscriberBuffer)
+
+	// Registered before reading the current state, so no transition is missed in between
+	m.stateSubscribers.Store(ch, struct{}{})
+	offerStateChange(ch, StateChange{State: m.GetState(), Timestamp: time.Now()})
+
+	go func() {
+		<-ctx.Done()
+		m.stateSubscribers.Delete(ch)
+	}()
+	return ch
+}
+
+// publishState delivers a transition to every subscriber without blocking
+// Called by the manager with its lock held
+func (m *ManagerWithLogs) publishState(from, to ProcessState) {
+	change := StateChange{Previous: from, State: to, Timestamp: time.Now()}
+	m.stateSubscribers.Range(func(key, _ any) bool {
+		offerStateChange(key.(chan StateChange), change)
+		return true
+	})
+}
 
-	return &ManagerWithLogs{
-		Manager:   mgr,
-		logBuffer: logBuffer,
-	}, nil
+// offerStateChange queues change on ch, dropping the oldest pending change if ch is full
+func offerStateChange(ch chan StateChange, change StateChange) {
+	select {
+	case ch <- change:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- change:
+	default:
+	}
 }
 
 // AddErrorLog adds an error message directly to the log buffer
@@ -67,6 +144,19 @@ func (m *ManagerWithLogs) AddErrorLog(message string) {
 	}
 }
 
+// AddInfoLog adds an informational message directly to the log buffer
+// Useful for surfacing pre-start progress (e.g. git clone) in the interim UI
+func (m *ManagerWithLogs) AddInfoLog(message string) {
+	if m.logBuffer != nil {
+		m.logBuffer.Append(LogEntry{
+			Timestamp: time.Now(),
+			Stream:    "stdout",
+			Line:      message,
+			PID:       m.GetPID(),
+		})
+	}
+}
+
 // GetRecentLogs returns the most recent N log entries
 // Returns empty slice if log capture is disabled
 func (m *ManagerWithLogs) GetRecentLogs(n int) []LogEntry {
@@ -110,6 +200,45 @@ func (m *ManagerWithLogs) GetLogsByStream(stream string, n int) []LogEntry {
 	return entries
 }
 
+// GetLogsRange returns buffered logs with line numbers in [from, to] (1-based, lifetime numbering)
+// The second return value is the line number of the first returned entry
+func (m *ManagerWithLogs) GetLogsRange(from, to int) ([]LogEntry, int) {
+	if m.logBuffer == nil {
+		return []LogEntry{}, 0
+	}
+	entries, first := m.logBuffer.GetRange(from, to)
+	// Update PIDs
+	pid := m.GetPID()
+	for i := range entries {
+		entries[i].PID = pid
+	}
+	return entries, first
+}
+
+// GetLogsPage returns up to limit buffered logs starting at offset (0-based, lifetime numbering)
+// Also returns the offset to request next and whether more buffered logs follow
+func (m *ManagerWithLogs) GetLogsPage(offset, limit int) ([]LogEntry, int, bool) {
+	if m.logBuffer == nil {
+		return []LogEntry{}, 0, false
+	}
+	entries, next, hasMore := m.logBuffer.GetPage(offset, limit)
+	// Update PIDs
+	pid := m.GetPID()
+	for i := range entries {
+		entries[i].PID = pid
+	}
+	return entries, next, hasMore
+}
+
+// FindLastLogMatch returns the line number of the most recent buffered log containing query
+// Returns 0 if nothing matches or log capture is disabled
+func (m *ManagerWithLogs) FindLastLogMatch(query string) int {
+	if m.logBuffer == nil {
+		return 0
+	}
+	return m.logBuffer.FindLastMatch(query)
+}
+
 // GetLogStats returns statistics about captured logs
 func (m *ManagerWithLogs) GetLogStats() LogStats {
 	if m.logBuffer == nil {
@@ -118,6 +247,15 @@ func (m *ManagerWithLogs) GetLogStats() LogStats {
 	return m.logBuffer.GetStats()
 }
 
+// GetLogLevelSummary counts buffered logs by detected level and stream
+// Returns an empty summary if log capture is disabled
+func (m *ManagerWithLogs) GetLogLevelSummary() LevelSummary {
+	if m.logBuffer == nil {
+		return LevelSummary{Levels: map[string]int{}, Streams: map[string]int{}}
+	}
+	return m.logBuffer.GetLevelSummary()
+}
+
 // GetLogsJSON returns logs in JSON format for API responses
 func (m *ManagerWithLogs) GetLogsJSON(n int) ([]byte, error) {
 	if m.logBuffer == nil {
@@ -193,6 +331,14 @@ func (m *ManagerWithLogs) GetLogFilePath() string {
 	return m.logBuffer.GetLogFilePath()
 }
 
+// RotateLogFile closes and reopens the log file for external log rotation (see LogBuffer.Rotate)
+func (m *ManagerWithLogs) RotateLogFile() error {
+	if m.logBuffer != nil {
+		return m.logBuffer.Rotate()
+	}
+	return nil
+}
+
 // CloseLogFile closes and cleans up the log file
 func (m *ManagerWithLogs) CloseLogFile() error {
 	if m.logBuffer != nil {
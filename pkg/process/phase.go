@@ -0,0 +1,75 @@
+// Package process - Startup phase tracking for the interim page
+package process
+
+import (
+	"sync"
+	"time"
+)
+
+// StartupPhase is a coarse stage of bringing the app up, reported to the interim page
+// Phases mirror the logger.Progress stages logged along the way.
+type StartupPhase string
+
+const (
+	PhaseInitializing     StartupPhase = "initializing"
+	PhaseCloning          StartupPhase = "cloning"          // Cloning the --repo git repository
+	PhaseActivatingConda  StartupPhase = "activating_conda" // Resolving the --conda-env environment
+	PhaseStartingProcess  StartupPhase = "starting_process" // Launching the subprocess
+	PhaseWaitingForHealth StartupPhase = "waiting_for_health"
+	PhaseReady            StartupPhase = "ready"
+	PhaseFailed           StartupPhase = "failed"
+)
+
+// PhaseTransition records when a startup phase was entered
+type PhaseTransition struct {
+	Phase     StartupPhase `json:"phase"`
+	StartedAt time.Time    `json:"started_at"`
+}
+
+// PhaseTracker records the current startup phase and the phases passed through
+// Shared between main (clone, conda) and the Manager (start, health, ready), so it
+// can be created before either. A nil *PhaseTracker ignores updates.
+type PhaseTracker struct {
+	mu      sync.RWMutex
+	history []PhaseTransition
+}
+
+// NewPhaseTracker creates a tracker in the initializing phase
+func NewPhaseTracker() *PhaseTracker {
+	return &PhaseTracker{
+		history: []PhaseTransition{{Phase: PhaseInitializing, StartedAt: time.Now()}},
+	}
+}
+
+// Set moves to phase; setting the current phase again is a no-op
+func (t *PhaseTracker) Set(phase StartupPhase) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.history[len(t.history)-1].Phase == phase {
+		return
+	}
+	t.history = append(t.history, PhaseTransition{Phase: phase, StartedAt: time.Now()})
+}
+
+// Current returns the current startup phase
+func (t *PhaseTracker) Current() StartupPhase {
+	if t == nil {
+		return PhaseInitializing
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.history[len(t.history)-1].Phase
+}
+
+// History returns the phases entered so far, oldest first
+func (t *PhaseTracker) History() []PhaseTransition {
+	if t == nil {
+		return []PhaseTransition{}
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]PhaseTransition(nil), t.history...)
+}
@@ -0,0 +1,18 @@
+// Package process - Subprocess scheduling priority (niceness)
+package process
+
+import "fmt"
+
+// Niceness range accepted by setpriority(2); higher values mean lower priority
+const (
+	MinNice = -20
+	MaxNice = 19
+)
+
+// ValidateNice checks that nice is a valid niceness
+func ValidateNice(nice int) error {
+	if nice < MinNice || nice > MaxNice {
+		return fmt.Errorf("invalid nice value %d: expected %d..%d", nice, MinNice, MaxNice)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// setPriority sets the niceness of a running process
+// Niceness is per thread on Linux, so it is applied to every thread the process has
+// started so far; threads and children created later inherit it.
+func setPriority(pid, nice int) error {
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice); err != nil {
+		return fmt.Errorf("setpriority failed: %w", err)
+	}
+
+	tasks, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return nil // Process already gone or no /proc: the main thread is all we can do
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil || tid == pid {
+			continue
+		}
+		// Threads may exit while we iterate
+		_ = syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+//go:build linux
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+// readNice returns a process's niceness from /proc/<pid>/stat
+func readNice(t *testing.T, pid int) int {
+	t.Helper()
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		t.Fatalf("failed to read process stat: %v", err)
+	}
+	// The command name may contain spaces, so count fields after its closing paren:
+	// state is field 3 and nice is field 19
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	nice, err := strconv.Atoi(fields[19-3])
+	if err != nil {
+		t.Fatalf("failed to parse nice from %q: %v", data, err)
+	}
+	return nice
+}
+
+func TestManager_Nice(t *testing.T) {
+	own := readNice(t, os.Getpid())
+	if own > MaxNice-5 {
+		t.Skipf("test process already runs at nice %d", own)
+	}
+
+	mgr, err := NewManager(Config{
+		Command: []string{"sleep", "30"},
+		Nice:    own + 5,
+	}, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+	}()
+
+	if got := readNice(t, mgr.GetPID()); got != own+5 {
+		t.Errorf("expected nice %d, got %d", own+5, got)
+	}
+}
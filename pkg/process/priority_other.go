@@ -0,0 +1,10 @@
+//go:build !linux
+
+package process
+
+import "errors"
+
+// setPriority is only implemented on Linux
+func setPriority(pid, nice int) error {
+	return errors.New("setting process priority is only supported on Linux")
+}
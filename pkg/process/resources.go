@@ -0,0 +1,105 @@
+// Package process - Resource usage sampling for the managed subprocess
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// clockTicksPerSecond is USER_HZ, the unit of CPU times in /proc/<pid>/stat
+// It is 100 on all mainstream Linux architectures
+const clockTicksPerSecond = 100
+
+// ResourceUsage describes the resources consumed by the subprocess and its children
+type ResourceUsage struct {
+	MemoryRSSBytes uint64  `json:"memory_rss_bytes"` // Resident set size summed over the process group
+	CPUSeconds     float64 `json:"cpu_seconds"`      // Cumulative user+system CPU time of the process group
+	Processes      int     `json:"processes"`        // Number of live processes in the process group
+}
+
+// GetResourceUsage samples the current CPU and memory usage of the subprocess
+// The subprocess runs in its own process group, so the whole group is summed;
+// this covers wrappers such as `conda run` whose real work happens in a child.
+// Requires /proc (Linux); returns an error on other platforms or when no process is running.
+func (m *Manager) GetResourceUsage() (ResourceUsage, error) {
+	pid := m.GetPID()
+	if pid == 0 {
+		return ResourceUsage{}, fmt.Errorf("no process running")
+	}
+	return readProcessGroupUsage(pid)
+}
+
+// readProcessGroupUsage sums /proc/<pid>/stat values for every process in the given group
+func readProcessGroupUsage(pgid int) (ResourceUsage, error) {
+	statFiles, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil || len(statFiles) == 0 {
+		return ResourceUsage{}, fmt.Errorf("resource usage unavailable: /proc not mounted")
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	var usage ResourceUsage
+
+	for _, statFile := range statFiles {
+		data, err := os.ReadFile(statFile)
+		if err != nil {
+			continue // Process exited while scanning
+		}
+		stat, err := parseProcStat(string(data))
+		if err != nil || stat.pgrp != pgid {
+			continue
+		}
+		usage.Processes++
+		usage.MemoryRSSBytes += stat.rssPages * pageSize
+		usage.CPUSeconds += float64(stat.utime+stat.stime) / clockTicksPerSecond
+	}
+
+	if usage.Processes == 0 {
+		return ResourceUsage{}, fmt.Errorf("process group %d not found", pgid)
+	}
+	return usage, nil
+}
+
+// procStat holds the /proc/<pid>/stat fields we care about
+type procStat struct {
+	pgrp     int
+	utime    uint64
+	stime    uint64
+	rssPages uint64
+}
+
+// parseProcStat parses the content of /proc/<pid>/stat
+// The command name (field 2) may contain spaces and parentheses, so fields are
+// counted from the last closing parenthesis
+func parseProcStat(data string) (procStat, error) {
+	end := strings.LastIndexByte(data, ')')
+	if end < 0 {
+		return procStat{}, fmt.Errorf("malformed stat: missing command name")
+	}
+	// fields[0] is field 3 (state) in proc(5) numbering
+	fields := strings.Fields(data[end+1:])
+	if len(fields) < 22 {
+		return procStat{}, fmt.Errorf("malformed stat: %d fields", len(fields))
+	}
+
+	pgrp, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stat pgrp: %w", err)
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stat utime: %w", err)
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stat stime: %w", err)
+	}
+	rss, err := strconv.ParseUint(fields[21], 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stat rss: %w", err)
+	}
+
+	return procStat{pgrp: pgrp, utime: utime, stime: stime, rssPages: rss}, nil
+}
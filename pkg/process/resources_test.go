@@ -0,0 +1,44 @@
+package process
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestParseProcStat(t *testing.T) {
+	// Command name containing spaces and parentheses must not shift the fields
+	stat := "4242 (my (weird) app) S 1 4242 4242 0 -1 4194560 1000 0 0 0 250 50 0 0 20 0 3 0 12345 104857600 2560 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0"
+
+	got, err := parseProcStat(stat)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := procStat{pgrp: 4242, utime: 250, stime: 50, rssPages: 2560}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestParseProcStat_Malformed(t *testing.T) {
+	for _, stat := range []string{"", "4242 no-parens S 1", "4242 (app) S 1 2"} {
+		if _, err := parseProcStat(stat); err == nil {
+			t.Errorf("expected error for %q", stat)
+		}
+	}
+}
+
+func TestReadProcessGroupUsage_Self(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("/proc not available")
+	}
+
+	usage, err := readProcessGroupUsage(syscall.Getpgrp())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if usage.MemoryRSSBytes == 0 {
+		t.Error("expected non-zero RSS for the test process group")
+	}
+}
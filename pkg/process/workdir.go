@@ -0,0 +1,62 @@
+// Package process - Working directory validation
+package process
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// WorkDirPerm is the mode of a working directory created by PrepareWorkDir
+const WorkDirPerm = 0o750
+
+// WorkDirInfo describes a validated working directory
+type WorkDirInfo struct {
+	Path     string // Absolute path
+	OwnerUID int    // Owner of the directory
+	Created  bool   // Created by PrepareWorkDir
+}
+
+// PrepareWorkDir checks that path is a directory the app can run in
+// A missing directory is created with WorkDirPerm when create is set and is an error
+// otherwise, so the app doesn't fail later with a confusing exec error.
+func PrepareWorkDir(path string, create bool) (WorkDirInfo, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return WorkDirInfo{}, fmt.Errorf("failed to resolve working directory %q: %w", path, err)
+	}
+	info := WorkDirInfo{Path: abs}
+
+	stat, err := os.Stat(abs)
+	if errors.Is(err, fs.ErrNotExist) {
+		if !create {
+			return WorkDirInfo{}, fmt.Errorf("working directory %s does not exist (use --workdir-create to create it)", abs)
+		}
+		if err := os.MkdirAll(abs, WorkDirPerm); err != nil {
+			return WorkDirInfo{}, fmt.Errorf("failed to create working directory %s: %w", abs, err)
+		}
+		info.Created = true
+		stat, err = os.Stat(abs)
+	}
+	if err != nil {
+		return WorkDirInfo{}, fmt.Errorf("failed to stat working directory %s: %w", abs, err)
+	}
+	if !stat.IsDir() {
+		return WorkDirInfo{}, fmt.Errorf("working directory %s is not a directory", abs)
+	}
+
+	// Execute (search) permission is what chdir needs
+	if err := unix.Access(abs, unix.X_OK); err != nil {
+		return WorkDirInfo{}, fmt.Errorf("working directory %s is not accessible by uid %d: %w", abs, os.Getuid(), err)
+	}
+
+	if sys, ok := stat.Sys().(*syscall.Stat_t); ok {
+		info.OwnerUID = int(sys.Uid)
+	}
+	return info, nil
+}
@@ -0,0 +1,85 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrepareWorkDir(t *testing.T) {
+	t.Run("create on miss", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "apps", "dashboard")
+		info, err := PrepareWorkDir(dir, true)
+		if err != nil {
+			t.Fatalf("expected the directory to be created, got %v", err)
+		}
+		if info.Path != dir || !info.Created {
+			t.Errorf("expected created %s, got %+v", dir, info)
+		}
+		stat, err := os.Stat(dir)
+		if err != nil || !stat.IsDir() {
+			t.Fatalf("expected a directory at %s: %v", dir, err)
+		}
+		if perm := stat.Mode().Perm(); perm != WorkDirPerm {
+			t.Errorf("expected mode %o, got %o", WorkDirPerm, perm)
+		}
+		if info.OwnerUID != os.Getuid() {
+			t.Errorf("expected owner uid %d, got %d", os.Getuid(), info.OwnerUID)
+		}
+	})
+
+	t.Run("exists", func(t *testing.T) {
+		dir := t.TempDir()
+		// Relative paths are resolved
+		wd, _ := os.Getwd()
+		rel, err := filepath.Rel(wd, dir)
+		if err != nil {
+			t.Fatalf("failed to make relative path: %v", err)
+		}
+		info, err := PrepareWorkDir(rel, false)
+		if err != nil {
+			t.Fatalf("expected an existing directory to be accepted, got %v", err)
+		}
+		if info.Path != dir || info.Created {
+			t.Errorf("expected existing %s, got %+v", dir, info)
+		}
+	})
+
+	t.Run("exists without permission", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root can enter any directory")
+		}
+		dir := filepath.Join(t.TempDir(), "locked")
+		if err := os.Mkdir(dir, 0o600); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		t.Cleanup(func() { _ = os.Chmod(dir, 0o700) })
+
+		_, err := PrepareWorkDir(dir, false)
+		if err == nil || !strings.Contains(err.Error(), "not accessible") {
+			t.Errorf("expected a permission error, got %v", err)
+		}
+	})
+
+	t.Run("missing without create", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "missing")
+		_, err := PrepareWorkDir(dir, false)
+		if err == nil || !strings.Contains(err.Error(), "does not exist") {
+			t.Errorf("expected a missing directory error, got %v", err)
+		}
+		if _, statErr := os.Stat(dir); statErr == nil {
+			t.Error("expected the directory not to be created")
+		}
+	})
+
+	t.Run("not a directory", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0o600); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+		if _, err := PrepareWorkDir(file, true); err == nil {
+			t.Error("expected an error for a file")
+		}
+	})
+}
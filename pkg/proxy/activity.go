@@ -0,0 +1,67 @@
+// Package proxy - Activity of upgraded WebSocket connections
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/activity"
+)
+
+// wsActivityInterval is the least time between two activity records of one WebSocket connection
+// Frames can arrive many times a second; the activity reporter only needs the latest time.
+const wsActivityInterval = time.Second
+
+// wsActivityConn records activity whenever data crosses an upgraded WebSocket connection
+// A WebSocket app is used through its open connection, which the router sees only once,
+// at the upgrade; without this a user active for hours would look idle and get culled.
+type wsActivityConn struct {
+	io.ReadWriteCloser
+	tracker      *activity.Tracker
+	lastRecorded atomic.Int64 // Unix nanoseconds of the last record
+}
+
+// record notes activity, at most once per wsActivityInterval
+func (c *wsActivityConn) record() {
+	now := time.Now()
+	last := c.lastRecorded.Load()
+	if now.UnixNano()-last < int64(wsActivityInterval) {
+		return
+	}
+	if c.lastRecorded.CompareAndSwap(last, now.UnixNano()) {
+		c.tracker.RecordActivity()
+	}
+}
+
+// Write forwards client data to the backend
+func (c *wsActivityConn) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		c.record()
+	}
+	return c.ReadWriteCloser.Write(p)
+}
+
+// Read returns backend data for the client
+func (c *wsActivityConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	if n > 0 {
+		c.record()
+	}
+	return n, err
+}
+
+// trackWebSocketActivity wraps a 101 response to a WebSocket upgrade so traffic in either
+// direction counts as activity
+func (h *Handler) trackWebSocketActivity(resp *http.Response) {
+	if resp.StatusCode != http.StatusSwitchingProtocols || !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		return
+	}
+	backend, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return
+	}
+	resp.Body = &wsActivityConn{ReadWriteCloser: backend, tracker: h.activity}
+}
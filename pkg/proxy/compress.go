@@ -0,0 +1,180 @@
+// Package proxy - Response compression (--compress)
+package proxy
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressMinBytes is the smallest response with a known length worth compressing
+// Below this the encoding overhead outweighs the savings.
+const compressMinBytes = 1024
+
+// incompressibleTypes are content types (or type/ prefixes) that are already compressed
+var incompressibleTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/pdf",
+	"text/event-stream", // Compression would buffer events; streaming matters more
+}
+
+// compressibleImages are image types that are text and do compress
+var compressibleImages = map[string]bool{
+	"image/svg+xml": true,
+	"image/x-icon":  true,
+	"image/bmp":     true,
+}
+
+// compressResponse gzip (or deflate) encodes resp.Body if the client accepts it and the
+// backend didn't already encode the response
+// WebSocket upgrades (101), bodyless responses and already-compressed types are left alone.
+func (h *Handler) compressResponse(resp *http.Response) {
+	if resp.Request == nil || resp.Request.Method == http.MethodHead {
+		return
+	}
+	switch resp.StatusCode {
+	case http.StatusSwitchingProtocols, http.StatusNoContent, http.StatusNotModified:
+		return
+	}
+	if resp.Header.Get("Content-Encoding") != "" || resp.Header.Get("Content-Range") != "" {
+		return
+	}
+	if resp.ContentLength >= 0 && resp.ContentLength < compressMinBytes {
+		return
+	}
+	if !compressibleType(resp.Header.Get("Content-Type")) {
+		return
+	}
+	encoding := acceptedEncoding(resp.Request.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		return
+	}
+
+	resp.Body = newCompressedBody(resp.Body, encoding)
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	resp.Header.Set("Content-Encoding", encoding)
+	resp.Header.Add("Vary", "Accept-Encoding")
+	// The encoded bytes differ from what a strong ETag identifies
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		resp.Header.Set("ETag", "W/"+etag)
+	}
+}
+
+// compressibleType reports whether a response of this Content-Type is worth compressing
+// An unknown type is compressed; Go's content sniffing would label most of them text anyway.
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType == ""
+	}
+	if compressibleImages[mediaType] {
+		return true
+	}
+	for _, t := range incompressibleTypes {
+		if strings.HasPrefix(mediaType, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptedEncoding picks gzip, else deflate, from an Accept-Encoding header
+// Returns "" if the client accepts neither (a q=0 weight refuses an encoding).
+func acceptedEncoding(header string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[name] = q > 0
+	}
+	switch {
+	case accepted["gzip"]:
+		return "gzip"
+	case accepted["deflate"]:
+		return "deflate"
+	default:
+		return ""
+	}
+}
+
+// compressedBody encodes a backend body as it is read
+// The encoder is flushed after every backend read so streamed responses aren't held back.
+type compressedBody struct {
+	src io.ReadCloser
+	pr  *io.PipeReader
+}
+
+// flushWriter is an encoder that can emit what it has buffered so far
+type flushWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// newCompressedBody returns a body producing src encoded with encoding ("gzip" or "deflate")
+func newCompressedBody(src io.ReadCloser, encoding string) *compressedBody {
+	pr, pw := io.Pipe()
+	var enc flushWriter
+	if encoding == "deflate" {
+		enc = zlib.NewWriter(pw) // HTTP "deflate" is the zlib format
+	} else {
+		enc = gzip.NewWriter(pw)
+	}
+
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := src.Read(buf)
+			if n > 0 {
+				if _, werr := enc.Write(buf[:n]); werr != nil {
+					pw.CloseWithError(werr)
+					return
+				}
+				if werr := enc.Flush(); werr != nil {
+					pw.CloseWithError(werr)
+					return
+				}
+			}
+			if err == io.EOF {
+				pw.CloseWithError(enc.Close())
+				return
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	return &compressedBody{src: src, pr: pr}
+}
+
+func (b *compressedBody) Read(p []byte) (int, error) {
+	return b.pr.Read(p)
+}
+
+// Close stops the encoder and closes the backend body
+func (b *compressedBody) Close() error {
+	b.pr.Close()
+	return b.src.Close()
+}
@@ -0,0 +1,87 @@
+// Package proxy - Styled error responses when the backend cannot be reached
+package proxy
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// errorPageTemplate matches the look of the interim log viewer
+var errorPageTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Status}} {{.Title}}</title>
+    <style>
+        body { font-family: 'IBM Plex Sans', -apple-system, BlinkMacSystemFont, 'Segoe UI', sans-serif; background: #f8fafc; color: #0f172a; display: flex; align-items: center; justify-content: center; min-height: 100vh; margin: 0; }
+        .container { max-width: 36rem; padding: 2rem; }
+        .status { font-size: 0.875rem; font-weight: 600; color: #991b1b; letter-spacing: 0.05em; }
+        h1 { font-size: 1.5rem; font-weight: 600; margin: 0.5rem 0 1rem; }
+        p { color: #475569; line-height: 1.5; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="status">{{.Status}}</div>
+        <h1>{{.Title}}</h1>
+        <p>{{.Message}}</p>
+    </div>
+</body>
+</html>
+`))
+
+// handleProxyError is the reverse proxy's ErrorHandler
+// Timeouts (dial, --proxy-timeout) become 504 Gateway Timeout, anything else 502 Bad Gateway
+func (h *Handler) handleProxyError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadGateway
+	title := "The application is not responding"
+	message := "The proxy could not reach your application. It may have crashed or still be starting; try again in a moment."
+	logMsg := "backend request failed"
+
+	if isTimeout(err) {
+		status = http.StatusGatewayTimeout
+		title = "The application took too long to respond"
+		message = "The proxy timed out waiting for your application. It may be overloaded; try again in a moment."
+		logMsg = "backend request timed out"
+	}
+
+	h.logger.Warn(logMsg,
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status_code", status,
+		"proxy_timeout", h.timeout,
+		"error", err.Error())
+
+	writeErrorPage(w, r, status, title, message)
+}
+
+// writeErrorPage writes an HTML error page for browsers and plain text otherwise
+func writeErrorPage(w http.ResponseWriter, r *http.Request, status int, title, message string) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Error(w, title, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = errorPageTemplate.Execute(w, map[string]interface{}{
+		"Status":  status,
+		"Title":   title,
+		"Message": message,
+	})
+}
+
+// isTimeout reports whether err is a dial or response timeout
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -6,74 +6,322 @@ package proxy
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/nebari-dev/jhub-app-proxy/pkg/activity"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/audit"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/auth"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+	"golang.org/x/net/http2"
 )
 
+// DefaultDialTimeout bounds connecting to the backend
+// Shorter than the stdlib's 30s so a backend that is bound but not accepting fails fast
+const DefaultDialTimeout = 10 * time.Second
+
+// SocketHost is the host of the upstream URL when the app listens on a Unix domain socket
+// The transport ignores it and dials the socket; it is what the app sees as Host.
+const SocketHost = "localhost"
+
 // Handler forwards HTTP requests to the backend application
 type Handler struct {
-	manager       *process.ManagerWithLogs
-	upstreamURL   string
-	reverseProxy  *httputil.ReverseProxy
-	logger        *logger.Logger
-	authType      string
-	oauthMW       *auth.OAuthMiddleware
-	progressive   bool
-	servicePrefix string // JupyterHub service prefix
-	stripPrefix   bool   // Whether to strip prefix before forwarding (default: true)
+	manager        *process.ManagerWithLogs
+	upstreamURL    string
+	reverseProxy   *httputil.ReverseProxy
+	logger         *logger.Logger
+	authType       string
+	authMW         auth.Middleware // nil = no authentication
+	progressive    bool
+	servicePrefix  string          // JupyterHub service prefix
+	stripPrefix    bool            // Whether to strip prefix before forwarding (default: true)
+	allowedMethods map[string]bool // HTTP methods forwarded to the backend (nil = all)
+	preserveHost   bool            // Forward the client's Host header instead of the backend address
+	backendH2C     bool            // Speak HTTP/2 cleartext (h2c) to the backend
+	timeout        time.Duration   // Deadline for non-WebSocket backend requests (0 = unlimited)
+	noIndex        bool            // Serve a disallow-all robots.txt and mark responses noindex
+	maxURLLength   int             // Longest request URI forwarded, in bytes (0 = unlimited)
+	trustProxy     bool            // Keep client identity headers set by an upstream proxy
+	auditLog       *audit.Logger   // Records authenticated requests (nil = disabled)
+	routes         []route         // Additional backends by path prefix, longest prefix first
+
+	wsMaxMessageSize int64 // Largest WebSocket message forwarded from clients, in bytes (0 = unlimited)
+	compress         bool  // gzip/deflate responses the backend didn't encode
+
+	activity *activity.Tracker // Records WebSocket traffic as app activity (nil = disabled)
+}
+
+// Route sends requests under a path prefix to an additional backend, such as an API
+// server the app starts on a second port
+// Prefix is relative to the service prefix; the forwarded path is the same as for the app.
+type Route struct {
+	Prefix      string // e.g. "/api"; matches "/api" and "/api/..."
+	UpstreamURL string // e.g. "http://127.0.0.1:8502"
+}
+
+// route is a Route with its reverse proxy
+type route struct {
+	prefix       string
+	upstreamURL  string
+	reverseProxy *httputil.ReverseProxy
+}
+
+// Config contains configuration for the proxy handler
+type Config struct {
+	Manager        *process.ManagerWithLogs
+	UpstreamURL    string
+	UpstreamSocket string // Unix domain socket the app listens on; UpstreamURL's host is then SocketHost (empty = TCP)
+	AuthType       string
+	Auth           auth.Middleware // Authenticates app requests; if nil, AuthType "oauth" creates an OAuthMiddleware
+	Progressive    bool
+	ServicePrefix  string          // JupyterHub service prefix
+	StripPrefix    bool            // Whether to strip prefix before forwarding
+	AllowedMethods []string        // HTTP methods forwarded to the backend (empty = all)
+	PreserveHost   bool            // Forward the client's Host header instead of the backend address
+	BackendH2C     bool            // Speak HTTP/2 cleartext (h2c) to the backend instead of HTTP/1.1
+	DialTimeout    time.Duration   // TCP connect timeout to the backend (0 = DefaultDialTimeout)
+	BackendRetries int             // Retries of GET/HEAD requests whose backend connection is refused or reset (0 = none)
+	Timeout        time.Duration   // Deadline for backend responses, WebSocket upgrades exempt (0 = unlimited)
+	NoIndex        bool            // Ask search engines not to index the app (robots.txt + X-Robots-Tag)
+	MaxURLLength   int             // Longest request URI forwarded, in bytes; longer ones get 414 (0 = unlimited)
+	TrustProxy     bool            // Append to X-Forwarded-For and keep X-Real-IP/X-Forwarded-Host from an upstream proxy
+	AuditLog       *audit.Logger   // Audit trail of authenticated requests (nil = disabled)
+	Access         auth.AuthConfig // Users and groups allowed through OAuth (empty = any user)
+	Routes         []Route         // Additional backends by path prefix; the longest matching prefix wins
+	Logger         *logger.Logger
+
+	WSMaxMessageSize int64 // Close WebSocket connections whose client sends a larger message, in bytes (0 = unlimited)
+	Compress         bool  // gzip (or deflate) responses for clients that accept it, unless already encoded
+
+	Activity *activity.Tracker // Records traffic on open WebSocket connections as app activity (nil = disabled)
 }
 
 // NewHandler creates a new proxy handler
-func NewHandler(manager *process.ManagerWithLogs, upstreamURL string, authType string, progressive bool, servicePrefix string, stripPrefix bool, log *logger.Logger) (*Handler, error) {
-	target, _ := url.Parse(upstreamURL)
+func NewHandler(cfg Config) (*Handler, error) {
+	log := cfg.Logger
+	target, _ := url.Parse(cfg.UpstreamURL)
 
-	var oauthMW *auth.OAuthMiddleware
-	if authType == "oauth" {
-		var err error
-		oauthMW, err = auth.NewOAuthMiddleware(log)
+	authMW := cfg.Auth
+	if authMW == nil && cfg.AuthType == "oauth" {
+		oauthMW, err := auth.NewOAuthMiddleware(log)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create OAuth middleware: %w", err)
 		}
+		oauthMW.SetAuthConfig(cfg.Access)
+		authMW = oauthMW
+	}
+
+	var allowedMethods map[string]bool
+	if len(cfg.AllowedMethods) > 0 {
+		allowedMethods = make(map[string]bool, len(cfg.AllowedMethods))
+		for _, method := range cfg.AllowedMethods {
+			allowedMethods[strings.ToUpper(strings.TrimSpace(method))] = true
+		}
 	}
 
 	h := &Handler{
-		manager:       manager,
-		upstreamURL:   upstreamURL,
-		logger:        log,
-		authType:      authType,
-		oauthMW:       oauthMW,
-		progressive:   progressive,
-		servicePrefix: servicePrefix,
-		stripPrefix:   stripPrefix,
-	}
-
-	// Configure reverse proxy
-	if progressive {
-		// For progressive mode, use custom transport with flushing
-		h.reverseProxy = httputil.NewSingleHostReverseProxy(target)
-		h.reverseProxy.FlushInterval = -1 // Flush immediately on each write
-	} else {
-		h.reverseProxy = httputil.NewSingleHostReverseProxy(target)
+		manager:        cfg.Manager,
+		upstreamURL:    cfg.UpstreamURL,
+		logger:         log,
+		authType:       cfg.AuthType,
+		authMW:         authMW,
+		progressive:    cfg.Progressive,
+		servicePrefix:  cfg.ServicePrefix,
+		stripPrefix:    cfg.StripPrefix,
+		allowedMethods: allowedMethods,
+		preserveHost:   cfg.PreserveHost,
+		backendH2C:     cfg.BackendH2C,
+		timeout:        cfg.Timeout,
+		noIndex:        cfg.NoIndex,
+		maxURLLength:   cfg.MaxURLLength,
+		trustProxy:     cfg.TrustProxy,
+		auditLog:       cfg.AuditLog,
+
+		wsMaxMessageSize: cfg.WSMaxMessageSize,
+		compress:         cfg.Compress,
+
+		activity: cfg.Activity,
+	}
+
+	dialTimeout := cfg.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = DefaultDialTimeout
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}
+
+	dialBackend := dialer.DialContext
+	if cfg.UpstreamSocket != "" {
+		dialBackend = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", cfg.UpstreamSocket)
+		}
+		log.Info("forwarding to backend over unix socket", "socket", cfg.UpstreamSocket)
+	}
+	transport := newTransport(cfg, dialBackend)
+	if cfg.BackendH2C {
+		log.Info("forwarding to backend over HTTP/2 cleartext (h2c)", "upstream", cfg.UpstreamURL)
 	}
 
+	h.reverseProxy = h.newReverseProxy(target, transport)
+
+	// Routes always reach their backends over TCP
+	routeTransport := transport
+	if cfg.UpstreamSocket != "" && len(cfg.Routes) > 0 {
+		routeTransport = newTransport(cfg, dialer.DialContext)
+	}
+
+	for _, rt := range cfg.Routes {
+		prefix := "/" + strings.Trim(rt.Prefix, "/")
+		if prefix == "/" {
+			return nil, fmt.Errorf("invalid route %q: prefix must not be the app root", rt.Prefix)
+		}
+		routeTarget, err := url.Parse(rt.UpstreamURL)
+		if err != nil || routeTarget.Host == "" {
+			return nil, fmt.Errorf("invalid route %s: bad upstream URL %q", prefix, rt.UpstreamURL)
+		}
+		h.routes = append(h.routes, route{
+			prefix:       prefix,
+			upstreamURL:  rt.UpstreamURL,
+			reverseProxy: h.newReverseProxy(routeTarget, routeTransport),
+		})
+		log.Info("routing path prefix to additional backend", "prefix", prefix, "upstream", rt.UpstreamURL)
+	}
+	sort.SliceStable(h.routes, func(i, j int) bool {
+		return len(h.routes[i].prefix) > len(h.routes[j].prefix)
+	})
+
 	return h, nil
 }
 
+// newReverseProxy creates the reverse proxy forwarding to one backend
+func (h *Handler) newReverseProxy(target *url.URL, transport http.RoundTripper) *httputil.ReverseProxy {
+	rp := httputil.NewSingleHostReverseProxy(target)
+	if h.progressive {
+		rp.FlushInterval = -1 // Flush immediately on each write
+	}
+	rp.Transport = transport
+	rp.ErrorHandler = h.handleProxyError
+	if h.noIndex || h.wsMaxMessageSize > 0 || h.compress || h.activity != nil {
+		rp.ModifyResponse = func(resp *http.Response) error {
+			if h.noIndex {
+				resp.Header.Set("X-Robots-Tag", "noindex")
+			}
+			if h.wsMaxMessageSize > 0 {
+				h.limitWebSocket(resp)
+			}
+			if h.activity != nil {
+				h.trackWebSocketActivity(resp)
+			}
+			if h.compress {
+				h.compressResponse(resp)
+			}
+			return nil
+		}
+	}
+
+	// Decide which Host header the backend sees
+	// Apps doing virtual-host routing or building absolute URLs need the client's Host
+	director := rp.Director
+	rp.Director = func(req *http.Request) {
+		incomingHost := req.Host
+		director(req)
+		if h.preserveHost {
+			req.Host = incomingHost
+		} else {
+			req.Host = target.Host
+		}
+		// Let the app log the same correlation ID as the proxy's access log
+		if id := middleware.RequestIDFromContext(req.Context()); id != "" {
+			req.Header.Set(middleware.RequestIDHeader, id)
+		}
+	}
+	return rp
+}
+
+// backendFor returns the reverse proxy and upstream URL for a request path
+// Routes are checked longest prefix first on the path below the service prefix; anything
+// unmatched goes to the app.
+func (h *Handler) backendFor(path string) (*httputil.ReverseProxy, string) {
+	if len(h.routes) == 0 {
+		return h.reverseProxy, h.upstreamURL
+	}
+
+	appPath := path
+	if h.servicePrefix != "" && strings.HasPrefix(path, h.servicePrefix) {
+		appPath = path[len(h.servicePrefix):]
+	}
+	for _, rt := range h.routes {
+		if appPath == rt.prefix || strings.HasPrefix(appPath, rt.prefix+"/") {
+			return rt.reverseProxy, rt.upstreamURL
+		}
+	}
+	return h.reverseProxy, h.upstreamURL
+}
+
+// dialFunc opens a connection to a backend
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// newTransport returns the transport forwarding to a backend through dial
+func newTransport(cfg Config, dial dialFunc) http.RoundTripper {
+	transport := newBackendTransport(cfg, dial)
+	if cfg.BackendRetries > 0 {
+		return &retryTransport{next: transport, retries: cfg.BackendRetries, logger: cfg.Logger}
+	}
+	return transport
+}
+
+// newBackendTransport returns the HTTP/1.1 or h2c transport for a backend
+func newBackendTransport(cfg Config, dial dialFunc) http.RoundTripper {
+	// Forward over h2c for backends that only speak HTTP/2 (gRPC-web, some modern frameworks)
+	// The http2 transport dials plain TCP in place of TLS; WebSocket upgrades are not supported over it
+	if cfg.BackendH2C {
+		return newH2CTransport(dial)
+	}
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	httpTransport.DialContext = dial
+	// For WebSockets this only bounds the handshake; the upgraded connection has no deadline
+	httpTransport.ResponseHeaderTimeout = cfg.Timeout
+	return httpTransport
+}
+
+// newH2CTransport returns a transport speaking HTTP/2 without TLS (prior knowledge h2c)
+func newH2CTransport(dial dialFunc) *http2.Transport {
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dial(ctx, network, addr)
+		},
+	}
+}
+
 // ServeHTTP implements http.Handler
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	handler := http.HandlerFunc(h.serve)
+	// Answered before OAuth: crawlers are never logged in
+	if h.noIndex && h.isRobotsTxt(r) {
+		h.serveRobotsTxt(w, r)
+		return
+	}
+
+	var handler http.Handler = http.HandlerFunc(h.serve)
+
+	// Audit inside the auth wrapper so the authenticated user is known
+	if h.auditLog != nil {
+		handler = h.auditLog.Wrap(handler)
+	}
 
-	// Wrap with OAuth if enabled
-	if h.oauthMW != nil {
-		h.oauthMW.Wrap(handler).ServeHTTP(w, r)
+	// Wrap with OAuth or Basic auth if enabled
+	if h.authMW != nil {
+		h.authMW.Wrap(handler).ServeHTTP(w, r)
 	} else {
 		handler.ServeHTTP(w, r)
 	}
@@ -100,6 +348,40 @@ func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
 	h.logger.Debug("incoming request headers",
 		"headers", r.Header)
 
+	// Reject methods outside the allowlist before anything reaches the backend
+	// WebSocket upgrades are GET requests, so they pass whenever GET is allowed
+	if h.allowedMethods != nil && !h.allowedMethods[r.Method] {
+		h.logger.Warn("method not allowed, request blocked",
+			"method", r.Method,
+			"path", r.URL.Path)
+		w.Header().Set("Allow", strings.Join(h.allowedMethodList(), ", "))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Reject over-long URLs here with a readable page rather than a bare backend error
+	if h.maxURLLength > 0 && len(r.RequestURI) > h.maxURLLength {
+		h.logger.Warn("request URL too long, request blocked",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"url_length", len(r.RequestURI),
+			"max_url_length", h.maxURLLength)
+		writeErrorPage(w, r, http.StatusRequestURITooLong,
+			"The address of this page is too long",
+			"The link, usually its query string, exceeds the length the app accepts. Try removing some parameters or sharing a shorter link.")
+		return
+	}
+
+	// Bound how long a hung backend can hold the request
+	// Not for WebSockets: the deadline would cut long-lived upgraded connections
+	if h.timeout > 0 && !isWebSocket {
+		ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	reverseProxy, upstreamURL := h.backendFor(originalPath)
+
 	// Create response writer wrapper to capture response details
 	rw := &responseWriter{
 		ResponseWriter: w,
@@ -108,7 +390,8 @@ func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
 
 	// Strip prefix if configured (default for most apps like Streamlit, Voila, etc.)
 	// Don't strip for apps like JupyterLab that are configured with ServerApp.base_url
-	if h.stripPrefix && h.servicePrefix != "" {
+	// gRPC calls routed without the prefix are forwarded as they are
+	if h.stripPrefix && h.servicePrefix != "" && strings.HasPrefix(originalPath, h.servicePrefix) {
 		// Strip the service prefix from the path
 		// e.g., /user/admin/custom-py/index.html -> /index.html
 		if len(originalPath) > len(h.servicePrefix) {
@@ -120,8 +403,9 @@ func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
 		// Create new request with stripped path
 		newReq := r.Clone(r.Context())
 		newReq.URL.Path = forwardPath
+		h.setForwardedHeaders(newReq, r)
 
-		backendURL := h.upstreamURL + forwardPath
+		backendURL := upstreamURL + forwardPath
 		h.logger.Info("proxying request to backend (prefix stripped)",
 			"original_path", originalPath,
 			"forwarded_path", forwardPath,
@@ -136,10 +420,10 @@ func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
 				"remote_addr", r.RemoteAddr)
 		}
 
-		h.reverseProxy.ServeHTTP(rw, newReq)
+		reverseProxy.ServeHTTP(rw, newReq)
 	} else {
 		// Forward as-is (for apps configured with base_url like JupyterLab)
-		backendURL := h.upstreamURL + originalPath
+		backendURL := upstreamURL + originalPath
 		h.logger.Info("proxying request to backend (no stripping)",
 			"path", originalPath,
 			"backend_url", backendURL,
@@ -153,7 +437,9 @@ func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
 				"remote_addr", r.RemoteAddr)
 		}
 
-		h.reverseProxy.ServeHTTP(rw, r)
+		outReq := r.Clone(r.Context())
+		h.setForwardedHeaders(outReq, r)
+		reverseProxy.ServeHTTP(rw, outReq)
 	}
 
 	// Log response details (header names only at INFO level)
@@ -168,6 +454,86 @@ func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
 		"headers", rw.Header())
 }
 
+// isRobotsTxt reports whether r asks for robots.txt at the root of the app
+func (h *Handler) isRobotsTxt(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	return r.URL.Path == "/robots.txt" || r.URL.Path == strings.TrimSuffix(h.servicePrefix, "/")+"/robots.txt"
+}
+
+// serveRobotsTxt disallows crawling the whole app instead of proxying the backend's robots.txt
+func (h *Handler) serveRobotsTxt(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("serving built-in robots.txt", "path", r.URL.Path)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	_, _ = io.WriteString(w, "User-agent: *\nDisallow: /\n")
+}
+
+// setForwardedHeaders tells the backend who the client is and which scheme and host it used
+// Backends only see http://127.0.0.1:<port>, so frameworks building absolute URLs
+// (Streamlit, Panel, ...) need these. Set on the request before proxying, so
+// WebSocket upgrades carry them too.
+//
+// X-Forwarded-For, X-Real-IP and X-Forwarded-Host sent by the client are replaced
+// unless trustProxy is set, so the app sees a single trusted hop; with trustProxy the
+// client IP is appended to the X-Forwarded-For chain and upstream values are kept.
+// X-Forwarded-Proto from an outer proxy such as JupyterHub's configurable-http-proxy,
+// which terminates TLS, is always kept.
+//
+// Without authentication, identity headers sent by the client are dropped so they can't be
+// mistaken for ones set by the auth middleware.
+func (h *Handler) setForwardedHeaders(out, in *http.Request) {
+	if h.authMW == nil {
+		for _, header := range identityHeaders {
+			out.Header.Del(header)
+		}
+	}
+
+	if in.Header.Get("X-Forwarded-Proto") == "" {
+		proto := "http"
+		if in.TLS != nil {
+			proto = "https"
+		}
+		out.Header.Set("X-Forwarded-Proto", proto)
+	}
+
+	// httputil.ReverseProxy appends the client IP to X-Forwarded-For itself;
+	// dropping the incoming chain leaves just the client IP
+	if !h.trustProxy {
+		out.Header.Del("X-Forwarded-For")
+	}
+
+	if !h.trustProxy || in.Header.Get("X-Real-IP") == "" {
+		out.Header.Set("X-Real-IP", clientIP(in))
+	}
+	if !h.trustProxy || in.Header.Get("X-Forwarded-Host") == "" {
+		out.Header.Set("X-Forwarded-Host", in.Host)
+	}
+}
+
+// identityHeaders are set by the auth middleware for the authenticated user
+var identityHeaders = []string{"X-Forwarded-User", "X-Forwarded-Groups", "X-Forwarded-User-Data"}
+
+// clientIP returns the IP of the directly connected client, without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// allowedMethodList returns the allowed methods in sorted order for the Allow header
+func (h *Handler) allowedMethodList() []string {
+	methods := make([]string, 0, len(h.allowedMethods))
+	for method := range h.allowedMethods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
 // extractHeaderNames returns a slice of header names from an http.Header map
 func extractHeaderNames(headers http.Header) []string {
 	names := make([]string, 0, len(headers))
@@ -188,6 +554,13 @@ func (rw *responseWriter) WriteHeader(statusCode int) {
 	rw.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Unwrap returns the underlying ResponseWriter
+// This lets http.ResponseController reach the original writer (flushing, deadlines)
+// so streamed responses such as 206 partial content are not held back by the wrapper
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Hijack implements http.Hijacker interface for WebSocket upgrades
 // This allows the reverse proxy to take control of the underlying TCP connection
 // for protocol upgrades like WebSocket (HTTP/1.1 101 Switching Protocols)
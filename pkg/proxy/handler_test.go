@@ -0,0 +1,1226 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/audit"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestHandler_RangeRequest(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+
+	// Backend that supports range requests like Python's http.server
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "data.bin", time.Now(), bytes.NewReader(content))
+	}))
+	defer backend.Close()
+
+	tests := []struct {
+		name         string
+		progressive  bool
+		rangeHeader  string
+		contentRange string
+		start, end   int
+	}{
+		{name: "standard mode", progressive: false, rangeHeader: "bytes=100-199", contentRange: "bytes 100-199/10000", start: 100, end: 200},
+		{name: "progressive mode", progressive: true, rangeHeader: "bytes=100-199", contentRange: "bytes 100-199/10000", start: 100, end: 200},
+		{name: "suffix range", progressive: true, rangeHeader: "bytes=-50", contentRange: "bytes 9950-9999/10000", start: 9950, end: 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.New(logger.Config{Output: io.Discard})
+			h, err := NewHandler(Config{
+				UpstreamURL:   backend.URL,
+				AuthType:      "none",
+				Progressive:   tt.progressive,
+				ServicePrefix: "/user/alice/app",
+				StripPrefix:   true,
+				Logger:        log,
+			})
+			if err != nil {
+				t.Fatalf("failed to create handler: %v", err)
+			}
+
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/user/alice/app/data.bin", nil)
+			req.Header.Set("Range", tt.rangeHeader)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusPartialContent {
+				t.Fatalf("expected status 206, got %d", resp.StatusCode)
+			}
+			if got := resp.Header.Get("Content-Range"); got != tt.contentRange {
+				t.Errorf("expected Content-Range %q, got %q", tt.contentRange, got)
+			}
+			if got := resp.Header.Get("Accept-Ranges"); got != "bytes" {
+				t.Errorf("expected Accept-Ranges %q, got %q", "bytes", got)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+			if !bytes.Equal(body, content[tt.start:tt.end]) {
+				t.Errorf("expected bytes %d-%d of content, got %q", tt.start, tt.end-1, body)
+			}
+		})
+	}
+}
+
+func TestHandler_AllowedMethods(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	h, err := NewHandler(Config{
+		UpstreamURL:    backend.URL,
+		AuthType:       "none",
+		AllowedMethods: []string{"get", "POST"},
+		Logger:         log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	tests := []struct {
+		method     string
+		wantStatus int
+	}{
+		{method: http.MethodGet, wantStatus: http.StatusOK},
+		{method: http.MethodPost, wantStatus: http.StatusOK},
+		{method: http.MethodDelete, wantStatus: http.StatusMethodNotAllowed},
+		{method: http.MethodTrace, wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, "/", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusMethodNotAllowed && rec.Header().Get("Allow") != "GET, POST" {
+				t.Errorf("expected Allow header %q, got %q", "GET, POST", rec.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+func TestHandler_PreserveHost(t *testing.T) {
+	// Echo backend reflecting the Host header it received
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Host)
+	}))
+	defer backend.Close()
+	backendHost := strings.TrimPrefix(backend.URL, "http://")
+
+	tests := []struct {
+		name         string
+		preserveHost bool
+		wantHost     string
+	}{
+		{name: "preserve client host", preserveHost: true, wantHost: "apps.example.com"},
+		{name: "rewrite to backend host", preserveHost: false, wantHost: backendHost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.New(logger.Config{Output: io.Discard})
+			h, err := NewHandler(Config{
+				UpstreamURL:  backend.URL,
+				AuthType:     "none",
+				PreserveHost: tt.preserveHost,
+				Logger:       log,
+			})
+			if err != nil {
+				t.Fatalf("failed to create handler: %v", err)
+			}
+
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/", nil)
+			req.Host = "apps.example.com"
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.wantHost {
+				t.Errorf("expected backend to receive Host %q, got %q", tt.wantHost, body)
+			}
+		})
+	}
+}
+
+func TestHandler_RequestID(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Header.Get(middleware.RequestIDHeader))
+	}))
+	defer backend.Close()
+
+	h, err := NewHandler(Config{
+		UpstreamURL: backend.URL,
+		AuthType:    "none",
+		Logger:      logger.New(logger.Config{Output: io.Discard}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	srv := httptest.NewServer(middleware.RequestID(h))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	id := resp.Header.Get(middleware.RequestIDHeader)
+	if id == "" || string(body) != id {
+		t.Errorf("expected the backend to receive request ID %q, got %q", id, body)
+	}
+}
+
+func TestHandler_BackendH2C(t *testing.T) {
+	// Backend that only accepts HTTP/2 cleartext and reports the protocol it saw
+	h2cOnly := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			http.Error(w, "HTTP/2 required", http.StatusHTTPVersionNotSupported)
+			return
+		}
+		_, _ = io.WriteString(w, r.Proto)
+	})
+	backend := httptest.NewServer(h2c.NewHandler(h2cOnly, &http2.Server{}))
+	defer backend.Close()
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	h, err := NewHandler(Config{
+		UpstreamURL: backend.URL,
+		AuthType:    "none",
+		BackendH2C:  true,
+		Logger:      log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", resp.StatusCode, body)
+	}
+	if string(body) != "HTTP/2.0" {
+		t.Errorf("expected backend to receive HTTP/2.0, got %q", body)
+	}
+}
+
+func TestHandler_UnixSocket(t *testing.T) {
+	// App listening on a unix socket, answering with the Host and path it received
+	socketPath := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("failed to listen on unix socket: %v", err)
+	}
+	app := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "app %s %s", r.Host, r.URL.Path)
+	}))
+	app.Listener = listener
+	app.Start()
+	defer app.Close()
+
+	// A --route backend stays on TCP
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "api %s", r.URL.Path)
+	}))
+	defer api.Close()
+
+	h, err := NewHandler(Config{
+		UpstreamURL:    "http://" + SocketHost,
+		UpstreamSocket: socketPath,
+		AuthType:       "none",
+		Routes:         []Route{{Prefix: "/api", UpstreamURL: api.URL}},
+		Logger:         logger.New(logger.Config{Output: io.Discard}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/dashboard", want: "app " + SocketHost + " /dashboard"},
+		{path: "/api/items", want: "api /api/items"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandler_AuditLog(t *testing.T) {
+	// Mock hub resolving any token to alice
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, `{"name":"alice"}`)
+	}))
+	defer hub.Close()
+	t.Setenv("JUPYTERHUB_API_URL", hub.URL)
+	t.Setenv("JUPYTERHUB_API_TOKEN", "service-token")
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := audit.NewFileLogger(auditPath)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	h, err := NewHandler(Config{
+		UpstreamURL: backend.URL,
+		AuthType:    "oauth",
+		AuditLog:    auditLog,
+		Logger:      log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/dashboard"},
+		{http.MethodPost, "/missing"},
+	} {
+		r, _ := http.NewRequest(req.method, srv.URL+req.path, nil)
+		r.Header.Set("X-Jupyterhub-Api-Token", "user-token")
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Unauthenticated requests are redirected to login and not audited
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := noRedirect.Get(srv.URL + "/anonymous")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if err := auditLog.Close(); err != nil {
+		t.Fatalf("failed to close audit log: %v", err)
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d: %q", len(lines), data)
+	}
+
+	want := []audit.Entry{
+		{User: "alice", Method: http.MethodGet, Path: "/dashboard", Status: http.StatusOK},
+		{User: "alice", Method: http.MethodPost, Path: "/missing", Status: http.StatusNotFound},
+	}
+	for i, line := range lines {
+		var got audit.Entry
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("invalid audit entry %q: %v", line, err)
+		}
+		if got.User != want[i].User || got.Method != want[i].Method || got.Path != want[i].Path || got.Status != want[i].Status {
+			t.Errorf("expected entry %+v, got %+v", want[i], got)
+		}
+		if got.Timestamp.IsZero() {
+			t.Errorf("expected entry %d to have a timestamp", i)
+		}
+	}
+}
+
+func TestHandler_ForwardedHeaders(t *testing.T) {
+	// Backend reporting the forwarded headers it received, over plain HTTP or a WebSocket
+	received := make(chan [2]string, 1)
+	upgrader := websocket.Upgrader{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- [2]string{r.Header.Get("X-Forwarded-Proto"), r.Header.Get("X-Forwarded-Host")}
+		if websocket.IsWebSocketUpgrade(r) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err == nil {
+				conn.Close()
+			}
+		}
+	}))
+	defer backend.Close()
+
+	tests := []struct {
+		name      string
+		tls       bool
+		websocket bool
+		trust     bool
+		headers   map[string]string
+		wantProto string
+		wantHost  string
+	}{
+		{name: "plain http", wantProto: "http", wantHost: "apps.example.com"},
+		{name: "tls", tls: true, wantProto: "https", wantHost: "apps.example.com"},
+		{
+			name:      "outer proxy values kept",
+			trust:     true,
+			headers:   map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "hub.example.org"},
+			wantProto: "https",
+			wantHost:  "hub.example.org",
+		},
+		{
+			name:      "untrusted host replaced",
+			headers:   map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "hub.example.org"},
+			wantProto: "https",
+			wantHost:  "apps.example.com",
+		},
+		{name: "websocket upgrade", websocket: true, wantProto: "http", wantHost: "apps.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.New(logger.Config{Output: io.Discard})
+			h, err := NewHandler(Config{
+				UpstreamURL: backend.URL,
+				AuthType:    "none",
+				TrustProxy:  tt.trust,
+				Logger:      log,
+			})
+			if err != nil {
+				t.Fatalf("failed to create handler: %v", err)
+			}
+
+			var srv *httptest.Server
+			if tt.tls {
+				srv = httptest.NewTLSServer(h)
+			} else {
+				srv = httptest.NewServer(h)
+			}
+			defer srv.Close()
+
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+
+			if tt.websocket {
+				header.Set("Host", "apps.example.com")
+				conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", header)
+				if err != nil {
+					t.Fatalf("WebSocket dial failed: %v", err)
+				}
+				conn.Close()
+			} else {
+				req, _ := http.NewRequest(http.MethodGet, srv.URL+"/", nil)
+				req.Header = header
+				req.Host = "apps.example.com"
+				resp, err := srv.Client().Do(req)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				resp.Body.Close()
+			}
+
+			got := <-received
+			if got[0] != tt.wantProto {
+				t.Errorf("expected X-Forwarded-Proto %q, got %q", tt.wantProto, got[0])
+			}
+			if got[1] != tt.wantHost {
+				t.Errorf("expected X-Forwarded-Host %q, got %q", tt.wantHost, got[1])
+			}
+		})
+	}
+}
+
+// newUnresponsiveBackend returns the address of a listener that never accepts
+// Its accept queue (backlog 0) is filled up front, so further connection attempts hang
+// like a backend that is bound but overloaded
+func newUnresponsiveBackend(t *testing.T) string {
+	t.Helper()
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("failed to create socket: %v", err)
+	}
+	t.Cleanup(func() { syscall.Close(fd) })
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		t.Fatalf("failed to bind: %v", err)
+	}
+	if err := syscall.Listen(fd, 0); err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		t.Fatalf("failed to get address: %v", err)
+	}
+	addr := fmt.Sprintf("127.0.0.1:%d", sa.(*syscall.SockaddrInet4).Port)
+
+	// Fill the accept queue until a connection attempt hangs
+	for i := 0; i < 16; i++ {
+		conn, err := net.DialTimeout("tcp", addr, 200*time.Millisecond)
+		if err != nil {
+			return addr
+		}
+		t.Cleanup(func() { conn.Close() })
+	}
+	t.Skip("could not fill the listener's accept queue on this system")
+	return ""
+}
+
+func TestHandler_BackendDialTimeout(t *testing.T) {
+	addr := newUnresponsiveBackend(t)
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	h, err := NewHandler(Config{
+		UpstreamURL: "http://" + addr,
+		AuthType:    "none",
+		DialTimeout: 300 * time.Millisecond,
+		Logger:      log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	h.ServeHTTP(rec, req)
+	elapsed := time.Since(start)
+
+	if elapsed > 3*time.Second {
+		t.Errorf("expected dial to time out after ~300ms, took %v", elapsed)
+	}
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status %d, got %d", http.StatusGatewayTimeout, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected styled HTML error page, got Content-Type %q", ct)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "took too long to respond") {
+		t.Errorf("expected timeout explanation in error page, got %q", body)
+	}
+}
+
+func TestHandler_Timeout(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			// Outlives the proxy timeout; the connection must stay open
+			time.Sleep(500 * time.Millisecond)
+			_ = conn.WriteMessage(websocket.TextMessage, []byte("still here"))
+			return
+		}
+		select {
+		case <-time.After(2 * time.Second):
+			_, _ = io.WriteString(w, "too late")
+		case <-r.Context().Done():
+		}
+	}))
+	defer backend.Close()
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	h, err := NewHandler(Config{
+		UpstreamURL: backend.URL,
+		AuthType:    "none",
+		Timeout:     200 * time.Millisecond,
+		Logger:      log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	t.Run("slow response", func(t *testing.T) {
+		start := time.Now()
+		resp, err := http.Get(srv.URL + "/slow")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected request to time out after ~200ms, took %v", elapsed)
+		}
+		if resp.StatusCode != http.StatusGatewayTimeout {
+			t.Errorf("expected status %d, got %d", http.StatusGatewayTimeout, resp.StatusCode)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if !strings.Contains(string(body), "took too long to respond") {
+			t.Errorf("expected friendly timeout message, got %q", body)
+		}
+	})
+
+	t.Run("websocket exempt", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+		if err != nil {
+			t.Fatalf("WebSocket dial failed: %v", err)
+		}
+		defer conn.Close()
+
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("expected WebSocket to outlive the proxy timeout, got %v", err)
+		}
+		if string(msg) != "still here" {
+			t.Errorf("expected %q, got %q", "still here", msg)
+		}
+	})
+}
+
+func TestHandler_NoIndex(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			_, _ = io.WriteString(w, "User-agent: *\nAllow: /\n")
+			return
+		}
+		_, _ = io.WriteString(w, "dashboard")
+	}))
+	defer backend.Close()
+
+	tests := []struct {
+		name       string
+		noIndex    bool
+		wantRobots string
+		wantTag    string
+	}{
+		{name: "enabled", noIndex: true, wantRobots: "User-agent: *\nDisallow: /\n", wantTag: "noindex"},
+		{name: "disabled", noIndex: false, wantRobots: "User-agent: *\nAllow: /\n", wantTag: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.New(logger.Config{Output: io.Discard})
+			h, err := NewHandler(Config{
+				UpstreamURL:   backend.URL,
+				AuthType:      "none",
+				ServicePrefix: "/user/alice/app",
+				StripPrefix:   true,
+				NoIndex:       tt.noIndex,
+				Logger:        log,
+			})
+			if err != nil {
+				t.Fatalf("failed to create handler: %v", err)
+			}
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+
+			for _, path := range []string{"/robots.txt", "/user/alice/app/robots.txt"} {
+				resp, err := http.Get(srv.URL + path)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if string(body) != tt.wantRobots {
+					t.Errorf("%s: expected %q, got %q", path, tt.wantRobots, body)
+				}
+			}
+
+			resp, err := http.Get(srv.URL + "/user/alice/app/index.html")
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if got := resp.Header.Get("X-Robots-Tag"); got != tt.wantTag {
+				t.Errorf("expected X-Robots-Tag %q, got %q", tt.wantTag, got)
+			}
+		})
+	}
+}
+
+func TestHandler_MaxURLLength(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	h, err := NewHandler(Config{
+		UpstreamURL:  backend.URL,
+		AuthType:     "none",
+		MaxURLLength: 32768,
+		Logger:       log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "dashboard state", query: "state=" + strings.Repeat("a", 4000), wantStatus: http.StatusOK},
+		{name: "over limit", query: "state=" + strings.Repeat("a", 40000), wantStatus: http.StatusRequestURITooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/dashboard?"+tt.query, nil)
+			req.Header.Set("Accept", "text/html")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if tt.wantStatus == http.StatusRequestURITooLong {
+				body, _ := io.ReadAll(resp.Body)
+				if !strings.Contains(string(body), "too long") {
+					t.Errorf("expected styled 414 page, got %q", body)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_ClientIPHeaders(t *testing.T) {
+	// Backend reporting the client identity headers it received
+	received := make(chan [2]string, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- [2]string{r.Header.Get("X-Forwarded-For"), r.Header.Get("X-Real-IP")}
+	}))
+	defer backend.Close()
+
+	tests := []struct {
+		name       string
+		trust      bool
+		headers    map[string]string
+		wantFor    string
+		wantRealIP string
+	}{
+		{name: "no prior headers", wantFor: "127.0.0.1", wantRealIP: "127.0.0.1"},
+		{name: "no prior headers, trusted", trust: true, wantFor: "127.0.0.1", wantRealIP: "127.0.0.1"},
+		{
+			name:       "upstream proxy, trusted",
+			trust:      true,
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.7", "X-Real-IP": "203.0.113.7"},
+			wantFor:    "203.0.113.7, 127.0.0.1",
+			wantRealIP: "203.0.113.7",
+		},
+		{
+			name:       "upstream proxy, untrusted",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.7", "X-Real-IP": "203.0.113.7"},
+			wantFor:    "127.0.0.1",
+			wantRealIP: "127.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.New(logger.Config{Output: io.Discard})
+			h, err := NewHandler(Config{
+				UpstreamURL: backend.URL,
+				AuthType:    "none",
+				TrustProxy:  tt.trust,
+				Logger:      log,
+			})
+			if err != nil {
+				t.Fatalf("failed to create handler: %v", err)
+			}
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			got := <-received
+			if got[0] != tt.wantFor {
+				t.Errorf("expected X-Forwarded-For %q, got %q", tt.wantFor, got[0])
+			}
+			if got[1] != tt.wantRealIP {
+				t.Errorf("expected X-Real-IP %q, got %q", tt.wantRealIP, got[1])
+			}
+		})
+	}
+}
+
+func TestHandler_StripsIdentityHeadersWithoutOAuth(t *testing.T) {
+	// Backend reporting the identity headers it received
+	received := make(chan http.Header, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer backend.Close()
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	h, err := NewHandler(Config{
+		UpstreamURL: backend.URL,
+		AuthType:    "none",
+		Logger:      log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/", nil)
+	req.Header.Set("X-Forwarded-User", "admin")
+	req.Header.Set("X-Forwarded-Groups", "admins")
+	req.Header.Set("X-Forwarded-User-Data", `{"name":"admin","admin":true}`)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	got := <-received
+	for _, header := range []string{"X-Forwarded-User", "X-Forwarded-Groups", "X-Forwarded-User-Data"} {
+		if value := got.Get(header); value != "" {
+			t.Errorf("expected client-sent %s to be dropped, got %q", header, value)
+		}
+	}
+}
+
+func TestHandler_Routes(t *testing.T) {
+	// echoBackend answers with its name and the path it received
+	echoBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "%s %s", name, r.URL.Path)
+		}))
+	}
+	app := echoBackend("app")
+	defer app.Close()
+	api := echoBackend("api")
+	defer api.Close()
+
+	h, err := NewHandler(Config{
+		UpstreamURL:   app.URL,
+		AuthType:      "none",
+		ServicePrefix: "/user/alice/app",
+		StripPrefix:   true,
+		Routes: []Route{
+			{Prefix: "/api", UpstreamURL: api.URL},
+			{Prefix: "/api/internal/", UpstreamURL: app.URL}, // Longer prefix wins
+		},
+		Logger: logger.New(logger.Config{Output: io.Discard}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/user/alice/app/", want: "app /"},
+		{path: "/user/alice/app/api", want: "api /api"},
+		{path: "/user/alice/app/api/items?limit=1", want: "api /api/items"},
+		{path: "/user/alice/app/apiary", want: "app /apiary"},
+		{path: "/user/alice/app/api/internal/status", want: "app /api/internal/status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("root prefix rejected", func(t *testing.T) {
+		_, err := NewHandler(Config{
+			UpstreamURL: app.URL,
+			AuthType:    "none",
+			Routes:      []Route{{Prefix: "/", UpstreamURL: api.URL}},
+			Logger:      logger.New(logger.Config{Output: io.Discard}),
+		})
+		if err == nil {
+			t.Error("expected error for a route on the app root, got nil")
+		}
+	})
+}
+
+func TestHandler_WSMaxMessageSize(t *testing.T) {
+	const limit = 8192
+
+	// Echo backend recording the size of every message it receives
+	received := make(chan int, 10)
+	backendDone := make(chan struct{})
+	upgrader := websocket.Upgrader{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(backendDone)
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- len(message)
+			if err := conn.WriteMessage(messageType, message); err != nil {
+				return
+			}
+		}
+	}))
+	defer backend.Close()
+
+	h, err := NewHandler(Config{
+		UpstreamURL:      backend.URL,
+		AuthType:         "none",
+		WSMaxMessageSize: limit,
+		Logger:           logger.New(logger.Config{Output: io.Discard}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// Sent in several frames by the client's 4KB write buffer, but within the limit as a message
+	for _, size := range []int{10, limit} {
+		if err := conn.WriteMessage(websocket.BinaryMessage, make([]byte, size)); err != nil {
+			t.Fatalf("failed to write %d byte message: %v", size, err)
+		}
+		_, echoed, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("expected %d byte message to be echoed, got %v", size, err)
+		}
+		if len(echoed) != size {
+			t.Errorf("expected %d byte echo, got %d", size, len(echoed))
+		}
+	}
+
+	if err := conn.WriteMessage(websocket.BinaryMessage, make([]byte, limit+1)); err != nil {
+		t.Fatalf("failed to write oversized message: %v", err)
+	}
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Fatalf("expected close code %d, got %v", websocket.CloseMessageTooBig, err)
+	}
+
+	// The backend connection is closed rather than sent the oversized message
+	select {
+	case <-backendDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the backend connection to be closed")
+	}
+	close(received)
+	for size := range received {
+		if size > limit {
+			t.Errorf("expected backend never to receive more than %d bytes, got a %d byte message", limit, size)
+		}
+	}
+}
+
+func TestHandler_Compress(t *testing.T) {
+	page := strings.Repeat("<div class=\"cell\">voila output</div>\n", 2000)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = io.WriteString(w, page)
+		case "/small":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = io.WriteString(w, "ok")
+		case "/encoded":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "br")
+			_, _ = io.WriteString(w, page)
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+			_, _ = io.WriteString(w, page)
+		}
+	}))
+	defer backend.Close()
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	h, err := NewHandler(Config{
+		UpstreamURL: backend.URL,
+		AuthType:    "none",
+		Compress:    true,
+		Logger:      log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	// Keep the client from decoding gzip itself
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	get := func(t *testing.T, path, acceptEncoding string) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read body: %v", err)
+		}
+		return resp, body
+	}
+
+	t.Run("large text gzipped", func(t *testing.T) {
+		resp, body := get(t, "/", "gzip, deflate, br")
+		if got := resp.Header.Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("expected Content-Encoding gzip, got %q", got)
+		}
+		if resp.Header.Get("Content-Length") != "" || resp.ContentLength != -1 {
+			t.Errorf("expected no Content-Length, got %q", resp.Header.Get("Content-Length"))
+		}
+		if len(body) >= len(page) {
+			t.Errorf("expected compressed body smaller than %d bytes, got %d", len(page), len(body))
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("invalid gzip body: %v", err)
+		}
+		decoded, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("failed to decode gzip body: %v", err)
+		}
+		if string(decoded) != page {
+			t.Error("expected decoded body to match the backend response")
+		}
+	})
+
+	t.Run("deflate only", func(t *testing.T) {
+		resp, body := get(t, "/", "deflate")
+		if got := resp.Header.Get("Content-Encoding"); got != "deflate" {
+			t.Fatalf("expected Content-Encoding deflate, got %q", got)
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("invalid deflate body: %v", err)
+		}
+		if decoded, _ := io.ReadAll(zr); string(decoded) != page {
+			t.Error("expected decoded body to match the backend response")
+		}
+	})
+
+	for _, tt := range []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{name: "client without gzip", path: "/", acceptEncoding: "", wantEncoding: ""},
+		{name: "gzip refused", path: "/", acceptEncoding: "gzip;q=0", wantEncoding: ""},
+		{name: "image", path: "/image.png", acceptEncoding: "gzip", wantEncoding: ""},
+		{name: "small response", path: "/small", acceptEncoding: "gzip", wantEncoding: ""},
+		{name: "already encoded", path: "/encoded", acceptEncoding: "gzip", wantEncoding: "br"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _ := get(t, tt.path, tt.acceptEncoding)
+			if got := resp.Header.Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("expected Content-Encoding %q, got %q", tt.wantEncoding, got)
+			}
+		})
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	// A port nothing listens on, so connecting to it is refused
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	refusedAddr := closed.Addr().String()
+	closed.Close()
+
+	tests := []struct {
+		name      string
+		method    string
+		wantDials int
+		wantErr   bool
+	}{
+		{name: "GET is retried", method: http.MethodGet, wantDials: 2},
+		{name: "HEAD is retried", method: http.MethodHead, wantDials: 2},
+		{name: "POST is not retried", method: http.MethodPost, wantDials: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The backend refuses the first connection, then accepts
+			dials := 0
+			dialer := &net.Dialer{}
+			transport := &http.Transport{
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					dials++
+					if dials == 1 {
+						return dialer.DialContext(ctx, network, refusedAddr)
+					}
+					return dialer.DialContext(ctx, network, backend.Listener.Addr().String())
+				},
+			}
+			defer transport.CloseIdleConnections()
+			rt := &retryTransport{
+				next:    transport,
+				retries: 2,
+				logger:  logger.New(logger.Config{Output: io.Discard}),
+			}
+
+			req := httptest.NewRequest(tt.method, backend.URL+"/", nil)
+			req.RequestURI = ""
+			resp, err := rt.RoundTrip(req)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("expected the request to fail")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("expected the request to succeed, got %v", err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					t.Errorf("expected status 200, got %d", resp.StatusCode)
+				}
+			}
+			if dials != tt.wantDials {
+				t.Errorf("expected %d connection attempts, got %d", tt.wantDials, dials)
+			}
+		})
+	}
+}
+
+func TestHandler_BackendRetryRefused(t *testing.T) {
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := closed.Addr().String()
+	closed.Close()
+
+	h, err := NewHandler(Config{
+		UpstreamURL:    "http://" + addr,
+		AuthType:       "none",
+		BackendRetries: 2,
+		Logger:         logger.New(logger.Config{Output: io.Discard}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+
+	// A backend that stays down still fails once the retries are used up
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("expected status %d, got %d", http.StatusBadGateway, rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed < 3*retryBackoff {
+		t.Errorf("expected two retries with backoff (>= %v), took %v", 3*retryBackoff, elapsed)
+	}
+}
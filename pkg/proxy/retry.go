@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+// retryBackoff is the wait before the first retry; it doubles for each further one
+const retryBackoff = 100 * time.Millisecond
+
+// retryTransport retries idempotent requests whose connection to the backend is refused or reset
+// Covers the moment a backend drops connections, e.g. while a worker reloads. Only GET and
+// HEAD requests without a body are retried: anything else may already have had an effect.
+type retryTransport struct {
+	next    http.RoundTripper
+	retries int
+	logger  *logger.Logger
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if !retryable(req) {
+		return resp, err
+	}
+
+	backoff := retryBackoff
+	for attempt := 1; attempt <= t.retries && retryableError(err); attempt++ {
+		t.logger.Warn("backend connection failed, retrying request",
+			"method", req.Method,
+			"path", req.URL.Path,
+			"attempt", attempt,
+			"error", err)
+
+		select {
+		case <-req.Context().Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		resp, err = t.next.RoundTrip(req)
+	}
+	return resp, err
+}
+
+// retryable reports whether a request can be sent again without side effects
+func retryable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+// retryableError reports whether err means the backend refused or dropped the connection
+func retryableError(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,192 @@
+// Package proxy - WebSocket message size limit (--ws-max-message-size)
+package proxy
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+// wsCloseMessageTooBig is the WebSocket close code for a message exceeding the size limit (RFC 6455 7.4.1)
+const wsCloseMessageTooBig = 1009
+
+// errWSMessageTooBig is returned by the frame scanner when a message exceeds the size limit
+var errWSMessageTooBig = errors.New("websocket message too big")
+
+// wsFrameScanner follows WebSocket frame boundaries in a byte stream without buffering payloads
+// With a limit, it also totals the payload of each (possibly fragmented) data message.
+type wsFrameScanner struct {
+	maxMessage int64  // Largest data message allowed, in bytes (0 = unlimited)
+	header     []byte // Header bytes of the frame being read
+	remaining  uint64 // Payload bytes left in the current frame
+	message    uint64 // Payload bytes of the current data message so far
+}
+
+// scan consumes p, returning errWSMessageTooBig as soon as a frame header pushes the
+// current message past the limit
+func (s *wsFrameScanner) scan(p []byte) error {
+	for len(p) > 0 {
+		if s.remaining > 0 {
+			n := uint64(len(p))
+			if n > s.remaining {
+				n = s.remaining
+			}
+			s.remaining -= n
+			p = p[n:]
+			continue
+		}
+
+		s.header = append(s.header, p[0])
+		p = p[1:]
+		if len(s.header) < wsHeaderLen(s.header) {
+			continue
+		}
+
+		length := wsPayloadLen(s.header)
+		opcode := s.header[0] & 0x0f
+		s.header = s.header[:0]
+		s.remaining = length
+
+		// Control frames (close, ping, pong) are capped at 125 bytes by the protocol
+		if opcode >= 0x8 {
+			continue
+		}
+		if opcode != 0x0 {
+			s.message = 0 // Text or binary frame starts a new message; 0x0 continues it
+		}
+		s.message += length
+		if s.maxMessage > 0 && (length > uint64(s.maxMessage) || s.message > uint64(s.maxMessage)) {
+			return errWSMessageTooBig
+		}
+	}
+	return nil
+}
+
+// atBoundary reports whether the scanner is between frames
+func (s *wsFrameScanner) atBoundary() bool {
+	return len(s.header) == 0 && s.remaining == 0
+}
+
+// wsHeaderLen returns the length of the frame header starting with h, once enough of it is known
+func wsHeaderLen(h []byte) int {
+	if len(h) < 2 {
+		return 2
+	}
+	n := 2
+	switch h[1] & 0x7f {
+	case 126:
+		n += 2
+	case 127:
+		n += 8
+	}
+	if h[1]&0x80 != 0 {
+		n += 4 // Masking key
+	}
+	return n
+}
+
+// wsPayloadLen returns the payload length from a complete frame header
+func wsPayloadLen(h []byte) uint64 {
+	switch length := h[1] & 0x7f; length {
+	case 126:
+		return uint64(binary.BigEndian.Uint16(h[2:4]))
+	case 127:
+		return binary.BigEndian.Uint64(h[2:10])
+	default:
+		return uint64(length)
+	}
+}
+
+// wsCloseFrame returns an unmasked (server to client) close frame
+func wsCloseFrame(code uint16, reason string) []byte {
+	frame := []byte{0x88, byte(2 + len(reason)), 0, 0}
+	binary.BigEndian.PutUint16(frame[2:], code)
+	return append(frame, reason...)
+}
+
+// wsLimitConn wraps the backend side of an upgraded WebSocket connection
+// The reverse proxy writes client data to it and reads backend data from it. Client messages
+// over the limit are not forwarded: the backend connection is closed and the client gets a
+// 1009 close frame once the backend stream is at a frame boundary (otherwise just a closed connection).
+type wsLimitConn struct {
+	io.ReadWriteCloser
+	logger *logger.Logger
+	path   string
+	limit  int64
+
+	fromClient  wsFrameScanner
+	fromBackend wsFrameScanner
+	violated    atomic.Bool
+	closeFrame  []byte // Close frame still to be sent to the client
+	closeSent   bool
+}
+
+// newWSLimitConn limits the size of client messages forwarded over backend
+func newWSLimitConn(backend io.ReadWriteCloser, limit int64, path string, log *logger.Logger) *wsLimitConn {
+	return &wsLimitConn{
+		ReadWriteCloser: backend,
+		logger:          log,
+		path:            path,
+		limit:           limit,
+		fromClient:      wsFrameScanner{maxMessage: limit},
+	}
+}
+
+// Write forwards client data to the backend until a message exceeds the limit
+func (c *wsLimitConn) Write(p []byte) (int, error) {
+	if c.violated.Load() {
+		return len(p), nil // Discarded; the connection is being closed
+	}
+	if err := c.fromClient.scan(p); err != nil {
+		c.logger.Warn("closing WebSocket connection: message exceeds the size limit",
+			"path", c.path,
+			"limit_bytes", c.limit,
+			"message_bytes", c.fromClient.message)
+		c.violated.Store(true)
+		// Unblocks Read, which then sends the close frame to the client
+		c.ReadWriteCloser.Close()
+		return len(p), nil
+	}
+	return c.ReadWriteCloser.Write(p)
+}
+
+// Read returns backend data for the client, followed by the close frame after a violation
+func (c *wsLimitConn) Read(p []byte) (int, error) {
+	if len(c.closeFrame) > 0 {
+		n := copy(p, c.closeFrame)
+		c.closeFrame = c.closeFrame[n:]
+		return n, nil
+	}
+
+	n, err := c.ReadWriteCloser.Read(p)
+	_ = c.fromBackend.scan(p[:n])
+	if err == nil || !c.violated.Load() {
+		return n, err
+	}
+	if n > 0 {
+		return n, nil // Deliver what was read; the next Read fails again
+	}
+	if !c.closeSent && c.fromBackend.atBoundary() {
+		c.closeSent = true
+		c.closeFrame = wsCloseFrame(wsCloseMessageTooBig, "message too big")
+		return c.Read(p)
+	}
+	return 0, io.EOF
+}
+
+// limitWebSocket wraps a 101 response to a WebSocket upgrade so client messages are size limited
+func (h *Handler) limitWebSocket(resp *http.Response) {
+	if resp.StatusCode != http.StatusSwitchingProtocols || !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		return
+	}
+	backend, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return
+	}
+	resp.Body = newWSLimitConn(backend, h.wsMaxMessageSize, resp.Request.URL.Path, h.logger)
+}
@@ -0,0 +1,179 @@
+// Package redact masks secret values wherever they appear in output
+//
+// A Redactor is built once at startup from the values of the environment variables
+// named with --redact-env, then applied to log lines, captured subprocess output and
+// API responses. Matching on values rather than formats catches secrets no pattern knows;
+// --redact-pattern regexes and DefaultPatterns cover tokens whose values aren't known up front.
+package redact
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Placeholder replaces redacted values
+const Placeholder = "REDACTED"
+
+// MinSecretLength is the shortest value that gets redacted
+// Shorter values (e.g. "1", "yes") would mask unrelated text everywhere.
+const MinSecretLength = 4
+
+// HubTokenEnv is always redacted, whether or not it is named with --redact-env
+// Apps commonly echo their environment, and the Hub API token would let anyone act as the user.
+const HubTokenEnv = "JUPYTERHUB_API_TOKEN"
+
+// DefaultPatterns match common token formats and are always applied
+// Only the first capture group is replaced, keeping the surrounding text readable.
+var DefaultPatterns = []string{
+	`(?i)token=([^\s&"']+)`,
+	`(?i)bearer\s+([A-Za-z0-9._~+/=-]+)`,
+}
+
+// Redactor replaces known secret values with Placeholder
+// A nil *Redactor is valid and redacts nothing.
+type Redactor struct {
+	replacer *strings.Replacer // Nil if there are no secret values
+	patterns []*regexp.Regexp
+}
+
+// New creates a Redactor for the given secret values
+// Values shorter than MinSecretLength are ignored. Returns nil if no value qualifies.
+func New(secrets []string) *Redactor {
+	var values []string
+	seen := make(map[string]bool)
+	for _, s := range secrets {
+		if len(s) < MinSecretLength || seen[s] {
+			continue
+		}
+		seen[s] = true
+		values = append(values, s)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	// Longest first so a secret containing another is replaced as a whole
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	pairs := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		pairs = append(pairs, v, Placeholder)
+	}
+	return &Redactor{replacer: strings.NewReplacer(pairs...)}
+}
+
+// FromEnv creates a Redactor for the current values of the named environment variables
+// HubTokenEnv is always included. Returns the names that are unset or too short to redact,
+// so callers can warn about them.
+func FromEnv(names []string) (*Redactor, []string) {
+	return FromLookup(names, os.Getenv)
+}
+
+// FromLookup is FromEnv with the variable values taken from lookup instead of the process environment
+// The proxy's own HubTokenEnv is masked in any case, and lookup's value for it on top, so
+// a lookup that gives the app another token can't unmask the real one.
+func FromLookup(names []string, lookup func(name string) string) (*Redactor, []string) {
+	var values, skipped []string
+	for _, name := range names {
+		value := lookup(name)
+		if len(value) < MinSecretLength {
+			skipped = append(skipped, name)
+			continue
+		}
+		values = append(values, value)
+	}
+	values = append(values, os.Getenv(HubTokenEnv), lookup(HubTokenEnv))
+	return New(values), skipped
+}
+
+// CompilePatterns compiles DefaultPatterns followed by the given regular expressions
+// Invalid expressions are left out and reported in the returned error.
+func CompilePatterns(exprs []string) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(DefaultPatterns)+len(exprs))
+	for _, expr := range DefaultPatterns {
+		patterns = append(patterns, regexp.MustCompile(expr))
+	}
+
+	var invalid []string
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%q: %v", expr, err))
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	if len(invalid) > 0 {
+		return patterns, fmt.Errorf("invalid --redact-pattern %s", strings.Join(invalid, "; "))
+	}
+	return patterns, nil
+}
+
+// WithPatterns returns a Redactor that also replaces matches of the given patterns
+// If a pattern has capture groups only the first group is replaced, otherwise the whole match.
+// Safe to call on a nil *Redactor.
+func (r *Redactor) WithPatterns(patterns []*regexp.Regexp) *Redactor {
+	if len(patterns) == 0 {
+		return r
+	}
+	out := &Redactor{}
+	if r != nil {
+		out.replacer = r.replacer
+		out.patterns = append(out.patterns, r.patterns...)
+	}
+	out.patterns = append(out.patterns, patterns...)
+	return out
+}
+
+// String returns s with every secret value and pattern match replaced
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	if r.replacer != nil {
+		s = r.replacer.Replace(s)
+	}
+	for _, re := range r.patterns {
+		s = replacePattern(re, s)
+	}
+	return s
+}
+
+// replacePattern replaces the first capture group (or the whole match) of every match of re in s
+func replacePattern(re *regexp.Regexp, s string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if start == end {
+			continue // Empty match or group; nothing to hide
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(Placeholder)
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// Strings returns a copy of ss with every secret value replaced
+func (r *Redactor) Strings(ss []string) []string {
+	if r == nil || ss == nil {
+		return ss
+	}
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = r.String(s)
+	}
+	return out
+}
@@ -0,0 +1,125 @@
+package redact
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactor_String(t *testing.T) {
+	tests := []struct {
+		name     string
+		secrets  []string
+		input    string
+		expected string
+	}{
+		{name: "no secrets", secrets: nil, input: "token=abcd1234", expected: "token=abcd1234"},
+		{name: "single secret", secrets: []string{"abcd1234"}, input: "token=abcd1234", expected: "token=REDACTED"},
+		{name: "every occurrence", secrets: []string{"abcd1234"}, input: "abcd1234 and abcd1234", expected: "REDACTED and REDACTED"},
+		{name: "overlapping secrets", secrets: []string{"abcd", "abcd1234"}, input: "key abcd1234", expected: "key REDACTED"},
+		{name: "short values ignored", secrets: []string{"1", "yes"}, input: "1 yes", expected: "1 yes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.secrets).String(tt.input); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRedactor_Nil(t *testing.T) {
+	var r *Redactor
+	if got := r.String("abcd1234"); got != "abcd1234" {
+		t.Errorf("expected nil redactor to leave input unchanged, got %q", got)
+	}
+	if got := r.Strings([]string{"a", "b"}); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("expected nil redactor to leave input unchanged, got %q", got)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("APP_DB_PASSWORD", "hunter2-hunter2")
+	t.Setenv("APP_FLAG", "on")
+
+	r, skipped := FromEnv([]string{"APP_DB_PASSWORD", "APP_FLAG", "APP_UNSET"})
+	if got := r.String("postgres://app:hunter2-hunter2@db"); got != "postgres://app:REDACTED@db" {
+		t.Errorf("expected password to be redacted, got %q", got)
+	}
+	if !reflect.DeepEqual(skipped, []string{"APP_FLAG", "APP_UNSET"}) {
+		t.Errorf("expected short and unset variables to be skipped, got %v", skipped)
+	}
+}
+
+func TestFromEnv_HubToken(t *testing.T) {
+	t.Setenv(HubTokenEnv, "0123456789abcdef")
+
+	r, skipped := FromEnv(nil)
+	if got := r.String("token 0123456789abcdef"); got != "token REDACTED" {
+		t.Errorf("expected Hub token to be redacted, got %q", got)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("expected nothing skipped, got %v", skipped)
+	}
+}
+
+func TestFromLookup(t *testing.T) {
+	vars := map[string]string{"APP_SECRET": "from-env-file", HubTokenEnv: "hub-token-value"}
+
+	r, skipped := FromLookup([]string{"APP_SECRET", "APP_UNSET"}, func(name string) string { return vars[name] })
+	if got := r.String("from-env-file hub-token-value"); got != "REDACTED REDACTED" {
+		t.Errorf("expected looked-up values to be redacted, got %q", got)
+	}
+	if !reflect.DeepEqual(skipped, []string{"APP_UNSET"}) {
+		t.Errorf("expected unset variable to be skipped, got %v", skipped)
+	}
+}
+
+func TestFromLookup_HubTokenOverridden(t *testing.T) {
+	t.Setenv(HubTokenEnv, "real-hub-token")
+
+	// An env file giving the app a different token must not unmask the proxy's own
+	r, _ := FromLookup(nil, func(name string) string { return "app-hub-token" })
+	if got := r.String("real-hub-token app-hub-token"); got != "REDACTED REDACTED" {
+		t.Errorf("expected both Hub tokens to be redacted, got %q", got)
+	}
+}
+
+func TestRedactor_Patterns(t *testing.T) {
+	patterns, err := CompilePatterns([]string{`sk-[A-Za-z0-9]{8,}`, `password: (\S+)`})
+	if err != nil {
+		t.Fatalf("failed to compile patterns: %v", err)
+	}
+	var nilRedactor *Redactor
+	r := nilRedactor.WithPatterns(patterns)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "token query parameter", input: "GET /lab?token=abc123&x=1", expected: "GET /lab?token=REDACTED&x=1"},
+		{name: "bearer header", input: "Authorization: Bearer eyJ.abc-def", expected: "Authorization: Bearer REDACTED"},
+		{name: "whole match", input: "key sk-abcdefgh12", expected: "key REDACTED"},
+		{name: "first group only", input: "password: hunter2", expected: "password: REDACTED"},
+		{name: "no match", input: "tokens are fine", expected: "tokens are fine"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.String(tt.input); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCompilePatterns_Invalid(t *testing.T) {
+	patterns, err := CompilePatterns([]string{`(unclosed`, `valid`})
+	if err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+	if len(patterns) != len(DefaultPatterns)+1 {
+		t.Errorf("expected the defaults and the valid pattern, got %d patterns", len(patterns))
+	}
+}
@@ -6,12 +6,30 @@ import (
 	"strings"
 
 	"github.com/nebari-dev/jhub-app-proxy/pkg/activity"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/api"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/interim"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/metrics"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/proxy"
 )
 
+// MetricsPath is where Prometheus metrics are served when enabled
+// Underscored so it can't shadow an app's own /metrics when there is no service prefix
+const MetricsPath = "/_metrics"
+
+// healthCheckUserAgents are User-Agent prefixes of load balancer and orchestrator probes
+// Probes reach the app like any request but aren't user activity, so they must not
+// keep an idle app from being culled when --keep-alive is off
+var healthCheckUserAgents = []string{
+	"kube-probe/",
+	"ELB-HealthChecker/",
+	"GoogleHC/",
+	"Consul Health Check",
+	"Blackbox Exporter/",
+}
+
 // Router handles intelligent routing between interim page, logs API, and backend application
 type Router struct {
 	log               *logger.Logger
@@ -24,7 +42,11 @@ type Router struct {
 	appRootPath       string
 	subprocessURL     string
 	oauthCallbackPath string // Empty if OAuth disabled for jhub-app-proxy
+	logoutPath        string // Empty if OAuth disabled for jhub-app-proxy
 	activityTracker   *activity.Tracker
+	metrics           *metrics.Metrics // Nil if metrics disabled
+	cors              *middleware.CORS // Nil if CORS disabled
+	grpc              bool             // Route gRPC calls to the app regardless of the service prefix
 }
 
 // Config contains configuration for the router
@@ -39,7 +61,11 @@ type Config struct {
 	AppRootPath       string
 	SubprocessURL     string
 	OAuthCallbackPath string // Empty if OAuth disabled for jhub-app-proxy
+	LogoutPath        string // Empty if OAuth disabled for jhub-app-proxy
 	ActivityTracker   *activity.Tracker
+	Metrics           *metrics.Metrics // Nil if metrics disabled
+	CORS              *middleware.CORS // Nil if CORS disabled
+	GRPC              bool             // Route gRPC calls to the app regardless of the service prefix
 }
 
 // New creates a new router with the given configuration
@@ -55,17 +81,53 @@ func New(cfg Config) *Router {
 		appRootPath:       cfg.AppRootPath,
 		subprocessURL:     cfg.SubprocessURL,
 		oauthCallbackPath: cfg.OAuthCallbackPath,
+		logoutPath:        cfg.LogoutPath,
 		activityTracker:   cfg.ActivityTracker,
+		metrics:           cfg.Metrics,
+		cors:              cfg.CORS,
+		grpc:              cfg.GRPC,
 	}
 }
 
 // ServeHTTP implements http.Handler with intelligent routing logic
 func (rtr *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var handler http.Handler = http.HandlerFunc(rtr.route)
+
+	// CORS runs before routing and therefore before any OAuth check
+	if rtr.cors != nil {
+		handler = rtr.cors.Wrap(handler)
+	}
+
+	if rtr.metrics != nil {
+		handler = rtr.metrics.Instrument(rtr.metricsPathPrefix(r.URL.Path), handler)
+	}
+
+	// Outermost, so every response (including CORS preflights) is logged with its request ID
+	handler = middleware.RequestID(middleware.AccessLog(rtr.log, handler))
+	handler.ServeHTTP(w, r)
+}
+
+// metricsPathPrefix maps a request path onto the path_prefix metrics label
+// Only a few fixed values, so app URLs can't blow up the label cardinality
+func (rtr *Router) metricsPathPrefix(path string) string {
+	switch {
+	case path == MetricsPath:
+		return MetricsPath
+	case strings.HasPrefix(path, rtr.interimBasePath):
+		return interim.InterimPath
+	default:
+		return "/"
+	}
+}
+
+// route sends the request to the metrics endpoint, the interim infrastructure or the app
+func (rtr *Router) route(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
-	rtr.log.Info("incoming request",
+	rtr.log.Debug("incoming request",
 		"method", r.Method,
 		"path", path,
-		"remote_addr", r.RemoteAddr)
+		"remote_addr", r.RemoteAddr,
+		"request_id", middleware.RequestIDFromContext(r.Context()))
 
 	// Route 0: OAuth callback for jhub-app-proxy (only when OAuth is enabled)
 	// CRITICAL: Only intercept if OAuth is enabled AND app is not running
@@ -85,12 +147,31 @@ func (rtr *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// Fall through to proxy
 	}
 
+	// Route 0b: Prometheus metrics (only when enabled)
+	// Served outside the service prefix so scrapers can reach it directly on the pod
+	if rtr.metrics != nil && path == MetricsPath {
+		rtr.metrics.Handler().ServeHTTP(w, r)
+		return
+	}
+
 	// Route 1: Interim page and its API (during startup + grace period)
 	if strings.HasPrefix(path, rtr.interimBasePath) {
 		rtr.handleInterimRoute(w, r, path)
 		return
 	}
 
+	// Route 1b: gRPC calls (only with --grpc)
+	// Method paths such as /echo.Echo/Say can't carry the service prefix, so they skip its check
+	if rtr.grpc && isGRPC(r) {
+		if !rtr.mgr.IsRunning() {
+			// gRPC clients report 503 as UNAVAILABLE, which they may retry
+			http.Error(w, "App is not running", http.StatusServiceUnavailable)
+			return
+		}
+		rtr.handleAppRunning(w, r, path)
+		return
+	}
+
 	// Route 2: Application routes
 	if !rtr.validateServicePrefix(w, r, path) {
 		return
@@ -107,6 +188,31 @@ func (rtr *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // handleInterimRoute routes requests to the interim infrastructure or redirects if grace period expired
 func (rtr *Router) handleInterimRoute(w http.ResponseWriter, r *http.Request, path string) {
+	// The mux registers the interim page at the exact base path, so serve the
+	// trailing-slash form the same way instead of letting it 404
+	if path == rtr.interimBasePath+"/" {
+		path = rtr.interimBasePath
+		r = r.Clone(r.Context())
+		r.URL.Path = path
+		r.URL.RawPath = ""
+	}
+
+	// Restarting or signalling a running app is the point of the process endpoints, so they never
+	// redirect, and logging out must work whatever state the app is in
+	if path == rtr.interimBasePath+api.ProcessRestartPath || path == rtr.interimBasePath+api.ProcessKillPath ||
+		(rtr.logoutPath != "" && path == rtr.logoutPath) {
+		rtr.log.Info("routing process control to interim infrastructure", "path", path)
+		rtr.mux.ServeHTTP(w, r)
+		return
+	}
+
+	// Readiness probes poll the health endpoint for the app's whole lifetime
+	if path == rtr.interimBasePath+api.HealthPath {
+		rtr.log.Debug("routing health check to interim infrastructure", "path", path)
+		rtr.mux.ServeHTTP(w, r)
+		return
+	}
+
 	if rtr.interimHandler.ShouldServeLogsAPI() {
 		rtr.log.Info("routing to interim infrastructure",
 			"path", path,
@@ -153,9 +259,27 @@ func (rtr *Router) handleAppRunning(w http.ResponseWriter, r *http.Request, path
 		"app_status", "running")
 
 	// Record activity for JupyterHub activity reporting
-	if rtr.activityTracker != nil {
+	// Interim pages and the logs API never get here, so only app traffic counts
+	if rtr.activityTracker != nil && !isHealthCheck(r) {
 		rtr.activityTracker.RecordActivity()
 	}
 
 	rtr.proxyHandler.ServeHTTP(w, r)
 }
+
+// isGRPC reports whether the request is a gRPC call (gRPC-Web, which also works over HTTP/1.1, is not)
+func isGRPC(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	return r.ProtoMajor == 2 && (contentType == "application/grpc" || strings.HasPrefix(contentType, "application/grpc+"))
+}
+
+// isHealthCheck reports whether the request comes from a health check probe
+func isHealthCheck(r *http.Request) bool {
+	ua := r.UserAgent()
+	for _, prefix := range healthCheckUserAgents {
+		if strings.HasPrefix(ua, prefix) {
+			return true
+		}
+	}
+	return false
+}
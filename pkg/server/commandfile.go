@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/api"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/command"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+// watchCommandFile re-reads --command-file every interval until ctx is cancelled and
+// passes it to apply whenever its command or env differs from current
+// A file that can't be read or parsed (e.g. while it is being rewritten) is reported and
+// the app keeps running as it is. A change that apply rejects is not retried until the
+// file changes again, except while another restart is in progress.
+func watchCommandFile(ctx context.Context, path string, interval time.Duration, current *command.CommandFile, apply func(*command.CommandFile) error, log *logger.Logger) {
+	log.Info("watching command file for changes", "path", path, "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		next, err := command.ReadCommandFile(path)
+		if err != nil {
+			log.Warn("failed to read command file, keeping the current command", "path", path, "error", err.Error())
+			continue
+		}
+		if next.Equal(current) {
+			continue
+		}
+
+		log.Info("command file changed, switching the app to the new command", "path", path, "command", next.Command)
+		if err := apply(next); errors.Is(err, api.ErrRestartInProgress) {
+			log.Info("a restart is already in progress, retrying on the next check", "path", path)
+			continue
+		} else if err != nil {
+			log.Error("failed to switch to the new command", err, "path", path)
+		}
+		current = next
+	}
+}
@@ -0,0 +1,709 @@
+// Package server - Full proxy lifecycle for embedding jhub-app-proxy as a library
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/command"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/conda"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/config"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/git"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/health"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logsink"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/port"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/proxy"
+)
+
+// Version is reported by the stats API when running through Run (set by main package)
+var Version = "dev"
+
+// Run drives the whole proxy lifecycle: it starts the HTTP server, clones the repository
+// (if configured), starts the app and blocks until ctx is cancelled, then shuts everything
+// down. cfg.Command or cfg.CommandFile must be set; start from config.Default() to get the
+// flag defaults.
+// Run does not install signal handlers; cancel ctx to stop (see SetupSignalHandling, which
+// also reopens the log file on SIGHUP). It logs the startup banner once --conda-env is checked.
+func Run(ctx context.Context, cfg *config.Config, log *logger.Logger) error {
+	// --command-file gives the command (and extra env) in place of cfg.Command
+	var cmdFile *command.CommandFile
+	if cfg.CommandFile != "" {
+		if len(cfg.Command) > 0 {
+			return fmt.Errorf("--command-file cannot be combined with a command")
+		}
+		var err error
+		cmdFile, err = command.ReadCommandFile(cfg.CommandFile)
+		if err != nil {
+			return err
+		}
+		cfg.Command = cmdFile.Command
+	}
+	if len(cfg.Command) == 0 {
+		return fmt.Errorf("no command to run")
+	}
+	cfg.NormalizePort()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	logFields, err := cfg.StaticLogFields()
+	if err != nil {
+		return err
+	}
+	logLocation, err := cfg.LogLocation()
+	if err != nil {
+		return err
+	}
+	repos, err := cfg.ParseRepos()
+	if err != nil {
+		return err
+	}
+	// Skipped --redact-env names and invalid patterns are reported by whoever built the logger
+	redactor, _, _ := cfg.Redactor()
+
+	// Ship subprocess logs to external sinks if configured
+	var logSinks process.MultiSink
+	for _, sinkCfg := range []logsink.Config{
+		{URL: cfg.LogSinkURL, Format: cfg.LogSinkFormat, Labels: logSinkLabels(logFields)},
+		{URL: cfg.OTelLogsEndpoint, Format: logsink.FormatOTLP, Labels: otelResourceAttributes(logFields)},
+	} {
+		if sinkCfg.URL == "" {
+			continue
+		}
+		sinkCfg.Logger = log
+		sink, err := logsink.New(sinkCfg)
+		if err != nil {
+			return fmt.Errorf("failed to create log sink: %w", err)
+		}
+		sink.Start(ctx)
+		// Flush queued entries on exit (cancel must run first to stop the shipper)
+		defer func() {
+			cancel()
+			sink.Wait()
+		}()
+		logSinks = append(logSinks, sink)
+	}
+	var logSink process.LogSink
+	if len(logSinks) > 0 {
+		logSink = logSinks
+	}
+
+	// Startup phases reported to the interim page, shared with the process manager
+	phases := process.NewPhaseTracker()
+
+	// Resolve the user the app runs as; only root can switch users
+	runAs, err := process.LookupRunAs(cfg.RunAsUser, cfg.RunAsUID, cfg.RunAsGID)
+	if err != nil {
+		return err
+	}
+	if runAs != nil && os.Geteuid() != 0 {
+		log.Warn("proxy is not running as root, the app runs as the proxy's user instead",
+			"run_as_user", runAs.User.Username,
+			"uid", os.Geteuid())
+		runAs = nil
+	}
+	var credential *syscall.Credential
+	if runAs != nil {
+		credential = runAs.Credential
+		log.Info("app runs as another user",
+			"user", runAs.User.Username,
+			"uid", credential.Uid,
+			"gid", credential.Gid)
+	}
+
+	// Check the working directory before building the command, so a typo fails fast
+	// One in a repository that is yet to be cloned is checked once the clone is done
+	workDir := cfg.WorkDir
+	workDirCloned := false
+	if workDir != "" {
+		workDir, err = filepath.Abs(workDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve working directory %q: %w", cfg.WorkDir, err)
+		}
+		workDirCloned = inRepoFolder(workDir, repos)
+		if workDirCloned {
+			log.Info("working directory is checked once the repositories are cloned", "path", workDir)
+		} else if err := prepareWorkDir(workDir, cfg.WorkDirCreate, credential, log); err != nil {
+			return err
+		}
+	}
+
+	// Check the conda environment before building the command, so --conda-strict fails fast
+	condaStatus, condaEnvPath, err := checkCondaEnv(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+	// A validated environment is passed on by path, so it isn't looked up again
+	condaEnv := cfg.CondaEnv
+	if condaEnvPath != "" {
+		condaEnv = condaEnvPath
+	}
+
+	log.StartupBanner(Version, map[string]interface{}{
+		"auth_type":        cfg.AuthType,
+		"port":             cfg.Port,
+		"dest_port":        cfg.DestPort,
+		"conda_env":        cfg.CondaEnv,
+		"conda_env_status": condaStatus,
+		"log_level":        cfg.LogLevel,
+		"log_format":       cfg.LogFormat,
+		"log_buffer_size":  cfg.LogBufferSize,
+		"ready_check_path": cfg.ReadyCheckPath,
+		"progressive":      cfg.Progressive,
+		"tls":              cfg.TLSEnabled(),
+	})
+
+	// Build command with conda activation if needed
+	if cfg.CondaEnv != "" {
+		phases.Set(process.PhaseActivatingConda)
+	}
+	cmdBuilder := command.NewBuilder(log)
+	cmdBuilder.SetFailOnMissingCondaEnv(cfg.FailOnMissingCondaEnv)
+	cmdBuilder.SetCondaEnvPreferRoot(cfg.CondaEnvPreferRoot)
+	cmd, err := cmdBuilder.Build(cfg.Command, condaEnv)
+	if err != nil {
+		return fmt.Errorf("failed to build command: %w", err)
+	}
+
+	// Allocate ports
+	proxyPort := cfg.Port
+	log.Info("proxy will listen on port", "port", proxyPort)
+
+	// An app on a Unix socket needs no port
+	var subprocessPort int
+	var socketPath string
+	rangeMode := cfg.DestPortMin != 0 || cfg.DestPortMax != 0
+	if rangeMode && (cfg.DestPortMin == 0 || cfg.DestPortMax == 0) {
+		return fmt.Errorf("--destport-min and --destport-max must be set together")
+	}
+	if cfg.DestSocket != "" {
+		if cfg.DestPort != 0 || rangeMode {
+			return fmt.Errorf("--dest-socket cannot be combined with --destport or --destport-min/--destport-max")
+		}
+		if command.UsesPort(cmd) {
+			return fmt.Errorf("--dest-socket is set but the command uses {port}: use {socket} instead")
+		}
+		socketPath, err = filepath.Abs(cfg.DestSocket)
+		if err != nil {
+			return fmt.Errorf("failed to resolve --dest-socket %q: %w", cfg.DestSocket, err)
+		}
+		log.Info("subprocess will listen on unix socket", "socket", socketPath)
+	} else if rangeMode {
+		subprocessPort, err = port.AllocateFromRange(cfg.DestPortMin, cfg.DestPortMax, cfg.DestPort)
+		// The proxy isn't listening yet, so its own port looks free
+		if err == nil && subprocessPort == proxyPort && cfg.DestPort != proxyPort {
+			if proxyPort < cfg.DestPortMax {
+				subprocessPort, err = port.ScanRange(proxyPort+1, cfg.DestPortMax)
+			} else {
+				err = fmt.Errorf("no available port in range %d-%d besides the proxy port", cfg.DestPortMin, cfg.DestPortMax)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to allocate subprocess port: %w", err)
+		}
+		log.Info("allocated internal port for subprocess from range", "port", subprocessPort,
+			"range_min", cfg.DestPortMin, "range_max", cfg.DestPortMax)
+	} else {
+		subprocessPort, err = port.Allocate(cfg.DestPort)
+		if err != nil {
+			return fmt.Errorf("failed to allocate subprocess port: %w", err)
+		}
+		log.Info("allocated internal port for subprocess", "port", subprocessPort)
+	}
+
+	// Allocate ports for --route backends and substitute their placeholders
+	routes, err := cfg.ParseRoutes()
+	if err != nil {
+		return err
+	}
+	namedPorts := make(map[string]int)
+	proxyRoutes := make([]proxy.Route, 0, len(routes))
+	for _, route := range routes {
+		routePort := route.Port
+		if route.Placeholder != "" {
+			if routePort = namedPorts[route.Placeholder]; routePort == 0 {
+				routePort, err = port.Allocate(0)
+				if err != nil {
+					return fmt.Errorf("failed to allocate port for route %s: %w", route.Prefix, err)
+				}
+				namedPorts[route.Placeholder] = routePort
+			}
+		}
+		if routePort == proxyPort {
+			return fmt.Errorf("invalid --route %s: port %d is the proxy's own port", route.Prefix, routePort)
+		}
+		proxyRoutes = append(proxyRoutes, proxy.Route{
+			Prefix:      route.Prefix,
+			UpstreamURL: fmt.Sprintf("http://127.0.0.1:%d", routePort),
+		})
+	}
+
+	// Substitute port placeholders, in the user's command too so the stats API shows
+	// both as they run
+	substitute := func(c []string) []string {
+		c = command.SubstituteNamedPorts(c, namedPorts)
+		if socketPath != "" {
+			c = command.SubstituteSocket(c, socketPath)
+		}
+		return command.SubstitutePort(c, subprocessPort, cfg.RootPathPrefix)
+	}
+	cmd = substitute(cmd)
+	userCmd := substitute(cfg.Command)
+
+	// Create health checker
+	subprocessURL := fmt.Sprintf("http://127.0.0.1:%d", subprocessPort)
+	if socketPath != "" {
+		subprocessURL = "http://" + proxy.SocketHost
+	}
+	upstreamURL := subprocessURL + cfg.ReadyCheckPath
+	if socketPath == "" {
+		if err := health.ValidateTarget(upstreamURL, subprocessPort, proxyPort); err != nil {
+			return fmt.Errorf("invalid health check configuration: %w", err)
+		}
+	}
+	if err := health.ValidateCheckType(cfg.ReadyCheckType); err != nil {
+		return err
+	}
+	if err := health.ValidateCheckRequest(cfg.ReadyCheckMethod, []byte(cfg.ReadyCheckBody)); err != nil {
+		return err
+	}
+	healthChecker := health.NewChecker(readyCheckConfig(cfg, subprocessURL, socketPath), log)
+
+	env := command.BuildEnv()
+	if runAs != nil {
+		maps.Copy(env, runAs.Env())
+	}
+	appEnv, err := command.LoadEnv(cfg.EnvFiles, cfg.Env)
+	if err != nil {
+		return err
+	}
+	maps.Copy(env, appEnv)
+
+	// The command file's env comes last; env stays the base for a changed command file
+	processEnv := env
+	if cmdFile != nil && len(cmdFile.Env) > 0 {
+		processEnv = maps.Clone(env)
+		maps.Copy(processEnv, cmdFile.Env)
+	}
+
+	// Create process manager with log capture
+	mgr, err := process.NewManagerWithLogs(
+		process.Config{
+			Command:     cmd,
+			UserCommand: userCmd,
+			Env:         processEnv,
+			WorkDir:     workDir,
+			Nice:        cfg.Nice,
+
+			NoProcessGroup: cfg.NoProcessGroup,
+
+			Credential: credential,
+			ReadyCheck: func(ctx context.Context) error {
+				return healthChecker.WaitUntilReady(ctx)
+			},
+			RestartPolicy: process.RestartPolicy{
+				MaxRestarts:        cfg.MaxRestarts,
+				BackoffInitial:     time.Duration(cfg.RestartBackoff) * time.Second,
+				BackoffMax:         time.Duration(cfg.RestartBackoffMax) * time.Second,
+				BackoffMultiplier:  cfg.RestartBackoffFactor,
+				CrashLoopThreshold: cfg.CrashLoopThreshold,
+				CrashLoopWindow:    time.Duration(cfg.CrashLoopWindow) * time.Second,
+			},
+			Phases: phases,
+		},
+		process.LogCaptureConfig{
+			Enabled:    true,
+			BufferSize: cfg.LogBufferSize,
+			Sink:       logSink,
+			Redactor:   redactor,
+			StripANSI:  cfg.StripANSI,
+
+			TimestampFormat: cfg.LogTimeFormat,
+			TimeZone:        logLocation,
+			MaxAge:          time.Duration(cfg.LogMaxAge) * time.Second,
+			MaxFileBytes:    cfg.LogFileMaxBytes,
+			NoSync:          cfg.LogNoSync,
+		},
+		log,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create process manager: %w", err)
+	}
+
+	// Reopen the subprocess log file on SIGHUP so it can be rotated externally
+	removeHook := OnHangup(func() {
+		if err := mgr.RotateLogFile(); err != nil {
+			log.Error("failed to reopen log file", err, "path", mgr.GetLogFilePath())
+			return
+		}
+		log.Info("reopened log file", "path", mgr.GetLogFilePath())
+	})
+	defer removeHook()
+
+	// Add conda warning to log buffer if there was a conda activation failure
+	// This ensures the warning appears in the interim UI logs
+	if condaWarning := cmdBuilder.GetCondaWarning(); condaWarning != "" {
+		mgr.AddErrorLog(condaWarning)
+	}
+
+	// Every repository is pending until its clone finishes
+	var gitStatus *git.StatusTracker
+	if len(repos) > 0 {
+		gitStatus = git.NewStatusTracker()
+		for _, repo := range repos {
+			gitStatus.Add(repo.URL, repo.Branch, repo.Folder, repo.Optional)
+		}
+	}
+
+	// Create and start HTTP server
+	srv, err := New(Config{
+		Manager:        mgr,
+		ProxyPort:      proxyPort,
+		SubprocessPort: subprocessPort,
+		SubprocessURL:  subprocessURL,
+		Routes:         proxyRoutes,
+		AppConfig:      cfg,
+		Logger:         log,
+		Version:        Version,
+		Redactor:       redactor,
+		HealthChecker:  healthChecker,
+		GitStatus:      gitStatus,
+
+		SubprocessSocket: socketPath,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create server: %w", err)
+	}
+
+	srv.Start(ctx)
+	defer srv.Shutdown()
+
+	// Keep probing the app once it runs; stops with ctx on shutdown
+	if cfg.LivenessInterval > 0 {
+		go watchLiveness(ctx, cfg, healthChecker, mgr, log)
+	}
+
+	// Switch to the new command whenever the command file changes
+	// Standard input can only be read once, so there is nothing to poll
+	if cmdFile != nil && cfg.CommandFilePoll > 0 && cfg.CommandFile != command.StdinCommandFile {
+		apply := func(cf *command.CommandFile) error {
+			built, err := cmdBuilder.Build(cf.Command, condaEnv)
+			if err != nil {
+				return err
+			}
+			newEnv := maps.Clone(env)
+			maps.Copy(newEnv, cf.Env)
+			if err := mgr.SetCommand(substitute(built), substitute(cf.Command), newEnv); err != nil {
+				return err
+			}
+			// Not started yet (e.g. still cloning): the new command is used when it starts
+			if mgr.GetState() == process.StateInitializing {
+				return nil
+			}
+			return srv.RestartSubprocess("Command file changed, restarting the app with the new command")
+		}
+		go watchCommandFile(ctx, cfg.CommandFile, time.Duration(cfg.CommandFilePoll)*time.Second, cmdFile, apply, log)
+	}
+
+	// Clone the git repositories (if specified) and start the subprocess in the background
+	// The server is already up, so users see the interim page while cloning
+	go func() {
+		if len(repos) > 0 {
+			phases.Set(process.PhaseCloning)
+			if err := handleGitClone(ctx, cfg, repos, gitStatus, mgr, log); err != nil {
+				log.Error("git clone failed", err, "repos", len(repos))
+				mgr.AddErrorLog(fmt.Sprintf("ERROR: Git clone failed: %s", err.Error()))
+				mgr.MarkFailed()
+				return
+			}
+		}
+		if workDirCloned {
+			if err := prepareWorkDir(workDir, cfg.WorkDirCreate, credential, log); err != nil {
+				log.Error("working directory check failed", err, "path", workDir)
+				mgr.AddErrorLog(fmt.Sprintf("ERROR: %s", err.Error()))
+				mgr.MarkFailed()
+				return
+			}
+		}
+		srv.StartSubprocess(ctx, cmd)
+	}()
+
+	// Wait for shutdown
+	<-ctx.Done()
+	return nil
+}
+
+// prepareWorkDir checks the working directory at path, creating it if create is set
+// A directory created for the app is handed to the user it runs as (credential, nil = ours).
+func prepareWorkDir(path string, create bool, credential *syscall.Credential, log *logger.Logger) error {
+	info, err := process.PrepareWorkDir(path, create)
+	if err != nil {
+		return err
+	}
+	if info.Created && credential != nil {
+		if err := os.Chown(info.Path, int(credential.Uid), int(credential.Gid)); err != nil {
+			return fmt.Errorf("failed to hand working directory %s to the run-as user: %w", info.Path, err)
+		}
+	}
+	log.Info("using working directory",
+		"path", info.Path,
+		"owner_uid", info.OwnerUID,
+		"created", info.Created)
+	return nil
+}
+
+// inRepoFolder reports whether the absolute path dir is, or is under, the folder of one of repos
+func inRepoFolder(dir string, repos []config.RepoSpec) bool {
+	for _, repo := range repos {
+		folder, err := filepath.Abs(repo.Folder)
+		if err != nil || repo.Folder == "" {
+			continue
+		}
+		rel, err := filepath.Rel(folder, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleGitClone clones (or pulls) every repository in parallel, recording each outcome in status
+// Returns the errors of the clones that aren't optional; failed optional clones are only logged.
+func handleGitClone(ctx context.Context, cfg *config.Config, repos []config.RepoSpec, status *git.StatusTracker, mgr *process.ManagerWithLogs, log *logger.Logger) error {
+	gitMgr := git.NewManager(log)
+
+	if !gitMgr.IsGitInstalled() {
+		err := fmt.Errorf("git is not installed")
+		for i := range repos {
+			status.SetError(i, err)
+		}
+		return err
+	}
+
+	if cfg.RepoCloneTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.RepoCloneTimeout)*time.Second)
+		defer cancel()
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(repos))
+	for i, repo := range repos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = cloneRepo(ctx, cfg, gitMgr, repo, i, status, mgr, len(repos) > 1)
+		}()
+	}
+	wg.Wait()
+
+	var mandatory []error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if repos[i].Optional {
+			log.Warn("optional git clone failed, continuing", "repo", repos[i].URL, "error", err.Error())
+			mgr.AddErrorLog(fmt.Sprintf("WARNING: Optional repository %s was not cloned: %s", repos[i].URL, err.Error()))
+			continue
+		}
+		mandatory = append(mandatory, err)
+	}
+	return errors.Join(mandatory...)
+}
+
+// cloneRepo clones and verifies a single repository and records its commit (or error) in status
+// With several repositories cloning at once, their output lines are prefixed with the folder.
+func cloneRepo(ctx context.Context, cfg *config.Config, gitMgr *git.Manager, repo config.RepoSpec, index int, status *git.StatusTracker, mgr *process.ManagerWithLogs, prefixOutput bool) error {
+	mgr.AddInfoLog(fmt.Sprintf("Cloning repository %s (branch %s) into %s...", repo.URL, repo.Branch, repo.Folder))
+
+	output := mgr.AddInfoLog
+	if prefixOutput {
+		output = func(line string) {
+			mgr.AddInfoLog("[" + repo.Folder + "] " + line)
+		}
+	}
+
+	cloneCfg := git.CloneConfig{
+		RepoURL:       repo.URL,
+		Branch:        repo.Branch,
+		DestPath:      repo.Folder,
+		Depth:         1,
+		OutputHandler: output,
+
+		RequireSignedCommits: cfg.RepoVerifyCommits,
+	}
+
+	if err := gitMgr.Clone(ctx, cloneCfg); err != nil {
+		status.SetError(index, err)
+		return fmt.Errorf("%s: %w", repo.Folder, err)
+	}
+	if err := gitMgr.Verify(ctx, cloneCfg); err != nil {
+		status.SetError(index, err)
+		return fmt.Errorf("%s: %w", repo.Folder, err)
+	}
+
+	// The app can do without the history, so a failed unshallow only leaves the clone shallow
+	if cfg.RepoUnshallow {
+		mgr.AddInfoLog(fmt.Sprintf("Fetching the full history of %s...", repo.URL))
+		if err := gitMgr.Unshallow(ctx, repo.Folder, output); err != nil {
+			mgr.AddErrorLog(fmt.Sprintf("WARNING: Could not fetch the full history of %s: %s", repo.URL, err.Error()))
+		}
+	}
+
+	commit, err := gitMgr.HeadCommit(ctx, repo.Folder)
+	if err != nil {
+		status.SetError(index, err)
+		return fmt.Errorf("%s: %w", repo.Folder, err)
+	}
+	// Clones are --depth 1, so only a clone that was unshallowed needs checking
+	shallow := true
+	if cfg.RepoUnshallow {
+		if isShallow, err := gitMgr.IsShallow(ctx, repo.Folder); err == nil {
+			shallow = isShallow
+		}
+	}
+	status.SetShallow(index, shallow)
+	status.SetDone(index, commit)
+
+	mgr.AddInfoLog(fmt.Sprintf("Repository %s cloned at commit %s", repo.URL, commit))
+	return nil
+}
+
+// readyCheckConfig builds the app's ready check from the --ready-check-* flags
+// --ready-check-type tcp (or --ready-check-protocol tcp) checks tcp://host:port, from which
+// DefaultCheckConfig picks the TCP check; otherwise the ready check path is requested.
+func readyCheckConfig(cfg *config.Config, subprocessURL, socketPath string) health.CheckConfig {
+	checkURL := subprocessURL + cfg.ReadyCheckPath
+	if cfg.ReadyCheckType == health.CheckTypeTCP {
+		checkURL = "tcp://" + strings.TrimPrefix(subprocessURL, "http://")
+	}
+
+	healthCfg := health.DefaultCheckConfig(checkURL)
+	healthCfg.Socket = socketPath
+	healthCfg.Method = cfg.ReadyCheckMethod
+	if cfg.ReadyCheckBody != "" {
+		healthCfg.RequestBody = []byte(cfg.ReadyCheckBody)
+		healthCfg.ContentType = cfg.ReadyCheckContentType
+	}
+	healthCfg.Timeout = time.Duration(cfg.ReadyTimeout) * time.Second
+	healthCfg.Stabilization = time.Duration(cfg.ReadyStabilization) * time.Second
+	return healthCfg
+}
+
+// watchLiveness marks the app degraded when it stops answering its ready check and, if the
+// restart policy allows, kills it so it is restarted
+func watchLiveness(ctx context.Context, cfg *config.Config, checker *health.Checker, mgr *process.ManagerWithLogs, log *logger.Logger) {
+	checker.WatchLiveness(ctx, health.LivenessConfig{
+		Interval:         time.Duration(cfg.LivenessInterval) * time.Second,
+		FailureThreshold: cfg.LivenessFailureThreshold,
+		IsRunning:        mgr.IsRunning,
+		OnUnhealthy: func(failures int, err error) {
+			reason := fmt.Sprintf("app failed %d consecutive liveness checks: %s", failures, err.Error())
+			log.Warn("app is degraded", "failures", failures, "error", err.Error())
+			mgr.SetDegraded(reason)
+			mgr.AddErrorLog("WARNING: " + reason)
+
+			if err := mgr.RestartUnhealthy(); err != nil {
+				log.Warn("not restarting degraded app", "reason", err.Error())
+				return
+			}
+			mgr.AddErrorLog("Restarting the unresponsive app")
+		},
+		OnRecovered: func() {
+			mgr.ClearDegraded()
+			mgr.AddInfoLog("App is answering liveness checks again")
+		},
+	})
+}
+
+// logSinkLabels builds the labels attached to shipped log batches
+// Reuses the static log fields (--log-field, --log-hub-fields) so sink streams can be filtered per deployment
+func logSinkLabels(fields map[string]interface{}) map[string]string {
+	labels := map[string]string{"job": "jhub-app-proxy"}
+	for key, value := range fields {
+		labels[key] = fmt.Sprint(value)
+	}
+	return labels
+}
+
+// otelResourceAttributes builds the OTLP resource attributes of shipped log records
+// JupyterHub deployment metadata is always included, on top of the static log fields.
+func otelResourceAttributes(fields map[string]interface{}) map[string]string {
+	attrs := map[string]string{"service.name": "jhub-app-proxy"}
+	for key, envVar := range map[string]string{
+		"jupyterhub.user":           "JUPYTERHUB_USER",
+		"jupyterhub.server_name":    "JUPYTERHUB_SERVER_NAME",
+		"jupyterhub.service_prefix": "JUPYTERHUB_SERVICE_PREFIX",
+	} {
+		if val := os.Getenv(envVar); val != "" {
+			attrs[key] = val
+		}
+	}
+	for key, value := range fields {
+		attrs[key] = fmt.Sprint(value)
+	}
+	return attrs
+}
+
+// Conda environment states reported in the startup banner
+const (
+	CondaEnvNone         = "none"          // No --conda-env
+	CondaEnvNotValidated = "not validated" // --conda-validate=false
+	CondaEnvValid        = "valid"
+	CondaEnvInvalid      = "invalid"
+)
+
+// checkCondaEnv checks --conda-env before the command is built, so a missing environment
+// shows up in the startup banner rather than as a conda warning once the app runs
+// At debug level it also logs the activation command, ready to paste into a terminal.
+// Returns the status and, for a valid environment, its path so the command is built without
+// looking it up again; an error for an invalid environment only with --conda-strict
+// (--fail-on-missing-conda-env).
+func checkCondaEnv(ctx context.Context, cfg *config.Config, log *logger.Logger) (string, string, error) {
+	condaMgr := conda.NewManager(log)
+	condaMgr.SetPreferRoot(cfg.CondaEnvPreferRoot)
+	status, envPath, err := validateCondaEnv(cfg, condaMgr, log)
+	if err != nil {
+		return status, "", err
+	}
+
+	if envPath != "" && log.GetSlog().Enabled(ctx, slog.LevelDebug) {
+		var line strings.Builder
+		if err := condaMgr.DryRun(&line, envPath, cfg.Command); err == nil {
+			log.Debug("conda activation command", "command", strings.TrimSpace(line.String()))
+		}
+	}
+	return status, envPath, nil
+}
+
+func validateCondaEnv(cfg *config.Config, condaMgr *conda.Manager, log *logger.Logger) (string, string, error) {
+	if cfg.CondaEnv == "" {
+		return CondaEnvNone, "", nil
+	}
+	if !cfg.ValidateAtStartup {
+		return CondaEnvNotValidated, "", nil
+	}
+	envPath, err := condaMgr.ValidateEnvironment(cfg.CondaEnv)
+	if err != nil {
+		if cfg.FailOnMissingCondaEnv {
+			return CondaEnvInvalid, "", fmt.Errorf("conda environment %q is not usable: %w", cfg.CondaEnv, err)
+		}
+		log.Warn("conda environment is not usable, the command will run without it",
+			"conda_env", cfg.CondaEnv,
+			"error", err.Error())
+		return CondaEnvInvalid, "", nil
+	}
+	return CondaEnvValid, envPath, nil
+}
@@ -0,0 +1,372 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/conda"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/config"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/health"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestRun(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not available")
+	}
+
+	cfg := config.Default()
+	cfg.Port = freePort(t)
+	cfg.AuthType = "none"
+	cfg.Command = []string{"python3", "-m", "http.server", "{port}", "--bind", "127.0.0.1"}
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, cfg, log)
+	}()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/", cfg.Port)
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			// The interim page is served until the app is ready; http.server lists the directory
+			if resp.StatusCode == http.StatusOK && strings.Contains(string(body), "Directory listing") {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("app was not proxied within deadline (last error: %v)", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected Run to return nil, got %v", err)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+
+	if _, err := http.Get(url); err == nil {
+		t.Error("expected proxy to stop listening after shutdown")
+	}
+}
+
+func TestRun_NoCommand(t *testing.T) {
+	cfg := config.Default()
+	log := logger.New(logger.Config{Output: io.Discard})
+	if err := Run(context.Background(), cfg, log); err == nil {
+		t.Error("expected error for missing command, got nil")
+	}
+}
+
+func TestRun_DestPortRange(t *testing.T) {
+	cfg := config.Default()
+	cfg.Command = []string{"true"}
+	cfg.DestPortMin = 20000
+	log := logger.New(logger.Config{Output: io.Discard})
+
+	err := Run(context.Background(), cfg, log)
+	if err == nil || !strings.Contains(err.Error(), "must be set together") {
+		t.Errorf("expected an error for --destport-min without --destport-max, got %v", err)
+	}
+}
+
+func TestRun_WorkDirIsRepoFolder(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	// A local repository to clone
+	source := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", source}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+
+	// The usual --repo ... --repofolder D --workdir D: D only exists once cloned
+	folder := filepath.Join(t.TempDir(), "app")
+	marker := filepath.Join(t.TempDir(), "pwd")
+	cfg := config.Default()
+	cfg.Port = freePort(t)
+	cfg.AuthType = "none"
+	cfg.Repo = []string{source}
+	cfg.RepoBranch = "main"
+	cfg.RepoFolder = folder
+	cfg.WorkDir = folder
+	cfg.Command = []string{"sh", "-c", "pwd > " + marker + "; sleep 300"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, cfg, logger.New(logger.Config{Output: io.Discard}))
+	}()
+
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		if data, err := os.ReadFile(marker); err == nil && strings.HasSuffix(string(data), "\n") {
+			if got := strings.TrimSpace(string(data)); got != folder {
+				t.Errorf("expected the app to run in %s, got %s", folder, got)
+			}
+			break
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("Run returned before the app started: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("app did not start in the cloned working directory")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected Run to return nil, got %v", err)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+}
+
+// lockedBuffer collects log output written by the proxy's goroutines
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRun_EnvFileValuesNotLogged(t *testing.T) {
+	const secret = "env-file-s3cr3t"
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("DB_PASSWORD="+secret+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.Port = freePort(t)
+	cfg.AuthType = "none"
+	cfg.EnvFiles = []string{envFile}
+	cfg.Command = []string{"sh", "-c", "sleep 300"}
+
+	output := &lockedBuffer{}
+	log := logger.New(logger.Config{Level: logger.LevelDebug, Format: logger.FormatJSON, Output: output})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, cfg, log)
+	}()
+
+	deadline := time.Now().Add(20 * time.Second)
+	for !strings.Contains(output.String(), "process started") {
+		if time.Now().After(deadline) {
+			t.Fatal("app was not started within deadline")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(15 * time.Second):
+		t.Fatal("Run did not return after context cancellation")
+	}
+
+	if strings.Contains(output.String(), secret) {
+		t.Errorf("expected the env file value to stay out of the logs, got %q", output.String())
+	}
+	if !strings.Contains(output.String(), "DB_PASSWORD") {
+		t.Errorf("expected the env file key in the process started record, got %q", output.String())
+	}
+}
+
+func TestRun_CondaStrict(t *testing.T) {
+	cfg := config.Default()
+	cfg.Port = freePort(t)
+	cfg.AuthType = "none"
+	cfg.Command = []string{"true"}
+	cfg.CondaEnv = "jhub-app-proxy-missing-env"
+	cfg.ValidateAtStartup = true
+	cfg.FailOnMissingCondaEnv = true
+
+	err := Run(context.Background(), cfg, logger.New(logger.Config{Output: io.Discard}))
+	if err == nil || !strings.Contains(err.Error(), "is not usable") {
+		t.Errorf("expected a conda environment error with --conda-strict, got %v", err)
+	}
+}
+
+func TestInRepoFolder(t *testing.T) {
+	repos := []config.RepoSpec{{Folder: "/srv/app"}, {Folder: "/srv/lib"}}
+	tests := []struct {
+		name string
+		dir  string
+		want bool
+	}{
+		{name: "repo folder", dir: "/srv/app", want: true},
+		{name: "under a repo folder", dir: "/srv/lib/src", want: true},
+		{name: "sibling with common prefix", dir: "/srv/app2", want: false},
+		{name: "parent", dir: "/srv", want: false},
+		{name: "unrelated", dir: "/home/jovyan", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inRepoFolder(tt.dir, repos); got != tt.want {
+				t.Errorf("inRepoFolder(%q) = %v, want %v", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+// condaInfoExecutor answers `conda info --json` with a fixed environment list
+type condaInfoExecutor struct {
+	envs []string
+}
+
+func (e condaInfoExecutor) Output(_ string, args ...string) ([]byte, error) {
+	if strings.Join(args, " ") != "info --json" {
+		return nil, fmt.Errorf("unexpected conda command %v", args)
+	}
+	return json.Marshal(conda.CondaInfo{Envs: e.envs})
+}
+
+func TestValidateCondaEnv(t *testing.T) {
+	t.Setenv("CONDA_PREFIX", "")
+	env := filepath.Join(t.TempDir(), "envs", "analytics")
+	if err := os.MkdirAll(filepath.Join(env, "bin"), 0o755); err != nil {
+		t.Fatalf("failed to create env: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(env, "bin", "python"), nil, 0o755); err != nil {
+		t.Fatalf("failed to create python: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		condaEnv   string
+		validate   bool
+		strict     bool
+		wantStatus string
+		wantErr    bool
+	}{
+		{name: "no conda env", condaEnv: "", validate: true, wantStatus: CondaEnvNone},
+		{name: "validation disabled", condaEnv: "missing", validate: false, strict: true, wantStatus: CondaEnvNotValidated},
+		{name: "valid", condaEnv: "analytics", validate: true, strict: true, wantStatus: CondaEnvValid},
+		{name: "invalid falls back", condaEnv: "missing", validate: true, wantStatus: CondaEnvInvalid},
+		{name: "invalid strict", condaEnv: "missing", validate: true, strict: true, wantStatus: CondaEnvInvalid, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.CondaEnv = tt.condaEnv
+			cfg.ValidateAtStartup = tt.validate
+			cfg.FailOnMissingCondaEnv = tt.strict
+
+			log := logger.New(logger.Config{Output: io.Discard})
+			condaMgr := conda.NewManager(log)
+			condaMgr.SetExecutor(condaInfoExecutor{envs: []string{env}})
+
+			status, envPath, err := validateCondaEnv(cfg, condaMgr, log)
+			if status != tt.wantStatus {
+				t.Errorf("expected status %q, got %q", tt.wantStatus, status)
+			}
+			wantPath := ""
+			if tt.wantStatus == CondaEnvValid {
+				wantPath = env
+			}
+			if envPath != wantPath {
+				t.Errorf("expected env path %q, got %q", wantPath, envPath)
+			}
+			if tt.wantErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReadyCheckConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		checkType     string
+		socketPath    string
+		subprocessURL string
+		wantType      string
+		wantURL       string
+	}{
+		{name: "default", subprocessURL: "http://127.0.0.1:8501", wantType: health.CheckTypeHTTP, wantURL: "http://127.0.0.1:8501/health"},
+		{name: "http", checkType: "http", subprocessURL: "http://127.0.0.1:8501", wantType: health.CheckTypeHTTP, wantURL: "http://127.0.0.1:8501/health"},
+		{name: "tcp", checkType: "tcp", subprocessURL: "http://127.0.0.1:8501", wantType: health.CheckTypeTCP, wantURL: "127.0.0.1:8501"},
+		{name: "tcp on socket", checkType: "tcp", socketPath: "/tmp/app.sock", subprocessURL: "http://localhost", wantType: health.CheckTypeTCP, wantURL: "localhost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.ReadyCheckPath = "/health"
+			cfg.ReadyCheckType = tt.checkType
+
+			got := readyCheckConfig(cfg, tt.subprocessURL, tt.socketPath)
+			if got.CheckType != tt.wantType {
+				t.Errorf("expected check type %q, got %q", tt.wantType, got.CheckType)
+			}
+			if got.URL != tt.wantURL {
+				t.Errorf("expected check URL %q, got %q", tt.wantURL, got.URL)
+			}
+			if got.Socket != tt.socketPath {
+				t.Errorf("expected socket %q, got %q", tt.socketPath, got.Socket)
+			}
+		})
+	}
+}
@@ -8,18 +8,25 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/nebari-dev/jhub-app-proxy/pkg/activity"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/api"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/audit"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/auth"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/config"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/git"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/health"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/hub"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/interim"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/metrics"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/middleware"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/proxy"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/redact"
 	"github.com/nebari-dev/jhub-app-proxy/pkg/router"
 )
 
@@ -28,6 +35,7 @@ type Server struct {
 	httpServer      *http.Server
 	manager         *process.ManagerWithLogs
 	interimHandler  *interim.Handler
+	logsHandler     *api.LogsHandler
 	router          *router.Router
 	logger          *logger.Logger
 	config          *config.Config
@@ -35,6 +43,10 @@ type Server struct {
 	subprocessPort  int
 	interimPath     string
 	activityTracker *activity.Tracker
+	auditLog        *audit.Logger
+	metrics         *metrics.Metrics // Nil if metrics disabled
+	hubClient       *hub.Client      // Nil unless OAuth is enabled and the JupyterHub environment is set
+	certReloader    *CertReloader    // Nil unless serving HTTPS (--tls-cert/--tls-key)
 }
 
 // Config contains all dependencies needed to create a server
@@ -43,9 +55,15 @@ type Config struct {
 	ProxyPort      int
 	SubprocessPort int
 	SubprocessURL  string
+	Routes         []proxy.Route // Additional backends by path prefix (--route)
 	AppConfig      *config.Config
 	Logger         *logger.Logger
 	Version        string
+	Redactor       *redact.Redactor   // Masks --redact-env secret values in API responses
+	HealthChecker  *health.Checker    // App ready check, reported by the health API (nil = none)
+	GitStatus      *git.StatusTracker // Clone status of each --repo, reported by the git status API (nil = none)
+
+	SubprocessSocket string // Unix domain socket the app listens on (--dest-socket); SubprocessURL's host is then a placeholder
 }
 
 // New creates and configures the HTTP server with all handlers
@@ -61,11 +79,32 @@ func New(cfg Config) (*Server, error) {
 	mux := http.NewServeMux()
 	api.Version = cfg.Version
 
+	// Users, groups and scopes allowed through OAuth, applied to both the interim pages and the app
+	access := auth.AuthConfig{
+		AllowedGroups:  cfg.AppConfig.AllowedGroups,
+		AllowedUsers:   cfg.AppConfig.AllowedUsers,
+		RequiredScopes: cfg.AppConfig.RequiredScopes,
+	}
+
+	// Create Prometheus metrics if enabled
+	var appMetrics *metrics.Metrics
+	if cfg.AppConfig.Metrics {
+		appMetrics = metrics.New(cfg.Manager)
+		log.Info("Prometheus metrics enabled", "path", router.MetricsPath)
+	}
+
+	switch cfg.AppConfig.AuthType {
+	case "oauth", "basic", "none":
+	default:
+		return nil, fmt.Errorf("invalid --authtype %q: expected oauth, basic or none", cfg.AppConfig.AuthType)
+	}
+
 	// CRITICAL SECURITY: Determine if OAuth authentication is needed
 	// Create a single shared OAuth middleware instance for both interim and proxy
 	// This ensures state cookies are shared between redirectToLogin and handleCallback
+	// Basic auth already protects every route, so --interim-page-auth adds nothing to it
 	var sharedOAuthMW *auth.OAuthMiddleware
-	needsOAuth := cfg.AppConfig.AuthType == "oauth" || cfg.AppConfig.InterimPageAuth
+	needsOAuth := cfg.AppConfig.AuthType == "oauth" || (cfg.AppConfig.InterimPageAuth && cfg.AppConfig.AuthType != "basic")
 
 	if needsOAuth {
 		var err error
@@ -74,24 +113,61 @@ func New(cfg Config) (*Server, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create OAuth middleware: %w", err)
 		}
+		sharedOAuthMW.SetAuthConfig(access)
+		sharedOAuthMW.SetLogoutRedirect(cfg.AppConfig.LogoutRedirect)
+		if err := sharedOAuthMW.SetUserCache(cfg.AppConfig.OAuthCacheSize, time.Duration(cfg.AppConfig.OAuthCacheTTL)*time.Second); err != nil {
+			return nil, err
+		}
+		if appMetrics != nil {
+			sharedOAuthMW.SetCacheObserver(appMetrics.ObserveOAuthCacheLookup)
+		}
+		if access.Restricted() {
+			log.Info("OAuth access restricted to allowed users, groups and scopes",
+				"allowed_groups", access.AllowedGroups,
+				"allowed_users", access.AllowedUsers,
+				"required_scopes", access.RequiredScopes)
+		}
 
 		if cfg.AppConfig.AuthType == "oauth" {
 			log.Info("OAuth authentication enabled for ALL routes (app + interim pages)")
 		} else if cfg.AppConfig.InterimPageAuth {
 			log.Info("OAuth authentication enabled for INTERIM PAGES ONLY (app is public)")
 		}
+	} else if access.Restricted() {
+		log.Warn("--allowed-groups/--allowed-users/--required-scope ignored without OAuth (use --authtype=oauth or --interim-page-auth)")
+	}
+
+	// The middleware protecting the app and the interim pages (nil = public)
+	var appAuth, interimAuth auth.Middleware
+	if sharedOAuthMW != nil {
+		interimAuth = sharedOAuthMW
+		if cfg.AppConfig.AuthType == "oauth" {
+			appAuth = sharedOAuthMW
+		}
+	}
+	if cfg.AppConfig.AuthType == "basic" {
+		basicMW, err := auth.NewBasicAuthMiddleware(auth.BasicAuthConfig{
+			User:     cfg.AppConfig.BasicAuthUser,
+			Password: cfg.AppConfig.BasicAuthPass,
+			File:     cfg.AppConfig.BasicAuthFile,
+		}, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create basic auth middleware: %w", err)
+		}
+		appAuth, interimAuth = basicMW, basicMW
+		log.Info("Basic authentication enabled for ALL routes (app + interim pages)")
 	}
 
 	// Determine if interim pages need authentication
-	protectInterim := cfg.AppConfig.AuthType == "oauth" || cfg.AppConfig.InterimPageAuth
+	protectInterim := interimAuth != nil
 
-	// CRITICAL SECURITY: Register logs API handler with or without authentication
-	logsHandler := api.NewLogsHandler(cfg.Manager, log)
-	if protectInterim && sharedOAuthMW != nil {
-		logsHandler.RegisterInterimRoutesWithAuth(mux, interimBasePath, sharedOAuthMW)
-	} else {
-		logsHandler.RegisterInterimRoutes(mux, interimBasePath)
-		log.Warn("logs API NOT protected - sensitive logs exposed!", "path", interimBasePath+"/api/*")
+	branding := interim.Branding{
+		Title:        cfg.AppConfig.BrandTitle,
+		LogoURL:      cfg.AppConfig.BrandLogoURL,
+		PrimaryColor: cfg.AppConfig.BrandPrimaryColor,
+	}
+	if err := branding.Validate(); err != nil {
+		return nil, err
 	}
 
 	// Create interim page handler
@@ -100,8 +176,34 @@ func New(cfg Config) (*Server, error) {
 		Logger:          log,
 		AppURLPath:      appRootPath,
 		InterimBasePath: interimBasePath,
+		// The server only speaks HTTP/2 when it terminates TLS
+		HTTP2Push: cfg.AppConfig.HTTP2Push && cfg.AppConfig.TLSEnabled(),
+		Branding:  branding,
 	})
 
+	// CRITICAL SECURITY: Register logs API handler with or without authentication
+	// The process restart endpoint only exists in the authenticated variant
+	logsHandler := api.NewLogsHandler(cfg.Manager, log)
+	logsHandler.SetRedactor(cfg.Redactor)
+	logsHandler.SetDeploymentTracker(interimHandler)
+	logsHandler.SetLogoURL(branding.LogoURL)
+	logsHandler.SetFailureLogLines(cfg.AppConfig.FailureLogLines)
+	if cfg.GitStatus != nil {
+		logsHandler.SetGitStatus(cfg.GitStatus)
+	}
+	logsHandler.SetRateLimiter(middleware.NewRateLimiter(middleware.RateLimitConfig{
+		Rate:       cfg.AppConfig.APIRateLimit,
+		Burst:      cfg.AppConfig.APIRateBurst,
+		TrustProxy: cfg.AppConfig.TrustProxyHeaders,
+	}))
+	if protectInterim {
+		logsHandler.RegisterInterimRoutesWithAuth(mux, interimBasePath, interimAuth)
+		api.NewProcessHandler(cfg.Manager, log).RegisterRoutesWithAuth(mux, interimBasePath, interimAuth)
+	} else {
+		logsHandler.RegisterInterimRoutes(mux, interimBasePath)
+		log.Warn("logs API NOT protected - sensitive logs exposed!", "path", interimBasePath+"/api/*")
+	}
+
 	// CRITICAL SECURITY: Register OAuth callback handler at servicePrefix/oauth_callback
 	// NOTE: This will collide with backend app OAuth callbacks (e.g., JupyterLab)
 	// The router will need to conditionally route this based on whether OAuth is enabled
@@ -119,34 +221,106 @@ func New(cfg Config) (*Server, error) {
 		log.Info("OAuth callback registered", "path", oauthCallbackPath)
 	}
 
-	// CRITICAL SECURITY: Wrap interim handler with OAuth authentication if needed
+	// Logout clears the OAuth session; it needs no valid session itself
+	var logoutPath string
+	if sharedOAuthMW != nil {
+		logoutPath = interimBasePath + auth.LogoutPath
+		mux.HandleFunc(logoutPath, sharedOAuthMW.HandleLogout)
+		log.Info("OAuth logout registered", "path", logoutPath)
+	}
+
+	// CRITICAL SECURITY: Wrap interim handler with OAuth or Basic authentication if needed
 	// Interim pages can expose sensitive subprocess logs!
 	// Register only the exact path - sub-routes (API, static files) are registered separately
-	if protectInterim && sharedOAuthMW != nil {
-		wrappedHandler := sharedOAuthMW.Wrap(interimHandler)
-		mux.Handle(interimBasePath, wrappedHandler)   // Exact path only
-		log.Info("interim page protected with OAuth authentication", "path", interimBasePath)
+	if protectInterim {
+		wrappedHandler := interimAuth.Wrap(interimHandler)
+		mux.Handle(interimBasePath, wrappedHandler) // Exact path only
+		log.Info("interim page protected with authentication", "path", interimBasePath)
 	} else {
-		mux.Handle(interimBasePath, interimHandler)   // Exact path only
+		mux.Handle(interimBasePath, interimHandler) // Exact path only
 		log.Warn("interim page NOT protected - sensitive logs exposed!", "path", interimBasePath)
 	}
 
+	// Open the audit trail for authenticated requests if configured
+	var auditLog *audit.Logger
+	if cfg.AppConfig.AuditLogFile != "" {
+		var err error
+		auditLog, err = audit.NewFileLogger(cfg.AppConfig.AuditLogFile)
+		if err != nil {
+			return nil, err
+		}
+		if appAuth == nil {
+			log.Warn("audit log enabled but app is not behind OAuth or Basic auth - only authenticated requests are recorded",
+				"audit_log_file", cfg.AppConfig.AuditLogFile)
+		} else {
+			log.Info("audit log enabled", "audit_log_file", cfg.AppConfig.AuditLogFile)
+		}
+	}
+
+	// Create activity tracker for JupyterHub activity reporting
+	// Fed by the router on each proxied request and by the proxy on WebSocket traffic
+	activityTracker := activity.NewTracker()
+
 	// Create backend proxy handler
-	proxyHandler, err := proxy.NewHandler(
-		cfg.Manager,
-		cfg.SubprocessURL,
-		cfg.AppConfig.AuthType,
-		cfg.AppConfig.Progressive,
-		servicePrefix,
-		cfg.AppConfig.StripPrefix,
-		log,
-	)
+	proxyHandler, err := proxy.NewHandler(proxy.Config{
+		Manager:        cfg.Manager,
+		UpstreamURL:    cfg.SubprocessURL,
+		UpstreamSocket: cfg.SubprocessSocket,
+		AuthType:       cfg.AppConfig.AuthType,
+		Auth:           appAuth,
+		Progressive:    cfg.AppConfig.Progressive,
+		ServicePrefix:  servicePrefix,
+		StripPrefix:    cfg.AppConfig.StripPrefix,
+		AllowedMethods: cfg.AppConfig.AllowedMethods,
+		PreserveHost:   cfg.AppConfig.PreserveHost,
+		BackendH2C:     cfg.AppConfig.BackendH2C || cfg.AppConfig.GRPC,
+		DialTimeout:    time.Duration(cfg.AppConfig.BackendDialTimeout) * time.Second,
+		BackendRetries: cfg.AppConfig.BackendRetry,
+		Timeout:        time.Duration(cfg.AppConfig.ProxyTimeout) * time.Second,
+		NoIndex:        cfg.AppConfig.NoIndex,
+		MaxURLLength:   cfg.AppConfig.MaxURLLength,
+		TrustProxy:     cfg.AppConfig.TrustProxyHeaders,
+		AuditLog:       auditLog,
+		Access:         access,
+		Routes:         cfg.Routes,
+		Logger:         log,
+
+		WSMaxMessageSize: cfg.AppConfig.WSMaxMessageSize,
+		Compress:         cfg.AppConfig.Compress,
+		Activity:         activityTracker,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create proxy handler: %w", err)
 	}
 
-	// Create activity tracker for JupyterHub activity reporting
-	activityTracker := activity.NewTracker()
+	// Create the JupyterHub client used for activity reporting and the health API
+	var hubClient *hub.Client
+	if cfg.AppConfig.AuthType == "oauth" {
+		hubClient, err = newHubClient(cfg.AppConfig, log, appMetrics)
+		if err != nil {
+			log.Warn("failed to create hub client, activity reporting disabled (continuing anyway)", "error", err)
+		}
+	}
+
+	// Report the app, proxy and hub status at the health endpoint
+	if cfg.HealthChecker != nil {
+		logsHandler.SetHealthChecker(cfg.HealthChecker)
+	}
+	if hubClient != nil {
+		// A report is due every interval ± jitter; allow one missed report before pinging
+		interval := time.Duration(cfg.AppConfig.KeepAliveInterval) * time.Second
+		jitter := time.Duration(cfg.AppConfig.KeepAliveJitter) * time.Second
+		logsHandler.SetHubChecker(hubClient, 2*interval+jitter)
+	}
+
+	// Answer cross-origin requests (e.g. the app embedded in an iframe) before authentication
+	cors := middleware.NewCORS(middleware.CORSConfig{
+		AllowedOrigins: cfg.AppConfig.CORSOrigins,
+		AllowedMethods: cfg.AppConfig.AllowedMethods,
+	})
+	if cors != nil {
+		log.Info("CORS enabled", "origins", cfg.AppConfig.CORSOrigins)
+	}
 
 	// Create main router
 	mainRouter := router.New(router.Config{
@@ -160,7 +334,11 @@ func New(cfg Config) (*Server, error) {
 		AppRootPath:       appRootPath,
 		SubprocessURL:     cfg.SubprocessURL,
 		OAuthCallbackPath: oauthCallbackPath, // Empty if OAuth disabled
+		LogoutPath:        logoutPath,        // Empty if OAuth disabled
 		ActivityTracker:   activityTracker,
+		Metrics:           appMetrics,
+		CORS:              cors,
+		GRPC:              cfg.AppConfig.GRPC,
 	})
 
 	// Create HTTP server
@@ -169,10 +347,37 @@ func New(cfg Config) (*Server, error) {
 		Handler: mainRouter,
 	}
 
+	// gRPC clients speak HTTP/2 with prior knowledge, also without TLS
+	if cfg.AppConfig.GRPC {
+		httpServer.Protocols = new(http.Protocols)
+		httpServer.Protocols.SetHTTP1(true)
+		httpServer.Protocols.SetHTTP2(true)
+		httpServer.Protocols.SetUnencryptedHTTP2(true)
+		log.Info("gRPC proxying enabled", "upstream", cfg.SubprocessURL)
+	}
+
+	// Terminate TLS directly if a certificate is configured
+	// The certificate is served through the reloader so renewals apply without a restart
+	if (cfg.AppConfig.TLSCertFile == "") != (cfg.AppConfig.TLSKeyFile == "") {
+		return nil, fmt.Errorf("--tls-cert and --tls-key must be set together")
+	}
+	var certReloader *CertReloader
+	if cfg.AppConfig.TLSEnabled() {
+		certReloader, err = NewCertReloader(cfg.AppConfig.TLSCertFile, cfg.AppConfig.TLSKeyFile, log)
+		if err != nil {
+			return nil, err
+		}
+		httpServer.TLSConfig, err = newTLSConfig(cfg.AppConfig.TLSMinVersion, cfg.AppConfig.TLSCipherSuites, certReloader.GetCertificate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Server{
 		httpServer:      httpServer,
 		manager:         cfg.Manager,
 		interimHandler:  interimHandler,
+		logsHandler:     logsHandler,
 		router:          mainRouter,
 		logger:          log,
 		config:          cfg.AppConfig,
@@ -180,19 +385,36 @@ func New(cfg Config) (*Server, error) {
 		subprocessPort:  cfg.SubprocessPort,
 		interimPath:     interimBasePath,
 		activityTracker: activityTracker,
+		auditLog:        auditLog,
+		metrics:         appMetrics,
+		hubClient:       hubClient,
+		certReloader:    certReloader,
 	}, nil
 }
 
 // Start starts the HTTP server in a goroutine
-func (s *Server) Start() {
+// When serving HTTPS, SIGHUP reloads the certificate until ctx is cancelled (once
+// SetupSignalHandling is installed)
+func (s *Server) Start(ctx context.Context) {
+	if s.certReloader != nil {
+		s.certReloader.ReloadOnHangup(ctx)
+	}
+
 	go func() {
-		s.logger.Info("starting proxy server", "port", s.proxyPort)
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.logger.Info("starting proxy server", "port", s.proxyPort, "tls", s.certReloader != nil)
+		var err error
+		if s.certReloader != nil {
+			// Certificate comes from TLSConfig.GetCertificate, not from files passed here
+			err = s.httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			s.logger.Error("proxy server failed", err)
 		}
 	}()
 
-	proxyURL := fmt.Sprintf("http://127.0.0.1:%d", s.proxyPort)
+	proxyURL := fmt.Sprintf("%s://127.0.0.1:%d", s.scheme(), s.proxyPort)
 	s.logger.Info("proxy server ready",
 		"proxy_url", proxyURL,
 		"logs_api", fmt.Sprintf("%s/api/logs", proxyURL),
@@ -214,7 +436,7 @@ func (s *Server) StartSubprocess(ctx context.Context, cmd []string) {
 		"pid", s.manager.GetPID(),
 		"internal_port", s.subprocessPort)
 
-	appURL := fmt.Sprintf("http://127.0.0.1:%d", s.proxyPort)
+	appURL := fmt.Sprintf("%s://127.0.0.1:%d", s.scheme(), s.proxyPort)
 	s.logger.Info("application ready",
 		"app_url", appURL,
 		"interim_page", fmt.Sprintf("%s%s", appURL, s.interimPath),
@@ -222,20 +444,35 @@ func (s *Server) StartSubprocess(ctx context.Context, cmd []string) {
 
 	s.interimHandler.MarkAppDeployed()
 
-	if s.config.AuthType == "oauth" {
-		if err := startActivityReporter(ctx, s.config, s.logger, s.activityTracker); err != nil {
+	if s.hubClient != nil {
+		if err := startActivityReporter(ctx, s.hubClient, s.config, s.logger, s.activityTracker); err != nil {
 			s.logger.Warn("failed to start activity reporter (continuing anyway)", "error", err)
 		}
 	}
 }
 
+// RestartSubprocess restarts the app the way the restart API does, showing reason in the logs
+// App URLs serve the interim page again until the app is back.
+func (s *Server) RestartSubprocess(reason string) error {
+	return s.logsHandler.RestartProcess(reason)
+}
+
+// scheme returns the URL scheme the proxy is served on
+func (s *Server) scheme() string {
+	if s.certReloader != nil {
+		return "https"
+	}
+	return "http"
+}
+
 // Shutdown performs graceful shutdown of the server and subprocess
 func (s *Server) Shutdown() {
 	s.logger.ShutdownBanner("shutting down")
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
 
-	if s.manager.IsRunning() {
+	// Also covers a subprocess still waiting for its ready check
+	if s.manager.IsAlive() {
 		s.logger.Info("stopping subprocess")
 		if err := s.manager.Stop(); err != nil {
 			s.logger.Error("failed to stop subprocess", err)
@@ -247,14 +484,37 @@ func (s *Server) Shutdown() {
 		s.logger.Error("proxy server shutdown error", err)
 	}
 
+	if s.auditLog != nil {
+		if err := s.auditLog.Close(); err != nil {
+			s.logger.Error("failed to close audit log", err)
+		}
+	}
+
 	s.logger.Info("shutdown complete")
 }
 
 // SetupSignalHandling configures signal handlers for graceful shutdown
+// SIGHUP runs the hooks registered with OnHangup (e.g. reopening the log file after rotation)
+// instead of terminating the process.
 func SetupSignalHandling(ctx context.Context, cancel context.CancelFunc, log *logger.Logger) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hupChan:
+				log.Info("received SIGHUP, reopening files")
+				runHangupHooks()
+			}
+		}
+	}()
+
 	go func() {
 		sig := <-sigChan
 		log.Info("received signal, initiating graceful shutdown (press Ctrl+C again to force quit)", "signal", sig)
@@ -266,6 +526,44 @@ func SetupSignalHandling(ctx context.Context, cancel context.CancelFunc, log *lo
 	}()
 }
 
+// hangupHooks run on SIGHUP once SetupSignalHandling is installed
+var (
+	hangupMu    sync.Mutex
+	hangupHooks = make(map[int]func())
+	nextHookID  int
+)
+
+// OnHangup registers fn to run on SIGHUP and returns a function that unregisters it
+// Hooks only run when SetupSignalHandling has been called; Run registers log file rotation
+// and Start the TLS certificate reload.
+func OnHangup(fn func()) (remove func()) {
+	hangupMu.Lock()
+	defer hangupMu.Unlock()
+
+	id := nextHookID
+	nextHookID++
+	hangupHooks[id] = fn
+	return func() {
+		hangupMu.Lock()
+		defer hangupMu.Unlock()
+		delete(hangupHooks, id)
+	}
+}
+
+// runHangupHooks calls every registered SIGHUP hook
+func runHangupHooks() {
+	hangupMu.Lock()
+	hooks := make([]func(), 0, len(hangupHooks))
+	for _, fn := range hangupHooks {
+		hooks = append(hooks, fn)
+	}
+	hangupMu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
 // GetServicePrefix retrieves and processes the JupyterHub service prefix from environment
 func GetServicePrefix(log *logger.Logger) string {
 	servicePrefix := os.Getenv("JUPYTERHUB_SERVICE_PREFIX")
@@ -276,22 +574,34 @@ func GetServicePrefix(log *logger.Logger) string {
 	return servicePrefix
 }
 
-// startActivityReporter starts the JupyterHub activity reporter
-func startActivityReporter(ctx context.Context, cfg *config.Config, log *logger.Logger, activityTracker *activity.Tracker) error {
-	hubClient, err := hub.NewClientFromEnv(log)
+// newHubClient creates the JupyterHub API client from the environment
+func newHubClient(cfg *config.Config, log *logger.Logger, appMetrics *metrics.Metrics) (*hub.Client, error) {
+	hubCfg := hub.ConfigFromEnv()
+	hubCfg.ConnectTimeout = time.Duration(cfg.HubConnectTimeout) * time.Second
+	hubCfg.SkipActivityIfCulled = cfg.SkipActivityIfCulled
+	if appMetrics != nil {
+		hubCfg.OnActivityReport = appMetrics.ObserveActivityReport
+	}
+	hubClient, err := hub.NewClient(hubCfg, log)
 	if err != nil {
-		return fmt.Errorf("failed to create hub client: %w", err)
+		return nil, fmt.Errorf("failed to create hub client: %w", err)
 	}
+	return hubClient, nil
+}
 
+// startActivityReporter starts the JupyterHub activity reporter
+func startActivityReporter(ctx context.Context, hubClient *hub.Client, cfg *config.Config, log *logger.Logger, activityTracker *activity.Tracker) error {
 	if err := hubClient.Ping(ctx); err != nil {
 		return fmt.Errorf("failed to ping hub: %w", err)
 	}
 
-	interval := 5 * time.Minute
-	_ = hubClient.StartActivityReporter(ctx, interval, cfg.KeepAlive, activityTracker)
+	interval := time.Duration(cfg.KeepAliveInterval) * time.Second
+	jitter := time.Duration(cfg.KeepAliveJitter) * time.Second
+	_ = hubClient.StartActivityReporter(ctx, interval, jitter, cfg.KeepAlive, activityTracker)
 
 	log.Info("activity reporter started",
 		"interval", interval,
+		"jitter", jitter,
 		"keep_alive", cfg.KeepAlive)
 
 	return nil
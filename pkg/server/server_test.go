@@ -0,0 +1,301 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/auth"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/config"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/interim"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+	"github.com/nebari-dev/jhub-app-proxy/pkg/process"
+)
+
+func TestServer_RecordsActivity(t *testing.T) {
+	t.Setenv("JUPYTERHUB_SERVICE_PREFIX", "")
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "app")
+	}))
+	defer backend.Close()
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+	}()
+
+	cfg := config.Default()
+	cfg.AuthType = "none"
+	srv, err := New(Config{
+		Manager:       mgr,
+		SubprocessURL: backend.URL,
+		AppConfig:     cfg,
+		Logger:        log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	get := func(t *testing.T, path, userAgent string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("User-Agent", userAgent)
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("interim page while starting", func(t *testing.T) {
+		get(t, "/", "Mozilla/5.0")
+		if last := srv.activityTracker.GetLastActivity(); last != nil {
+			t.Errorf("expected no activity before the app runs, got %v", last)
+		}
+	})
+
+	if err := mgr.Start(t.Context()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	var last time.Time
+	t.Run("proxied request", func(t *testing.T) {
+		if code := get(t, "/", "Mozilla/5.0"); code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+		}
+		recorded := srv.activityTracker.GetLastActivity()
+		if recorded == nil {
+			t.Fatal("expected activity to be recorded")
+		}
+		last = *recorded
+	})
+
+	t.Run("proxied request advances activity", func(t *testing.T) {
+		time.Sleep(10 * time.Millisecond)
+		get(t, "/page", "Mozilla/5.0")
+		if recorded := srv.activityTracker.GetLastActivity(); recorded == nil || !recorded.After(last) {
+			t.Fatalf("expected activity after %v, got %v", last, recorded)
+		}
+		last = *srv.activityTracker.GetLastActivity()
+	})
+
+	t.Run("health checks and logs API ignored", func(t *testing.T) {
+		time.Sleep(10 * time.Millisecond)
+		for _, ua := range []string{"kube-probe/1.30", "ELB-HealthChecker/2.0", "GoogleHC/1.0"} {
+			if code := get(t, "/", ua); code != http.StatusOK {
+				t.Errorf("expected probe %q to be proxied, got status %d", ua, code)
+			}
+		}
+		get(t, interim.InterimPath+"/api/logs", "Mozilla/5.0")
+
+		if recorded := srv.activityTracker.GetLastActivity(); !recorded.Equal(last) {
+			t.Errorf("expected activity to stay at %v, got %v", last, recorded)
+		}
+	})
+}
+
+func TestServer_WebSocketActivity(t *testing.T) {
+	t.Setenv("JUPYTERHUB_SERVICE_PREFIX", "")
+
+	upgrader := websocket.Upgrader{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			msgType, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(msgType, msg); err != nil {
+				return
+			}
+		}
+	}))
+	defer backend.Close()
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer func() {
+		_ = mgr.Stop()
+	}()
+	if err := mgr.Start(t.Context()); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.AuthType = "none"
+	srv, err := New(Config{
+		Manager:       mgr,
+		SubprocessURL: backend.URL,
+		AppConfig:     cfg,
+		Logger:        log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	proxy := httptest.NewServer(srv.router)
+	defer proxy.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(proxy.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("failed to dial WebSocket: %v", err)
+	}
+	defer conn.Close()
+
+	upgraded := srv.activityTracker.GetLastActivity()
+	if upgraded == nil {
+		t.Fatal("expected the upgrade request to record activity")
+	}
+
+	// Only the open connection is used from here on, as in a WebSocket app
+	last := *upgraded
+	for i := 0; i < 2; i++ {
+		time.Sleep(1100 * time.Millisecond)
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+			t.Fatalf("failed to write message: %v", err)
+		}
+		if _, _, err := conn.ReadMessage(); err != nil {
+			t.Fatalf("failed to read echo: %v", err)
+		}
+
+		recorded := srv.activityTracker.GetLastActivity()
+		if recorded.Sub(last) < time.Second {
+			t.Fatalf("expected WebSocket traffic to advance activity past %v, got %v", last, recorded)
+		}
+		last = *recorded
+	}
+}
+
+func TestSetupSignalHandling_Hangup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	SetupSignalHandling(ctx, cancel, logger.New(logger.Config{Output: io.Discard}))
+
+	called := make(chan struct{}, 1)
+	remove := OnHangup(func() { called <- struct{}{} })
+	defer remove()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("failed to send SIGHUP: %v", err)
+	}
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected SIGHUP to run the hangup hook")
+	}
+	if ctx.Err() != nil {
+		t.Error("expected SIGHUP not to cancel the context")
+	}
+}
+
+func TestServer_InterimTrailingSlash(t *testing.T) {
+	t.Setenv("JUPYTERHUB_SERVICE_PREFIX", "")
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.AuthType = "none"
+	srv, err := New(Config{
+		Manager:       mgr,
+		SubprocessURL: "http://127.0.0.1:1",
+		AppConfig:     cfg,
+		Logger:        log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	exact := get(interim.InterimPath)
+	slash := get(interim.InterimPath + "/")
+	if exact.Code != http.StatusOK {
+		t.Fatalf("expected status %d for %s, got %d", http.StatusOK, interim.InterimPath, exact.Code)
+	}
+	if slash.Code != exact.Code {
+		t.Errorf("expected status %d with trailing slash, got %d", exact.Code, slash.Code)
+	}
+	// Each response carries its own CSP nonce
+	nonce := regexp.MustCompile(`nonce="[^"]*"`)
+	if nonce.ReplaceAllString(slash.Body.String(), "") != nonce.ReplaceAllString(exact.Body.String(), "") {
+		t.Error("expected the same interim page with and without trailing slash")
+	}
+}
+
+func TestServer_Logout(t *testing.T) {
+	t.Setenv("JUPYTERHUB_SERVICE_PREFIX", "/user/alice/app/")
+	t.Setenv("JUPYTERHUB_API_URL", "http://hub.invalid/hub/api")
+	t.Setenv("JUPYTERHUB_API_TOKEN", "service-token")
+	t.Setenv("JUPYTERHUB_CLIENT_ID", "service-app")
+	t.Setenv("JUPYTERHUB_BASE_URL", "/")
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	mgr, err := process.NewManagerWithLogs(process.Config{
+		Command: []string{"sleep", "30"},
+	}, process.LogCaptureConfig{Enabled: true, BufferSize: 10}, log)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.AuthType = "oauth"
+	srv, err := New(Config{
+		Manager:       mgr,
+		SubprocessURL: "http://127.0.0.1:1",
+		AppConfig:     cfg,
+		Logger:        log,
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	// No valid session is needed to log out
+	req := httptest.NewRequest(http.MethodGet, "/user/alice/app"+interim.InterimPath+auth.LogoutPath, nil)
+	req.AddCookie(&http.Cookie{Name: "service-app", Value: "stale-token"})
+	rec := httptest.NewRecorder()
+	srv.router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/hub/home" {
+		t.Fatalf("expected a redirect to the Hub home page, got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	var cleared bool
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == "service-app" && cookie.MaxAge < 0 {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Error("expected the token cookie to be expired")
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+// CertReloader serves a TLS certificate loaded from disk and picks up rotated files
+// (e.g. cert-manager renewals) without restarting the proxy.
+//
+// The certificate is re-read on a handshake whenever the cert or key file's
+// modification time changes, and can be forced with SIGHUP via ReloadOnHangup.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	logger   *logger.Logger
+
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// NewCertReloader loads the certificate and key and returns a reloader for them
+// Returns an error if the initial load fails so misconfiguration is caught at startup
+func NewCertReloader(certFile, keyFile string, log *logger.Logger) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   log.WithComponent("tls"),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload unconditionally re-reads the certificate and key from disk
+// On failure the previously loaded certificate stays in use
+func (r *CertReloader) Reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("failed to stat TLS certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to stat TLS key: %w", err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	r.mu.Unlock()
+
+	r.logger.Info("TLS certificate loaded",
+		"cert_file", r.certFile,
+		"key_file", r.keyFile)
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+// Reloads the certificate first if either file changed on disk since the last load
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if r.changedOnDisk() {
+		if err := r.Reload(); err != nil {
+			// Keep serving the old certificate - a half-written renewal must not break TLS
+			r.logger.Warn("failed to reload TLS certificate, keeping current one", "error", err)
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// ReloadOnHangup forces a certificate reload on SIGHUP, through OnHangup, until ctx is cancelled
+func (r *CertReloader) ReloadOnHangup(ctx context.Context) {
+	remove := OnHangup(func() {
+		r.logger.Info("reloading TLS certificate")
+		if err := r.Reload(); err != nil {
+			r.logger.Error("failed to reload TLS certificate", err)
+		}
+	})
+	go func() {
+		<-ctx.Done()
+		remove()
+	}()
+}
+
+// changedOnDisk reports whether the cert or key file modification time differs from the loaded one
+func (r *CertReloader) changedOnDisk() bool {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !certInfo.ModTime().Equal(r.certModTime) || !keyInfo.ModTime().Equal(r.keyModTime)
+}
+
+// tlsVersions maps --tls-min-version values to crypto/tls versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion converts a --tls-min-version value ("1.0" to "1.3") to a crypto/tls version
+// An empty value means TLS 1.2, the flag default.
+func ParseTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return tls.VersionTLS12, nil
+	}
+	v, ok := tlsVersions[version]
+	if !ok {
+		return 0, fmt.Errorf("invalid --tls-min-version %q: expected 1.0, 1.1, 1.2 or 1.3", version)
+	}
+	return v, nil
+}
+
+// ParseCipherSuites converts --tls-cipher-suites names (e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)
+// to crypto/tls IDs. Suites Go considers insecure are rejected. Returns nil for no names,
+// leaving Go's default selection in place.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	insecure := make(map[string]bool)
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = true
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if insecure[name] {
+			return nil, fmt.Errorf("invalid --tls-cipher-suites: %s is insecure", name)
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid --tls-cipher-suites: unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// newTLSConfig builds the TLS listener configuration from --tls-min-version and --tls-cipher-suites
+// Cipher suites only apply up to TLS 1.2; TLS 1.3 suites are not configurable in Go.
+func newTLSConfig(minVersion string, cipherSuites []string, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) (*tls.Config, error) {
+	version, err := ParseTLSVersion(minVersion)
+	if err != nil {
+		return nil, err
+	}
+	suites, err := ParseCipherSuites(cipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		GetCertificate: getCertificate,
+		MinVersion:     version,
+		CipherSuites:   suites,
+	}, nil
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nebari-dev/jhub-app-proxy/pkg/logger"
+)
+
+// writeSelfSignedCert writes a self-signed certificate with the given common name
+// to certFile/keyFile and bumps their modification time to mtime
+func writeSelfSignedCert(t *testing.T, certFile, keyFile, commonName string, mtime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatalf("failed to write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, mtime, mtime); err != nil {
+			t.Fatalf("failed to set mtime: %v", err)
+		}
+	}
+}
+
+// peerCommonName connects to addr and returns the common name of the served certificate
+func peerCommonName(t *testing.T, addr string) string {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("TLS dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestCertReloader_PicksUpRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	start := time.Now().Add(-time.Minute)
+
+	writeSelfSignedCert(t, certFile, keyFile, "original", start)
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	reloader, err := NewCertReloader(certFile, keyFile, log)
+	if err != nil {
+		t.Fatalf("failed to create reloader: %v", err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: reloader.GetCertificate})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	addr := listener.Addr().String()
+	if got := peerCommonName(t, addr); got != "original" {
+		t.Fatalf("expected original certificate, got %q", got)
+	}
+
+	// Rotate the certificate on disk
+	writeSelfSignedCert(t, certFile, keyFile, "rotated", start.Add(30*time.Second))
+
+	if got := peerCommonName(t, addr); got != "rotated" {
+		t.Errorf("expected rotated certificate on new connection, got %q", got)
+	}
+
+	// A broken renewal keeps the last good certificate
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("failed to corrupt cert: %v", err)
+	}
+	if got := peerCommonName(t, addr); got != "rotated" {
+		t.Errorf("expected previous certificate after failed reload, got %q", got)
+	}
+}
+
+func TestCertReloader_ReloadOnHangup(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	mtime := time.Now().Add(-time.Minute)
+	writeSelfSignedCert(t, certFile, keyFile, "original", mtime)
+
+	reloader, err := NewCertReloader(certFile, keyFile, logger.New(logger.Config{Output: io.Discard}))
+	if err != nil {
+		t.Fatalf("failed to create reloader: %v", err)
+	}
+	hookCount := func() int {
+		hangupMu.Lock()
+		defer hangupMu.Unlock()
+		return len(hangupHooks)
+	}
+	before := hookCount()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloader.ReloadOnHangup(ctx)
+
+	// Same modification time, so only the hangup hook can pick up the new certificate
+	writeSelfSignedCert(t, certFile, keyFile, "rotated", mtime)
+	runHangupHooks()
+
+	cert, err := reloader.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("failed to get certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	if leaf.Subject.CommonName != "rotated" {
+		t.Errorf("expected the hangup hook to reload the certificate, got %q", leaf.Subject.CommonName)
+	}
+
+	// The hook is removed once ctx is cancelled
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for hookCount() != before {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the hangup hook to be removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewCertReloader_MissingFiles(t *testing.T) {
+	log := logger.New(logger.Config{Output: io.Discard})
+	if _, err := NewCertReloader("/nonexistent/tls.crt", "/nonexistent/tls.key", log); err == nil {
+		t.Error("expected error for missing certificate files")
+	}
+}
+
+func TestNewTLSConfig_MinVersion(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, "proxy", time.Now())
+
+	log := logger.New(logger.Config{Output: io.Discard})
+	reloader, err := NewCertReloader(certFile, keyFile, log)
+	if err != nil {
+		t.Fatalf("failed to create reloader: %v", err)
+	}
+	tlsConfig, err := newTLSConfig("1.2", nil, reloader.GetCertificate)
+	if err != nil {
+		t.Fatalf("failed to build TLS config: %v", err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	dial := func(version uint16) error {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+			InsecureSkipVerify: true,
+			MinVersion:         version,
+			MaxVersion:         version,
+		})
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	if err := dial(tls.VersionTLS10); err == nil {
+		t.Error("expected TLS 1.0 handshake to be rejected")
+	}
+	if err := dial(tls.VersionTLS12); err != nil {
+		t.Errorf("expected TLS 1.2 handshake to succeed, got %v", err)
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  uint16
+		expectErr bool
+	}{
+		{input: "", expected: tls.VersionTLS12},
+		{input: "1.0", expected: tls.VersionTLS10},
+		{input: "1.3", expected: tls.VersionTLS13},
+		{input: "TLS1.2", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTLSVersion(tt.input)
+		if tt.expectErr {
+			if err == nil {
+				t.Errorf("expected error for %q, got %x", tt.input, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("expected %x for %q, got %x (err %v)", tt.expected, tt.input, got, err)
+		}
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	ids, err := ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", " TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}
+	if len(ids) != len(expected) || ids[0] != expected[0] || ids[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, ids)
+	}
+
+	if _, err := ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"}); err == nil {
+		t.Error("expected insecure cipher suite to be rejected")
+	}
+	if _, err := ParseCipherSuites([]string{"TLS_MADE_UP"}); err == nil {
+		t.Error("expected unknown cipher suite to be rejected")
+	}
+}
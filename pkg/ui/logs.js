@@ -6,10 +6,21 @@ const versionText = document.getElementById('versionText');
 const logo = document.getElementById('logo');
 const autoScrollToggle = document.getElementById('autoScrollToggle');
 const elapsedTime = document.getElementById('elapsedTime');
+const startupPhase = document.getElementById('startupPhase');
+
+// Labels for the in-progress startup phases reported by the stats API
+const startupPhaseLabels = {
+    initializing: 'Initializing',
+    cloning: 'Cloning repository',
+    activating_conda: 'Activating conda environment',
+    starting_process: 'Starting application',
+    waiting_for_health: 'Waiting for application to respond',
+};
 
 let isReady = false;
 let lastLogCount = 0;
 let authErrorShown = false;
+let failureLogsShown = false;
 let logoLoaded = false;
 
 // Get basePath from the global scope (set in HTML head)
@@ -19,6 +30,24 @@ const basePath = window.basePath || '/_temp/jhub-app-proxy';
 // API base is basePath + /api
 const apiBase = basePath + '/api';
 
+// Branding injected by the server (--brand-title, --brand-primary-color)
+// The logo itself is served from /static/logo.png, which redirects to --brand-logo-url
+function applyBranding() {
+    const meta = (name) => {
+        const tag = document.querySelector(`meta[name="${name}"]`);
+        return tag ? tag.getAttribute('content') : '';
+    };
+    const brandTitle = meta('brand-title');
+    if (brandTitle) {
+        logo.alt = brandTitle + ' Logo';
+    }
+    const primaryColor = meta('brand-primary-color');
+    if (primaryColor) {
+        document.documentElement.style.setProperty('--brand-primary', primaryColor);
+    }
+}
+applyBranding();
+
 // Auto-scroll state (default: true)
 let autoScrollEnabled = localStorage.getItem('autoScroll') !== 'false';
 
@@ -147,6 +176,14 @@ async function checkAppStatus() {
         if (data.process_state) {
             const state = data.process_state.state;
 
+            // Show which startup step we're in; hidden once ready or failed
+            const phase = data.process_state.startup_phase;
+            if (phase && startupPhaseLabels[phase]) {
+                startupPhase.textContent = startupPhaseLabels[phase] + '...';
+            } else {
+                startupPhase.textContent = '';
+            }
+
             // Update elapsed time (show even if uptime is 0)
             if (data.process_state.uptime !== undefined) {
                 elapsedTime.textContent = formatElapsedTime(data.process_state.uptime);
@@ -175,6 +212,11 @@ async function checkAppStatus() {
                     }
                 }
 
+                // The logs API stays up until the grace period expires; show the final logs while it does
+                if (data.grace_period_active && Date.parse(data.grace_period_expires_at) > Date.now()) {
+                    await loadAllLogs();
+                }
+
                 console.log('Redirecting to app:', appRoot);
 
                 // Redirect to application root
@@ -182,9 +224,18 @@ async function checkAppStatus() {
                     window.location.href = appRoot;
                 }, 500); // Small delay to show "redirecting..." message
             } else if (state === 'failed') {
-                title.innerHTML = 'Your app failed to deploy, please fix your mistakes!';
+                if (data.process_state.message) {
+                    // e.g. crash loop detected - explain why the app is no longer restarted
+                    title.textContent = 'Your app failed to deploy: ' + data.process_state.message;
+                } else {
+                    title.innerHTML = 'Your app failed to deploy, please fix your mistakes!';
+                }
                 title.classList.add('error');
                 progressContainer.classList.add('hidden');
+                if (!failureLogsShown) {
+                    failureLogsShown = true;
+                    await showFailureLogs();
+                }
             }
         }
     } catch (err) {
@@ -192,6 +243,33 @@ async function checkAppStatus() {
     }
 }
 
+// Replace the log view with the tail of the output once the app has failed
+// Without lines, the logs API returns the last --failure-log-lines lines of a failed app,
+// which is what explains the failure; the whole log is still in /api/logs/all
+async function showFailureLogs() {
+    try {
+        const response = await fetch(apiBase + '/logs');
+        if (!response.ok) {
+            return;
+        }
+        const contentType = response.headers.get('content-type');
+        if (!contentType || !contentType.includes('application/json')) {
+            return;
+        }
+
+        const data = await response.json();
+        if (data.logs && data.logs.length > 0) {
+            logsContainer.innerHTML = '';
+            data.logs.forEach(log => {
+                addLog(log.stream, log.line);
+            });
+            lastLogCount = data.stats.total_lines;
+        }
+    } catch (err) {
+        console.error('Failed to load failure logs:', err);
+    }
+}
+
 let isInitialLoad = true;
 async function loadAllLogs() {
     try {
@@ -286,6 +364,68 @@ async function fetchRecentLogs() {
     }
 }
 
+// Stream new log lines over a WebSocket as they are captured
+// Falls back to polling when the upgrade fails (e.g. a proxy in between without WebSocket support)
+function streamLogs() {
+    const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
+    let opened = false;
+    let socket;
+
+    try {
+        socket = new WebSocket(`${protocol}//${window.location.host}${apiBase}/logs/stream`);
+    } catch (err) {
+        console.warn('Log streaming unavailable, falling back to polling:', err);
+        startPolling();
+        return;
+    }
+
+    socket.onopen = () => {
+        opened = true;
+    };
+    socket.onmessage = (event) => {
+        const entry = JSON.parse(event.data);
+        addLog(entry.stream, entry.line);
+    };
+    socket.onclose = () => {
+        // Closed after opening means the process exited; checkAppStatus reports that
+        if (!opened) {
+            console.warn('Log streaming unavailable, falling back to polling');
+            startPolling();
+        }
+    };
+}
+
+function startPolling() {
+    setInterval(fetchRecentLogs, 1000);
+}
+
+// Watch process state changes pushed by the server
+// Checks the status right away on a change instead of waiting for the next poll. Polling
+// keeps going until the redirect happens, since that check can fail (network error, 429
+// from the rate limiter, expired login) and would otherwise leave the page waiting forever
+function watchState() {
+    if (!window.EventSource) {
+        return;
+    }
+
+    const source = new EventSource(apiBase + '/logs/state');
+    source.addEventListener('state', (event) => {
+        const change = JSON.parse(event.data);
+        if (change.state === 'running') {
+            source.close();
+            checkAppStatus();
+        } else if (change.state === 'failed') {
+            checkAppStatus();
+        }
+    });
+    // EventSource reconnects by itself; polling covers the gaps
+    source.onerror = () => {
+        if (isReady) {
+            source.close();
+        }
+    };
+}
+
 // Copy functionality
 function copyToClipboard(text, button) {
     navigator.clipboard.writeText(text).then(() => {
@@ -324,7 +464,6 @@ document.getElementById('copyLogs').addEventListener('click', function() {
 // Initial calls
 loadLogo();
 checkAppStatus();
-loadAllLogs().then(() => {
-    setInterval(fetchRecentLogs, 1000);
-});
+loadAllLogs().then(streamLogs);
 setInterval(checkAppStatus, 2000);
+watchState();
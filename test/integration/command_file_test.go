@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCommandFile verifies that --command-file starts the app and that editing the file
+// mid-run restarts the app with the new command and env
+func TestCommandFile(t *testing.T) {
+	proxyPort := getFreePort(t)
+	destPort := getFreePort(t)
+	binaryPath := buildBinary(t)
+
+	workDir := t.TempDir()
+	commandFile := filepath.Join(t.TempDir(), "command.json")
+	writeCommandFile := func(name string, extraArgs ...string) {
+		t.Helper()
+		script := "echo $APP_NAME > which.txt; exec python3 -m http.server {port}" + strings.Join(extraArgs, " ")
+		content, err := json.Marshal(map[string]interface{}{
+			"command": []string{"sh", "-c", script},
+			"env":     map[string]string{"APP_NAME": name},
+		})
+		if err != nil {
+			t.Fatalf("Failed to encode command file: %v", err)
+		}
+		// Written aside and renamed so the proxy never reads a half-written file
+		tmp := commandFile + ".tmp"
+		if err := os.WriteFile(tmp, content, 0o644); err != nil {
+			t.Fatalf("Failed to write command file: %v", err)
+		}
+		if err := os.Rename(tmp, commandFile); err != nil {
+			t.Fatalf("Failed to replace command file: %v", err)
+		}
+	}
+	writeCommandFile("first")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath,
+		"--port", fmt.Sprintf("%d", proxyPort),
+		"--destport", fmt.Sprintf("%d", destPort),
+		"--authtype", "none",
+		"--workdir", workDir,
+		"--command-file", commandFile,
+		"--command-file-poll", "1",
+		"--log-format", "pretty",
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start jhub-app-proxy: %v", err)
+	}
+	defer func() {
+		if cmd.Process != nil {
+			if err := cmd.Process.Kill(); err != nil {
+				t.Logf("Failed to kill process: %v", err)
+			}
+		}
+	}()
+
+	proxyURL := fmt.Sprintf("http://127.0.0.1:%d", proxyPort)
+
+	// waitForApp polls the app until it says which command file started it
+	waitForApp := func(want string) {
+		t.Helper()
+		var last string
+		deadline := time.Now().Add(20 * time.Second)
+		for time.Now().Before(deadline) {
+			resp, err := http.Get(proxyURL + "/which.txt")
+			if err == nil {
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				last = strings.TrimSpace(string(body))
+				if resp.StatusCode == http.StatusOK && last == want {
+					return
+				}
+			}
+			time.Sleep(200 * time.Millisecond)
+		}
+		t.Fatalf("Expected the app started by command file %q, last response %q", want, last)
+	}
+
+	waitForApp("first")
+
+	writeCommandFile("second", " --bind 127.0.0.1")
+	waitForApp("second")
+
+	resp, err := http.Get(proxyURL + interimPath + "/api/logs/stats")
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	defer resp.Body.Close()
+	var stats struct {
+		ProcessInfo struct {
+			UserCommand []string `json:"user_command"`
+		} `json:"process_info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if got := strings.Join(stats.ProcessInfo.UserCommand, " "); !strings.Contains(got, "--bind 127.0.0.1") {
+		t.Errorf("Expected the stats API to report the new command, got %q", got)
+	}
+}
@@ -0,0 +1,41 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// TestFailOnMissingCondaEnv verifies that startup fails fast when the conda
+// environment is missing and --fail-on-missing-conda-env is set
+func TestFailOnMissingCondaEnv(t *testing.T) {
+	binaryPath := buildBinary(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath,
+		"--port", fmt.Sprintf("%d", getFreePort(t)),
+		"--authtype", "none",
+		"--conda-env", "jhub-app-proxy-test-env-that-does-not-exist",
+		"--fail-on-missing-conda-env",
+		"--",
+		"python3", "-m", "http.server", "{port}",
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		t.Fatalf("jhub-app-proxy did not exit, expected startup failure")
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() == 0 {
+		t.Fatalf("expected non-zero exit code, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowGitScript is a fake git that takes a while to "clone" so we can observe
+// the proxy while the clone is still in progress
+// Only the clone is slow; the checks run after it answer like a real shallow clone.
+const slowGitScript = `#!/bin/sh
+case "$1" in
+clone)
+	for dest; do :; done
+	echo "Cloning into '$dest'..."
+	sleep 4
+	mkdir -p "$dest/.git"
+	echo "done."
+	;;
+rev-parse)
+	if [ "$2" = "--is-shallow-repository" ]; then
+		echo true
+	else
+		echo 0123456789abcdef0123456789abcdef01234567
+	fi
+	;;
+esac
+exit 0
+`
+
+// TestInterimPageDuringSlowClone verifies that the proxy serves the interim page
+// and surfaces clone progress while a slow git clone is still running
+func TestInterimPageDuringSlowClone(t *testing.T) {
+	proxyPort := getFreePort(t)
+	destPort := getFreePort(t)
+
+	binaryPath := buildBinary(t)
+
+	// Install a fake git in front of PATH
+	binDir := t.TempDir()
+	gitPath := filepath.Join(binDir, "git")
+	if err := os.WriteFile(gitPath, []byte(slowGitScript), 0755); err != nil {
+		t.Fatalf("Failed to write fake git: %v", err)
+	}
+	repoDir := filepath.Join(t.TempDir(), "repo")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath,
+		"--port", fmt.Sprintf("%d", proxyPort),
+		"--destport", fmt.Sprintf("%d", destPort),
+		"--authtype", "none",
+		"--log-format", "pretty",
+		"--repo", "https://example.com/slow.git",
+		"--repofolder", repoDir,
+		"--repo-clone-timeout", "20",
+		"--",
+		"python3", "-m", "http.server", "{port}",
+	)
+	cmd.Env = append(os.Environ(), "PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start jhub-app-proxy: %v", err)
+	}
+	defer func() {
+		if cmd.Process != nil {
+			if err := cmd.Process.Kill(); err != nil {
+				t.Logf("Failed to kill process: %v", err)
+			}
+		}
+	}()
+
+	proxyURL := fmt.Sprintf("http://127.0.0.1:%d", proxyPort)
+
+	// The proxy must come up well before the clone finishes
+	if err := waitForHTTP(proxyURL, 2*time.Second); err != nil {
+		t.Fatalf("Proxy was not reachable during clone: %v", err)
+	}
+
+	t.Run("InterimPageServedDuringClone", func(t *testing.T) {
+		resp, err := http.Get(proxyURL + interimPath)
+		if err != nil {
+			t.Fatalf("Failed to get interim page: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("CloneProgressInLogs", func(t *testing.T) {
+		resp, err := http.Get(proxyURL + interimPath + "/api/logs")
+		if err != nil {
+			t.Fatalf("Failed to get logs: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var result struct {
+			Logs []struct {
+				Line string `json:"line"`
+			} `json:"logs"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode logs: %v", err)
+		}
+
+		found := false
+		for _, entry := range result.Logs {
+			if strings.Contains(entry.Line, "Cloning") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected clone progress in logs, got %+v", result.Logs)
+		}
+	})
+
+	t.Run("AppStartsAfterClone", func(t *testing.T) {
+		if err := waitForAppReady(proxyURL, 15*time.Second); err != nil {
+			t.Fatalf("App did not become ready after clone: %v", err)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// buildGRPCEchoServer builds the gRPC echo server and returns its path
+func buildGRPCEchoServer(t *testing.T) string {
+	testDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	binaryPath := filepath.Join(testDir, "testdata", "grpc-echo")
+
+	cmd := exec.Command("go", "build", "-o", binaryPath, "./testdata/grpc_server.go")
+	cmd.Dir = testDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build grpc-echo server: %v", err)
+	}
+
+	t.Cleanup(func() {
+		os.Remove(binaryPath)
+	})
+
+	return binaryPath
+}
+
+// TestGRPCProxy verifies that --grpc proxies a unary gRPC call end-to-end over HTTP/2
+// cleartext, including the grpc-status trailer, even though the method path carries no
+// service prefix
+func TestGRPCProxy(t *testing.T) {
+	proxyPort := getFreePort(t)
+	destPort := getFreePort(t)
+	binaryPath := buildBinary(t)
+	grpcEchoPath := buildGRPCEchoServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath,
+		"--port", fmt.Sprintf("%d", proxyPort),
+		"--destport", fmt.Sprintf("%d", destPort),
+		"--authtype", "none",
+		"--grpc",
+		// The backend only speaks HTTP/2, so the HTTP/1.1 ready check can't reach it
+		"--ready-check-type", "tcp",
+		"--log-format", "pretty",
+		"--",
+		grpcEchoPath, "-port", "{port}",
+	)
+	cmd.Env = append(os.Environ(), "JUPYTERHUB_SERVICE_PREFIX=/user/testuser/grpc/")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start jhub-app-proxy: %v", err)
+	}
+	defer func() {
+		if cmd.Process != nil {
+			if err := cmd.Process.Kill(); err != nil {
+				t.Logf("Failed to kill process: %v", err)
+			}
+		}
+	}()
+
+	// A gRPC client: HTTP/2 with prior knowledge, no TLS
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{
+		Timeout:   2 * time.Second,
+		Transport: &http.Transport{Protocols: protocols},
+	}
+
+	message := []byte("\x0a\x05hello") // protobuf: field 1, string "hello"
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	frame = append(frame, message...)
+
+	// call makes the unary call, returning the response once the app is up
+	call := func() (*http.Response, []byte, error) {
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://127.0.0.1:%d/echo.Echo/Say", proxyPort), bytes.NewReader(frame))
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("TE", "trailers")
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body) // Trailers are only filled in once the body is read
+		return resp, body, err
+	}
+
+	var (
+		resp *http.Response
+		body []byte
+		err  error
+	)
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		resp, body, err = call()
+		if err == nil && resp.StatusCode == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("gRPC call did not succeed through the proxy (last error: %v, response: %+v)", err, resp)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2 from the proxy, got %s", resp.Proto)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/grpc" {
+		t.Errorf("Expected Content-Type application/grpc, got %q", ct)
+	}
+	if !bytes.Equal(body, frame) {
+		t.Errorf("Expected the echoed message %q, got %q", frame, body)
+	}
+	if status := resp.Trailer.Get("Grpc-Status"); status != "0" {
+		t.Errorf("Expected trailer grpc-status 0, got %q (trailers: %v)", status, resp.Trailer)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// A minimal gRPC server with one unary method, /echo.Echo/Say, returning the request message
+// Speaks only HTTP/2 cleartext like a real gRPC server, and frames messages by hand so the
+// test needs no gRPC dependency: each message is a compressed flag byte, a 4-byte
+// big-endian length and the (here opaque) protobuf bytes.
+func main() {
+	port := flag.Int("port", 8080, "Port to listen on")
+	flag.Parse()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo.Echo/Say", func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.Header.Get("Content-Type") != "application/grpc" {
+			http.Error(w, "expected a gRPC call over HTTP/2", http.StatusBadRequest)
+			return
+		}
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(r.Body, header); err != nil {
+			http.Error(w, "missing message", http.StatusBadRequest)
+			return
+		}
+		message := make([]byte, binary.BigEndian.Uint32(header[1:]))
+		if _, err := io.ReadFull(r.Body, message); err != nil {
+			http.Error(w, "truncated message", http.StatusBadRequest)
+			return
+		}
+
+		// Trailers are not announced up front, just like grpc-go
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(header)
+		_, _ = w.Write(message)
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", "")
+	})
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	server := &http.Server{
+		Addr:      fmt.Sprintf("127.0.0.1:%d", *port),
+		Handler:   mux,
+		Protocols: protocols,
+	}
+	log.Printf("gRPC echo server listening on %s", server.Addr)
+	log.Fatal(server.ListenAndServe())
+}
@@ -0,0 +1,170 @@
+package integration
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 with the given common name
+func writeTestCert(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+}
+
+// TestTLSTermination verifies the proxy serves HTTPS with --tls-cert/--tls-key
+// and reloads the certificate on SIGHUP
+func TestTLSTermination(t *testing.T) {
+	proxyPort := getFreePort(t)
+	destPort := getFreePort(t)
+
+	certDir := t.TempDir()
+	certFile := filepath.Join(certDir, "tls.crt")
+	keyFile := filepath.Join(certDir, "tls.key")
+	writeTestCert(t, certFile, keyFile, "first")
+
+	binaryPath := buildBinary(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath,
+		"--port", fmt.Sprintf("%d", proxyPort),
+		"--destport", fmt.Sprintf("%d", destPort),
+		"--authtype", "none",
+		"--tls-cert", certFile,
+		"--tls-key", keyFile,
+		"--log-format", "pretty",
+		"--",
+		"python3", "-m", "http.server", "{port}",
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start jhub-app-proxy: %v", err)
+	}
+	defer func() {
+		if cmd.Process != nil {
+			if err := cmd.Process.Kill(); err != nil {
+				t.Logf("Failed to kill process: %v", err)
+			}
+		}
+	}()
+
+	proxyURL := fmt.Sprintf("https://127.0.0.1:%d", proxyPort)
+
+	// A fresh connection per request so each one sees the currently served certificate
+	newClient := func() *http.Client {
+		return &http.Client{
+			Timeout: 2 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+				DisableKeepAlives: true,
+			},
+		}
+	}
+
+	// get polls the proxy until the response body contains want and returns the response
+	get := func(t *testing.T, want string) *http.Response {
+		t.Helper()
+		deadline := time.Now().Add(15 * time.Second)
+		for {
+			resp, err := newClient().Get(proxyURL + "/")
+			if err == nil {
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode == http.StatusOK && strings.Contains(string(body), want) {
+					return resp
+				}
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Did not get %q over HTTPS (last error: %v)", want, err)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	t.Run("ServesHTTPS", func(t *testing.T) {
+		resp := get(t, "Directory listing")
+		if resp.TLS == nil {
+			t.Fatal("Expected a TLS connection")
+		}
+		if cn := resp.TLS.PeerCertificates[0].Subject.CommonName; cn != "first" {
+			t.Errorf("Expected certificate CN %q, got %q", "first", cn)
+		}
+	})
+
+	t.Run("RejectsPlainHTTP", func(t *testing.T) {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/", proxyPort))
+		if err == nil {
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected plain HTTP to be rejected, got status %d", resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("ReloadsOnSIGHUP", func(t *testing.T) {
+		writeTestCert(t, certFile, keyFile, "second")
+		if err := cmd.Process.Signal(syscall.SIGHUP); err != nil {
+			t.Fatalf("Failed to send SIGHUP: %v", err)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			resp := get(t, "Directory listing")
+			if resp.TLS.PeerCertificates[0].Subject.CommonName == "second" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Certificate was not reloaded after SIGHUP")
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	})
+}
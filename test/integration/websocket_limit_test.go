@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestWebSocketMaxMessageSize verifies that --ws-max-message-size closes proxied WebSocket
+// connections whose client sends an oversized message, with close code 1009
+func TestWebSocketMaxMessageSize(t *testing.T) {
+	const limit = 1024
+
+	proxyPort := getFreePort(t)
+	destPort := getFreePort(t)
+	binaryPath := buildBinary(t)
+	wsEchoPath := buildWebSocketEchoServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath,
+		"--port", fmt.Sprintf("%d", proxyPort),
+		"--destport", fmt.Sprintf("%d", destPort),
+		"--authtype", "none",
+		"--ws-max-message-size", fmt.Sprintf("%d", limit),
+		"--log-format", "pretty",
+		"--",
+		wsEchoPath, "-port", "{port}",
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start jhub-app-proxy: %v", err)
+	}
+	defer func() {
+		if cmd.Process != nil {
+			if err := cmd.Process.Kill(); err != nil {
+				t.Logf("Failed to kill process: %v", err)
+			}
+		}
+	}()
+
+	proxyURL := fmt.Sprintf("http://127.0.0.1:%d", proxyPort)
+	if err := waitForAppReady(proxyURL, 15*time.Second); err != nil {
+		t.Fatalf("App did not become ready: %v", err)
+	}
+
+	wsURL := "ws://" + strings.TrimPrefix(proxyURL, "http://") + "/"
+	dialer := websocket.Dialer{HandshakeTimeout: 2 * time.Second}
+
+	t.Run("SmallMessageEchoed", func(t *testing.T) {
+		conn, _, err := dialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatalf("Failed to dial WebSocket: %v", err)
+		}
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("hello")); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read echo: %v", err)
+		}
+		if string(message) != "hello" {
+			t.Errorf("Expected echo %q, got %q", "hello", message)
+		}
+	})
+
+	t.Run("OversizedMessageClosesConnection", func(t *testing.T) {
+		conn, _, err := dialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatalf("Failed to dial WebSocket: %v", err)
+		}
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", limit+1))); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+		_, message, err := conn.ReadMessage()
+		if err == nil {
+			t.Fatalf("Expected the connection to be closed, got echo of %d bytes", len(message))
+		}
+		if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+			t.Errorf("Expected close code %d, got %v", websocket.CloseMessageTooBig, err)
+		}
+	})
+}